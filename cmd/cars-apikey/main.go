@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"effective_mobile_test/internal/config"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage/postgres"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+const usage = `usage:
+	cars-apikey create -name NAME [-role viewer|editor|admin] [-tenant TENANT | -multi-tenant]
+	cars-apikey list
+	cars-apikey set-role -id ID -role viewer|editor|admin
+	cars-apikey revoke -id ID`
+
+// cars-apikey manages the API keys clients authenticate with. A key is printed once,
+// when it is created; only its hash is stored.
+//
+//	CONFIG_PATH=.env cars-apikey create -name billing -role editor -tenant acme
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+	flags := flag.NewFlagSet(cmd, flag.ExitOnError)
+	name := flags.String("name", "", "name of the key, recorded as the actor of the changes made with it")
+	role := flags.String("role", auth.RoleViewer, "role of the key: viewer, editor or admin")
+	keyTenant := flags.String("tenant", "", "tenant the key is bound to; without one the key works in the default tenant")
+	multiTenant := flags.Bool("multi-tenant", false, "let a key without a tenant choose it with the X-Tenant-ID header")
+	id := flags.Int("id", 0, "id of the key")
+	_ = flags.Parse(args)
+
+	cfg := config.InitConfig()
+
+	storage, err := postgres.New(cfg.Storage)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to init storage:", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	switch cmd {
+	case "create":
+		err = create(ctx, storage, *name, *role, *keyTenant, *multiTenant)
+	case "list":
+		err = list(ctx, storage)
+	case "set-role":
+		err = setRole(ctx, storage, *id, *role)
+	case "revoke":
+		err = revoke(ctx, storage, *id)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func create(ctx context.Context, storage *postgres.Storage, name string, role string, keyTenant string, multiTenant bool) error {
+	if name == "" {
+		return fmt.Errorf("-name is required")
+	}
+	if !auth.ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	if keyTenant != "" && !tenant.Valid(keyTenant) {
+		return fmt.Errorf("invalid tenant %q", keyTenant)
+	}
+	if keyTenant != "" && multiTenant {
+		return fmt.Errorf("-tenant and -multi-tenant can't be combined")
+	}
+
+	key, err := auth.GenerateAPIKey()
+	if err != nil {
+		return fmt.Errorf("failed to generate api key: %w", err)
+	}
+
+	id, err := storage.SaveAPIKey(ctx, name, role, &keyTenant, multiTenant, auth.HashAPIKey(key))
+	if err != nil {
+		return fmt.Errorf("failed to save api key: %w", err)
+	}
+
+	fmt.Printf("%s api key %d created for %s, it won't be shown again:\n%s\n", role, id, name, key)
+
+	return nil
+}
+
+func list(ctx context.Context, storage *postgres.Storage) error {
+	keys, err := storage.GetAPIKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get api keys: %w", err)
+	}
+
+	for _, k := range keys {
+		revoked := ""
+		if k.RevokedAt != nil {
+			revoked = "revoked " + k.RevokedAt.Format(time.RFC3339)
+		}
+		principal := auth.Principal{Role: k.Role, MultiTenant: k.MultiTenant}
+		keyTenant := tenant.Default
+		if k.Tenant != nil {
+			keyTenant = *k.Tenant
+		} else if principal.ChoosesTenant() {
+			keyTenant = "*"
+		}
+		fmt.Printf("%d\t%s\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Role, keyTenant, k.CreatedAt.Format(time.RFC3339), revoked)
+	}
+
+	return nil
+}
+
+func setRole(ctx context.Context, storage *postgres.Storage, id int, role string) error {
+	if id < 1 {
+		return fmt.Errorf("-id is required")
+	}
+	if !auth.ValidRole(role) {
+		return fmt.Errorf("unknown role %q", role)
+	}
+
+	if err := storage.SetAPIKeyRole(ctx, id, role); err != nil {
+		return fmt.Errorf("failed to set api key role: %w", err)
+	}
+
+	fmt.Printf("api key %d is now %s\n", id, role)
+
+	return nil
+}
+
+func revoke(ctx context.Context, storage *postgres.Storage, id int) error {
+	if id < 1 {
+		return fmt.Errorf("-id is required")
+	}
+
+	if err := storage.RevokeAPIKey(ctx, id); err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+
+	fmt.Printf("api key %d revoked\n", id)
+
+	return nil
+}
@@ -3,25 +3,67 @@ package main
 import (
 	"context"
 	_ "effective_mobile_test/docs" // docs is generated by Swag CLI, you have to import it.
+	"effective_mobile_test/internal/client"
 	"effective_mobile_test/internal/config"
+	auditList "effective_mobile_test/internal/http-server/handlers/audit/list"
+	carBulk "effective_mobile_test/internal/http-server/handlers/car/bulk"
 	carDelete "effective_mobile_test/internal/http-server/handlers/car/delete"
+	carExport "effective_mobile_test/internal/http-server/handlers/car/export"
+	carFilter "effective_mobile_test/internal/http-server/handlers/car/filter"
+	carGet "effective_mobile_test/internal/http-server/handlers/car/get"
+	carImport "effective_mobile_test/internal/http-server/handlers/car/importer"
+	carOwner "effective_mobile_test/internal/http-server/handlers/car/owner"
+	carRestore "effective_mobile_test/internal/http-server/handlers/car/restore"
 	carSave "effective_mobile_test/internal/http-server/handlers/car/save"
 	carSearch "effective_mobile_test/internal/http-server/handlers/car/search"
 	carUpdate "effective_mobile_test/internal/http-server/handlers/car/update"
+	eventStream "effective_mobile_test/internal/http-server/handlers/event/stream"
+	healthLive "effective_mobile_test/internal/http-server/handlers/health/live"
+	healthReady "effective_mobile_test/internal/http-server/handlers/health/ready"
+	ownerCars "effective_mobile_test/internal/http-server/handlers/owner/cars"
 	ownerDelete "effective_mobile_test/internal/http-server/handlers/owner/delete"
+	ownerDuplicates "effective_mobile_test/internal/http-server/handlers/owner/duplicates"
+	ownerGet "effective_mobile_test/internal/http-server/handlers/owner/get"
+	ownerList "effective_mobile_test/internal/http-server/handlers/owner/list"
+	ownerMerge "effective_mobile_test/internal/http-server/handlers/owner/merge"
+	ownerRestore "effective_mobile_test/internal/http-server/handlers/owner/restore"
 	ownerSave "effective_mobile_test/internal/http-server/handlers/owner/save"
 	ownerUpdate "effective_mobile_test/internal/http-server/handlers/owner/update"
+	webhookDelete "effective_mobile_test/internal/http-server/handlers/webhook/delete"
+	webhookDeliveries "effective_mobile_test/internal/http-server/handlers/webhook/deliveries"
+	webhookList "effective_mobile_test/internal/http-server/handlers/webhook/list"
+	webhookRetry "effective_mobile_test/internal/http-server/handlers/webhook/retry"
+	webhookSave "effective_mobile_test/internal/http-server/handlers/webhook/save"
+	mwAuth "effective_mobile_test/internal/http-server/middleware/auth"
+	mwIdempotency "effective_mobile_test/internal/http-server/middleware/idempotency"
 	mwLogger "effective_mobile_test/internal/http-server/middleware/logger"
+	mwMetrics "effective_mobile_test/internal/http-server/middleware/metrics"
+	mwRateLimit "effective_mobile_test/internal/http-server/middleware/ratelimit"
+	mwRBAC "effective_mobile_test/internal/http-server/middleware/rbac"
+	mwTenant "effective_mobile_test/internal/http-server/middleware/tenant"
+	mwTracing "effective_mobile_test/internal/http-server/middleware/tracing"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/health"
 	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/metrics"
+	"effective_mobile_test/internal/lib/publisher"
+	"effective_mobile_test/internal/lib/tracing"
+	"effective_mobile_test/internal/lifecycle"
 	"effective_mobile_test/internal/storage/postgres"
+	"effective_mobile_test/internal/storage/schema"
+	"effective_mobile_test/internal/worker/events"
+	"effective_mobile_test/internal/worker/outbox"
+	"effective_mobile_test/internal/worker/purge"
+	"effective_mobile_test/internal/worker/webhook"
+	"errors"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	httpSwagger "github.com/swaggo/http-swagger"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
 	"time"
 )
 
@@ -31,6 +73,9 @@ const (
 	envProd  = "prod"
 )
 
+// traceFlushTimeout limits exporting the spans left on shutdown.
+const traceFlushTimeout = 5 * time.Second
+
 //	@title			Cars Catalog API
 //	@version		1.0
 //	@description	API for Effective Mobile Test
@@ -40,6 +85,19 @@ const (
 //	@contact.url	https://t.me/belkindelcin
 //	@contact.email	naklz9@mail.ru
 
+//	@securityDefinitions.apikey	ApiKeyAuth
+//	@in							header
+//	@name						X-API-Key
+//	@description				API key created with cars-apikey
+
+//	@securityDefinitions.apikey	BearerAuth
+//	@in							header
+//	@name						Authorization
+//	@description				"Bearer " followed by a JWT or an API key
+
+//	@security	ApiKeyAuth
+//	@security	BearerAuth
+
 func main() {
 	cfg := config.InitConfig()
 
@@ -48,28 +106,166 @@ func main() {
 	log.Info("starting cars-catalog api", slog.String("env", cfg.Env))
 	log.Debug("debug messages are enabled")
 
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TraceExporter)
+	if err != nil {
+		log.Error("failed to init tracing", sl.Err(err))
+		os.Exit(1)
+	}
+
 	storage, err := postgres.New(cfg.Storage)
 	if err != nil {
 		log.Error("failed to init storage", sl.Err(err))
 		os.Exit(1)
 	}
 
-	_ = storage
+	if err = waitForStorage(storage, cfg.DBConnectTimeout); err != nil {
+		log.Error("database is unreachable", sl.Err(err))
+		os.Exit(1)
+	}
+
+	schemaVersion, err := schema.Version()
+	if err != nil {
+		log.Error("failed to get schema version", sl.Err(err))
+		os.Exit(1)
+	}
+
+	metrics.RegisterDBStats(storage.Stats)
+
+	var state health.State
+
+	outboxPublisher, err := publisher.New(cfg.OutboxPublisher, cfg.OutboxTimeout)
+	if err != nil {
+		log.Error("failed to init outbox publisher", sl.Err(err))
+		os.Exit(1)
+	}
+
+	lc := lifecycle.New(log)
+
+	lc.Go("purge", purge.New(log, storage, cfg.PurgeRetention, cfg.PurgeInterval).Run)
+
+	eventsHub := events.New(log, storage, cfg.EventsPollInterval)
+	lc.Go("events hub", eventsHub.Run)
+
+	lc.Go("webhook", webhook.New(log, storage, eventsHub, cfg.WebhookTimeout).Run)
+
+	lc.Go("outbox", outbox.New(log, storage, outboxPublisher, eventsHub).Run)
+
+	jwtVerifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+		HS256Secret:        cfg.JWTSecret,
+		RS256PublicKeyFile: cfg.JWTPublicKeyFile,
+		JWKSFile:           cfg.JWKSFile,
+		Issuer:             cfg.JWTIssuer,
+		Audience:           cfg.JWTAudience,
+	})
+	if err != nil {
+		log.Error("failed to init jwt verifier", sl.Err(err))
+		os.Exit(1)
+	}
 
 	router := chi.NewRouter()
 
 	router.Use(middleware.RequestID)
-	router.Use(mwLogger.New(log))
 	router.Use(middleware.Recoverer)
+	router.Use(mwTracing.New(log))
+	router.Use(mwLogger.New(log))
+	router.Use(mwMetrics.New(log))
+
+	router.Group(func(r chi.Router) {
+		// limited by IP before authentication, so that floods of bad credentials are refused too
+		r.Use(mwRateLimit.New(log, "ip", mwRateLimit.Limit{Rate: cfg.IPRateLimit, Burst: cfg.IPRateLimitBurst}))
+		r.Use(mwAuth.New(log, storage, jwtVerifier))
+		r.Use(mwTenant.New(log))
+		r.Use(mwRateLimit.New(log, "api", mwRateLimit.Limit{Rate: cfg.RateLimit, Burst: cfg.RateLimitBurst}))
+		r.Use(mwIdempotency.New(log, storage, cfg.IdempotencyTTL))
 
-	router.Post("/car/save", carSave.New(log, storage, cfg.HelpAPI))
-	router.Get("/car/search", carSearch.New(log, storage))
-	router.Delete("/car/delete", carDelete.New(log, storage))
-	router.Put("/car/update", carUpdate.New(log, storage))
+		r.Group(func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermCarRead))
+			r.Get("/car/search", carSearch.New(log, storage))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermCarWrite))
+			// saving calls the info API, whose quota is shared by all clients
+			r.With(mwRateLimit.New(log, "car-save", mwRateLimit.Limit{Rate: cfg.SaveRateLimit, Burst: cfg.SaveRateLimitBurst})).
+				Post("/car/save", carSave.New(log, storage, cfg.HelpAPI))
+			r.Put("/car/update", carUpdate.New(log, storage))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermCarDelete))
+			r.Delete("/car/delete", carDelete.New(log, storage))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermCarRead, auth.PermOwnerRead))
+			r.Get("/car/owner", carOwner.New(log, storage))
+			r.Get("/owner/cars", ownerCars.New(log, storage))
+			r.Get("/events", eventStream.New(log, storage, eventsHub))
+		})
 
-	router.Post("/owner/save", ownerSave.New(log, storage))
-	router.Delete("/owner/delete", ownerDelete.New(log, storage))
-	router.Put("/owner/update", ownerUpdate.New(log, storage))
+		r.Group(func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermOwnerWrite))
+			r.Post("/owner/save", ownerSave.New(log, storage))
+			r.Put("/owner/update", ownerUpdate.New(log, storage))
+		})
+		r.Group(func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermOwnerDelete))
+			r.Delete("/owner/delete", ownerDelete.New(log, storage))
+		})
+
+		r.Route("/cars", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(mwRBAC.New(log, auth.PermCarRead))
+				r.Post("/export", carExport.New(log, storage))
+				r.Get("/{id}", carGet.New(log, storage))
+			})
+			// deletions in bulk and by filter also need car:delete, which the handlers check
+			r.Group(func(r chi.Router) {
+				r.Use(mwRBAC.New(log, auth.PermCarWrite))
+				r.Post("/bulk", carBulk.New(log, storage))
+				r.Post("/by-filter", carFilter.New(log, storage))
+				r.Post("/import", carImport.New(log, storage))
+				r.Post("/{id}/restore", carRestore.New(log, storage))
+			})
+		})
+
+		r.Route("/owners", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(mwRBAC.New(log, auth.PermOwnerRead))
+				r.Get("/", ownerList.New(log, storage))
+				r.Get("/duplicates", ownerDuplicates.New(log, storage))
+				r.Get("/{id}", ownerGet.New(log, storage))
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(mwRBAC.New(log, auth.PermOwnerWrite))
+				r.Post("/{id}/restore", ownerRestore.New(log, storage))
+			})
+			r.Group(func(r chi.Router) {
+				r.Use(mwRBAC.New(log, auth.PermOwnerDelete))
+				r.Delete("/{id}", ownerDelete.New(log, storage))
+			})
+			// a merge deletes the merged owner
+			r.Group(func(r chi.Router) {
+				r.Use(mwRBAC.New(log, auth.PermOwnerWrite, auth.PermOwnerDelete))
+				r.Post("/{id}/merge", ownerMerge.New(log, storage))
+			})
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermAdminAudit))
+			r.Get("/audit", auditList.New(log, storage))
+		})
+
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(mwRBAC.New(log, auth.PermAdminWebhooks))
+			r.Post("/", webhookSave.New(log, storage))
+			r.Get("/", webhookList.New(log, storage))
+			r.Delete("/{id}", webhookDelete.New(log, storage))
+			r.Get("/{id}/deliveries", webhookDeliveries.New(log, storage))
+			r.Post("/{id}/deliveries/{deliveryId}/retry", webhookRetry.New(log, storage))
+		})
+	})
+
+	router.Handle("/metrics", promhttp.Handler())
+	router.Get("/healthz", healthLive.New())
+	router.Get("/readyz", healthReady.New(log, &state, storage, &client.SearchClient{URL: cfg.HelpAPI}, schemaVersion))
 
 	router.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("http://localhost:8082/swagger/doc.json"), //The url pointing to API definition
@@ -77,8 +273,11 @@ func main() {
 
 	log.Info("starting server", slog.String("address", cfg.Address))
 
-	done := make(chan os.Signal, 1)
-	signal.Notify(done, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
+	listener, err := net.Listen("tcp", cfg.Address)
+	if err != nil {
+		log.Error("failed to listen", sl.Err(err))
+		os.Exit(1)
+	}
 
 	srv := &http.Server{
 		Addr:         cfg.Address,
@@ -87,28 +286,63 @@ func main() {
 		WriteTimeout: cfg.Timeout,
 		IdleTimeout:  cfg.IdleTimeout,
 	}
+	// event streams never go idle, so they are ended as soon as the server starts shutting down;
+	// the webhook and outbox workers stop with them, after the job they are in the middle of
+	srv.RegisterOnShutdown(eventsHub.Close)
 
-	go func() {
-		if err := srv.ListenAndServe(); err != nil {
-			log.Error("failed to start server")
-		}
-	}()
+	// probes see the service going away before it stops accepting requests
+	lc.OnShutdown("readiness", 0, func(ctx context.Context) error {
+		state.SetReady(false)
+		time.Sleep(cfg.ShutdownDelay)
+
+		return nil
+	})
+	// stops accepting connections and waits for the in-flight requests, including the lookups
+	// of the info API of the cars being saved
+	lc.OnShutdown("http server", cfg.ShutdownTimeout, srv.Shutdown)
+	lc.OnShutdown("workers", cfg.WorkersShutdownTimeout, lc.StopWorkers)
+	lc.OnShutdown("outbox publisher", 0, func(context.Context) error {
+		return outboxPublisher.Close()
+	})
+	lc.OnShutdown("storage", 0, func(context.Context) error {
+		return storage.Close()
+	})
+	lc.OnShutdown("tracing", traceFlushTimeout, shutdownTracing)
+
+	state.SetReady(true)
 
 	log.Info("server started")
 
-	<-done
-	log.Info("stopping server")
+	os.Exit(lc.Run(func() error {
+		if err := srv.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+
+		return nil
+	}))
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+// waitForStorage pings the database until it answers or timeout passes, so that the service
+// doesn't report itself started without it.
+func waitForStorage(storage *postgres.Storage, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Error("failed to stop server", sl.Err(err))
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
 
-		return
-	}
+	for {
+		err := storage.Ping(ctx)
+		if err == nil {
+			return nil
+		}
 
-	log.Info("server stopped")
+		select {
+		case <-ctx.Done():
+			return err
+		case <-ticker.C:
+		}
+	}
 }
 
 func setupLogger(env string) *slog.Logger {
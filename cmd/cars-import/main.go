@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"effective_mobile_test/internal/config"
+	"effective_mobile_test/internal/lib/actor"
+	"effective_mobile_test/internal/lib/csvimport"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage/postgres"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// cars-import loads cars with their owners from a CSV file, the same way POST /cars/import does.
+//
+//	CONFIG_PATH=.env cars-import -file fleet.csv [-tenant acme] [-dry-run] [-skip-invalid]
+func main() {
+	file := flag.String("file", "-", "CSV file to import, - for stdin")
+	dryRun := flag.Bool("dry-run", false, "only report the rows that can't be imported")
+	skipInvalid := flag.Bool("skip-invalid", false, "import the valid rows even if some rows can't be imported")
+	who := flag.String("actor", "cli", "actor recorded in the audit log")
+	into := flag.String("tenant", tenant.Default, "tenant the cars are imported into")
+	flag.Parse()
+
+	if !tenant.Valid(*into) {
+		fmt.Fprintf(os.Stderr, "invalid tenant %q\n", *into)
+		os.Exit(2)
+	}
+
+	cfg := config.InitConfig()
+
+	storage, err := postgres.New(cfg.Storage)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to init storage:", err)
+		os.Exit(1)
+	}
+
+	var r io.Reader = os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "failed to open file:", err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	ctx = actor.WithActor(ctx, *who)
+	ctx = tenant.WithTenant(ctx, *into)
+
+	opts := postgres.ImportOptions{DryRun: *dryRun, SkipInvalid: *skipInvalid}
+	result, err := csvimport.Import(ctx, storage, r, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "failed to import cars:", err)
+		os.Exit(1)
+	}
+
+	for _, e := range result.Errors {
+		fmt.Printf("line %d: %s\n", e.Line, e.Error)
+	}
+
+	if *dryRun {
+		fmt.Printf("%d cars would be imported, %d rows can't be imported\n", result.Imported, len(result.Errors))
+	} else {
+		fmt.Printf("%d cars imported, %d rows can't be imported\n", result.Imported, len(result.Errors))
+	}
+
+	if len(result.Errors) > 0 && !*skipInvalid {
+		os.Exit(1)
+	}
+}
@@ -20,9 +20,83 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/audit": {
+            "get": {
+                "description": "List recorded data changes filtered by entity, entity id, actor and time range, oldest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Audit"
+                ],
+                "summary": "List audit log",
+                "parameters": [
+                    {
+                        "enum": [
+                            "car",
+                            "owner"
+                        ],
+                        "type": "string",
+                        "description": "Entity",
+                        "name": "entity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Entity ID",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Actor",
+                        "name": "actor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "From, RFC 3339, inclusive",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "To, RFC 3339, exclusive",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "PageNum",
+                        "name": "pageNum",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "PageSize",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_audit_list.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
         "/car/delete": {
             "delete": {
-                "description": "Delete car by carId",
+                "description": "Soft-delete car by carId, it can be restored with /cars/{id}/restore",
                 "consumes": [
                     "application/json"
                 ],
@@ -42,6 +116,20 @@ const docTemplate = `{
                         "schema": {
                             "type": "integer"
                         }
+                    },
+                    {
+                        "description": "Version",
+                        "name": "version",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the car",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -56,13 +144,78 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/car/owner": {
+            "get": {
+                "description": "Get car by regNum together with the owner valid at asOf (RFC 3339, defaults to now)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Car"
+                ],
+                "summary": "Get car owner at a point in time",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "RegNum",
+                        "name": "regNum",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "AsOf",
+                        "name": "asOf",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/owner.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "ETag of the car"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
                     }
                 }
             }
         },
         "/car/save": {
             "post": {
-                "description": "Save a new car by regNums",
+                "description": "Save a new car by regNums. A request with an Idempotency-Key header can be retried safely:\nthe original response is replayed, reusing the key with a different body is refused with 422.",
                 "consumes": [
                     "application/json"
                 ],
@@ -85,6 +238,12 @@ const docTemplate = `{
                                 "type": "string"
                             }
                         }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -99,18 +258,31 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
                     }
                 }
             }
         },
         "/car/search": {
             "get": {
-                "description": "Search cars by search request",
+                "description": "Search cars by search request.\nWith Accept: application/x-ndjson the cars are streamed one JSON object per line as they are read,\nand a zero pageSize returns all matching cars. If the stream fails midway, its last line is an error response.\nSoft-deleted cars are included on request to callers with the admin:deleted permission.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/x-ndjson"
                 ],
                 "tags": [
                     "Car"
@@ -128,13 +300,19 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
                     }
                 }
             }
         },
         "/car/update": {
             "put": {
-                "description": "Update car by carId and new data",
+                "description": "Update car by carId and new data in one change. The current version can be required\nwith the If-Match header or the version field, a stale one is refused with 412.",
                 "consumes": [
                     "application/json"
                 ],
@@ -186,6 +364,20 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/postgres.Owner"
                         }
+                    },
+                    {
+                        "description": "Version",
+                        "name": "version",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the car",
+                        "name": "If-Match",
+                        "in": "header"
                     }
                 ],
                 "responses": {
@@ -193,6 +385,12 @@ const docTemplate = `{
                         "description": "OK",
                         "schema": {
                             "$ref": "#/definitions/internal_http-server_handlers_car_update.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "ETag of the updated car"
+                            }
                         }
                     },
                     "400": {
@@ -200,13 +398,31 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
                     }
                 }
             }
         },
-        "/owner/delete": {
-            "delete": {
-                "description": "Delete owner by ownerId",
+        "/cars/bulk": {
+            "post": {
+                "description": "Create, patch and delete cars by id or regNum in one request. In transaction mode a failing\noperation rolls back all of them and the request is refused with 409, in bestEffort mode\nthe failing operations are skipped. Results are returned in the order of operations.",
                 "consumes": [
                     "application/json"
                 ],
@@ -214,17 +430,28 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Owner"
+                    "Car"
                 ],
-                "summary": "Delete owner",
+                "summary": "Bulk change cars",
                 "parameters": [
                     {
-                        "description": "OwnerId",
-                        "name": "ownerId",
+                        "description": "Mode",
+                        "name": "mode",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Operations",
+                        "name": "operations",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "integer"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/postgres.BulkOperation"
+                            }
                         }
                     }
                 ],
@@ -232,7 +459,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_owner_delete.Response"
+                            "$ref": "#/definitions/bulk.Response"
                         }
                     },
                     "400": {
@@ -240,13 +467,25 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/bulk.Response"
+                        }
                     }
                 }
             }
         },
-        "/owner/save": {
+        "/cars/by-filter": {
             "post": {
-                "description": "Save a new owner by name, surname, patronymic",
+                "description": "Apply a patch or a deletion to every car matching the search filter in one transaction.\nA dry run returns the number of matching cars and a sample of them without changing anything.\nIf more cars than limit (default 100) match, the request is refused with 422.",
                 "consumes": [
                     "application/json"
                 ],
@@ -254,35 +493,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Owner"
+                    "Car"
                 ],
-                "summary": "Save a new owner",
+                "summary": "Patch or delete cars by filter",
                 "parameters": [
                     {
-                        "description": "Name",
-                        "name": "name",
+                        "description": "Filter",
+                        "name": "filter",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/postgres.CarFilter"
                         }
                     },
                     {
-                        "description": "Surname",
-                        "name": "surname",
+                        "description": "Action",
+                        "name": "action",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "type": "string"
+                            "type": "string",
+                            "enum": [
+                                "patch",
+                                "delete"
+                            ]
                         }
                     },
                     {
-                        "description": "Patronymic",
-                        "name": "patronymic",
+                        "description": "Patch",
+                        "name": "patch",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/postgres.CarPatch"
+                        }
+                    },
+                    {
+                        "description": "DryRun",
+                        "name": "dryRun",
+                        "in": "body",
+                        "schema": {
+                            "type": "boolean"
+                        }
+                    },
+                    {
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
                         }
                     }
                 ],
@@ -290,7 +548,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_owner_save.Response"
+                            "$ref": "#/definitions/filter.Response"
                         }
                     },
                     "400": {
@@ -298,63 +556,129 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
                     }
                 }
             }
         },
-        "/owner/update": {
-            "put": {
-                "description": "Update owner by ownerId and new data",
+        "/cars/export": {
+            "post": {
+                "description": "Stream all cars matching the search filter, without pagination, as a CSV or XLSX file.\nColumns: id, regNum, mark, model, year, version, deletedAt, ownerId, ownerName, ownerSurname,\nownerPatronymic, ownerBirthDate, ownerPhone, ownerEmail, ownerDocumentNumber.\nSoft-deleted cars are included on request to callers with the admin:deleted permission.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/csv",
+                    "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
                 ],
                 "tags": [
-                    "Owner"
+                    "Car"
                 ],
-                "summary": "Update owner",
+                "summary": "Export cars",
                 "parameters": [
                     {
-                        "description": "OwnerId",
-                        "name": "ownerId",
+                        "description": "Filter",
+                        "name": "filter",
                         "in": "body",
-                        "required": true,
                         "schema": {
-                            "type": "integer"
+                            "$ref": "#/definitions/postgres.CarFilter"
                         }
                     },
                     {
-                        "description": "Name",
-                        "name": "name",
+                        "description": "Format",
+                        "name": "format",
                         "in": "body",
                         "schema": {
-                            "type": "string"
+                            "type": "string",
+                            "enum": [
+                                "csv",
+                                "xlsx"
+                            ]
                         }
                     },
                     {
-                        "description": "Surname",
-                        "name": "surname",
+                        "description": "Columns",
+                        "name": "columns",
                         "in": "body",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "file"
                         }
                     },
-                    {
-                        "description": "Patronymic",
-                        "name": "patronymic",
-                        "in": "body",
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
                         }
                     }
+                }
+            }
+        },
+        "/cars/import": {
+            "post": {
+                "description": "Import cars with their owners from a CSV file with a header row. Columns: regNum, mark, model, year,\nownerName, ownerSurname are required; ownerPatronymic, ownerBirthDate, ownerPhone, ownerEmail,\nownerDocumentNumber are optional; id, version, deletedAt, ownerId of an exported file are ignored.\nOwners are reused the way /owner/save finds them. Invalid rows and rows whose regNum is taken\nare reported with their line numbers; unless skipInvalid is set, nothing is imported then and\nthe request fails with 422. A dry run only reports the errors.",
+                "consumes": [
+                    "text/csv"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Car"
+                ],
+                "summary": "Import cars",
+                "parameters": [
+                    {
+                        "type": "boolean",
+                        "description": "DryRun",
+                        "name": "dryRun",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "SkipInvalid",
+                        "name": "skipInvalid",
+                        "in": "query"
+                    }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_http-server_handlers_owner_update.Response"
+                            "$ref": "#/definitions/importer.Response"
                         }
                     },
                     "400": {
@@ -362,54 +686,1543 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/response.Response"
                         }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/importer.Response"
+                        }
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "internal_http-server_handlers_car_delete.Response": {
-            "type": "object",
-            "properties": {
-                "error": {
-                    "type": "string"
-                },
-                "status": {
-                    "type": "string"
+        },
+        "/cars/{id}": {
+            "get": {
+                "description": "Get car by id together with its current owner",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Car"
+                ],
+                "summary": "Get car",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "CarId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_car_get.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "ETag of the car"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/cars/{id}/restore": {
+            "post": {
+                "description": "Restore a soft-deleted car by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Car"
+                ],
+                "summary": "Restore car",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "CarId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_car_restore.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/events": {
+            "get": {
+                "description": "Stream changes of cars and owners as Server-Sent Events: car.created, car.updated, car.deleted,\ncar.restored, car.purged and the same owner.* events. The event name is the type and the data is\na JSON postgres.Event with the state of the entity after the change, or before it for deletions.\nA reconnecting client resumes after the event given by the Last-Event-ID header or lastEventId;\nlastEventId=0 replays all events. Without either only new events are streamed.",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "Event"
+                ],
+                "summary": "Stream catalog events",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Last received event ID",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Last received event ID",
+                        "name": "lastEventId",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Comma-separated event types, e.g. car.updated,owner.*",
+                        "name": "types",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/postgres.Event"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/live.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owner/cars": {
+            "get": {
+                "description": "Get all cars held by the owner at asOf (RFC 3339, defaults to now)",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Get owner cars at a point in time",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "OwnerId",
+                        "name": "ownerId",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "AsOf",
+                        "name": "asOf",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/cars.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owner/delete": {
+            "delete": {
+                "description": "Delete owner by ownerId (or id in the path). The policy decides what happens to the cars the owner holds:\nrestrict (default) refuses with 409, cascade deletes them, reassign hands them over to reassignTo.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Delete owner",
+                "parameters": [
+                    {
+                        "description": "OwnerId (for /owner/delete)",
+                        "name": "ownerId",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Policy",
+                        "name": "policy",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "ReassignTo",
+                        "name": "reassignTo",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Version",
+                        "name": "version",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the owner",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_owner_delete.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owner/save": {
+            "post": {
+                "description": "Save a new owner by name, surname and optional patronymic, birth date, phone, email and document number.\nA request with an Idempotency-Key header can be retried safely, see /car/save.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Save a new owner",
+                "parameters": [
+                    {
+                        "description": "Name",
+                        "name": "name",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Surname",
+                        "name": "surname",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Patronymic",
+                        "name": "patronymic",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "BirthDate (YYYY-MM-DD)",
+                        "name": "birthDate",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Phone (E.164)",
+                        "name": "phone",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Email",
+                        "name": "email",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "DocumentNumber",
+                        "name": "documentNumber",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "Idempotency key",
+                        "name": "Idempotency-Key",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_owner_save.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owner/update": {
+            "put": {
+                "description": "Update owner by ownerId and new data in one change. The current version can be required\nwith the If-Match header or the version field, a stale one is refused with 412.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Update owner",
+                "parameters": [
+                    {
+                        "description": "OwnerId",
+                        "name": "ownerId",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Name",
+                        "name": "name",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Surname",
+                        "name": "surname",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Patronymic",
+                        "name": "patronymic",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "BirthDate (YYYY-MM-DD)",
+                        "name": "birthDate",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Phone (E.164)",
+                        "name": "phone",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Email",
+                        "name": "email",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "DocumentNumber",
+                        "name": "documentNumber",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Version",
+                        "name": "version",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the owner",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_owner_update.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "ETag of the updated owner"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owners": {
+            "get": {
+                "description": "List owners filtered by name, surname and patronymic with pagination.\nSoft-deleted owners are included on request to callers with the admin:deleted permission.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "List owners",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Name",
+                        "name": "name",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Surname",
+                        "name": "surname",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Patronymic",
+                        "name": "patronymic",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "PageNum",
+                        "name": "pageNum",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "PageSize",
+                        "name": "pageSize",
+                        "in": "query"
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "IncludeDeleted",
+                        "name": "includeDeleted",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_owner_list.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/problem.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/owners/duplicates": {
+            "get": {
+                "description": "Report pairs of owners that are likely the same person, most similar first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Owner duplicate candidates",
+                "parameters": [
+                    {
+                        "type": "number",
+                        "description": "Minimal similarity from 0 to 1",
+                        "name": "threshold",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Limit",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/duplicates.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owners/{id}": {
+            "get": {
+                "description": "Get owner by id together with the cars they currently own",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Get owner",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "OwnerId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_owner_get.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "ETag of the owner"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete owner by ownerId (or id in the path). The policy decides what happens to the cars the owner holds:\nrestrict (default) refuses with 409, cascade deletes them, reassign hands them over to reassignTo.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Delete owner",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "OwnerId (for /owners/{id})",
+                        "name": "id",
+                        "in": "path"
+                    },
+                    {
+                        "description": "OwnerId (for /owner/delete)",
+                        "name": "ownerId",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Policy",
+                        "name": "policy",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "ReassignTo",
+                        "name": "reassignTo",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "description": "Version",
+                        "name": "version",
+                        "in": "body",
+                        "schema": {
+                            "type": "integer"
+                        }
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the owner",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_owner_delete.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owners/{id}/merge": {
+            "post": {
+                "description": "Move all cars of the source owner to the owner {id} and delete the source owner",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Merge owners",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "OwnerId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "SourceOwnerId",
+                        "name": "sourceOwnerId",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "integer"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/merge.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/owners/{id}/restore": {
+            "post": {
+                "description": "Restore a soft-deleted owner by id",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Owner"
+                ],
+                "summary": "Restore owner",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "OwnerId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_owner_restore.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Check whether the service can serve requests: its lifecycle, the database, the schema version and the info API",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/ready.Response"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/ready.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "List the webhook subscriptions without their secrets",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "List webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_webhook_list.Response"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribe a URL to catalog events, optionally only to events of the given types\n(e.g. car.updated, owner.*) and of cars with the given regNum or mark.\nEvery event is POSTed as JSON with the headers X-Webhook-Id, X-Webhook-Delivery, X-Webhook-Event,\nX-Webhook-Timestamp (Unix seconds) and X-Webhook-Signature: \"sha256=\" and the hex-encoded\nHMAC-SHA256 of \"\u003ctimestamp\u003e.\u003cbody\u003e\" keyed with the secret. Any 2xx response acknowledges it;\nfailed deliveries are retried with exponential backoff and end up dead after 10 attempts.\nRedirects are not followed, and URLs of the loopback, private or link-local networks are refused.\nThe secret is returned only in this response.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Subscribe a webhook",
+                "parameters": [
+                    {
+                        "description": "URL",
+                        "name": "url",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "EventTypes",
+                        "name": "eventTypes",
+                        "in": "body",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
+                        }
+                    },
+                    {
+                        "description": "Secret",
+                        "name": "secret",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "RegNum",
+                        "name": "regNum",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    {
+                        "description": "Mark",
+                        "name": "mark",
+                        "in": "body",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_webhook_save.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}": {
+            "delete": {
+                "description": "Unsubscribe a webhook; its pending deliveries are dropped together with the delivery log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "WebhookId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_http-server_handlers_webhook_delete.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries": {
+            "get": {
+                "description": "List the deliveries of a webhook, newest first, with the outcome of their last attempt",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "List webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "WebhookId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "delivered",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "Status",
+                        "name": "status",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "PageNum",
+                        "name": "pageNum",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "PageSize",
+                        "name": "pageSize",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/deliveries.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/{id}/deliveries/{deliveryId}/retry": {
+            "post": {
+                "description": "Send a dead delivery again, with a fresh number of attempts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Webhook"
+                ],
+                "summary": "Retry webhook delivery",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "WebhookId",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "DeliveryId",
+                        "name": "deliveryId",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/retry.Response"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/response.Response"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "bulk.Response": {
+            "type": "object",
+            "properties": {
+                "applied": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "results": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.BulkResult"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "cars.Response": {
+            "type": "object",
+            "properties": {
+                "cars": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.Car"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "deliveries.Response": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.WebhookDelivery"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "duplicates.Response": {
+            "type": "object",
+            "properties": {
+                "duplicates": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.OwnerDuplicate"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "filter.Response": {
+            "type": "object",
+            "properties": {
+                "affected": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "matched": {
+                    "type": "integer"
+                },
+                "sample": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.Car"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "importer.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "errors": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.ImportError"
+                    }
+                },
+                "imported": {
+                    "description": "Imported is the number of imported cars, or the number of cars that would be imported on a dry run",
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_audit_list.Response": {
+            "type": "object",
+            "properties": {
+                "entries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.AuditEntry"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_car_delete.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_car_get.Response": {
+            "type": "object",
+            "properties": {
+                "car": {
+                    "$ref": "#/definitions/postgres.Car"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_car_restore.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_car_save.Response": {
+            "type": "object",
+            "properties": {
+                "cars_ids": {
+                    "type": "array",
+                    "items": {
+                        "type": "integer"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_car_update.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_http-server_handlers_owner_delete.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_owner_get.Response": {
+            "type": "object",
+            "properties": {
+                "cars": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.Car"
+                    }
+                },
+                "error": {
+                    "type": "string"
+                },
+                "owner": {
+                    "$ref": "#/definitions/postgres.Owner"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_owner_list.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "owners": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.Owner"
+                    }
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_owner_restore.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_owner_save.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "integer"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_owner_update.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "version": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_http-server_handlers_webhook_delete.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_http-server_handlers_webhook_list.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/postgres.Webhook"
+                    }
                 }
             }
         },
-        "internal_http-server_handlers_car_save.Response": {
+        "internal_http-server_handlers_webhook_save.Response": {
             "type": "object",
             "properties": {
-                "cars_ids": {
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
-                },
                 "error": {
                     "type": "string"
                 },
+                "secret": {
+                    "type": "string"
+                },
                 "status": {
                     "type": "string"
+                },
+                "webhookId": {
+                    "type": "integer"
                 }
             }
         },
-        "internal_http-server_handlers_car_update.Response": {
+        "live.Response": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "merge.Response": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string"
                 },
+                "reassigned": {
+                    "type": "integer"
+                },
                 "status": {
                     "type": "string"
                 }
             }
         },
-        "internal_http-server_handlers_owner_delete.Response": {
+        "owner.Response": {
             "type": "object",
             "properties": {
+                "car": {
+                    "$ref": "#/definitions/postgres.Car"
+                },
                 "error": {
                     "type": "string"
                 },
@@ -418,32 +2231,172 @@ const docTemplate = `{
                 }
             }
         },
-        "internal_http-server_handlers_owner_save.Response": {
+        "postgres.AuditEntry": {
             "type": "object",
             "properties": {
-                "error": {
+                "action": {
                     "type": "string"
                 },
-                "owner_id": {
+                "actor": {
+                    "type": "string"
+                },
+                "after": {
+                    "type": "object"
+                },
+                "before": {
+                    "type": "object"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "entityId": {
                     "type": "integer"
                 },
-                "status": {
+                "id": {
+                    "type": "integer"
+                },
+                "requestId": {
                     "type": "string"
                 }
             }
         },
-        "internal_http-server_handlers_owner_update.Response": {
+        "postgres.BulkAction": {
+            "type": "string",
+            "enum": [
+                "create",
+                "patch",
+                "delete"
+            ],
+            "x-enum-varnames": [
+                "BulkCreate",
+                "BulkPatch",
+                "BulkDelete"
+            ]
+        },
+        "postgres.BulkOperation": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/postgres.BulkAction"
+                },
+                "car": {
+                    "description": "Car is the car to create",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/postgres.Car"
+                        }
+                    ]
+                },
+                "id": {
+                    "description": "ID or RegNum identifies the car to patch or delete",
+                    "type": "integer"
+                },
+                "patch": {
+                    "description": "Patch holds the fields to change",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/postgres.CarPatch"
+                        }
+                    ]
+                },
+                "regNum": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version must match the current version of the car to patch or delete if set",
+                    "type": "integer"
+                }
+            }
+        },
+        "postgres.BulkResult": {
             "type": "object",
             "properties": {
                 "error": {
                     "type": "string"
                 },
+                "id": {
+                    "description": "ID and Version describe the created or patched car",
+                    "type": "integer"
+                },
                 "status": {
                     "type": "string"
+                },
+                "version": {
+                    "type": "integer"
                 }
             }
         },
         "postgres.Car": {
+            "type": "object",
+            "properties": {
+                "deletedAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mark": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "owner": {
+                    "$ref": "#/definitions/postgres.Owner"
+                },
+                "regNum": {
+                    "type": "string"
+                },
+                "version": {
+                    "description": "Version is incremented on every change of the car, including a change of its owner",
+                    "type": "integer"
+                },
+                "year": {
+                    "type": "integer"
+                }
+            }
+        },
+        "postgres.CarFilter": {
+            "type": "object",
+            "properties": {
+                "asOf": {
+                    "description": "AsOf selects the owner valid at the given instant instead of the current one",
+                    "type": "string"
+                },
+                "includeDeleted": {
+                    "description": "IncludeDeleted also returns soft-deleted cars, to admins only",
+                    "type": "boolean"
+                },
+                "mark": {
+                    "type": "string"
+                },
+                "model": {
+                    "type": "string"
+                },
+                "ownerId": {
+                    "type": "integer"
+                },
+                "query": {
+                    "description": "Query matches a substring of the regNum, mark, model or owner names",
+                    "type": "string"
+                },
+                "regNum": {
+                    "description": "RegNum, Mark and Model match exactly",
+                    "type": "string"
+                },
+                "yearFrom": {
+                    "description": "YearFrom and YearTo bound the year inclusively",
+                    "type": "integer"
+                },
+                "yearTo": {
+                    "type": "integer"
+                }
+            }
+        },
+        "postgres.CarPatch": {
             "type": "object",
             "properties": {
                 "mark": {
@@ -463,17 +2416,208 @@ const docTemplate = `{
                 }
             }
         },
+        "postgres.Event": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "data": {
+                    "type": "object"
+                },
+                "entity": {
+                    "type": "string"
+                },
+                "entityId": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "tenant": {
+                    "type": "string"
+                },
+                "type": {
+                    "description": "Type is the entity and the action, e.g. car.created, car.updated, owner.deleted",
+                    "type": "string"
+                }
+            }
+        },
+        "postgres.ImportError": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "line": {
+                    "type": "integer"
+                }
+            }
+        },
         "postgres.Owner": {
             "type": "object",
             "properties": {
+                "birthDate": {
+                    "description": "BirthDate is formatted as YYYY-MM-DD",
+                    "type": "string"
+                },
+                "deletedAt": {
+                    "type": "string"
+                },
+                "documentNumber": {
+                    "type": "string"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
                 "name": {
                     "type": "string"
                 },
                 "patronymic": {
                     "type": "string"
                 },
+                "phone": {
+                    "type": "string"
+                },
                 "surname": {
                     "type": "string"
+                },
+                "version": {
+                    "description": "Version is incremented on every change of the owner",
+                    "type": "integer"
+                }
+            }
+        },
+        "postgres.OwnerDuplicate": {
+            "type": "object",
+            "properties": {
+                "candidate": {
+                    "$ref": "#/definitions/postgres.Owner"
+                },
+                "owner": {
+                    "$ref": "#/definitions/postgres.Owner"
+                },
+                "score": {
+                    "type": "number"
+                }
+            }
+        },
+        "postgres.Webhook": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "eventTypes": {
+                    "description": "EventTypes are types like car.updated or patterns like owner.*; empty matches every event",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "mark": {
+                    "type": "string"
+                },
+                "regNum": {
+                    "description": "RegNum and Mark restrict the webhook to the events of matching cars",
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret signs the payloads; it is returned only when the webhook is created",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "postgres.WebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "deliveredAt": {
+                    "type": "string"
+                },
+                "eventId": {
+                    "type": "integer"
+                },
+                "eventType": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "lastError": {
+                    "type": "string"
+                },
+                "lastStatusCode": {
+                    "type": "integer"
+                },
+                "nextAttemptAt": {
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Status is pending, delivered or dead",
+                    "type": "string"
+                },
+                "webhookId": {
+                    "type": "integer"
+                }
+            }
+        },
+        "problem.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string"
+                },
+                "instance": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "ready.Check": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "ready.Response": {
+            "type": "object",
+            "properties": {
+                "checks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/ready.Check"
+                    }
+                },
+                "status": {
+                    "type": "string"
                 }
             }
         },
@@ -488,6 +2632,17 @@ const docTemplate = `{
                 }
             }
         },
+        "retry.Response": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
         "search.Response": {
             "type": "object",
             "properties": {
@@ -505,7 +2660,29 @@ const docTemplate = `{
                 }
             }
         }
-    }
+    },
+    "securityDefinitions": {
+        "ApiKeyAuth": {
+            "description": "API key created with cars-apikey",
+            "type": "apiKey",
+            "name": "X-API-Key",
+            "in": "header"
+        },
+        "BearerAuth": {
+            "description": "\"Bearer \" followed by a JWT or an API key",
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
+    },
+    "security": [
+        {
+            "ApiKeyAuth": []
+        },
+        {
+            "BearerAuth": []
+        }
+    ]
 }`
 
 // SwaggerInfo holds exported Swagger Info so clients can modify it
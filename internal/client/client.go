@@ -2,12 +2,21 @@ package client
 
 import (
 	"bytes"
+	"context"
+	"effective_mobile_test/internal/lib/metrics"
+	"effective_mobile_test/internal/lib/tracing"
 	"effective_mobile_test/internal/storage/postgres"
 	"encoding/json"
 	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
 )
 
@@ -32,7 +41,32 @@ type SearchClient struct {
 }
 
 // FindUsers отправляет запрос во внешнюю систему, которая непосредственно ищет пользователей
-func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
+// в рамках трассировки из ctx: вызов записывается как span, а его контекст передаётся в заголовке traceparent
+func (srv *SearchClient) FindUsers(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
+	ctx, span := tracing.Start(ctx, "GET /info", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", http.MethodGet),
+			attribute.String("url.full", srv.URL+"/info"),
+			attribute.Int("info.reg_nums", len(req.RegNums)),
+		),
+	)
+	defer span.End()
+
+	start := time.Now()
+
+	resp, err := srv.findUsers(ctx, req)
+	metrics.InfoRequestDuration.WithLabelValues(outcome(err)).Observe(time.Since(start).Seconds())
+
+	span.SetAttributes(attribute.String("info.outcome", outcome(err)))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return resp, err
+}
+
+func (srv *SearchClient) findUsers(ctx context.Context, req SearchRequest) (*SearchResponse, error) {
 	rawBody, err := json.Marshal(req)
 	if err != nil {
 		return nil, ErrBadReq
@@ -40,19 +74,21 @@ func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
 
 	body := bytes.NewReader(rawBody)
 
-	searcherReq, _ := http.NewRequest("GET", srv.URL+"/info", body)
+	searcherReq, _ := http.NewRequestWithContext(ctx, "GET", srv.URL+"/info", body)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(searcherReq.Header))
 
 	client := &http.Client{Timeout: time.Second}
 
 	resp, err := client.Do(searcherReq)
 	if err != nil {
-		var err net.Error
-		if errors.As(err, &err) && err.Timeout() {
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
 			return nil, ErrTimeout
 		}
 		return nil, ErrBadConn
 	}
 	defer resp.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int("http.response.status_code", resp.StatusCode))
 	receivedBody, _ := io.ReadAll(resp.Body) //nolint:errcheck
 
 	switch resp.StatusCode {
@@ -70,3 +106,44 @@ func (srv *SearchClient) FindUsers(req SearchRequest) (*SearchResponse, error) {
 
 	return &result, err
 }
+
+// Ping проверяет, что внешняя система принимает соединения
+func (srv *SearchClient) Ping(ctx context.Context) error {
+	addr := srv.URL
+	port := "80"
+	if u, err := url.Parse(srv.URL); err == nil && u.Host != "" {
+		addr = u.Host
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, port)
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return ErrBadConn
+	}
+
+	return conn.Close()
+}
+
+// outcome labels the result of a call to the info API in metrics.
+func outcome(err error) string {
+	switch {
+	case err == nil:
+		return metrics.OutcomeOK
+	case errors.Is(err, ErrTimeout):
+		return metrics.OutcomeTimeout
+	case errors.Is(err, ErrBadConn):
+		return metrics.OutcomeConnError
+	case errors.Is(err, ErrServerFatal):
+		return metrics.OutcomeServerError
+	case errors.Is(err, ErrBadReq):
+		return metrics.OutcomeBadRequest
+	default:
+		return metrics.OutcomeBadResponse
+	}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestFindUsersOutcome(t *testing.T) {
+	cases := []struct {
+		name        string
+		handler     http.HandlerFunc
+		wantErr     error
+		wantOutcome string
+	}{
+		{
+			name: "ok",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(`{"Cars":[{"regNum":"X123XX150"}]}`))
+			},
+			wantOutcome: metrics.OutcomeOK,
+		},
+		{
+			name: "server error",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantErr:     ErrServerFatal,
+			wantOutcome: metrics.OutcomeServerError,
+		},
+		{
+			name: "bad request",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			wantErr:     ErrBadReq,
+			wantOutcome: metrics.OutcomeBadRequest,
+		},
+		{
+			name: "bad response",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("not json"))
+			},
+			wantErr:     ErrBadResp,
+			wantOutcome: metrics.OutcomeBadResponse,
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(1500 * time.Millisecond)
+			},
+			wantErr:     ErrTimeout,
+			wantOutcome: metrics.OutcomeTimeout,
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			srv := httptest.NewServer(tc.handler)
+			defer srv.Close()
+
+			before := infoRequests(t, tc.wantOutcome)
+
+			c := &SearchClient{URL: srv.URL}
+			_, err := c.FindUsers(context.Background(), SearchRequest{RegNums: []string{"X123XX150"}})
+			if tc.wantErr != nil {
+				require.ErrorIs(t, err, tc.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, before+1, infoRequests(t, tc.wantOutcome))
+		})
+	}
+}
+
+func TestFindUsersConnError(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	before := infoRequests(t, metrics.OutcomeConnError)
+
+	c := &SearchClient{URL: url}
+	_, err := c.FindUsers(context.Background(), SearchRequest{RegNums: []string{"X123XX150"}})
+	require.ErrorIs(t, err, ErrBadConn)
+
+	assert.Equal(t, before+1, infoRequests(t, metrics.OutcomeConnError))
+}
+
+func TestFindUsersTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "POST /car/save")
+	c := &SearchClient{URL: srv.URL}
+	_, err := c.FindUsers(ctx, SearchRequest{RegNums: []string{"X123XX150"}})
+	parent.End()
+	require.ErrorIs(t, err, ErrServerFatal)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	span := spans[0]
+
+	assert.Equal(t, "GET /info", span.Name())
+	assert.Equal(t, trace.SpanKindClient, span.SpanKind())
+	assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	assert.Contains(t, span.Attributes(), attribute.String("info.outcome", metrics.OutcomeServerError))
+	assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusInternalServerError))
+	assert.Equal(t, codes.Error, span.Status().Code)
+
+	want := "00-" + span.SpanContext().TraceID().String() + "-" + span.SpanContext().SpanID().String() + "-01"
+	assert.Equal(t, want, traceparent)
+}
+
+func TestPing(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+
+	c := &SearchClient{URL: srv.URL}
+	assert.NoError(t, c.Ping(context.Background()))
+
+	srv.Close()
+	assert.ErrorIs(t, c.Ping(context.Background()), ErrBadConn)
+}
+
+// infoRequests returns the number of calls to the info API observed with outcome.
+func infoRequests(t *testing.T, outcome string) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	err := metrics.InfoRequestDuration.WithLabelValues(outcome).(prometheus.Metric).Write(&m)
+	require.NoError(t, err)
+
+	return m.GetHistogram().GetSampleCount()
+}
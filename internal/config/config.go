@@ -4,6 +4,7 @@ import (
 	"github.com/joho/godotenv"
 	"log"
 	"os"
+	"strconv"
 	"time"
 )
 
@@ -14,6 +15,49 @@ type Config struct {
 	HelpAPI     string
 	Timeout     time.Duration
 	IdleTimeout time.Duration
+	// PurgeRetention is how long soft-deleted cars and owners are kept before being purged
+	PurgeRetention time.Duration
+	PurgeInterval  time.Duration
+	// IdempotencyTTL is how long responses to requests with an Idempotency-Key are kept for replay
+	IdempotencyTTL time.Duration
+	// EventsPollInterval is how often event streams check for new events when no notification arrives
+	EventsPollInterval time.Duration
+	// WebhookTimeout limits a single attempt to deliver a webhook
+	WebhookTimeout time.Duration
+	// OutboxPublisher is where the outbox relay publishes events: stderr, file:///path or nats://host:port
+	OutboxPublisher string
+	// OutboxTimeout limits publishing a single outbox message
+	OutboxTimeout time.Duration
+	// JWTSecret verifies HS256 bearer tokens
+	JWTSecret string
+	// JWTPublicKeyFile is a PEM file with the RSA public key verifying RS256 bearer tokens
+	JWTPublicKeyFile string
+	// JWKSFile is a JSON Web Key Set file verifying RS256 bearer tokens by their kid
+	JWKSFile string
+	// JWTIssuer and JWTAudience, if set, must match the iss and aud claims of bearer tokens
+	JWTIssuer   string
+	JWTAudience string
+	// RateLimit is the number of requests per second a client may make on average, zero for no limit,
+	// and RateLimitBurst the number it may make at once
+	RateLimit      float64
+	RateLimitBurst int
+	// SaveRateLimit and SaveRateLimitBurst limit POST /car/save, which calls the info API, on top of RateLimit
+	SaveRateLimit      float64
+	SaveRateLimitBurst int
+	// IPRateLimit and IPRateLimitBurst limit the requests from every IP before they are authenticated
+	IPRateLimit      float64
+	IPRateLimitBurst int
+	// TraceExporter is where spans are exported: none, stdout or otlp
+	TraceExporter string
+	// DBConnectTimeout is how long the service waits for the database on startup before giving up
+	DBConnectTimeout time.Duration
+	// ShutdownDelay is how long the service keeps serving after it reports itself not ready on shutdown,
+	// so that load balancers stop sending it requests first
+	ShutdownDelay time.Duration
+	// ShutdownTimeout limits draining the in-flight requests on shutdown
+	ShutdownTimeout time.Duration
+	// WorkersShutdownTimeout limits waiting for the background workers to finish their jobs on shutdown
+	WorkersShutdownTimeout time.Duration
 }
 
 func InitConfig() *Config {
@@ -42,6 +86,86 @@ func InitConfig() *Config {
 		log.Fatalf("Error parsing IDLE_TIMEOUT: %v", err)
 	}
 
+	purgeRetention, err := time.ParseDuration(os.Getenv("PURGE_RETENTION"))
+	if err != nil {
+		log.Fatalf("Error parsing PURGE_RETENTION: %v", err)
+	}
+
+	purgeInterval, err := time.ParseDuration(os.Getenv("PURGE_INTERVAL"))
+	if err != nil {
+		log.Fatalf("Error parsing PURGE_INTERVAL: %v", err)
+	}
+
+	idempotencyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_TTL"))
+	if err != nil {
+		log.Fatalf("Error parsing IDEMPOTENCY_TTL: %v", err)
+	}
+
+	eventsPollInterval, err := time.ParseDuration(os.Getenv("EVENTS_POLL_INTERVAL"))
+	if err != nil {
+		log.Fatalf("Error parsing EVENTS_POLL_INTERVAL: %v", err)
+	}
+
+	webhookTimeout, err := time.ParseDuration(os.Getenv("WEBHOOK_TIMEOUT"))
+	if err != nil {
+		log.Fatalf("Error parsing WEBHOOK_TIMEOUT: %v", err)
+	}
+
+	outboxTimeout, err := time.ParseDuration(os.Getenv("OUTBOX_TIMEOUT"))
+	if err != nil {
+		log.Fatalf("Error parsing OUTBOX_TIMEOUT: %v", err)
+	}
+
+	rateLimit, err := strconv.ParseFloat(os.Getenv("RATE_LIMIT"), 64)
+	if err != nil {
+		log.Fatalf("Error parsing RATE_LIMIT: %v", err)
+	}
+
+	rateLimitBurst, err := strconv.Atoi(os.Getenv("RATE_LIMIT_BURST"))
+	if err != nil {
+		log.Fatalf("Error parsing RATE_LIMIT_BURST: %v", err)
+	}
+
+	saveRateLimit, err := strconv.ParseFloat(os.Getenv("SAVE_RATE_LIMIT"), 64)
+	if err != nil {
+		log.Fatalf("Error parsing SAVE_RATE_LIMIT: %v", err)
+	}
+
+	saveRateLimitBurst, err := strconv.Atoi(os.Getenv("SAVE_RATE_LIMIT_BURST"))
+	if err != nil {
+		log.Fatalf("Error parsing SAVE_RATE_LIMIT_BURST: %v", err)
+	}
+
+	ipRateLimit, err := strconv.ParseFloat(os.Getenv("IP_RATE_LIMIT"), 64)
+	if err != nil {
+		log.Fatalf("Error parsing IP_RATE_LIMIT: %v", err)
+	}
+
+	ipRateLimitBurst, err := strconv.Atoi(os.Getenv("IP_RATE_LIMIT_BURST"))
+	if err != nil {
+		log.Fatalf("Error parsing IP_RATE_LIMIT_BURST: %v", err)
+	}
+
+	dbConnectTimeout, err := time.ParseDuration(os.Getenv("DB_CONNECT_TIMEOUT"))
+	if err != nil {
+		log.Fatalf("Error parsing DB_CONNECT_TIMEOUT: %v", err)
+	}
+
+	shutdownDelay, err := time.ParseDuration(os.Getenv("SHUTDOWN_DELAY"))
+	if err != nil {
+		log.Fatalf("Error parsing SHUTDOWN_DELAY: %v", err)
+	}
+
+	shutdownTimeout, err := time.ParseDuration(os.Getenv("SHUTDOWN_TIMEOUT"))
+	if err != nil {
+		log.Fatalf("Error parsing SHUTDOWN_TIMEOUT: %v", err)
+	}
+
+	workersShutdownTimeout, err := time.ParseDuration(os.Getenv("WORKERS_SHUTDOWN_TIMEOUT"))
+	if err != nil {
+		log.Fatalf("Error parsing WORKERS_SHUTDOWN_TIMEOUT: %v", err)
+	}
+
 	return &Config{
 		Env:         os.Getenv("ENV"),
 		Storage:     os.Getenv("STORAGE"),
@@ -49,5 +173,36 @@ func InitConfig() *Config {
 		HelpAPI:     os.Getenv("HELP_API"),
 		Timeout:     timeout,
 		IdleTimeout: idleTimeout,
+
+		PurgeRetention: purgeRetention,
+		PurgeInterval:  purgeInterval,
+		IdempotencyTTL: idempotencyTTL,
+
+		EventsPollInterval: eventsPollInterval,
+		WebhookTimeout:     webhookTimeout,
+
+		OutboxPublisher: os.Getenv("OUTBOX_PUBLISHER"),
+		OutboxTimeout:   outboxTimeout,
+
+		JWTSecret:        os.Getenv("JWT_SECRET"),
+		JWTPublicKeyFile: os.Getenv("JWT_PUBLIC_KEY_FILE"),
+		JWKSFile:         os.Getenv("JWKS_FILE"),
+		JWTIssuer:        os.Getenv("JWT_ISSUER"),
+		JWTAudience:      os.Getenv("JWT_AUDIENCE"),
+
+		RateLimit:          rateLimit,
+		RateLimitBurst:     rateLimitBurst,
+		SaveRateLimit:      saveRateLimit,
+		SaveRateLimitBurst: saveRateLimitBurst,
+		IPRateLimit:        ipRateLimit,
+		IPRateLimitBurst:   ipRateLimitBurst,
+
+		TraceExporter: os.Getenv("TRACE_EXPORTER"),
+
+		DBConnectTimeout: dbConnectTimeout,
+
+		ShutdownDelay:          shutdownDelay,
+		ShutdownTimeout:        shutdownTimeout,
+		WorkersShutdownTimeout: workersShutdownTimeout,
 	}
 }
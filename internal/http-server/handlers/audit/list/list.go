@@ -0,0 +1,156 @@
+package list
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage/postgres"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultPageNum  = 1
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+type Request struct {
+	postgres.AuditSearchRequest
+}
+
+type Response struct {
+	response.Response
+	Entries []postgres.AuditEntry `json:"entries"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=AuditLogGetter
+type AuditLogGetter interface {
+	GetAuditLog(ctx context.Context, searchRequest postgres.AuditSearchRequest) ([]postgres.AuditEntry, error)
+}
+
+//	@Summary		List audit log
+//	@Description	List recorded data changes filtered by entity, entity id, actor and time range, oldest first
+//	@Tags			Audit
+//	@Produce		json
+//	@Param			entity		query		string	false	"Entity"	Enums(car, owner)
+//	@Param			id			query		int		false	"Entity ID"
+//	@Param			actor		query		string	false	"Actor"
+//	@Param			from		query		string	false	"From, RFC 3339, inclusive"
+//	@Param			to			query		string	false	"To, RFC 3339, exclusive"
+//	@Param			pageNum		query		int		false	"PageNum"
+//	@Param			pageSize	query		int		false	"PageSize"
+//	@Success		200			{object}	Response
+//	@Failure		400			{object}	response.Response
+//	@Router			/audit [get]
+func New(log *slog.Logger, auditLogGetter AuditLogGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.audit.list.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		entries, err := auditLogGetter.GetAuditLog(r.Context(), req.AuditSearchRequest)
+		if err != nil {
+			log.Error("failed to get audit log", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get audit log"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			entries,
+		})
+	}
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	query := r.URL.Query()
+
+	req := Request{postgres.AuditSearchRequest{
+		Entity:   query.Get("entity"),
+		Actor:    query.Get("actor"),
+		PageNum:  defaultPageNum,
+		PageSize: defaultPageSize,
+	}}
+
+	var err error
+	if id := query.Get("id"); id != "" {
+		if req.EntityID, err = strconv.Atoi(id); err != nil {
+			return Request{}, err
+		}
+	}
+	if from := query.Get("from"); from != "" {
+		t, err := time.Parse(time.RFC3339, from)
+		if err != nil {
+			return Request{}, err
+		}
+		req.From = &t
+	}
+	if to := query.Get("to"); to != "" {
+		t, err := time.Parse(time.RFC3339, to)
+		if err != nil {
+			return Request{}, err
+		}
+		req.To = &t
+	}
+	if pageNum := query.Get("pageNum"); pageNum != "" {
+		if req.PageNum, err = strconv.Atoi(pageNum); err != nil {
+			return Request{}, err
+		}
+	}
+	if pageSize := query.Get("pageSize"); pageSize != "" {
+		if req.PageSize, err = strconv.Atoi(pageSize); err != nil {
+			return Request{}, err
+		}
+	}
+
+	return req, nil
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if req.Entity != "" && req.Entity != postgres.EntityCar && req.Entity != postgres.EntityOwner {
+		return false, slog.String("field", "entity"), "field entity is not valid"
+	}
+	if req.EntityID < 0 {
+		return false, slog.String("field", "id"), "field id is not valid"
+	}
+	if req.From != nil && req.To != nil && !req.From.Before(*req.To) {
+		return false, slog.String("field", "to"), "field to is not valid"
+	}
+	if req.PageSize < 1 || req.PageSize > maxPageSize {
+		return false, slog.String("field", "pageSize"), "field pageSize is not valid"
+	}
+	if req.PageNum < 1 {
+		return false, slog.String("field", "pageNum"), "field pageNum is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
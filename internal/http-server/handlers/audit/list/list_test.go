@@ -0,0 +1,103 @@
+package list_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/audit/list"
+	"effective_mobile_test/internal/http-server/handlers/audit/list/mocks"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestListHandler(t *testing.T) {
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	cases := []struct {
+		name  string
+		query string
+		// want is the request expected by the storage, nil if it must not be called
+		want      *postgres.AuditSearchRequest
+		mockErr   error
+		wantError string
+	}{
+		{
+			name: "defaults",
+			want: &postgres.AuditSearchRequest{PageNum: 1, PageSize: 50},
+		},
+		{
+			name:  "filters and page",
+			query: "?entity=car&id=5&actor=alice&from=2024-03-01T00:00:00Z&to=2024-03-02T00:00:00Z&pageNum=2&pageSize=10",
+			want: &postgres.AuditSearchRequest{
+				Entity: postgres.EntityCar, EntityID: 5, Actor: "alice", From: &from, To: &to, PageNum: 2, PageSize: 10,
+			},
+		},
+		{
+			name:      "storage failure",
+			want:      &postgres.AuditSearchRequest{PageNum: 1, PageSize: 50},
+			mockErr:   errors.New("unexpected error"),
+			wantError: "failed to get audit log",
+		},
+		{
+			name:      "from is not a timestamp",
+			query:     "?from=yesterday",
+			wantError: "failed to parse request",
+		},
+		{
+			name:      "unknown entity",
+			query:     "?entity=driver",
+			wantError: "field entity is not valid",
+		},
+		{
+			name:      "negative id",
+			query:     "?id=-1",
+			wantError: "field id is not valid",
+		},
+		{
+			name:      "empty time range",
+			query:     "?from=2024-03-02T00:00:00Z&to=2024-03-01T00:00:00Z",
+			wantError: "field to is not valid",
+		},
+		{
+			name:      "page size above the limit",
+			query:     "?pageSize=1001",
+			wantError: "field pageSize is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			auditLogGetter := mocks.NewAuditLogGetter(t)
+			if tc.want != nil {
+				auditLogGetter.On("GetAuditLog", mock.Anything, *tc.want).
+					Return([]postgres.AuditEntry{{ID: 1, Entity: postgres.EntityCar, EntityID: 5, Action: "update"}}, tc.mockErr).
+					Once()
+			}
+
+			handler := list.New(slog.New(slog.NewTextHandler(io.Discard, nil)), auditLogGetter)
+
+			req := httptest.NewRequest(http.MethodGet, "/audit"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Len(t, resp.Entries, 1)
+			}
+		})
+	}
+}
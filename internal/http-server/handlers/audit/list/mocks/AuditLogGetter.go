@@ -0,0 +1,60 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// AuditLogGetter is an autogenerated mock type for the AuditLogGetter type
+type AuditLogGetter struct {
+	mock.Mock
+}
+
+// GetAuditLog provides a mock function with given fields: ctx, searchRequest
+func (_m *AuditLogGetter) GetAuditLog(ctx context.Context, searchRequest postgres.AuditSearchRequest) ([]postgres.AuditEntry, error) {
+	ret := _m.Called(ctx, searchRequest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetAuditLog")
+	}
+
+	var r0 []postgres.AuditEntry
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.AuditSearchRequest) ([]postgres.AuditEntry, error)); ok {
+		return rf(ctx, searchRequest)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.AuditSearchRequest) []postgres.AuditEntry); ok {
+		r0 = rf(ctx, searchRequest)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.AuditEntry)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.AuditSearchRequest) error); ok {
+		r1 = rf(ctx, searchRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewAuditLogGetter creates a new instance of AuditLogGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewAuditLogGetter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *AuditLogGetter {
+	mock := &AuditLogGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
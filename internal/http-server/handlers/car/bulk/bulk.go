@@ -0,0 +1,210 @@
+package bulk
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	// ModeTransaction applies all operations or none of them
+	ModeTransaction = "transaction"
+	// ModeBestEffort applies the operations that succeed and skips the failing ones
+	ModeBestEffort = "bestEffort"
+
+	maxOperations = 1000
+)
+
+type Request struct {
+	// Mode is transaction (default) or bestEffort
+	Mode       string                   `json:"mode,omitempty"`
+	Operations []postgres.BulkOperation `json:"operations"`
+}
+
+type Response struct {
+	response.Response
+	Applied int                   `json:"applied"`
+	Results []postgres.BulkResult `json:"results"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarBulker
+type CarBulker interface {
+	BulkCars(ctx context.Context, ops []postgres.BulkOperation, atomic bool) ([]postgres.BulkResult, error)
+}
+
+//	@Summary		Bulk change cars
+//	@Description	Create, patch and delete cars by id or regNum in one request. In transaction mode a failing
+//	@Description	operation rolls back all of them and the request is refused with 409, in bestEffort mode
+//	@Description	the failing operations are skipped. Results are returned in the order of operations.
+//	@Tags			Car
+//	@Accept			json
+//	@Produce		json
+//	@Param			mode		body		string						false	"Mode"
+//	@Param			operations	body		[]postgres.BulkOperation	true	"Operations"
+//	@Success		200			{object}	Response
+//	@Failure		400			{object}	response.Response
+//	@Failure		403			{object}	problem.Problem
+//	@Failure		409			{object}	Response
+//	@Router			/cars/bulk [post]
+func New(log *slog.Logger, carBulker CarBulker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.car.bulk.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if req.Mode == "" {
+			req.Mode = ModeTransaction
+		}
+
+		log.Info("request body decoded", slog.String("mode", req.Mode), slog.Int("operations", len(req.Operations)))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		if !canDelete(r, req) {
+			log.Info("permission denied", slog.String("permission", auth.PermCarDelete))
+
+			problem.Write(w, r, http.StatusForbidden, "permission "+auth.PermCarDelete+" is required to delete cars")
+
+			return
+		}
+
+		results, err := carBulker.BulkCars(r.Context(), req.Operations, req.Mode == ModeTransaction)
+		if errors.Is(err, storage.ErrCarExists) {
+			log.Info("car with the same regNum already exists")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("car with the same regNum already exists"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to apply bulk operations", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to apply bulk operations"))
+
+			return
+		}
+
+		applied := 0
+		for _, result := range results {
+			if result.Status == postgres.BulkStatusOK {
+				applied++
+			}
+		}
+
+		log.Info("bulk operations applied", slog.Int("applied", applied))
+
+		if req.Mode == ModeTransaction && applied < len(results) {
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, Response{
+				response.Error("bulk operations rolled back"),
+				0,
+				results,
+			})
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			applied,
+			results,
+		})
+	}
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if req.Mode != ModeTransaction && req.Mode != ModeBestEffort {
+		return false, slog.String("field", "mode"), "field mode is not valid"
+	}
+	if len(req.Operations) < 1 || len(req.Operations) > maxOperations {
+		return false, slog.String("field", "operations"), "field operations is not valid"
+	}
+	for i, o := range req.Operations {
+		if ok, field, msg := validateOperation(o, fmt.Sprintf("operations[%d].", i)); !ok {
+			return ok, field, msg
+		}
+	}
+	return true, slog.Attr{}, ""
+}
+
+func validateOperation(o postgres.BulkOperation, prefix string) (bool, slog.Attr, string) {
+	invalid := func(field string) (bool, slog.Attr, string) {
+		return false, slog.String("field", prefix+field), "field " + prefix + field + " is not valid"
+	}
+
+	switch o.Action {
+	case postgres.BulkCreate:
+		if o.Car == nil || o.ID != 0 || o.RegNum != "" || o.Patch != nil || o.Version != nil {
+			return invalid("car")
+		}
+		return validate.Car(*o.Car, prefix+"car.")
+	case postgres.BulkPatch, postgres.BulkDelete:
+		if (o.ID == 0) == (o.RegNum == "") || o.ID < 0 || len(o.RegNum) > 255 {
+			return invalid("id")
+		}
+		if o.Car != nil {
+			return invalid("car")
+		}
+		if o.Version != nil && *o.Version < 1 {
+			return invalid("version")
+		}
+	default:
+		return invalid("action")
+	}
+
+	if o.Action == postgres.BulkDelete {
+		if o.Patch != nil {
+			return invalid("patch")
+		}
+		return true, slog.Attr{}, ""
+	}
+
+	if o.Patch == nil {
+		return invalid("patch")
+	}
+	return validate.CarPatch(*o.Patch, prefix+"patch.")
+}
+
+// canDelete reports whether the caller may make the deletions of the request.
+func canDelete(r *http.Request, req Request) bool {
+	for _, o := range req.Operations {
+		if o.Action == postgres.BulkDelete {
+			return auth.Can(r.Context(), auth.PermCarDelete)
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,201 @@
+package bulk_test
+
+import (
+	"bytes"
+	"effective_mobile_test/internal/http-server/handlers/car/bulk"
+	"effective_mobile_test/internal/http-server/handlers/car/bulk/mocks"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBulkHandler(t *testing.T) {
+	create := postgres.BulkOperation{
+		Action: postgres.BulkCreate,
+		Car: &postgres.Car{
+			RegNum: "X123XX150",
+			Mark:   "Lada",
+			Model:  "Vesta",
+			Year:   2002,
+			Owner:  postgres.Owner{Name: "Ivan", Surname: "Ivanov"},
+		},
+	}
+	mark := "Kia"
+	patch := postgres.BulkOperation{Action: postgres.BulkPatch, ID: 1, Patch: &postgres.CarPatch{Mark: &mark}}
+	del := postgres.BulkOperation{Action: postgres.BulkDelete, RegNum: "A001AA77"}
+
+	cases := []struct {
+		name string
+		role string
+		req  bulk.Request
+		// atomic is the mode the storage is expected to be called with, nil if it must not be called
+		atomic     *bool
+		results    []postgres.BulkResult
+		mockErr    error
+		wantStatus int
+		wantError  string
+		wantApply  int
+	}{
+		{
+			name:       "transaction by default",
+			role:       auth.RoleAdmin,
+			req:        bulk.Request{Operations: []postgres.BulkOperation{create, patch, del}},
+			atomic:     ptr(true),
+			results:    []postgres.BulkResult{ok(10), ok(1), ok(2)},
+			wantStatus: http.StatusOK,
+			wantApply:  3,
+		},
+		{
+			name:   "rolled back transaction",
+			role:   auth.RoleEditor,
+			req:    bulk.Request{Mode: bulk.ModeTransaction, Operations: []postgres.BulkOperation{create, patch}},
+			atomic: ptr(true),
+			results: []postgres.BulkResult{
+				{Status: postgres.BulkStatusRolledBack},
+				{Status: postgres.BulkStatusFailed, Error: storage.ErrCarNotFound.Error()},
+			},
+			wantStatus: http.StatusConflict,
+			wantError:  "bulk operations rolled back",
+		},
+		{
+			name:   "best effort skips failed operations",
+			role:   auth.RoleEditor,
+			req:    bulk.Request{Mode: bulk.ModeBestEffort, Operations: []postgres.BulkOperation{create, patch}},
+			atomic: ptr(false),
+			results: []postgres.BulkResult{
+				{Status: postgres.BulkStatusFailed, Error: storage.ErrCarExists.Error()},
+				ok(1),
+			},
+			wantStatus: http.StatusOK,
+			wantApply:  1,
+		},
+		{
+			name:       "conflict that can't be resolved",
+			role:       auth.RoleEditor,
+			req:        bulk.Request{Operations: []postgres.BulkOperation{create}},
+			atomic:     ptr(true),
+			mockErr:    fmt.Errorf("bulk: %w", storage.ErrCarExists),
+			wantStatus: http.StatusConflict,
+			wantError:  "car with the same regNum already exists",
+		},
+		{
+			name:       "storage failure",
+			role:       auth.RoleEditor,
+			req:        bulk.Request{Operations: []postgres.BulkOperation{create}},
+			atomic:     ptr(true),
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to apply bulk operations",
+		},
+		{
+			name:       "deletion needs car:delete",
+			role:       auth.RoleEditor,
+			req:        bulk.Request{Operations: []postgres.BulkOperation{patch, del}},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "unknown mode",
+			role:       auth.RoleAdmin,
+			req:        bulk.Request{Mode: "sometimes", Operations: []postgres.BulkOperation{create}},
+			wantStatus: http.StatusOK,
+			wantError:  "field mode is not valid",
+		},
+		{
+			name:       "no operations",
+			role:       auth.RoleAdmin,
+			req:        bulk.Request{},
+			wantStatus: http.StatusOK,
+			wantError:  "field operations is not valid",
+		},
+		{
+			name: "create with an id",
+			role: auth.RoleAdmin,
+			req: bulk.Request{Operations: []postgres.BulkOperation{
+				patch, {Action: postgres.BulkCreate, ID: 3, Car: create.Car},
+			}},
+			wantStatus: http.StatusOK,
+			wantError:  "field operations[1].car is not valid",
+		},
+		{
+			name: "patch by both id and regNum",
+			role: auth.RoleAdmin,
+			req: bulk.Request{Operations: []postgres.BulkOperation{
+				{Action: postgres.BulkPatch, ID: 1, RegNum: "X123XX150", Patch: patch.Patch},
+			}},
+			wantStatus: http.StatusOK,
+			wantError:  "field operations[0].id is not valid",
+		},
+		{
+			name: "delete with a patch",
+			role: auth.RoleAdmin,
+			req: bulk.Request{Operations: []postgres.BulkOperation{
+				{Action: postgres.BulkDelete, ID: 1, Patch: patch.Patch},
+			}},
+			wantStatus: http.StatusOK,
+			wantError:  "field operations[0].patch is not valid",
+		},
+		{
+			name: "unknown action",
+			role: auth.RoleAdmin,
+			req: bulk.Request{Operations: []postgres.BulkOperation{
+				{Action: "upsert", ID: 1},
+			}},
+			wantStatus: http.StatusOK,
+			wantError:  "field operations[0].action is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carBulker := mocks.NewCarBulker(t)
+			if tc.atomic != nil {
+				carBulker.On("BulkCars", mock.Anything, tc.req.Operations, *tc.atomic).
+					Return(tc.results, tc.mockErr).Once()
+			}
+
+			handler := bulk.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carBulker)
+
+			body, err := json.Marshal(tc.req)
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodPost, "/cars/bulk", bytes.NewReader(body))
+			req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "test", Role: tc.role}))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			if tc.wantStatus == http.StatusForbidden {
+				return
+			}
+
+			var resp bulk.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			assert.Equal(t, tc.wantApply, resp.Applied)
+			if tc.results != nil {
+				assert.Equal(t, tc.results, resp.Results)
+			}
+		})
+	}
+}
+
+func ok(id int) postgres.BulkResult {
+	return postgres.BulkResult{Status: postgres.BulkStatusOK, ID: id, Version: 1}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,59 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// CarBulker is an autogenerated mock type for the CarBulker type
+type CarBulker struct {
+	mock.Mock
+}
+
+// BulkCars provides a mock function with given fields: ctx, ops, atomic
+func (_m *CarBulker) BulkCars(ctx context.Context, ops []postgres.BulkOperation, atomic bool) ([]postgres.BulkResult, error) {
+	ret := _m.Called(ctx, ops, atomic)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BulkCars")
+	}
+
+	var r0 []postgres.BulkResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []postgres.BulkOperation, bool) ([]postgres.BulkResult, error)); ok {
+		return rf(ctx, ops, atomic)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []postgres.BulkOperation, bool) []postgres.BulkResult); ok {
+		r0 = rf(ctx, ops, atomic)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.BulkResult)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []postgres.BulkOperation, bool) error); ok {
+		r1 = rf(ctx, ops, atomic)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCarBulker creates a new instance of CarBulker. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarBulker(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarBulker {
+	mock := &CarBulker{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -1,8 +1,12 @@
 package delete
 
 import (
+	"context"
+	"effective_mobile_test/internal/lib/api/etag"
 	"effective_mobile_test/internal/lib/api/response"
 	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
@@ -11,6 +15,8 @@ import (
 
 type Request struct {
 	CarId int `json:"carId"`
+	// Version is an alternative to the If-Match header
+	Version *int `json:"version,omitempty"`
 }
 
 type Response struct {
@@ -18,17 +24,21 @@ type Response struct {
 }
 
 type CarDeleter interface {
-	DeleteCar(carID int) error
+	DeleteCar(ctx context.Context, carID int, versions []int) error
 }
 
 //	@Summary		Delete car
-//	@Description	Delete car by carId
+//	@Description	Soft-delete car by carId, it can be restored with /cars/{id}/restore
 //	@Tags			Car
 //	@Accept			json
 //	@Produce		json
-//	@Param			carId	body		int	true	"CarId"
+//	@Param			carId		body		int		true	"CarId"
+//	@Param			version		body		int		false	"Version"
+//	@Param			If-Match	header		string	false	"ETag of the car"
 //	@Success		200		{object}	Response
 //	@Failure		400		{object}	response.Response
+//	@Failure		404		{object}	response.Response
+//	@Failure		412		{object}	response.Response
 //	@Router			/car/delete [delete]
 func New(log *slog.Logger, carDeleter CarDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -37,6 +47,7 @@ func New(log *slog.Logger, carDeleter CarDeleter) http.HandlerFunc {
 		log = log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
 		)
 
 		var req Request
@@ -60,7 +71,41 @@ func New(log *slog.Logger, carDeleter CarDeleter) http.HandlerFunc {
 			return
 		}
 
-		err = carDeleter.DeleteCar(req.CarId)
+		versions, err := etag.Expected(r, req.Version)
+		if errors.Is(err, etag.ErrPreconditionFailed) {
+			log.Info("car version precondition failed", slog.Int("car_id", req.CarId))
+
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("car was changed by someone else"))
+
+			return
+		}
+		if err != nil {
+			log.Error("invalid request", slog.String("field", "If-Match"), sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("header If-Match is not valid"))
+
+			return
+		}
+
+		err = carDeleter.DeleteCar(r.Context(), req.CarId, versions)
+		if errors.Is(err, storage.ErrCarNotFound) {
+			log.Info("car not found", slog.Int("car_id", req.CarId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("car not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			log.Info("car version mismatch", slog.Int("car_id", req.CarId))
+
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("car was changed by someone else"))
+
+			return
+		}
 		if err != nil {
 			log.Error("failed to delete car", sl.Err(err))
 
@@ -79,5 +124,8 @@ func validateRequest(req Request) (bool, slog.Attr, string) {
 	if req.CarId < 1 {
 		return false, slog.String("field", "car_id"), "field car_id is not valid"
 	}
+	if req.Version != nil && *req.Version < 1 {
+		return false, slog.String("field", "version"), "field version is not valid"
+	}
 	return true, slog.Attr{}, ""
 }
@@ -0,0 +1,165 @@
+package export
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/export"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage/postgres"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// flushEvery is the number of rows after which the written part of the file is sent to the client.
+const flushEvery = 1000
+
+type Request struct {
+	postgres.CarFilter
+	// Format is csv (default) or xlsx
+	Format string `json:"format,omitempty"`
+	// Columns lists the exported columns in order, see export.DefaultColumns for the default
+	Columns []string `json:"columns,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarExporter
+type CarExporter interface {
+	ExportCars(ctx context.Context, filter postgres.CarFilter, fn func(car postgres.Car) error) error
+}
+
+// flusher is implemented by the export writers that buffer rows.
+type flusher interface {
+	Flush() error
+}
+
+//	@Summary		Export cars
+//	@Description	Stream all cars matching the search filter, without pagination, as a CSV or XLSX file.
+//	@Description	Columns: id, regNum, mark, model, year, version, deletedAt, ownerId, ownerName, ownerSurname,
+//	@Description	ownerPatronymic, ownerBirthDate, ownerPhone, ownerEmail, ownerDocumentNumber.
+//	@Description	Soft-deleted cars are included on request to callers with the admin:deleted permission.
+//	@Tags			Car
+//	@Accept			json
+//	@Produce		text/csv
+//	@Produce		application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+//	@Param			filter	body		postgres.CarFilter	false	"Filter"
+//	@Param			format	body		string				false	"Format"	Enums(csv, xlsx)
+//	@Param			columns	body		[]string			false	"Columns"
+//	@Success		200		{file}		file
+//	@Failure		400		{object}	response.Response
+//	@Failure		403		{object}	problem.Problem
+//	@Router			/cars/export [post]
+func New(log *slog.Logger, carExporter CarExporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.car.export.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if req.Format == "" {
+			req.Format = export.FormatCSV
+		}
+		if len(req.Columns) == 0 {
+			req.Columns = export.DefaultColumns
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		if req.Format != export.FormatCSV && req.Format != export.FormatXLSX {
+			log.Error("invalid request", slog.String("field", "format"))
+
+			render.JSON(w, r, response.Error("field format is not valid"))
+
+			return
+		}
+
+		columns, err := export.Columns(req.Columns)
+		if err != nil {
+			log.Error("invalid request", slog.String("field", "columns"), sl.Err(err))
+
+			render.JSON(w, r, response.Error("field columns is not valid"))
+
+			return
+		}
+
+		if req.IncludeDeleted && !auth.Can(r.Context(), auth.PermAdminDeleted) {
+			log.Info("permission denied", slog.String("permission", auth.PermAdminDeleted))
+
+			problem.Write(w, r, http.StatusForbidden, "permission "+auth.PermAdminDeleted+" is required to include deleted cars")
+
+			return
+		}
+
+		// an export may take much longer than the server write timeout
+		rc := http.NewResponseController(w)
+		if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn("failed to disable write deadline", sl.Err(err))
+		}
+
+		w.Header().Set("Content-Type", export.ContentType(req.Format))
+		w.Header().Set("Content-Disposition", `attachment; filename="cars.`+req.Format+`"`)
+
+		writer, err := export.NewWriter(req.Format, w)
+		if err != nil {
+			log.Error("failed to start export", sl.Err(err))
+
+			return
+		}
+
+		if err = writer.WriteRow(export.Header(columns)); err != nil {
+			log.Error("failed to write header", sl.Err(err))
+
+			return
+		}
+
+		rows := 0
+		err = carExporter.ExportCars(r.Context(), req.CarFilter, func(car postgres.Car) error {
+			if err := writer.WriteRow(export.Row(columns, car)); err != nil {
+				return err
+			}
+
+			rows++
+			if rows%flushEvery != 0 {
+				return nil
+			}
+			if f, ok := writer.(flusher); ok {
+				if err := f.Flush(); err != nil {
+					return err
+				}
+			}
+
+			return rc.Flush()
+		})
+		if err != nil {
+			// the response has already started, the client sees a truncated file
+			log.Error("failed to export cars", sl.Err(err), slog.Int("rows", rows))
+
+			return
+		}
+
+		if err = writer.Close(); err != nil {
+			log.Error("failed to finish export", sl.Err(err))
+
+			return
+		}
+
+		log.Info("cars exported", slog.Int("rows", rows))
+	}
+}
@@ -0,0 +1,152 @@
+package export_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/car/export"
+	"effective_mobile_test/internal/http-server/handlers/car/export/mocks"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportHandler(t *testing.T) {
+	patronymic := "Ivanovich"
+	cars := []postgres.Car{
+		{ID: 1, RegNum: "X123XX150", Mark: "Lada", Model: "Vesta", Year: 2002,
+			Owner: postgres.Owner{Name: "Ivan", Surname: "Ivanov", Patronymic: &patronymic}},
+		{ID: 2, RegNum: "X124XX150", Mark: "Lada", Model: "Granta", Year: 2015,
+			Owner: postgres.Owner{Name: "Petr", Surname: "Petrov"}},
+	}
+
+	cases := []struct {
+		name string
+		body string
+		// filter is the filter the storage is expected to be called with, nil if it must not be called
+		filter    *postgres.CarFilter
+		mockErr   error
+		wantType  string
+		wantBody  string
+		wantError string
+	}{
+		{
+			name:     "csv with default columns",
+			body:     `{"mark":"Lada"}`,
+			filter:   &postgres.CarFilter{Mark: "Lada"},
+			wantType: "text/csv; charset=utf-8",
+			wantBody: "id,regNum,mark,model,year,ownerName,ownerSurname,ownerPatronymic\n" +
+				"1,X123XX150,Lada,Vesta,2002,Ivan,Ivanov,Ivanovich\n" +
+				"2,X124XX150,Lada,Granta,2015,Petr,Petrov,\n",
+		},
+		{
+			name:     "chosen columns",
+			body:     `{"mark":"Lada","columns":["regNum","year"]}`,
+			filter:   &postgres.CarFilter{Mark: "Lada"},
+			wantType: "text/csv; charset=utf-8",
+			wantBody: "regNum,year\nX123XX150,2002\nX124XX150,2015\n",
+		},
+		{
+			name:     "xlsx",
+			body:     `{"format":"xlsx"}`,
+			filter:   &postgres.CarFilter{},
+			wantType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		},
+		{
+			// the headers are already sent, so the file is cut off instead of being replaced by an error
+			name:     "failure after the response started",
+			body:     `{}`,
+			filter:   &postgres.CarFilter{},
+			mockErr:  errors.New("connection lost"),
+			wantType: "text/csv; charset=utf-8",
+		},
+		{
+			name:      "unknown format",
+			body:      `{"format":"ods"}`,
+			wantError: "field format is not valid",
+		},
+		{
+			name:      "unknown column",
+			body:      `{"columns":["id","price"]}`,
+			wantError: "field columns is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carExporter := mocks.NewCarExporter(t)
+			if tc.filter != nil {
+				carExporter.On("ExportCars", mock.Anything, *tc.filter, mock.Anything).
+					Return(tc.mockErr).Once().
+					Run(func(args mock.Arguments) {
+						fn := args.Get(2).(func(postgres.Car) error)
+						for _, car := range cars {
+							require.NoError(t, fn(car))
+						}
+					})
+			}
+
+			handler := export.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carExporter)
+
+			req := httptest.NewRequest(http.MethodPost, "/cars/export", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			if tc.wantError != "" {
+				var resp map[string]any
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+				assert.Equal(t, tc.wantError, resp["error"])
+
+				return
+			}
+
+			assert.Equal(t, tc.wantType, rr.Header().Get("Content-Type"))
+			assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment")
+			if tc.wantBody != "" {
+				assert.Equal(t, tc.wantBody, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestExportHandlerIncludeDeleted(t *testing.T) {
+	body := `{"mark":"Lada","includeDeleted":true}`
+
+	t.Run("admin", func(t *testing.T) {
+		carExporter := mocks.NewCarExporter(t)
+		carExporter.On("ExportCars", mock.Anything, postgres.CarFilter{Mark: "Lada", IncludeDeleted: true}, mock.Anything).
+			Return(nil).Once()
+
+		handler := export.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carExporter)
+
+		req := httptest.NewRequest(http.MethodPost, "/cars/export", strings.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "test", Role: auth.RoleAdmin}))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("forbidden to viewers", func(t *testing.T) {
+		handler := export.New(slog.New(slog.NewTextHandler(io.Discard, nil)), mocks.NewCarExporter(t))
+
+		req := httptest.NewRequest(http.MethodPost, "/cars/export", strings.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "test", Role: auth.RoleViewer}))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
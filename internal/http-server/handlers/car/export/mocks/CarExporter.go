@@ -0,0 +1,48 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// CarExporter is an autogenerated mock type for the CarExporter type
+type CarExporter struct {
+	mock.Mock
+}
+
+// ExportCars provides a mock function with given fields: ctx, filter, fn
+func (_m *CarExporter) ExportCars(ctx context.Context, filter postgres.CarFilter, fn func(postgres.Car) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ExportCars")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.CarFilter, func(postgres.Car) error) error); ok {
+		r0 = rf(ctx, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewCarExporter creates a new instance of CarExporter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarExporter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarExporter {
+	mock := &CarExporter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,196 @@
+package filter
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	defaultLimit = 100
+	maxLimit     = 10000
+	sampleSize   = 20
+)
+
+type Request struct {
+	Filter postgres.CarFilter `json:"filter"`
+	// Action is patch or delete
+	Action postgres.BulkAction `json:"action"`
+	Patch  *postgres.CarPatch  `json:"patch,omitempty"`
+	// DryRun only counts the matching cars and returns a sample of them
+	DryRun bool `json:"dryRun,omitempty"`
+	// Limit is the most cars the action may change, more matches refuse it
+	Limit int `json:"limit,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	Matched  int            `json:"matched"`
+	Affected int            `json:"affected"`
+	Sample   []postgres.Car `json:"sample,omitempty"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarFilterer
+type CarFilterer interface {
+	CountCarsByFilter(ctx context.Context, filter postgres.CarFilter, sampleSize int) (int, []postgres.Car, error)
+	PatchCarsByFilter(ctx context.Context, filter postgres.CarFilter, patch postgres.CarPatch, limit int) (int, error)
+	DeleteCarsByFilter(ctx context.Context, filter postgres.CarFilter, limit int) (int, error)
+}
+
+//	@Summary		Patch or delete cars by filter
+//	@Description	Apply a patch or a deletion to every car matching the search filter in one transaction.
+//	@Description	A dry run returns the number of matching cars and a sample of them without changing anything.
+//	@Description	If more cars than limit (default 100) match, the request is refused with 422.
+//	@Tags			Car
+//	@Accept			json
+//	@Produce		json
+//	@Param			filter	body		postgres.CarFilter	true	"Filter"
+//	@Param			action	body		string				true	"Action"	Enums(patch, delete)
+//	@Param			patch	body		postgres.CarPatch	false	"Patch"
+//	@Param			dryRun	body		bool				false	"DryRun"
+//	@Param			limit	body		int					false	"Limit"
+//	@Success		200		{object}	Response
+//	@Failure		400		{object}	response.Response
+//	@Failure		403		{object}	problem.Problem
+//	@Failure		409		{object}	response.Response
+//	@Failure		422		{object}	response.Response
+//	@Router			/cars/by-filter [post]
+func New(log *slog.Logger, carFilterer CarFilterer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.car.filter.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		if req.Limit == 0 {
+			req.Limit = defaultLimit
+		}
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		if req.Action == postgres.BulkDelete && !req.DryRun && !auth.Can(r.Context(), auth.PermCarDelete) {
+			log.Info("permission denied", slog.String("permission", auth.PermCarDelete))
+
+			problem.Write(w, r, http.StatusForbidden, "permission "+auth.PermCarDelete+" is required to delete cars")
+
+			return
+		}
+
+		if req.DryRun {
+			matched, sample, err := carFilterer.CountCarsByFilter(r.Context(), req.Filter, sampleSize)
+			if err != nil {
+				log.Error("failed to count cars by filter", sl.Err(err))
+
+				render.JSON(w, r, response.Error("failed to count cars by filter"))
+
+				return
+			}
+
+			render.JSON(w, r, Response{
+				Response: response.OK(),
+				Matched:  matched,
+				Sample:   sample,
+			})
+
+			return
+		}
+
+		var affected int
+		if req.Action == postgres.BulkDelete {
+			affected, err = carFilterer.DeleteCarsByFilter(r.Context(), req.Filter, req.Limit)
+		} else {
+			affected, err = carFilterer.PatchCarsByFilter(r.Context(), req.Filter, *req.Patch, req.Limit)
+		}
+		if errors.Is(err, storage.ErrTooManyMatches) {
+			log.Info("too many cars match the filter", slog.Int("limit", req.Limit))
+
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, response.Error("more cars than limit match the filter"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrCarExists) {
+			log.Info("car with the same regNum already exists")
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("car with the same regNum already exists"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to apply action by filter", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to apply action by filter"))
+
+			return
+		}
+
+		log.Info("action applied by filter", slog.String("action", string(req.Action)), slog.Int("affected", affected))
+
+		render.JSON(w, r, Response{
+			Response: response.OK(),
+			Matched:  affected,
+			Affected: affected,
+		})
+	}
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	// an empty filter would change the whole catalog
+	if req.Filter.Empty() {
+		return false, slog.String("field", "filter"), "field filter is not valid"
+	}
+	if req.Filter.IncludeDeleted {
+		return false, slog.String("field", "filter.includeDeleted"), "field filter.includeDeleted is not valid"
+	}
+	if req.Limit < 1 || req.Limit > maxLimit {
+		return false, slog.String("field", "limit"), "field limit is not valid"
+	}
+	switch req.Action {
+	case postgres.BulkDelete:
+		if req.Patch != nil {
+			return false, slog.String("field", "patch"), "field patch is not valid"
+		}
+	case postgres.BulkPatch:
+		// several cars can't share a regNum
+		if req.Patch == nil || req.Patch.RegNum != nil {
+			return false, slog.String("field", "patch"), "field patch is not valid"
+		}
+		return validate.CarPatch(*req.Patch, "patch.")
+	default:
+		return false, slog.String("field", "action"), "field action is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
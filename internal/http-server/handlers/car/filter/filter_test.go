@@ -0,0 +1,208 @@
+package filter_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/car/filter"
+	"effective_mobile_test/internal/http-server/handlers/car/filter/mocks"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDryRun(t *testing.T) {
+	cases := []struct {
+		name    string
+		matched int
+		sample  []postgres.Car
+	}{
+		{
+			name:    "no match",
+			matched: 0,
+			sample:  []postgres.Car{},
+		},
+		{
+			name:    "matches",
+			matched: 2,
+			sample:  []postgres.Car{{ID: 1, RegNum: "X123XX150", Mark: "Lada"}, {ID: 2, RegNum: "X124XX150", Mark: "Lada"}},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carFilterer := mocks.NewCarFilterer(t)
+			carFilterer.On("CountCarsByFilter", mock.Anything, postgres.CarFilter{Mark: "Lada"}, mock.Anything).
+				Return(tc.matched, tc.sample, nil).Once()
+
+			handler := filter.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carFilterer)
+
+			req := httptest.NewRequest(http.MethodPost, "/cars/by-filter",
+				strings.NewReader(`{"filter":{"mark":"Lada"},"action":"delete","dryRun":true}`))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			// the count is present even when nothing matches
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, float64(tc.matched), body["matched"])
+			assert.Equal(t, float64(0), body["affected"])
+		})
+	}
+}
+
+func TestFilterHandler(t *testing.T) {
+	lada := "Lada"
+
+	cases := []struct {
+		name string
+		body string
+		// role of the caller, editor if empty
+		role string
+		// action is the storage method expected to be called, empty if none must be
+		action     string
+		patch      postgres.CarPatch
+		limit      int
+		affected   int
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "patch",
+			body:       `{"filter":{"mark":"Lado"},"action":"patch","patch":{"mark":"Lada"}}`,
+			action:     "PatchCarsByFilter",
+			patch:      postgres.CarPatch{Mark: &lada},
+			limit:      100,
+			affected:   3,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "delete with limit",
+			role:       auth.RoleAdmin,
+			body:       `{"filter":{"mark":"Lado"},"action":"delete","limit":5}`,
+			action:     "DeleteCarsByFilter",
+			limit:      5,
+			affected:   5,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "more matches than limit",
+			role:       auth.RoleAdmin,
+			body:       `{"filter":{"mark":"Lado"},"action":"delete","limit":5}`,
+			action:     "DeleteCarsByFilter",
+			limit:      5,
+			mockErr:    fmt.Errorf("delete: %w", storage.ErrTooManyMatches),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantError:  "more cars than limit match the filter",
+		},
+		{
+			name:       "patch to a taken regNum",
+			body:       `{"filter":{"mark":"Lado"},"action":"patch","patch":{"mark":"Lada"}}`,
+			action:     "PatchCarsByFilter",
+			patch:      postgres.CarPatch{Mark: &lada},
+			limit:      100,
+			mockErr:    fmt.Errorf("patch: %w", storage.ErrCarExists),
+			wantStatus: http.StatusConflict,
+			wantError:  "car with the same regNum already exists",
+		},
+		{
+			name:       "deletion needs car:delete",
+			body:       `{"filter":{"mark":"Lado"},"action":"delete"}`,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "storage failure",
+			body:       `{"filter":{"mark":"Lado"},"action":"delete"}`,
+			role:       auth.RoleAdmin,
+			action:     "DeleteCarsByFilter",
+			limit:      100,
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to apply action by filter",
+		},
+		{
+			name:       "empty filter",
+			body:       `{"filter":{},"action":"delete"}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field filter is not valid",
+		},
+		{
+			name:       "deleted cars",
+			body:       `{"filter":{"mark":"Lado","includeDeleted":true},"action":"delete"}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field filter.includeDeleted is not valid",
+		},
+		{
+			name:       "limit too big",
+			body:       `{"filter":{"mark":"Lado"},"action":"delete","limit":10001}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field limit is not valid",
+		},
+		{
+			name:       "patch of the regNum",
+			body:       `{"filter":{"mark":"Lado"},"action":"patch","patch":{"regNum":"X123XX150"}}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field patch is not valid",
+		},
+		{
+			name:       "unknown action",
+			body:       `{"filter":{"mark":"Lado"},"action":"create"}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field action is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carFilterer := mocks.NewCarFilterer(t)
+			switch tc.action {
+			case "PatchCarsByFilter":
+				carFilterer.On(tc.action, mock.Anything, postgres.CarFilter{Mark: "Lado"}, tc.patch, tc.limit).
+					Return(tc.affected, tc.mockErr).Once()
+			case "DeleteCarsByFilter":
+				carFilterer.On(tc.action, mock.Anything, postgres.CarFilter{Mark: "Lado"}, tc.limit).
+					Return(tc.affected, tc.mockErr).Once()
+			}
+
+			handler := filter.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carFilterer)
+
+			role := tc.role
+			if role == "" {
+				role = auth.RoleEditor
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/cars/by-filter", strings.NewReader(tc.body))
+			req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "test", Role: role}))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			if tc.wantStatus == http.StatusForbidden {
+				return
+			}
+
+			var resp filter.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Equal(t, tc.affected, resp.Matched)
+				assert.Equal(t, tc.affected, resp.Affected)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// CarFilterer is an autogenerated mock type for the CarFilterer type
+type CarFilterer struct {
+	mock.Mock
+}
+
+// CountCarsByFilter provides a mock function with given fields: ctx, _a1, sampleSize
+func (_m *CarFilterer) CountCarsByFilter(ctx context.Context, _a1 postgres.CarFilter, sampleSize int) (int, []postgres.Car, error) {
+	ret := _m.Called(ctx, _a1, sampleSize)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CountCarsByFilter")
+	}
+
+	var r0 int
+	var r1 []postgres.Car
+	var r2 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.CarFilter, int) (int, []postgres.Car, error)); ok {
+		return rf(ctx, _a1, sampleSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.CarFilter, int) int); ok {
+		r0 = rf(ctx, _a1, sampleSize)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.CarFilter, int) []postgres.Car); ok {
+		r1 = rf(ctx, _a1, sampleSize)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]postgres.Car)
+		}
+	}
+
+	if rf, ok := ret.Get(2).(func(context.Context, postgres.CarFilter, int) error); ok {
+		r2 = rf(ctx, _a1, sampleSize)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// DeleteCarsByFilter provides a mock function with given fields: ctx, _a1, limit
+func (_m *CarFilterer) DeleteCarsByFilter(ctx context.Context, _a1 postgres.CarFilter, limit int) (int, error) {
+	ret := _m.Called(ctx, _a1, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteCarsByFilter")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.CarFilter, int) (int, error)); ok {
+		return rf(ctx, _a1, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.CarFilter, int) int); ok {
+		r0 = rf(ctx, _a1, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.CarFilter, int) error); ok {
+		r1 = rf(ctx, _a1, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// PatchCarsByFilter provides a mock function with given fields: ctx, _a1, patch, limit
+func (_m *CarFilterer) PatchCarsByFilter(ctx context.Context, _a1 postgres.CarFilter, patch postgres.CarPatch, limit int) (int, error) {
+	ret := _m.Called(ctx, _a1, patch, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PatchCarsByFilter")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.CarFilter, postgres.CarPatch, int) (int, error)); ok {
+		return rf(ctx, _a1, patch, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.CarFilter, postgres.CarPatch, int) int); ok {
+		r0 = rf(ctx, _a1, patch, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.CarFilter, postgres.CarPatch, int) error); ok {
+		r1 = rf(ctx, _a1, patch, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCarFilterer creates a new instance of CarFilterer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarFilterer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarFilterer {
+	mock := &CarFilterer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,82 @@
+package get
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/etag"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Response struct {
+	response.Response
+	Car postgres.Car `json:"car"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarGetter
+type CarGetter interface {
+	GetCar(ctx context.Context, carID int) (postgres.Car, error)
+}
+
+//	@Summary		Get car
+//	@Description	Get car by id together with its current owner
+//	@Tags			Car
+//	@Produce		json
+//	@Param			id	path		int	true	"CarId"
+//	@Success		200	{object}	Response
+//	@Header			200	{string}	ETag	"ETag of the car"
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Router			/cars/{id} [get]
+func New(log *slog.Logger, carGetter CarGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.car.get.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		carId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || carId < 1 {
+			log.Error("invalid request", slog.String("field", "id"))
+
+			render.JSON(w, r, response.Error("field id is not valid"))
+
+			return
+		}
+
+		car, err := carGetter.GetCar(r.Context(), carId)
+		if errors.Is(err, storage.ErrCarNotFound) {
+			log.Info("car not found", slog.Int("car_id", carId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("car not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get car", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get car"))
+
+			return
+		}
+
+		etag.Set(w, car.Version)
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			car,
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package get_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/car/get"
+	"effective_mobile_test/internal/http-server/handlers/car/get/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		callGet    bool
+		mockErr    error
+		wantStatus int
+		wantError  string
+		wantETag   string
+	}{
+		{
+			name:       "car with its version",
+			id:         "5",
+			callGet:    true,
+			wantStatus: http.StatusOK,
+			wantETag:   `"3"`,
+		},
+		{
+			name:       "unknown car",
+			id:         "5",
+			callGet:    true,
+			mockErr:    storage.ErrCarNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "car not found",
+		},
+		{
+			name:       "invalid id",
+			id:         "0",
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carGetter := mocks.NewCarGetter(t)
+			if tc.callGet {
+				carGetter.On("GetCar", mock.Anything, 5).
+					Return(postgres.Car{ID: 5, RegNum: "X123XX150", Version: 3}, tc.mockErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Get("/cars/{id}", get.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carGetter))
+
+			req := httptest.NewRequest(http.MethodGet, "/cars/"+tc.id, nil)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantETag, rr.Header().Get("ETag"))
+
+			var resp get.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Equal(t, 3, resp.Car.Version)
+			}
+		})
+	}
+}
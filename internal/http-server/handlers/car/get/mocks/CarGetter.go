@@ -0,0 +1,58 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// CarGetter is an autogenerated mock type for the CarGetter type
+type CarGetter struct {
+	mock.Mock
+}
+
+// GetCar provides a mock function with given fields: ctx, carID
+func (_m *CarGetter) GetCar(ctx context.Context, carID int) (postgres.Car, error) {
+	ret := _m.Called(ctx, carID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCar")
+	}
+
+	var r0 postgres.Car
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (postgres.Car, error)); ok {
+		return rf(ctx, carID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) postgres.Car); ok {
+		r0 = rf(ctx, carID)
+	} else {
+		r0 = ret.Get(0).(postgres.Car)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, carID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCarGetter creates a new instance of CarGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarGetter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarGetter {
+	mock := &CarGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,133 @@
+package importer
+
+import (
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/csvimport"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// maxFileSize is the largest accepted CSV file in bytes.
+const maxFileSize = 32 << 20
+
+type Request struct {
+	postgres.ImportOptions
+}
+
+type Response struct {
+	response.Response
+	postgres.ImportResult
+}
+
+//	@Summary		Import cars
+//	@Description	Import cars with their owners from a CSV file with a header row. Columns: regNum, mark, model, year,
+//	@Description	ownerName, ownerSurname are required; ownerPatronymic, ownerBirthDate, ownerPhone, ownerEmail,
+//	@Description	ownerDocumentNumber are optional; id, version, deletedAt, ownerId of an exported file are ignored.
+//	@Description	Owners are reused the way /owner/save finds them. Invalid rows and rows whose regNum is taken
+//	@Description	are reported with their line numbers; unless skipInvalid is set, nothing is imported then and
+//	@Description	the request fails with 422. A dry run only reports the errors.
+//	@Tags			Car
+//	@Accept			text/csv
+//	@Produce		json
+//	@Param			dryRun		query		bool	false	"DryRun"
+//	@Param			skipInvalid	query		bool	false	"SkipInvalid"
+//	@Success		200			{object}	Response
+//	@Failure		400			{object}	response.Response
+//	@Failure		422			{object}	Response
+//	@Router			/cars/import [post]
+func New(log *slog.Logger, carImporter csvimport.CarImporter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.car.importer.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		// a large file takes longer to upload and load than the server timeouts allow
+		rc := http.NewResponseController(w)
+		if err = rc.SetReadDeadline(time.Time{}); err != nil {
+			log.Warn("failed to disable read deadline", sl.Err(err))
+		}
+		if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn("failed to disable write deadline", sl.Err(err))
+		}
+
+		body := http.MaxBytesReader(w, r.Body, maxFileSize)
+
+		result, err := csvimport.Import(r.Context(), carImporter, body, req.ImportOptions)
+		if errors.Is(err, csvimport.ErrInvalidFile) {
+			log.Error("invalid file", sl.Err(err))
+
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			log.Error("file is too large", sl.Err(err))
+
+			render.Status(r, http.StatusRequestEntityTooLarge)
+			render.JSON(w, r, response.Error("file is too large"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to import cars", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to import cars"))
+
+			return
+		}
+
+		log.Info("cars imported", slog.Int("imported", result.Imported), slog.Int("errors", len(result.Errors)),
+			slog.Bool("dry_run", req.DryRun))
+
+		if len(result.Errors) > 0 && !req.SkipInvalid {
+			render.Status(r, http.StatusUnprocessableEntity)
+			render.JSON(w, r, Response{response.Error("some rows can't be imported"), result})
+
+			return
+		}
+
+		render.JSON(w, r, Response{response.OK(), result})
+	}
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	query := r.URL.Query()
+
+	var req Request
+	var err error
+	if dryRun := query.Get("dryRun"); dryRun != "" {
+		if req.DryRun, err = strconv.ParseBool(dryRun); err != nil {
+			return Request{}, err
+		}
+	}
+	if skipInvalid := query.Get("skipInvalid"); skipInvalid != "" {
+		if req.SkipInvalid, err = strconv.ParseBool(skipInvalid); err != nil {
+			return Request{}, err
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,119 @@
+package importer_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/car/importer"
+	"effective_mobile_test/internal/lib/csvimport/mocks"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImporterHandler(t *testing.T) {
+	const file = "regNum,mark,model,year,ownerName,ownerSurname\n" +
+		"X123XX150,Lada,Vesta,2002,Ivan,Ivanov\n"
+
+	cases := []struct {
+		name  string
+		query string
+		file  string
+		// opts are the options the storage is expected to be called with, nil if it must not be called
+		opts       *postgres.ImportOptions
+		result     postgres.ImportResult
+		mockErr    error
+		wantStatus int
+		wantError  string
+		wantResult postgres.ImportResult
+	}{
+		{
+			name:       "imported",
+			file:       file,
+			opts:       &postgres.ImportOptions{},
+			result:     postgres.ImportResult{Imported: 1, Errors: []postgres.ImportError{}},
+			wantStatus: http.StatusOK,
+			wantResult: postgres.ImportResult{Imported: 1, Errors: []postgres.ImportError{}},
+		},
+		{
+			name:       "dry run",
+			query:      "?dryRun=true",
+			file:       file,
+			opts:       &postgres.ImportOptions{DryRun: true},
+			result:     postgres.ImportResult{Imported: 1, Errors: []postgres.ImportError{}},
+			wantStatus: http.StatusOK,
+			wantResult: postgres.ImportResult{Imported: 1, Errors: []postgres.ImportError{}},
+		},
+		{
+			name:       "taken regNum refuses the import",
+			file:       file,
+			opts:       &postgres.ImportOptions{},
+			result:     postgres.ImportResult{Errors: []postgres.ImportError{{Line: 2, Error: "car already exists"}}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantError:  "some rows can't be imported",
+			wantResult: postgres.ImportResult{Errors: []postgres.ImportError{{Line: 2, Error: "car already exists"}}},
+		},
+		{
+			name:       "taken regNum is skipped",
+			query:      "?skipInvalid=1",
+			file:       file + "X124XX150,Lada,Vesta,2002,Ivan,Ivanov\n",
+			opts:       &postgres.ImportOptions{SkipInvalid: true},
+			result:     postgres.ImportResult{Imported: 1, Errors: []postgres.ImportError{{Line: 2, Error: "car already exists"}}},
+			wantStatus: http.StatusOK,
+			wantResult: postgres.ImportResult{Imported: 1, Errors: []postgres.ImportError{{Line: 2, Error: "car already exists"}}},
+		},
+		{
+			name:       "storage failure",
+			file:       file,
+			opts:       &postgres.ImportOptions{},
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to import cars",
+		},
+		{
+			name:       "invalid file",
+			file:       "regNum,price\n",
+			wantStatus: http.StatusOK,
+			wantError:  `invalid file: unknown column "price"`,
+		},
+		{
+			name:       "invalid query",
+			query:      "?dryRun=maybe",
+			file:       file,
+			wantStatus: http.StatusOK,
+			wantError:  "failed to parse request",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carImporter := mocks.NewCarImporter(t)
+			if tc.opts != nil {
+				carImporter.On("ImportCars", mock.Anything, mock.Anything, *tc.opts).
+					Return(tc.result, tc.mockErr).Once()
+			}
+
+			handler := importer.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carImporter)
+
+			req := httptest.NewRequest(http.MethodPost, "/cars/import"+tc.query, strings.NewReader(tc.file))
+			req.Header.Set("Content-Type", "text/csv")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp importer.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			assert.Equal(t, tc.wantResult, resp.ImportResult)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package owner
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/etag"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+type Request struct {
+	RegNum string    `json:"regNum"`
+	AsOf   time.Time `json:"asOf"`
+}
+
+type Response struct {
+	response.Response
+	Car postgres.Car `json:"car"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarGetter
+type CarGetter interface {
+	GetCarByRegNum(ctx context.Context, regNum string, asOf time.Time) (postgres.Car, error)
+}
+
+//	@Summary		Get car owner at a point in time
+//	@Description	Get car by regNum together with the owner valid at asOf (RFC 3339, defaults to now)
+//	@Tags			Car
+//	@Produce		json
+//	@Param			regNum	query		string	true	"RegNum"
+//	@Param			asOf	query		string	false	"AsOf"
+//	@Success		200		{object}	Response
+//	@Header			200		{string}	ETag	"ETag of the car"
+//	@Failure		400		{object}	response.Response
+//	@Failure		404		{object}	response.Response
+//	@Router			/car/owner [get]
+func New(log *slog.Logger, carGetter CarGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.car.owner.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		car, err := carGetter.GetCarByRegNum(r.Context(), req.RegNum, req.AsOf)
+		if errors.Is(err, storage.ErrCarNotFound) {
+			log.Info("car not found", slog.String("reg_num", req.RegNum))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("car not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get car", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get car"))
+
+			return
+		}
+
+		etag.Set(w, car.Version)
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			car,
+		})
+	}
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	req := Request{
+		RegNum: r.URL.Query().Get("regNum"),
+		AsOf:   time.Now(),
+	}
+
+	if asOf := r.URL.Query().Get("asOf"); asOf != "" {
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			return Request{}, err
+		}
+		req.AsOf = t
+	}
+
+	return req, nil
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if len(req.RegNum) < 1 || len(req.RegNum) > 255 {
+		return false, slog.String("field", "regNum"), "field regNum is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
@@ -0,0 +1,98 @@
+package owner_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/car/owner"
+	"effective_mobile_test/internal/http-server/handlers/car/owner/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestOwnerHandler(t *testing.T) {
+	asOf := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name  string
+		query string
+		// asOf is the instant expected by the storage, zero for now, nil if it must not be called
+		asOf       *time.Time
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "current owner",
+			query:      "?regNum=X123XX150",
+			asOf:       &time.Time{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "owner at the given instant",
+			query:      "?regNum=X123XX150&asOf=2020-01-01T03:00:00%2B03:00",
+			asOf:       &asOf,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "no owner at that instant",
+			query:      "?regNum=X123XX150&asOf=2020-01-01T00:00:00Z",
+			asOf:       &asOf,
+			mockErr:    storage.ErrCarNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "car not found",
+		},
+		{
+			name:       "invalid as of",
+			query:      "?regNum=X123XX150&asOf=2020-01-01",
+			wantStatus: http.StatusOK,
+			wantError:  "failed to parse request",
+		},
+		{
+			name:       "missing reg num",
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantError:  "field regNum is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carGetter := mocks.NewCarGetter(t)
+			if tc.asOf != nil {
+				matchAsOf := mock.MatchedBy(func(at time.Time) bool {
+					if tc.asOf.IsZero() {
+						return time.Since(at) < time.Minute
+					}
+					return at.Equal(*tc.asOf)
+				})
+				carGetter.On("GetCarByRegNum", mock.Anything, "X123XX150", matchAsOf).
+					Return(postgres.Car{RegNum: "X123XX150", Owner: postgres.Owner{Name: "Ivan"}}, tc.mockErr).Once()
+			}
+
+			handler := owner.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carGetter)
+
+			req := httptest.NewRequest(http.MethodGet, "/car/owner"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp owner.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Equal(t, "Ivan", resp.Car.Owner.Name)
+			}
+		})
+	}
+}
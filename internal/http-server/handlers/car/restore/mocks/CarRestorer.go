@@ -0,0 +1,46 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// CarRestorer is an autogenerated mock type for the CarRestorer type
+type CarRestorer struct {
+	mock.Mock
+}
+
+// RestoreCar provides a mock function with given fields: ctx, carID
+func (_m *CarRestorer) RestoreCar(ctx context.Context, carID int) error {
+	ret := _m.Called(ctx, carID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreCar")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, carID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewCarRestorer creates a new instance of CarRestorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarRestorer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarRestorer {
+	mock := &CarRestorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
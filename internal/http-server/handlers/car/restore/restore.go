@@ -0,0 +1,86 @@
+package restore
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Response struct {
+	response.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarRestorer
+type CarRestorer interface {
+	RestoreCar(ctx context.Context, carID int) error
+}
+
+//	@Summary		Restore car
+//	@Description	Restore a soft-deleted car by id
+//	@Tags			Car
+//	@Produce		json
+//	@Param			id	path		int	true	"CarId"
+//	@Success		200	{object}	Response
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Failure		409	{object}	response.Response
+//	@Router			/cars/{id}/restore [post]
+func New(log *slog.Logger, carRestorer CarRestorer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.car.restore.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		carId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || carId < 1 {
+			log.Error("invalid request", slog.String("field", "id"))
+
+			render.JSON(w, r, response.Error("field id is not valid"))
+
+			return
+		}
+
+		err = carRestorer.RestoreCar(r.Context(), carId)
+		if errors.Is(err, storage.ErrCarNotFound) {
+			log.Info("deleted car not found", slog.Int("car_id", carId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("deleted car not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrCarExists) || errors.Is(err, storage.ErrOwnerExists) {
+			log.Info("car conflicts with an existing one", slog.Int("car_id", carId), sl.Err(err))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error(err.Error()))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to restore car", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to restore car"))
+
+			return
+		}
+
+		log.Info("car restored", slog.Int("car_id", carId))
+
+		render.JSON(w, r, Response{
+			response.OK(),
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package restore_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/car/restore"
+	"effective_mobile_test/internal/http-server/handlers/car/restore/mocks"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRestoreHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		id          string
+		callRestore bool
+		mockErr     error
+		wantStatus  int
+		wantError   string
+	}{
+		{
+			name:        "restored",
+			id:          "5",
+			callRestore: true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "car is not deleted",
+			id:          "5",
+			callRestore: true,
+			mockErr:     storage.ErrCarNotFound,
+			wantStatus:  http.StatusNotFound,
+			wantError:   "deleted car not found",
+		},
+		{
+			name:        "reg num taken by another car",
+			id:          "5",
+			callRestore: true,
+			mockErr:     storage.ErrCarExists,
+			wantStatus:  http.StatusConflict,
+			wantError:   storage.ErrCarExists.Error(),
+		},
+		{
+			name:        "owner identity taken by another owner",
+			id:          "5",
+			callRestore: true,
+			mockErr:     storage.ErrOwnerExists,
+			wantStatus:  http.StatusConflict,
+			wantError:   storage.ErrOwnerExists.Error(),
+		},
+		{
+			name:        "storage failure",
+			id:          "5",
+			callRestore: true,
+			mockErr:     errors.New("unexpected error"),
+			wantStatus:  http.StatusOK,
+			wantError:   "failed to restore car",
+		},
+		{
+			name:       "invalid id",
+			id:         "-5",
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carRestorer := mocks.NewCarRestorer(t)
+			if tc.callRestore {
+				carRestorer.On("RestoreCar", mock.Anything, 5).Return(tc.mockErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Post("/cars/{id}/restore", restore.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carRestorer))
+
+			req := httptest.NewRequest(http.MethodPost, "/cars/"+tc.id+"/restore", nil)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp restore.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+		})
+	}
+}
@@ -1,10 +1,14 @@
 package save
 
 import (
+	"context"
 	client2 "effective_mobile_test/internal/client"
 	"effective_mobile_test/internal/lib/api/response"
 	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/metrics"
+	"effective_mobile_test/internal/storage"
 	"effective_mobile_test/internal/storage/postgres"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
@@ -21,17 +25,21 @@ type Response struct {
 }
 
 type CarSaver interface {
-	SaveCar(car postgres.Car) (int, error)
+	SaveCar(ctx context.Context, car postgres.Car) (int, error)
 }
 
 //	@Summary		Save a new car
-//	@Description	Save a new car by regNums
+//	@Description	Save a new car by regNums. A request with an Idempotency-Key header can be retried safely:
+//	@Description	the original response is replayed, reusing the key with a different body is refused with 422.
 //	@Tags			Car
 //	@Accept			json
 //	@Produce		json
-//	@Param			regNums	body		[]string	true	"RegNums"
+//	@Param			regNums			body		[]string	true	"RegNums"
+//	@Param			Idempotency-Key	header		string		false	"Idempotency key"
 //	@Success		200		{object}	Response
 //	@Failure		400		{object}	response.Response
+//	@Failure		409		{object}	response.Response
+//	@Failure		422		{object}	response.Response
 //	@Router			/car/save [post]
 func New(log *slog.Logger, carSaver CarSaver, helpAPIUrl string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -40,6 +48,7 @@ func New(log *slog.Logger, carSaver CarSaver, helpAPIUrl string) http.HandlerFun
 		log = log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
 		)
 
 		var req Request
@@ -64,24 +73,40 @@ func New(log *slog.Logger, carSaver CarSaver, helpAPIUrl string) http.HandlerFun
 		}
 
 		client := client2.SearchClient{URL: helpAPIUrl}
-		resp, err := client.FindUsers(client2.SearchRequest{RegNums: req.RegNums})
+		resp, err := client.FindUsers(r.Context(), client2.SearchRequest{RegNums: req.RegNums})
 		if err != nil {
 			log.Error("failed to find car", sl.Err(err))
 
+			metrics.EnrichmentFailures.Inc()
+
 			render.JSON(w, r, response.Error("failed to find car"))
+
+			return
 		}
 
 		var carsIds []int
 		for _, car := range resp.Cars {
-			carId, err := carSaver.SaveCar(car)
+			carId, err := carSaver.SaveCar(r.Context(), car)
+			if errors.Is(err, storage.ErrCarExists) {
+				log.Info("car already exists", slog.String("reg_num", car.RegNum))
+
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, response.Error("car already exists"))
+
+				return
+			}
 			if err != nil {
 				log.Error("failed to save car", sl.Err(err))
 
 				render.JSON(w, r, response.Error("failed to save car"))
+
+				return
 			}
 
 			log.Info("car saved", slog.Int("car_id", carId))
 
+			metrics.CarsSaved.Inc()
+
 			carsIds = append(carsIds, carId)
 		}
 
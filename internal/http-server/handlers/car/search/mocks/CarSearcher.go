@@ -0,0 +1,77 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// CarSearcher is an autogenerated mock type for the CarSearcher type
+type CarSearcher struct {
+	mock.Mock
+}
+
+// EachCarBySearchRequest provides a mock function with given fields: ctx, searchRequest, fn
+func (_m *CarSearcher) EachCarBySearchRequest(ctx context.Context, searchRequest postgres.SearchRequest, fn func(postgres.Car) error) error {
+	ret := _m.Called(ctx, searchRequest, fn)
+
+	if len(ret) == 0 {
+		panic("no return value specified for EachCarBySearchRequest")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.SearchRequest, func(postgres.Car) error) error); ok {
+		r0 = rf(ctx, searchRequest, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// GetCarsBySearchRequest provides a mock function with given fields: ctx, searchRequest
+func (_m *CarSearcher) GetCarsBySearchRequest(ctx context.Context, searchRequest postgres.SearchRequest) ([]postgres.Car, error) {
+	ret := _m.Called(ctx, searchRequest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetCarsBySearchRequest")
+	}
+
+	var r0 []postgres.Car
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.SearchRequest) ([]postgres.Car, error)); ok {
+		return rf(ctx, searchRequest)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.SearchRequest) []postgres.Car); ok {
+		r0 = rf(ctx, searchRequest)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.Car)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.SearchRequest) error); ok {
+		r1 = rf(ctx, searchRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCarSearcher creates a new instance of CarSearcher. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarSearcher(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarSearcher {
+	mock := &CarSearcher{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
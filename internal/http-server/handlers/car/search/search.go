@@ -1,13 +1,25 @@
 package search
 
 import (
+	"context"
+	"effective_mobile_test/internal/lib/api/problem"
 	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
 	"effective_mobile_test/internal/lib/logger/sl"
 	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	contentTypeNDJSON = "application/x-ndjson"
+	// flushEvery is the number of streamed cars after which they are sent to the client.
+	flushEvery = 100
 )
 
 type Request struct {
@@ -21,16 +33,22 @@ type Response struct {
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarSearcher
 type CarSearcher interface {
-	GetCarsBySearchRequest(searchRequest postgres.SearchRequest) ([]postgres.Car, error)
+	GetCarsBySearchRequest(ctx context.Context, searchRequest postgres.SearchRequest) ([]postgres.Car, error)
+	EachCarBySearchRequest(ctx context.Context, searchRequest postgres.SearchRequest, fn func(car postgres.Car) error) error
 }
 
 //	@Summary		Search cars
-//	@Description	Search cars by search request
+//	@Description	Search cars by search request.
+//	@Description	With Accept: application/x-ndjson the cars are streamed one JSON object per line as they are read,
+//	@Description	and a zero pageSize returns all matching cars. If the stream fails midway, its last line is an error response.
+//	@Description	Soft-deleted cars are included on request to callers with the admin:deleted permission.
 //	@Tags			Car
 //	@Accept			json
 //	@Produce		json
+//	@Produce		application/x-ndjson
 //	@Success		200	{object}	Response
 //	@Failure		400	{object}	response.Response
+//	@Failure		403	{object}	problem.Problem
 //	@Router			/car/search [get]
 func New(log *slog.Logger, carSearcher CarSearcher) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -39,6 +57,7 @@ func New(log *slog.Logger, carSearcher CarSearcher) http.HandlerFunc {
 		log = log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
 		)
 
 		var req Request
@@ -54,7 +73,9 @@ func New(log *slog.Logger, carSearcher CarSearcher) http.HandlerFunc {
 
 		log.Info("request body decoded", slog.Any("request", req))
 
-		if ok, field, msg := validateRequest(req); !ok {
+		stream := strings.Contains(r.Header.Get("Accept"), contentTypeNDJSON)
+
+		if ok, field, msg := validateRequest(req, stream); !ok {
 			log.Error("invalid request", field)
 
 			render.JSON(w, r, response.Error(msg))
@@ -62,7 +83,21 @@ func New(log *slog.Logger, carSearcher CarSearcher) http.HandlerFunc {
 			return
 		}
 
-		cars, err := carSearcher.GetCarsBySearchRequest(req.SearchRequest)
+		if req.IncludeDeleted && !auth.Can(r.Context(), auth.PermAdminDeleted) {
+			log.Info("permission denied", slog.String("permission", auth.PermAdminDeleted))
+
+			problem.Write(w, r, http.StatusForbidden, "permission "+auth.PermAdminDeleted+" is required to include deleted cars")
+
+			return
+		}
+
+		if stream {
+			streamCars(log, w, r, carSearcher, req.SearchRequest)
+
+			return
+		}
+
+		cars, err := carSearcher.GetCarsBySearchRequest(r.Context(), req.SearchRequest)
 		if err != nil {
 			log.Error("failed to get cars by search request", sl.Err(err))
 
@@ -78,7 +113,48 @@ func New(log *slog.Logger, carSearcher CarSearcher) http.HandlerFunc {
 	}
 }
 
-func validateRequest(req Request) (bool, slog.Attr, string) {
+// streamCars writes the cars as NDJSON while they are read from the storage.
+func streamCars(log *slog.Logger, w http.ResponseWriter, r *http.Request, carSearcher CarSearcher, searchRequest postgres.SearchRequest) {
+	// the whole catalog may take much longer than the server write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Warn("failed to disable write deadline", sl.Err(err))
+	}
+
+	w.Header().Set("Content-Type", contentTypeNDJSON)
+
+	enc := json.NewEncoder(w)
+
+	rows := 0
+	err := carSearcher.EachCarBySearchRequest(r.Context(), searchRequest, func(car postgres.Car) error {
+		if err := enc.Encode(car); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%flushEvery != 0 {
+			return nil
+		}
+
+		return rc.Flush()
+	})
+	if err != nil {
+		// the status has already been sent, so the failure is reported in the stream itself
+		log.Error("failed to stream cars", sl.Err(err), slog.Int("rows", rows))
+
+		_ = enc.Encode(response.Error("failed to get cars by search request"))
+
+		return
+	}
+
+	log.Info("cars streamed", slog.Int("rows", rows))
+}
+
+func validateRequest(req Request, stream bool) (bool, slog.Attr, string) {
+	// a stream doesn't need to be paginated
+	if stream && req.PageSize == 0 {
+		return true, slog.Attr{}, ""
+	}
 	if req.PageSize < 1 {
 		return false, slog.String("field", "pageSize"), "field pageSize is not valid"
 	}
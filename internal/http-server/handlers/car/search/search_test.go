@@ -0,0 +1,196 @@
+package search_test
+
+import (
+	"bufio"
+	"effective_mobile_test/internal/http-server/handlers/car/search"
+	"effective_mobile_test/internal/http-server/handlers/car/search/mocks"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var cars = []postgres.Car{
+	{ID: 1, RegNum: "X123XX150", Mark: "Lada", Model: "Vesta", Year: 2002},
+	{ID: 2, RegNum: "X124XX150", Mark: "Lada", Model: "Granta", Year: 2015},
+}
+
+func TestSearchHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		callSearch bool
+		mockErr    error
+		wantError  string
+		wantCars   []postgres.Car
+	}{
+		{
+			name:       "page of cars",
+			body:       `{"mark":"Lada","pageNum":1,"pageSize":10}`,
+			callSearch: true,
+			wantCars:   cars,
+		},
+		{
+			name:       "storage failure",
+			body:       `{"mark":"Lada","pageNum":1,"pageSize":10}`,
+			callSearch: true,
+			mockErr:    errors.New("unexpected error"),
+			wantError:  "failed to get cars by search request",
+		},
+		{
+			name:      "page size is required without streaming",
+			body:      `{"mark":"Lada","pageNum":1}`,
+			wantError: "field pageSize is not valid",
+		},
+		{
+			name:      "invalid page number",
+			body:      `{"mark":"Lada","pageNum":0,"pageSize":10}`,
+			wantError: "field pageNum is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carSearcher := mocks.NewCarSearcher(t)
+			if tc.callSearch {
+				carSearcher.On("GetCarsBySearchRequest", mock.Anything, postgres.SearchRequest{
+					CarFilter: postgres.CarFilter{Mark: "Lada"}, PageNum: 1, PageSize: 10,
+				}).Return(cars, tc.mockErr).Once()
+			}
+
+			handler := search.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carSearcher)
+
+			req := httptest.NewRequest(http.MethodGet, "/car/search", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp search.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			assert.Equal(t, tc.wantCars, resp.Cars)
+		})
+	}
+}
+
+func TestSearchHandlerStream(t *testing.T) {
+	cases := []struct {
+		name      string
+		body      string
+		request   postgres.SearchRequest
+		mockErr   error
+		wantLines int
+		wantError string
+	}{
+		{
+			name:      "whole catalog without pagination",
+			body:      `{"mark":"Lada"}`,
+			request:   postgres.SearchRequest{CarFilter: postgres.CarFilter{Mark: "Lada"}},
+			wantLines: 2,
+		},
+		{
+			name:      "page",
+			body:      `{"mark":"Lada","pageNum":2,"pageSize":2}`,
+			request:   postgres.SearchRequest{CarFilter: postgres.CarFilter{Mark: "Lada"}, PageNum: 2, PageSize: 2},
+			wantLines: 2,
+		},
+		{
+			name:      "failure midway ends the stream with an error",
+			body:      `{"mark":"Lada"}`,
+			request:   postgres.SearchRequest{CarFilter: postgres.CarFilter{Mark: "Lada"}},
+			mockErr:   errors.New("connection lost"),
+			wantLines: 2,
+			wantError: "failed to get cars by search request",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carSearcher := mocks.NewCarSearcher(t)
+			carSearcher.On("EachCarBySearchRequest", mock.Anything, tc.request, mock.Anything).
+				Return(tc.mockErr).Once().
+				Run(func(args mock.Arguments) {
+					fn := args.Get(2).(func(postgres.Car) error)
+					for _, car := range cars {
+						require.NoError(t, fn(car))
+					}
+				})
+
+			handler := search.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carSearcher)
+
+			req := httptest.NewRequest(http.MethodGet, "/car/search", strings.NewReader(tc.body))
+			req.Header.Set("Accept", "application/x-ndjson")
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+
+			scanner := bufio.NewScanner(rr.Body)
+			var streamed []postgres.Car
+			for i := 0; i < tc.wantLines && scanner.Scan(); i++ {
+				var car postgres.Car
+				require.NoError(t, json.Unmarshal(scanner.Bytes(), &car))
+				streamed = append(streamed, car)
+			}
+			assert.Equal(t, cars, streamed)
+
+			if tc.wantError == "" {
+				assert.False(t, scanner.Scan(), "unexpected line %q", scanner.Text())
+
+				return
+			}
+
+			require.True(t, scanner.Scan())
+			var last map[string]any
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &last))
+			assert.Equal(t, tc.wantError, last["error"])
+		})
+	}
+}
+
+func TestSearchHandlerIncludeDeleted(t *testing.T) {
+	body := `{"mark":"Lada","includeDeleted":true,"pageNum":1,"pageSize":10}`
+
+	t.Run("admin", func(t *testing.T) {
+		carSearcher := mocks.NewCarSearcher(t)
+		carSearcher.On("GetCarsBySearchRequest", mock.Anything, postgres.SearchRequest{
+			CarFilter: postgres.CarFilter{Mark: "Lada", IncludeDeleted: true}, PageNum: 1, PageSize: 10,
+		}).Return(cars, nil).Once()
+
+		handler := search.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carSearcher)
+
+		req := httptest.NewRequest(http.MethodGet, "/car/search", strings.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "test", Role: auth.RoleAdmin}))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("forbidden to editors", func(t *testing.T) {
+		handler := search.New(slog.New(slog.NewTextHandler(io.Discard, nil)), mocks.NewCarSearcher(t))
+
+		req := httptest.NewRequest(http.MethodGet, "/car/search", strings.NewReader(body))
+		req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "test", Role: auth.RoleEditor}))
+		rr := httptest.NewRecorder()
+
+		handler.ServeHTTP(rr, req)
+
+		require.Equal(t, http.StatusForbidden, rr.Code)
+	})
+}
@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// CarUpdater is an autogenerated mock type for the CarUpdater type
+type CarUpdater struct {
+	mock.Mock
+}
+
+// PatchCar provides a mock function with given fields: ctx, carID, patch, versions
+func (_m *CarUpdater) PatchCar(ctx context.Context, carID int, patch postgres.CarPatch, versions []int) (int, error) {
+	ret := _m.Called(ctx, carID, patch, versions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for PatchCar")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, postgres.CarPatch, []int) (int, error)); ok {
+		return rf(ctx, carID, patch, versions)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, postgres.CarPatch, []int) int); ok {
+		r0 = rf(ctx, carID, patch, versions)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, postgres.CarPatch, []int) error); ok {
+		r1 = rf(ctx, carID, patch, versions)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCarUpdater creates a new instance of CarUpdater. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarUpdater(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarUpdater {
+	mock := &CarUpdater{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -1,9 +1,14 @@
 package update
 
 import (
+	"context"
+	"effective_mobile_test/internal/lib/api/etag"
 	"effective_mobile_test/internal/lib/api/response"
 	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage"
 	"effective_mobile_test/internal/storage/postgres"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
@@ -17,23 +22,23 @@ type Request struct {
 	Model  *string         `json:"model,omitempty"`
 	Year   *int            `json:"year,omitempty"`
 	Owner  *postgres.Owner `json:"owner,omitempty"`
+	// Version is an alternative to the If-Match header
+	Version *int `json:"version,omitempty"`
 }
 
 type Response struct {
 	response.Response
+	Version int `json:"version"`
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarUpdater
 type CarUpdater interface {
-	UpdateRegNum(carID int, newRegNum string) error
-	UpdateMark(carID int, newMark string) error
-	UpdateModel(carID int, newModel string) error
-	UpdateYear(carID int, newYear int) error
-	UpdateOwner(carID int, newOwner postgres.Owner) error
+	PatchCar(ctx context.Context, carID int, patch postgres.CarPatch, versions []int) (int, error)
 }
 
 //	@Summary		Update car
-//	@Description	Update car by carId and new data
+//	@Description	Update car by carId and new data in one change. The current version can be required
+//	@Description	with the If-Match header or the version field, a stale one is refused with 412.
 //	@Tags			Car
 //	@Accept			json
 //	@Produce		json
@@ -42,8 +47,14 @@ type CarUpdater interface {
 //	@Param			model	body		string			false	"Model"
 //	@Param			year	body		int				false	"Year"
 //	@Param			owner	body		postgres.Owner	false	"Owner"
+//	@Param			version	body		int				false	"Version"
+//	@Param			If-Match	header	string			false	"ETag of the car"
 //	@Success		200		{object}	Response
+//	@Header			200		{string}	ETag	"ETag of the updated car"
 //	@Failure		400		{object}	response.Response
+//	@Failure		404		{object}	response.Response
+//	@Failure		409		{object}	response.Response
+//	@Failure		412		{object}	response.Response
 //	@Router			/car/update [put]
 func New(log *slog.Logger, carUpdater CarUpdater) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -52,6 +63,7 @@ func New(log *slog.Logger, carUpdater CarUpdater) http.HandlerFunc {
 		log = log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
 		)
 
 		var req Request
@@ -75,63 +87,67 @@ func New(log *slog.Logger, carUpdater CarUpdater) http.HandlerFunc {
 			return
 		}
 
-		if req.RegNum != nil {
-			err = carUpdater.UpdateRegNum(req.CarId, *req.RegNum)
-			if err != nil {
-				log.Error("failed to update regNum", sl.Err(err))
+		versions, err := etag.Expected(r, req.Version)
+		if errors.Is(err, etag.ErrPreconditionFailed) {
+			log.Info("car version precondition failed", slog.Int("car_id", req.CarId))
 
-				render.JSON(w, r, response.Error("failed to update regNum"))
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("car was changed by someone else"))
 
-				return
-			}
+			return
 		}
+		if err != nil {
+			log.Error("invalid request", slog.String("field", "If-Match"), sl.Err(err))
 
-		if req.Mark != nil {
-			err = carUpdater.UpdateMark(req.CarId, *req.Mark)
-			if err != nil {
-				log.Error("failed to update mark", sl.Err(err))
-
-				render.JSON(w, r, response.Error("failed to update mark"))
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("header If-Match is not valid"))
 
-				return
-			}
+			return
 		}
 
-		if req.Model != nil {
-			err = carUpdater.UpdateModel(req.CarId, *req.Model)
-			if err != nil {
-				log.Error("failed to update model", sl.Err(err))
+		newVersion, err := carUpdater.PatchCar(r.Context(), req.CarId, postgres.CarPatch{
+			RegNum: req.RegNum,
+			Mark:   req.Mark,
+			Model:  req.Model,
+			Year:   req.Year,
+			Owner:  req.Owner,
+		}, versions)
+		if errors.Is(err, storage.ErrCarNotFound) {
+			log.Info("car not found", slog.Int("car_id", req.CarId))
 
-				render.JSON(w, r, response.Error("failed to update model"))
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("car not found"))
 
-				return
-			}
+			return
 		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			log.Info("car version mismatch", slog.Int("car_id", req.CarId))
 
-		if req.Year != nil {
-			err = carUpdater.UpdateYear(req.CarId, *req.Year)
-			if err != nil {
-				log.Error("failed to update year", sl.Err(err))
-
-				render.JSON(w, r, response.Error("failed to update year"))
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("car was changed by someone else"))
 
-				return
-			}
+			return
 		}
+		if errors.Is(err, storage.ErrCarExists) {
+			log.Info("car with the same regNum already exists", slog.Int("car_id", req.CarId))
 
-		if req.Owner != nil {
-			err = carUpdater.UpdateOwner(req.CarId, *req.Owner)
-			if err != nil {
-				log.Error("failed to update owner", sl.Err(err))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("car with the same regNum already exists"))
 
-				render.JSON(w, r, response.Error("failed to update owner"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update car", sl.Err(err))
 
-				return
-			}
+			render.JSON(w, r, response.Error("failed to update car"))
+
+			return
 		}
 
+		etag.Set(w, newVersion)
 		render.JSON(w, r, Response{
 			response.OK(),
+			newVersion,
 		})
 	}
 }
@@ -152,14 +168,11 @@ func validateRequest(req Request) (bool, slog.Attr, string) {
 	if req.Year != nil && *req.Year < 1900 {
 		return false, slog.String("field", "year"), "field year is not valid"
 	}
-	if req.Owner != nil && len(req.Owner.Name) < 1 {
-		return false, slog.String("field", "owner.name"), "field owner.name is not valid"
-	}
-	if req.Owner != nil && len(req.Owner.Surname) < 1 {
-		return false, slog.String("field", "owner.surname"), "field owner.surname is not valid"
+	if req.Version != nil && *req.Version < 1 {
+		return false, slog.String("field", "version"), "field version is not valid"
 	}
-	if req.Owner != nil && len(req.Owner.Patronymic) < 1 {
-		return false, slog.String("field", "owner.patronymic"), "field owner.patronymic is not valid"
+	if req.Owner != nil {
+		return validate.Owner(*req.Owner, "owner.")
 	}
 	return true, slog.Attr{}, ""
 }
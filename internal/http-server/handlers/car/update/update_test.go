@@ -0,0 +1,126 @@
+package update_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/car/update"
+	"effective_mobile_test/internal/http-server/handlers/car/update/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpdateHandler(t *testing.T) {
+	cases := []struct {
+		name    string
+		body    string
+		ifMatch string
+		// callPatch tells whether the storage must be called, with versions
+		callPatch  bool
+		versions   []int
+		mockErr    error
+		wantStatus int
+		wantError  string
+		wantETag   string
+	}{
+		{
+			name:       "without precondition",
+			body:       `{"carId": 5, "mark": "Kia"}`,
+			callPatch:  true,
+			wantStatus: http.StatusOK,
+			wantETag:   `"4"`,
+		},
+		{
+			name:       "any existing car",
+			body:       `{"carId": 5, "mark": "Kia"}`,
+			ifMatch:    "*",
+			callPatch:  true,
+			wantStatus: http.StatusOK,
+			wantETag:   `"4"`,
+		},
+		{
+			name:       "any of the listed versions",
+			body:       `{"carId": 5, "mark": "Kia"}`,
+			ifMatch:    `"2", W/"3", "3"`,
+			callPatch:  true,
+			versions:   []int{2, 3},
+			wantStatus: http.StatusOK,
+			wantETag:   `"4"`,
+		},
+		{
+			name:       "changed by someone else",
+			body:       `{"carId": 5, "mark": "Kia"}`,
+			ifMatch:    `"2"`,
+			callPatch:  true,
+			versions:   []int{2},
+			mockErr:    storage.ErrVersionMismatch,
+			wantStatus: http.StatusPreconditionFailed,
+			wantError:  "car was changed by someone else",
+		},
+		{
+			name:       "weak tag never matches",
+			body:       `{"carId": 5, "mark": "Kia"}`,
+			ifMatch:    `W/"3"`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantError:  "car was changed by someone else",
+		},
+		{
+			name:       "body version not listed by the header",
+			body:       `{"carId": 5, "mark": "Kia", "version": 2}`,
+			ifMatch:    `"3"`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantError:  "car was changed by someone else",
+		},
+		{
+			name:       "malformed If-Match",
+			body:       `{"carId": 5, "mark": "Kia"}`,
+			ifMatch:    `"3`,
+			wantStatus: http.StatusBadRequest,
+			wantError:  "header If-Match is not valid",
+		},
+		{
+			name:       "unknown car",
+			body:       `{"carId": 5, "mark": "Kia"}`,
+			callPatch:  true,
+			mockErr:    storage.ErrCarNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "car not found",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			carUpdater := mocks.NewCarUpdater(t)
+			if tc.callPatch {
+				mark := "Kia"
+				carUpdater.On("PatchCar", mock.Anything, 5, postgres.CarPatch{Mark: &mark}, tc.versions).
+					Return(4, tc.mockErr).Once()
+			}
+
+			handler := update.New(slog.New(slog.NewTextHandler(io.Discard, nil)), carUpdater)
+
+			req := httptest.NewRequest(http.MethodPut, "/car/update", strings.NewReader(tc.body))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantETag, rr.Header().Get("ETag"))
+
+			var resp update.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+		})
+	}
+}
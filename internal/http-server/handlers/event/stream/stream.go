@@ -0,0 +1,253 @@
+package stream
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// batchSize is the number of events read from the storage at once.
+	batchSize = 100
+	// heartbeatInterval keeps idle connections from being closed by proxies.
+	heartbeatInterval = 15 * time.Second
+	// retryDelay is how long clients wait before reconnecting, in milliseconds.
+	retryDelay = 3000
+)
+
+type Request struct {
+	// After is the id of the last received event; nil streams only the events published from now on
+	After *int64
+	// Types are event types like car.updated or patterns like owner.*; empty matches every event
+	Types []string
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=EventReader
+type EventReader interface {
+	GetEvents(ctx context.Context, after int64, limit int) ([]postgres.Event, error)
+	LastEventID(ctx context.Context) (int64, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Subscriber
+type Subscriber interface {
+	Subscribe() (<-chan struct{}, func())
+}
+
+//	@Summary		Stream catalog events
+//	@Description	Stream changes of cars and owners as Server-Sent Events: car.created, car.updated, car.deleted,
+//	@Description	car.restored, car.purged and the same owner.* events. The event name is the type and the data is
+//	@Description	a JSON postgres.Event with the state of the entity after the change, or before it for deletions.
+//	@Description	A reconnecting client resumes after the event given by the Last-Event-ID header or lastEventId;
+//	@Description	lastEventId=0 replays all events. Without either only new events are streamed.
+//	@Tags			Event
+//	@Produce		text/event-stream
+//	@Param			Last-Event-ID	header		int		false	"Last received event ID"
+//	@Param			lastEventId		query		int		false	"Last received event ID"
+//	@Param			types			query		string	false	"Comma-separated event types, e.g. car.updated,owner.*"
+//	@Success		200				{object}	postgres.Event
+//	@Failure		400				{object}	response.Response
+//	@Failure		404				{object}	response.Response
+//	@Router			/events [get]
+func New(log *slog.Logger, eventReader EventReader, subscriber Subscriber) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.event.stream.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		// subscribe first, so that nothing published after the first read is missed
+		wake, unsubscribe := subscriber.Subscribe()
+		defer unsubscribe()
+
+		var after int64
+		if req.After != nil {
+			after = *req.After
+		} else if after, err = eventReader.LastEventID(r.Context()); err != nil {
+			log.Error("failed to get last event id", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get events"))
+
+			return
+		}
+
+		events, err := eventReader.GetEvents(r.Context(), after, batchSize)
+		if errors.Is(err, storage.ErrEventNotFound) {
+			log.Info("event not found", slog.Int64("after", after))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("event not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get events", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get events"))
+
+			return
+		}
+
+		// the stream lasts as long as the client stays connected
+		rc := http.NewResponseController(w)
+		if err = rc.SetWriteDeadline(time.Time{}); err != nil {
+			log.Warn("failed to disable write deadline", sl.Err(err))
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if _, err = fmt.Fprintf(w, "retry: %d\n\n", retryDelay); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		sent := 0
+		for {
+			for _, e := range events {
+				after = e.ID
+				if !matchType(req.Types, e.Type) {
+					continue
+				}
+				if err = writeEvent(w, e); err != nil {
+					log.Info("client disconnected", sl.Err(err), slog.Int("sent", sent))
+
+					return
+				}
+				sent++
+			}
+
+			if len(events) < batchSize {
+				if err = rc.Flush(); err != nil {
+					log.Info("client disconnected", sl.Err(err), slog.Int("sent", sent))
+
+					return
+				}
+
+				if !waitForEvents(r.Context(), w, rc, wake, heartbeat.C) {
+					log.Info("stream closed", slog.Int("sent", sent))
+
+					return
+				}
+			}
+
+			events, err = eventReader.GetEvents(r.Context(), after, batchSize)
+			if err != nil {
+				// the client reconnects and resumes after the last sent event
+				log.Error("failed to get events", sl.Err(err), slog.Int("sent", sent))
+
+				return
+			}
+		}
+	}
+}
+
+// waitForEvents blocks until new events may be available, sending heartbeats meanwhile.
+// It reports false when the stream must end.
+func waitForEvents(ctx context.Context, w http.ResponseWriter, rc *http.ResponseController,
+	wake <-chan struct{}, heartbeat <-chan time.Time) bool {
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case _, ok := <-wake:
+			return ok
+		case <-heartbeat:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return false
+			}
+			if err := rc.Flush(); err != nil {
+				return false
+			}
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, e postgres.Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+
+	return err
+}
+
+// matchType reports whether the event type matches one of the types or patterns like owner.*.
+func matchType(types []string, eventType string) bool {
+	if len(types) == 0 {
+		return true
+	}
+
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(t, "*"); ok && strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	query := r.URL.Query()
+
+	var req Request
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = query.Get("lastEventId")
+	}
+	if lastEventID != "" {
+		after, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			return Request{}, err
+		}
+		if after < 0 {
+			return Request{}, errors.New("negative event id")
+		}
+		req.After = &after
+	}
+
+	if types := query.Get("types"); types != "" {
+		for _, t := range strings.Split(types, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				req.Types = append(req.Types, t)
+			}
+		}
+	}
+
+	return req, nil
+}
@@ -0,0 +1,228 @@
+package stream_test
+
+import (
+	"context"
+	"effective_mobile_test/internal/http-server/handlers/event/stream"
+	"effective_mobile_test/internal/http-server/handlers/event/stream/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// closedSubscriber returns a subscriber whose hub has stopped, so that a stream ends
+// as soon as it has sent the events available on start.
+func closedSubscriber(t *testing.T) *mocks.Subscriber {
+	wake := make(chan struct{})
+	close(wake)
+
+	unsubscribed := false
+	t.Cleanup(func() { assert.True(t, unsubscribed, "subscription was not cancelled") })
+
+	subscriber := mocks.NewSubscriber(t)
+	subscriber.On("Subscribe").Return((<-chan struct{})(wake), func() { unsubscribed = true }).Once()
+
+	return subscriber
+}
+
+func TestStreamHandler(t *testing.T) {
+	events := []postgres.Event{
+		{ID: 6, Type: "car.updated", Entity: "car", EntityID: 5, Data: json.RawMessage(`{"car_id":5}`)},
+		{ID: 7, Type: "owner.created", Entity: "owner", EntityID: 3, Data: json.RawMessage(`{"owner_id":3}`)},
+		{ID: 8, Type: "car.deleted", Entity: "car", EntityID: 5},
+	}
+
+	cases := []struct {
+		name    string
+		query   string
+		header  string
+		after   int64
+		last    bool
+		wantIDs []int64
+	}{
+		{
+			name:    "resumes after Last-Event-ID",
+			header:  "5",
+			after:   5,
+			wantIDs: []int64{6, 7, 8},
+		},
+		{
+			name:    "resumes after lastEventId",
+			query:   "?lastEventId=5",
+			after:   5,
+			wantIDs: []int64{6, 7, 8},
+		},
+		{
+			name:    "header takes precedence over the query",
+			query:   "?lastEventId=1",
+			header:  "5",
+			after:   5,
+			wantIDs: []int64{6, 7, 8},
+		},
+		{
+			name:    "types and patterns",
+			query:   "?lastEventId=5&types=car.deleted,%20owner.*",
+			after:   5,
+			wantIDs: []int64{7, 8},
+		},
+		{
+			name:    "new events only without an id",
+			after:   5,
+			last:    true,
+			wantIDs: []int64{6, 7, 8},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			eventReader := mocks.NewEventReader(t)
+			if tc.last {
+				eventReader.On("LastEventID", mock.Anything).Return(tc.after, nil).Once()
+			}
+			eventReader.On("GetEvents", mock.Anything, tc.after, mock.Anything).Return(events, nil).Once()
+
+			handler := stream.New(slog.New(slog.NewTextHandler(io.Discard, nil)), eventReader, closedSubscriber(t))
+
+			req := httptest.NewRequest(http.MethodGet, "/events"+tc.query, nil)
+			if tc.header != "" {
+				req.Header.Set("Last-Event-ID", tc.header)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+
+			body := rr.Body.String()
+			require.True(t, strings.HasPrefix(body, "retry: 3000\n\n"))
+
+			var ids []int64
+			for _, e := range events {
+				if strings.Contains(body, fmt.Sprintf("id: %d\n", e.ID)) {
+					ids = append(ids, e.ID)
+				}
+			}
+			assert.Equal(t, tc.wantIDs, ids)
+		})
+	}
+}
+
+func TestStreamHandlerEventFormat(t *testing.T) {
+	event := postgres.Event{ID: 6, Type: "car.updated", Entity: "car", EntityID: 5, Data: json.RawMessage(`{"car_id":5}`)}
+
+	eventReader := mocks.NewEventReader(t)
+	eventReader.On("GetEvents", mock.Anything, int64(0), mock.Anything).Return([]postgres.Event{event}, nil).Once()
+
+	handler := stream.New(slog.New(slog.NewTextHandler(io.Discard, nil)), eventReader, closedSubscriber(t))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events?lastEventId=0", nil))
+
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	assert.Equal(t, "retry: 3000\n\nid: 6\nevent: car.updated\ndata: "+string(data)+"\n\n", rr.Body.String())
+}
+
+func TestStreamHandlerWakesUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	wake := make(chan struct{}, 1)
+	wake <- struct{}{}
+
+	subscriber := mocks.NewSubscriber(t)
+	subscriber.On("Subscribe").Return((<-chan struct{})(wake), func() {}).Once()
+
+	eventReader := mocks.NewEventReader(t)
+	eventReader.On("GetEvents", mock.Anything, int64(5), mock.Anything).
+		Return([]postgres.Event{{ID: 6, Type: "car.updated"}}, nil).Once()
+	// the stream goes on after the last sent event until the client goes away
+	eventReader.On("GetEvents", mock.Anything, int64(6), mock.Anything).
+		Return([]postgres.Event{{ID: 7, Type: "car.deleted"}}, nil).Once().
+		Run(func(mock.Arguments) { cancel() })
+
+	handler := stream.New(slog.New(slog.NewTextHandler(io.Discard, nil)), eventReader, subscriber)
+
+	req := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	req.Header.Set("Last-Event-ID", "5")
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Contains(t, rr.Body.String(), "id: 6\n")
+	assert.Contains(t, rr.Body.String(), "id: 7\n")
+}
+
+func TestStreamHandlerErrors(t *testing.T) {
+	cases := []struct {
+		name       string
+		query      string
+		getEvents  bool
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "event not found",
+			query:      "?lastEventId=42",
+			getEvents:  true,
+			mockErr:    fmt.Errorf("get: %w", storage.ErrEventNotFound),
+			wantStatus: http.StatusNotFound,
+			wantError:  "event not found",
+		},
+		{
+			name:       "storage failure",
+			query:      "?lastEventId=42",
+			getEvents:  true,
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to get events",
+		},
+		{
+			name:       "invalid id",
+			query:      "?lastEventId=abc",
+			wantStatus: http.StatusOK,
+			wantError:  "failed to parse request",
+		},
+		{
+			name:       "negative id",
+			query:      "?lastEventId=-1",
+			wantStatus: http.StatusOK,
+			wantError:  "failed to parse request",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			eventReader := mocks.NewEventReader(t)
+			subscriber := mocks.NewSubscriber(t)
+			if tc.getEvents {
+				subscriber.On("Subscribe").Return((<-chan struct{})(make(chan struct{})), func() {}).Once()
+				eventReader.On("GetEvents", mock.Anything, int64(42), mock.Anything).Return(nil, tc.mockErr).Once()
+			}
+
+			handler := stream.New(slog.New(slog.NewTextHandler(io.Discard, nil)), eventReader, subscriber)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/events"+tc.query, nil))
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp map[string]any
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, tc.wantError, resp["error"])
+		})
+	}
+}
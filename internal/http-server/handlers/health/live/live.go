@@ -0,0 +1,23 @@
+package live
+
+import (
+	"github.com/go-chi/render"
+	"net/http"
+)
+
+type Response struct {
+	Status string `json:"status"`
+}
+
+// New answers liveness probes: the process is up as long as it serves them.
+//
+//	@Summary	Liveness probe
+//	@Tags		Health
+//	@Produce	json
+//	@Success	200	{object}	Response
+//	@Router		/healthz [get]
+func New() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.JSON(w, r, Response{Status: "ok"})
+	}
+}
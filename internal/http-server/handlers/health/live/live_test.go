@@ -0,0 +1,18 @@
+package live_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/health/live"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLiveHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	live.New().ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+}
@@ -0,0 +1,121 @@
+package ready
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"fmt"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// checkTimeout bounds every check, so that a hanging dependency fails the probe instead of stalling it.
+const checkTimeout = 2 * time.Second
+
+const (
+	StatusOK       = "ok"
+	StatusFailed   = "failed"
+	StatusDegraded = "degraded"
+
+	StatusReady    = "ready"
+	StatusNotReady = "not ready"
+)
+
+type Check struct {
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+type Response struct {
+	Status string           `json:"status"`
+	Checks map[string]Check `json:"checks"`
+}
+
+type Readiness interface {
+	Ready() bool
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Database
+type Database interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (uint, bool, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=InfoAPI
+type InfoAPI interface {
+	Ping(ctx context.Context) error
+}
+
+// New answers readiness probes with 200 if the service can serve requests and 503 otherwise,
+// with the result of every check. Check details are short statuses; the errors behind them are logged. The service is not ready while it starts or shuts down,
+// if the database is unreachable or not migrated to schemaVersion. An unreachable info API
+// only degrades the service, as only saving cars needs it.
+//
+//	@Summary		Readiness probe
+//	@Description	Check whether the service can serve requests: its lifecycle, the database, the schema version and the info API
+//	@Tags			Health
+//	@Produce		json
+//	@Success		200	{object}	Response
+//	@Failure		503	{object}	Response
+//	@Router			/readyz [get]
+func New(log *slog.Logger, readiness Readiness, db Database, info InfoAPI, schemaVersion uint) http.HandlerFunc {
+	log = log.With(
+		slog.String("op", "handlers.health.ready.New"),
+	)
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := Response{Status: StatusReady, Checks: make(map[string]Check)}
+		fail := func(name string, detail string) {
+			resp.Status = StatusNotReady
+			resp.Checks[name] = Check{Status: StatusFailed, Detail: detail}
+		}
+
+		if readiness.Ready() {
+			resp.Checks["lifecycle"] = Check{Status: StatusOK}
+		} else {
+			fail("lifecycle", "the service is starting or shutting down")
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+		defer cancel()
+
+		if err := db.Ping(ctx); err != nil {
+			log.Error("database is unreachable", sl.Err(err))
+
+			fail("database", "unreachable")
+		} else {
+			resp.Checks["database"] = Check{Status: StatusOK}
+		}
+
+		version, dirty, err := db.SchemaVersion(ctx)
+		switch {
+		case err != nil:
+			log.Error("failed to get schema version", sl.Err(err))
+
+			fail("migrations", "schema version is unknown")
+		case dirty:
+			fail("migrations", fmt.Sprintf("migration %d failed halfway", version))
+		case version != schemaVersion:
+			fail("migrations", fmt.Sprintf("schema version is %d, %d is expected", version, schemaVersion))
+		default:
+			resp.Checks["migrations"] = Check{Status: StatusOK, Detail: fmt.Sprintf("schema version %d", version)}
+		}
+
+		if err = info.Ping(ctx); err != nil {
+			log.Warn("info API is unreachable", sl.Err(err))
+
+			resp.Checks["info_api"] = Check{Status: StatusDegraded, Detail: "unreachable"}
+		} else {
+			resp.Checks["info_api"] = Check{Status: StatusOK}
+		}
+
+		if resp.Status != StatusReady {
+			log.Warn("service is not ready", slog.Any("checks", resp.Checks))
+
+			render.Status(r, http.StatusServiceUnavailable)
+		}
+
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,143 @@
+package ready_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/health/ready"
+	"effective_mobile_test/internal/http-server/handlers/health/ready/mocks"
+	"effective_mobile_test/internal/lib/health"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestReadyHandler(t *testing.T) {
+	const schemaVersion = 16
+
+	cases := []struct {
+		name       string
+		notReady   bool
+		pingErr    error
+		version    uint
+		dirty      bool
+		versionErr error
+		infoErr    error
+		wantCode   int
+		wantStatus string
+		wantChecks map[string]ready.Check
+	}{
+		{
+			name:       "ready",
+			version:    schemaVersion,
+			wantCode:   http.StatusOK,
+			wantStatus: ready.StatusReady,
+			wantChecks: map[string]ready.Check{
+				"lifecycle":  {Status: ready.StatusOK},
+				"database":   {Status: ready.StatusOK},
+				"migrations": {Status: ready.StatusOK, Detail: "schema version 16"},
+				"info_api":   {Status: ready.StatusOK},
+			},
+		},
+		{
+			name:       "info API down only degrades",
+			version:    schemaVersion,
+			infoErr:    errors.New("dial tcp 10.0.0.7:8081: connect: connection refused"),
+			wantCode:   http.StatusOK,
+			wantStatus: ready.StatusReady,
+			wantChecks: map[string]ready.Check{
+				"lifecycle":  {Status: ready.StatusOK},
+				"database":   {Status: ready.StatusOK},
+				"migrations": {Status: ready.StatusOK, Detail: "schema version 16"},
+				"info_api":   {Status: ready.StatusDegraded, Detail: "unreachable"},
+			},
+		},
+		{
+			name:       "shutting down",
+			notReady:   true,
+			version:    schemaVersion,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: ready.StatusNotReady,
+			wantChecks: map[string]ready.Check{
+				"lifecycle":  {Status: ready.StatusFailed, Detail: "the service is starting or shutting down"},
+				"database":   {Status: ready.StatusOK},
+				"migrations": {Status: ready.StatusOK, Detail: "schema version 16"},
+				"info_api":   {Status: ready.StatusOK},
+			},
+		},
+		{
+			name:       "database down hides the error",
+			pingErr:    errors.New("failed to connect to `host=db.internal user=cars`: password authentication failed"),
+			versionErr: errors.New("failed to connect to `host=db.internal user=cars`: password authentication failed"),
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: ready.StatusNotReady,
+			wantChecks: map[string]ready.Check{
+				"lifecycle":  {Status: ready.StatusOK},
+				"database":   {Status: ready.StatusFailed, Detail: "unreachable"},
+				"migrations": {Status: ready.StatusFailed, Detail: "schema version is unknown"},
+				"info_api":   {Status: ready.StatusOK},
+			},
+		},
+		{
+			name:       "dirty migration",
+			version:    schemaVersion,
+			dirty:      true,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: ready.StatusNotReady,
+			wantChecks: map[string]ready.Check{
+				"lifecycle":  {Status: ready.StatusOK},
+				"database":   {Status: ready.StatusOK},
+				"migrations": {Status: ready.StatusFailed, Detail: "migration 16 failed halfway"},
+				"info_api":   {Status: ready.StatusOK},
+			},
+		},
+		{
+			name:       "outdated schema",
+			version:    schemaVersion - 1,
+			wantCode:   http.StatusServiceUnavailable,
+			wantStatus: ready.StatusNotReady,
+			wantChecks: map[string]ready.Check{
+				"lifecycle":  {Status: ready.StatusOK},
+				"database":   {Status: ready.StatusOK},
+				"migrations": {Status: ready.StatusFailed, Detail: "schema version is 15, 16 is expected"},
+				"info_api":   {Status: ready.StatusOK},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var state health.State
+			state.SetReady(!tc.notReady)
+
+			db := mocks.NewDatabase(t)
+			db.On("Ping", mock.Anything).Return(tc.pingErr).Once()
+			db.On("SchemaVersion", mock.Anything).Return(tc.version, tc.dirty, tc.versionErr).Once()
+
+			info := mocks.NewInfoAPI(t)
+			info.On("Ping", mock.Anything).Return(tc.infoErr).Once()
+
+			handler := ready.New(slog.New(slog.NewTextHandler(io.Discard, nil)), &state, db, info, schemaVersion)
+
+			req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantCode, rr.Code)
+
+			var resp ready.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantStatus, resp.Status)
+			assert.Equal(t, tc.wantChecks, resp.Checks)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package cars
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Request struct {
+	OwnerId int       `json:"ownerId"`
+	AsOf    time.Time `json:"asOf"`
+}
+
+type Response struct {
+	response.Response
+	Cars []postgres.Car `json:"cars"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerCarsGetter
+type OwnerCarsGetter interface {
+	GetOwnerCars(ctx context.Context, ownerID int, asOf time.Time) ([]postgres.Car, error)
+}
+
+//	@Summary		Get owner cars at a point in time
+//	@Description	Get all cars held by the owner at asOf (RFC 3339, defaults to now)
+//	@Tags			Owner
+//	@Produce		json
+//	@Param			ownerId	query		int		true	"OwnerId"
+//	@Param			asOf	query		string	false	"AsOf"
+//	@Success		200		{object}	Response
+//	@Failure		400		{object}	response.Response
+//	@Failure		404		{object}	response.Response
+//	@Router			/owner/cars [get]
+func New(log *slog.Logger, ownerCarsGetter OwnerCarsGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.owner.cars.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		cars, err := ownerCarsGetter.GetOwnerCars(r.Context(), req.OwnerId, req.AsOf)
+		if errors.Is(err, storage.ErrOwnerNotFound) {
+			log.Info("owner not found", slog.Int("owner_id", req.OwnerId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("owner not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get owner cars", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get owner cars"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			cars,
+		})
+	}
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	ownerId, err := strconv.Atoi(r.URL.Query().Get("ownerId"))
+	if err != nil {
+		return Request{}, err
+	}
+
+	req := Request{
+		OwnerId: ownerId,
+		AsOf:    time.Now(),
+	}
+
+	if asOf := r.URL.Query().Get("asOf"); asOf != "" {
+		t, err := time.Parse(time.RFC3339, asOf)
+		if err != nil {
+			return Request{}, err
+		}
+		req.AsOf = t
+	}
+
+	return req, nil
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if req.OwnerId < 1 {
+		return false, slog.String("field", "owner_id"), "field owner_id is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
@@ -0,0 +1,113 @@
+package cars_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/cars"
+	"effective_mobile_test/internal/http-server/handlers/owner/cars/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCarsHandler(t *testing.T) {
+	asOf := time.Date(2023, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name  string
+		query string
+		// asOf is the instant expected by the storage, zero for now, nil if it must not be called
+		asOf       *time.Time
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "as of now",
+			query:      "?ownerId=1",
+			asOf:       &time.Time{},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "as of the given instant",
+			query:      "?ownerId=1&asOf=2023-06-01T12:00:00Z",
+			asOf:       &asOf,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "owner not found",
+			query:      "?ownerId=1",
+			asOf:       &time.Time{},
+			mockErr:    storage.ErrOwnerNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "owner not found",
+		},
+		{
+			name:       "storage failure",
+			query:      "?ownerId=1",
+			asOf:       &time.Time{},
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to get owner cars",
+		},
+		{
+			name:       "invalid as of",
+			query:      "?ownerId=1&asOf=yesterday",
+			wantStatus: http.StatusOK,
+			wantError:  "failed to parse request",
+		},
+		{
+			name:       "missing owner id",
+			query:      "",
+			wantStatus: http.StatusOK,
+			wantError:  "failed to parse request",
+		},
+		{
+			name:       "invalid owner id",
+			query:      "?ownerId=0",
+			wantStatus: http.StatusOK,
+			wantError:  "field owner_id is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerCarsGetter := mocks.NewOwnerCarsGetter(t)
+			if tc.asOf != nil {
+				matchAsOf := mock.MatchedBy(func(at time.Time) bool {
+					if tc.asOf.IsZero() {
+						return time.Since(at) < time.Minute
+					}
+					return at.Equal(*tc.asOf)
+				})
+				ownerCarsGetter.On("GetOwnerCars", mock.Anything, 1, matchAsOf).
+					Return([]postgres.Car{{RegNum: "X123XX150", Mark: "Lada"}}, tc.mockErr).Once()
+			}
+
+			handler := cars.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerCarsGetter)
+
+			req := httptest.NewRequest(http.MethodGet, "/owner/cars"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp cars.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Len(t, resp.Cars, 1)
+			}
+		})
+	}
+}
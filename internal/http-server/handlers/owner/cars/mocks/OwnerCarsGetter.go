@@ -0,0 +1,61 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// OwnerCarsGetter is an autogenerated mock type for the OwnerCarsGetter type
+type OwnerCarsGetter struct {
+	mock.Mock
+}
+
+// GetOwnerCars provides a mock function with given fields: ctx, ownerID, asOf
+func (_m *OwnerCarsGetter) GetOwnerCars(ctx context.Context, ownerID int, asOf time.Time) ([]postgres.Car, error) {
+	ret := _m.Called(ctx, ownerID, asOf)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOwnerCars")
+	}
+
+	var r0 []postgres.Car
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) ([]postgres.Car, error)); ok {
+		return rf(ctx, ownerID, asOf)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) []postgres.Car); ok {
+		r0 = rf(ctx, ownerID, asOf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.Car)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, ownerID, asOf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOwnerCarsGetter creates a new instance of OwnerCarsGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnerCarsGetter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnerCarsGetter {
+	mock := &OwnerCarsGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -1,16 +1,29 @@
 package delete
 
 import (
+	"context"
+	"effective_mobile_test/internal/lib/api/etag"
 	"effective_mobile_test/internal/lib/api/response"
 	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
+	"io"
 	"log/slog"
 	"net/http"
+	"strconv"
 )
 
 type Request struct {
 	OwnerId int `json:"ownerId"`
+	// Policy is one of restrict (default), cascade or reassign
+	Policy     postgres.OwnerDeletePolicy `json:"policy,omitempty"`
+	ReassignTo int                        `json:"reassignTo,omitempty"`
+	// Version is an alternative to the If-Match header
+	Version *int `json:"version,omitempty"`
 }
 
 type Response struct {
@@ -19,18 +32,28 @@ type Response struct {
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerDeleter
 type OwnerDeleter interface {
-	DeleteOwner(OwnerId int) error
+	DeleteOwner(ctx context.Context, ownerID int, policy postgres.OwnerDeletePolicy, reassignTo int, versions []int) error
 }
 
-//	@Summary		Delete owner
-//	@Description	Delete owner by ownerId
-//	@Tags			Owner
-//	@Accept			json
-//	@Produce		json
-//	@Param			ownerId	body		int	true	"OwnerId"
-//	@Success		200		{object}	Response
-//	@Failure		400		{object}	response.Response
-//	@Router			/owner/delete [delete]
+// @Summary		Delete owner
+// @Description	Delete owner by ownerId (or id in the path). The policy decides what happens to the cars the owner holds:
+// @Description	restrict (default) refuses with 409, cascade deletes them, reassign hands them over to reassignTo.
+// @Tags			Owner
+// @Accept			json
+// @Produce		json
+// @Param			id			path		int		false	"OwnerId (for /owners/{id})"
+// @Param			ownerId		body		int		false	"OwnerId (for /owner/delete)"
+// @Param			policy		body		string	false	"Policy"
+// @Param			reassignTo	body		int		false	"ReassignTo"
+// @Param			version		body		int		false	"Version"
+// @Param			If-Match	header		string	false	"ETag of the owner"
+// @Success		200			{object}	Response
+// @Failure		400			{object}	response.Response
+// @Failure		404			{object}	response.Response
+// @Failure		409			{object}	response.Response
+// @Failure		412			{object}	response.Response
+// @Router			/owner/delete [delete]
+// @Router			/owners/{id} [delete]
 func New(log *slog.Logger, ownerDeleter OwnerDeleter) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		const op = "handlers.owner.delete.New"
@@ -38,12 +61,14 @@ func New(log *slog.Logger, ownerDeleter OwnerDeleter) http.HandlerFunc {
 		log = log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
 		)
 
 		var req Request
 
+		// the body is optional for DELETE /owners/{id}
 		err := render.DecodeJSON(r.Body, &req)
-		if err != nil {
+		if err != nil && !errors.Is(err, io.EOF) {
 			log.Error("failed to decode request", sl.Err(err))
 
 			render.JSON(w, r, response.Error("failed to decode request"))
@@ -51,6 +76,13 @@ func New(log *slog.Logger, ownerDeleter OwnerDeleter) http.HandlerFunc {
 			return
 		}
 
+		if id := chi.URLParam(r, "id"); id != "" {
+			req.OwnerId, _ = strconv.Atoi(id)
+		}
+		if req.Policy == "" {
+			req.Policy = postgres.OwnerDeleteRestrict
+		}
+
 		log.Info("request body decoded", slog.Any("request", req))
 
 		if ok, field, msg := validateRequest(req); !ok {
@@ -61,7 +93,49 @@ func New(log *slog.Logger, ownerDeleter OwnerDeleter) http.HandlerFunc {
 			return
 		}
 
-		err = ownerDeleter.DeleteOwner(req.OwnerId)
+		versions, err := etag.Expected(r, req.Version)
+		if errors.Is(err, etag.ErrPreconditionFailed) {
+			log.Info("owner version precondition failed", slog.Int("owner_id", req.OwnerId))
+
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("owner was changed by someone else"))
+
+			return
+		}
+		if err != nil {
+			log.Error("invalid request", slog.String("field", "If-Match"), sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("header If-Match is not valid"))
+
+			return
+		}
+
+		err = ownerDeleter.DeleteOwner(r.Context(), req.OwnerId, req.Policy, req.ReassignTo, versions)
+		if errors.Is(err, storage.ErrOwnerNotFound) {
+			log.Info("owner not found", slog.Int("owner_id", req.OwnerId), slog.Int("reassign_to", req.ReassignTo))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("owner not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrOwnerHasCars) {
+			log.Info("owner still holds cars", slog.Int("owner_id", req.OwnerId))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("owner still holds cars"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			log.Info("owner version mismatch", slog.Int("owner_id", req.OwnerId))
+
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("owner was changed by someone else"))
+
+			return
+		}
 		if err != nil {
 			log.Error("failed to delete owner", sl.Err(err))
 
@@ -80,5 +154,17 @@ func validateRequest(req Request) (bool, slog.Attr, string) {
 	if req.OwnerId < 1 {
 		return false, slog.String("field", "owner_id"), "field owner_id is not valid"
 	}
+	switch req.Policy {
+	case postgres.OwnerDeleteRestrict, postgres.OwnerDeleteCascade:
+	case postgres.OwnerDeleteReassign:
+		if req.ReassignTo < 1 || req.ReassignTo == req.OwnerId {
+			return false, slog.String("field", "reassignTo"), "field reassignTo is not valid"
+		}
+	default:
+		return false, slog.String("field", "policy"), "field policy is not valid"
+	}
+	if req.Version != nil && *req.Version < 1 {
+		return false, slog.String("field", "version"), "field version is not valid"
+	}
 	return true, slog.Attr{}, ""
 }
@@ -0,0 +1,173 @@
+package delete_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/delete"
+	"effective_mobile_test/internal/http-server/handlers/owner/delete/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name    string
+		path    string
+		body    string
+		ifMatch string
+		// policy, reassignTo and versions are expected by the storage, an empty policy if it must not be called
+		policy     postgres.OwnerDeletePolicy
+		reassignTo int
+		versions   []int
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "restrict by default",
+			path:       "/owners/1",
+			policy:     postgres.OwnerDeleteRestrict,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "owner still holds cars",
+			path:       "/owners/1",
+			policy:     postgres.OwnerDeleteRestrict,
+			mockErr:    storage.ErrOwnerHasCars,
+			wantStatus: http.StatusConflict,
+			wantError:  "owner still holds cars",
+		},
+		{
+			name:       "cascade with the legacy route",
+			path:       "/owner/delete",
+			body:       `{"ownerId": 1, "policy": "cascade"}`,
+			policy:     postgres.OwnerDeleteCascade,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reassign",
+			path:       "/owners/1",
+			body:       `{"policy": "reassign", "reassignTo": 2}`,
+			policy:     postgres.OwnerDeleteReassign,
+			reassignTo: 2,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "reassign to an unknown owner",
+			path:       "/owners/1",
+			body:       `{"policy": "reassign", "reassignTo": 2}`,
+			policy:     postgres.OwnerDeleteReassign,
+			reassignTo: 2,
+			mockErr:    storage.ErrOwnerNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "owner not found",
+		},
+		{
+			name:       "any of the listed versions",
+			path:       "/owners/1",
+			ifMatch:    `"2", "3"`,
+			policy:     postgres.OwnerDeleteRestrict,
+			versions:   []int{2, 3},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "version from the body",
+			path:       "/owner/delete",
+			body:       `{"ownerId": 1, "version": 2}`,
+			policy:     postgres.OwnerDeleteRestrict,
+			versions:   []int{2},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "changed by someone else",
+			path:       "/owners/1",
+			ifMatch:    `"2"`,
+			policy:     postgres.OwnerDeleteRestrict,
+			versions:   []int{2},
+			mockErr:    storage.ErrVersionMismatch,
+			wantStatus: http.StatusPreconditionFailed,
+			wantError:  "owner was changed by someone else",
+		},
+		{
+			name:       "weak tag never matches",
+			path:       "/owners/1",
+			ifMatch:    `W/"2"`,
+			wantStatus: http.StatusPreconditionFailed,
+			wantError:  "owner was changed by someone else",
+		},
+		{
+			name:       "malformed If-Match",
+			path:       "/owners/1",
+			ifMatch:    "2",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "header If-Match is not valid",
+		},
+		{
+			name:       "reassign to the same owner",
+			path:       "/owners/1",
+			body:       `{"policy": "reassign", "reassignTo": 1}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field reassignTo is not valid",
+		},
+		{
+			name:       "reassign without a new owner",
+			path:       "/owners/1",
+			body:       `{"policy": "reassign"}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field reassignTo is not valid",
+		},
+		{
+			name:       "unknown policy",
+			path:       "/owners/1",
+			body:       `{"policy": "orphan"}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field policy is not valid",
+		},
+		{
+			name:       "missing owner id",
+			path:       "/owner/delete",
+			body:       `{}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field owner_id is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerDeleter := mocks.NewOwnerDeleter(t)
+			if tc.policy != "" {
+				ownerDeleter.On("DeleteOwner", mock.Anything, 1, tc.policy, tc.reassignTo, tc.versions).Return(tc.mockErr).Once()
+			}
+
+			handler := delete.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerDeleter)
+
+			router := chi.NewRouter()
+			router.Delete("/owner/delete", handler)
+			router.Delete("/owners/{id}", handler)
+
+			req := httptest.NewRequest(http.MethodDelete, tc.path, strings.NewReader(tc.body))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp delete.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// OwnerDeleter is an autogenerated mock type for the OwnerDeleter type
+type OwnerDeleter struct {
+	mock.Mock
+}
+
+// DeleteOwner provides a mock function with given fields: ctx, ownerID, policy, reassignTo, versions
+func (_m *OwnerDeleter) DeleteOwner(ctx context.Context, ownerID int, policy postgres.OwnerDeletePolicy, reassignTo int, versions []int) error {
+	ret := _m.Called(ctx, ownerID, policy, reassignTo, versions)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteOwner")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, postgres.OwnerDeletePolicy, int, []int) error); ok {
+		r0 = rf(ctx, ownerID, policy, reassignTo, versions)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewOwnerDeleter creates a new instance of OwnerDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnerDeleter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnerDeleter {
+	mock := &OwnerDeleter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
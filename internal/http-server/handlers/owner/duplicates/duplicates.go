@@ -0,0 +1,121 @@
+package duplicates
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage/postgres"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultThreshold = 0.6
+	defaultLimit     = 50
+	maxLimit         = 1000
+)
+
+type Request struct {
+	Threshold float64 `json:"threshold"`
+	Limit     int     `json:"limit"`
+}
+
+type Response struct {
+	response.Response
+	Duplicates []postgres.OwnerDuplicate `json:"duplicates"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=DuplicatesFinder
+type DuplicatesFinder interface {
+	GetOwnerDuplicates(ctx context.Context, threshold float64, limit int) ([]postgres.OwnerDuplicate, error)
+}
+
+//	@Summary		Owner duplicate candidates
+//	@Description	Report pairs of owners that are likely the same person, most similar first
+//	@Tags			Owner
+//	@Produce		json
+//	@Param			threshold	query		number	false	"Minimal similarity from 0 to 1"
+//	@Param			limit		query		int		false	"Limit"
+//	@Success		200			{object}	Response
+//	@Failure		400			{object}	response.Response
+//	@Router			/owners/duplicates [get]
+func New(log *slog.Logger, duplicatesFinder DuplicatesFinder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.owner.duplicates.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		duplicates, err := duplicatesFinder.GetOwnerDuplicates(r.Context(), req.Threshold, req.Limit)
+		if err != nil {
+			log.Error("failed to get owner duplicates", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get owner duplicates"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			duplicates,
+		})
+	}
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	query := r.URL.Query()
+
+	req := Request{
+		Threshold: defaultThreshold,
+		Limit:     defaultLimit,
+	}
+
+	var err error
+	if threshold := query.Get("threshold"); threshold != "" {
+		if req.Threshold, err = strconv.ParseFloat(threshold, 64); err != nil {
+			return Request{}, err
+		}
+	}
+	if limit := query.Get("limit"); limit != "" {
+		if req.Limit, err = strconv.Atoi(limit); err != nil {
+			return Request{}, err
+		}
+	}
+
+	return req, nil
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if req.Threshold <= 0 || req.Threshold > 1 {
+		return false, slog.String("field", "threshold"), "field threshold is not valid"
+	}
+	if req.Limit < 1 || req.Limit > maxLimit {
+		return false, slog.String("field", "limit"), "field limit is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
@@ -0,0 +1,96 @@
+package duplicates_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/duplicates"
+	"effective_mobile_test/internal/http-server/handlers/owner/duplicates/mocks"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDuplicatesHandler(t *testing.T) {
+	patronymic := "Ivanovich"
+
+	cases := []struct {
+		name  string
+		query string
+		// callFind tells whether the storage is expected to be asked with the threshold and limit
+		callFind      bool
+		wantThreshold float64
+		wantLimit     int
+		wantError     string
+	}{
+		{
+			name:          "defaults",
+			callFind:      true,
+			wantThreshold: 0.6,
+			wantLimit:     50,
+		},
+		{
+			name:          "threshold and limit",
+			query:         "?threshold=0.85&limit=10",
+			callFind:      true,
+			wantThreshold: 0.85,
+			wantLimit:     10,
+		},
+		{
+			name:      "threshold out of range",
+			query:     "?threshold=1.5",
+			wantError: "field threshold is not valid",
+		},
+		{
+			name:      "zero threshold",
+			query:     "?threshold=0",
+			wantError: "field threshold is not valid",
+		},
+		{
+			name:      "limit too large",
+			query:     "?limit=1001",
+			wantError: "field limit is not valid",
+		},
+		{
+			name:      "threshold is not a number",
+			query:     "?threshold=high",
+			wantError: "failed to parse request",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			duplicatesFinder := mocks.NewDuplicatesFinder(t)
+			if tc.callFind {
+				duplicatesFinder.On("GetOwnerDuplicates", mock.Anything, tc.wantThreshold, tc.wantLimit).
+					Return([]postgres.OwnerDuplicate{{
+						Owner:     postgres.Owner{ID: 1, Name: "Ivan", Surname: "Ivanov"},
+						Candidate: postgres.Owner{ID: 2, Name: "Ivan", Surname: "Ivanov", Patronymic: &patronymic},
+						Score:     0.9,
+					}}, nil).Once()
+			}
+
+			handler := duplicates.New(slog.New(slog.NewTextHandler(io.Discard, nil)), duplicatesFinder)
+
+			req := httptest.NewRequest(http.MethodGet, "/owners/duplicates"+tc.query, nil)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp duplicates.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.callFind {
+				require.Len(t, resp.Duplicates, 1)
+				assert.Equal(t, 2, resp.Duplicates[0].Candidate.ID)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// DuplicatesFinder is an autogenerated mock type for the DuplicatesFinder type
+type DuplicatesFinder struct {
+	mock.Mock
+}
+
+// GetOwnerDuplicates provides a mock function with given fields: ctx, threshold, limit
+func (_m *DuplicatesFinder) GetOwnerDuplicates(ctx context.Context, threshold float64, limit int) ([]postgres.OwnerDuplicate, error) {
+	ret := _m.Called(ctx, threshold, limit)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOwnerDuplicates")
+	}
+
+	var r0 []postgres.OwnerDuplicate
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, float64, int) ([]postgres.OwnerDuplicate, error)); ok {
+		return rf(ctx, threshold, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, float64, int) []postgres.OwnerDuplicate); ok {
+		r0 = rf(ctx, threshold, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.OwnerDuplicate)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, float64, int) error); ok {
+		r1 = rf(ctx, threshold, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewDuplicatesFinder creates a new instance of DuplicatesFinder. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDuplicatesFinder(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DuplicatesFinder {
+	mock := &DuplicatesFinder{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,95 @@
+package get
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/etag"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type Response struct {
+	response.Response
+	Owner postgres.Owner `json:"owner"`
+	Cars  []postgres.Car `json:"cars"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerGetter
+type OwnerGetter interface {
+	GetOwner(ctx context.Context, ownerID int) (postgres.Owner, error)
+	GetOwnerCars(ctx context.Context, ownerID int, asOf time.Time) ([]postgres.Car, error)
+}
+
+//	@Summary		Get owner
+//	@Description	Get owner by id together with the cars they currently own
+//	@Tags			Owner
+//	@Produce		json
+//	@Param			id	path		int	true	"OwnerId"
+//	@Success		200	{object}	Response
+//	@Header			200	{string}	ETag	"ETag of the owner"
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Router			/owners/{id} [get]
+func New(log *slog.Logger, ownerGetter OwnerGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.owner.get.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		ownerId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || ownerId < 1 {
+			log.Error("invalid request", slog.String("field", "id"))
+
+			render.JSON(w, r, response.Error("field id is not valid"))
+
+			return
+		}
+
+		owner, err := ownerGetter.GetOwner(r.Context(), ownerId)
+		if errors.Is(err, storage.ErrOwnerNotFound) {
+			log.Info("owner not found", slog.Int("owner_id", ownerId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("owner not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get owner", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get owner"))
+
+			return
+		}
+
+		cars, err := ownerGetter.GetOwnerCars(r.Context(), ownerId, time.Now())
+		if err != nil {
+			log.Error("failed to get owner cars", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get owner cars"))
+
+			return
+		}
+
+		etag.Set(w, owner.Version)
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			owner,
+			cars,
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package get_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/get"
+	"effective_mobile_test/internal/http-server/handlers/owner/get/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		callOwner  bool
+		ownerErr   error
+		callCars   bool
+		carsErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "owner with cars",
+			id:         "1",
+			callOwner:  true,
+			callCars:   true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "owner not found",
+			id:         "1",
+			callOwner:  true,
+			ownerErr:   storage.ErrOwnerNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "owner not found",
+		},
+		{
+			name:       "cars failure",
+			id:         "1",
+			callOwner:  true,
+			callCars:   true,
+			carsErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to get owner cars",
+		},
+		{
+			name:       "id is not a number",
+			id:         "one",
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+		{
+			name:       "id is not positive",
+			id:         "0",
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerGetter := mocks.NewOwnerGetter(t)
+			if tc.callOwner {
+				ownerGetter.On("GetOwner", mock.Anything, 1).
+					Return(postgres.Owner{ID: 1, Name: "Ivan"}, tc.ownerErr).Once()
+			}
+			if tc.callCars {
+				ownerGetter.On("GetOwnerCars", mock.Anything, 1, mock.Anything).
+					Return([]postgres.Car{{RegNum: "X123XX150"}}, tc.carsErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Get("/owners/{id}", get.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerGetter))
+
+			req := httptest.NewRequest(http.MethodGet, "/owners/"+tc.id, nil)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp get.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Equal(t, "Ivan", resp.Owner.Name)
+				assert.Len(t, resp.Cars, 1)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	time "time"
+)
+
+// OwnerGetter is an autogenerated mock type for the OwnerGetter type
+type OwnerGetter struct {
+	mock.Mock
+}
+
+// GetOwner provides a mock function with given fields: ctx, ownerID
+func (_m *OwnerGetter) GetOwner(ctx context.Context, ownerID int) (postgres.Owner, error) {
+	ret := _m.Called(ctx, ownerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOwner")
+	}
+
+	var r0 postgres.Owner
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (postgres.Owner, error)); ok {
+		return rf(ctx, ownerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) postgres.Owner); ok {
+		r0 = rf(ctx, ownerID)
+	} else {
+		r0 = ret.Get(0).(postgres.Owner)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, ownerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetOwnerCars provides a mock function with given fields: ctx, ownerID, asOf
+func (_m *OwnerGetter) GetOwnerCars(ctx context.Context, ownerID int, asOf time.Time) ([]postgres.Car, error) {
+	ret := _m.Called(ctx, ownerID, asOf)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOwnerCars")
+	}
+
+	var r0 []postgres.Car
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) ([]postgres.Car, error)); ok {
+		return rf(ctx, ownerID, asOf)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Time) []postgres.Car); ok {
+		r0 = rf(ctx, ownerID, asOf)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.Car)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Time) error); ok {
+		r1 = rf(ctx, ownerID, asOf)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOwnerGetter creates a new instance of OwnerGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnerGetter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnerGetter {
+	mock := &OwnerGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,143 @@
+package list
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage/postgres"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageNum  = 1
+	defaultPageSize = 20
+)
+
+type Request struct {
+	postgres.OwnerSearchRequest
+}
+
+type Response struct {
+	response.Response
+	Owners []postgres.Owner `json:"owners"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerSearcher
+type OwnerSearcher interface {
+	GetOwnersBySearchRequest(ctx context.Context, searchRequest postgres.OwnerSearchRequest) ([]postgres.Owner, error)
+}
+
+//	@Summary		List owners
+//	@Description	List owners filtered by name, surname and patronymic with pagination.
+//	@Description	Soft-deleted owners are included on request to callers with the admin:deleted permission.
+//	@Tags			Owner
+//	@Produce		json
+//	@Param			name			query		string	false	"Name"
+//	@Param			surname			query		string	false	"Surname"
+//	@Param			patronymic		query		string	false	"Patronymic"
+//	@Param			pageNum			query		int		false	"PageNum"
+//	@Param			pageSize		query		int		false	"PageSize"
+//	@Param			includeDeleted	query		bool	false	"IncludeDeleted"
+//	@Success		200				{object}	Response
+//	@Failure		400				{object}	response.Response
+//	@Failure		403				{object}	problem.Problem
+//	@Router			/owners [get]
+func New(log *slog.Logger, ownerSearcher OwnerSearcher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.owner.list.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		if req.IncludeDeleted && !auth.Can(r.Context(), auth.PermAdminDeleted) {
+			log.Info("permission denied", slog.String("permission", auth.PermAdminDeleted))
+
+			problem.Write(w, r, http.StatusForbidden, "permission "+auth.PermAdminDeleted+" is required to include deleted owners")
+
+			return
+		}
+
+		owners, err := ownerSearcher.GetOwnersBySearchRequest(r.Context(), req.OwnerSearchRequest)
+		if err != nil {
+			log.Error("failed to get owners by search request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get owners by search request"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			owners,
+		})
+	}
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	query := r.URL.Query()
+
+	req := Request{postgres.OwnerSearchRequest{
+		Name:       query.Get("name"),
+		Surname:    query.Get("surname"),
+		Patronymic: query.Get("patronymic"),
+		PageNum:    defaultPageNum,
+		PageSize:   defaultPageSize,
+	}}
+
+	var err error
+	if pageNum := query.Get("pageNum"); pageNum != "" {
+		if req.PageNum, err = strconv.Atoi(pageNum); err != nil {
+			return Request{}, err
+		}
+	}
+	if pageSize := query.Get("pageSize"); pageSize != "" {
+		if req.PageSize, err = strconv.Atoi(pageSize); err != nil {
+			return Request{}, err
+		}
+	}
+	if includeDeleted := query.Get("includeDeleted"); includeDeleted != "" {
+		if req.IncludeDeleted, err = strconv.ParseBool(includeDeleted); err != nil {
+			return Request{}, err
+		}
+	}
+
+	return req, nil
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if req.PageSize < 1 {
+		return false, slog.String("field", "pageSize"), "field pageSize is not valid"
+	}
+	if req.PageNum < 1 {
+		return false, slog.String("field", "pageNum"), "field pageNum is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
@@ -0,0 +1,126 @@
+package list_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/list"
+	"effective_mobile_test/internal/http-server/handlers/owner/list/mocks"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListHandler(t *testing.T) {
+	cases := []struct {
+		name  string
+		role  string
+		query string
+		// want is the request expected by the storage, nil if it must not be called
+		want       *postgres.OwnerSearchRequest
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name: "defaults",
+			want: &postgres.OwnerSearchRequest{PageNum: 1, PageSize: 20},
+		},
+		{
+			name:  "filters and page",
+			query: "?name=Ivan&surname=Ivanov&patronymic=Ivanovich&pageNum=2&pageSize=5",
+			want: &postgres.OwnerSearchRequest{
+				Name: "Ivan", Surname: "Ivanov", Patronymic: "Ivanovich", PageNum: 2, PageSize: 5,
+			},
+		},
+		{
+			name:      "storage failure",
+			want:      &postgres.OwnerSearchRequest{PageNum: 1, PageSize: 20},
+			mockErr:   errors.New("unexpected error"),
+			wantError: "failed to get owners by search request",
+		},
+		{
+			name:  "admin includes deleted",
+			role:  auth.RoleAdmin,
+			query: "?includeDeleted=true",
+			want:  &postgres.OwnerSearchRequest{PageNum: 1, PageSize: 20, IncludeDeleted: true},
+		},
+		{
+			name:       "editor can't include deleted",
+			role:       auth.RoleEditor,
+			query:      "?includeDeleted=true",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "viewer can't include deleted",
+			query:      "?includeDeleted=1",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:  "excluding deleted needs no permission",
+			query: "?includeDeleted=false",
+			want:  &postgres.OwnerSearchRequest{PageNum: 1, PageSize: 20},
+		},
+		{
+			name:      "page num is not a number",
+			query:     "?pageNum=first",
+			wantError: "failed to parse request",
+		},
+		{
+			name:      "invalid page size",
+			query:     "?pageSize=0",
+			wantError: "field pageSize is not valid",
+		},
+		{
+			name:      "invalid page num",
+			query:     "?pageNum=-1",
+			wantError: "field pageNum is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerSearcher := mocks.NewOwnerSearcher(t)
+			if tc.want != nil {
+				ownerSearcher.On("GetOwnersBySearchRequest", mock.Anything, *tc.want).
+					Return([]postgres.Owner{{ID: 1, Name: "Ivan", Surname: "Ivanov"}}, tc.mockErr).Once()
+			}
+
+			handler := list.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerSearcher)
+
+			role := tc.role
+			if role == "" {
+				role = auth.RoleViewer
+			}
+			wantStatus := tc.wantStatus
+			if wantStatus == 0 {
+				wantStatus = http.StatusOK
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/owners"+tc.query, nil)
+			req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "test", Role: role}))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, wantStatus, rr.Code)
+			if wantStatus == http.StatusForbidden {
+				return
+			}
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Len(t, resp.Owners, 1)
+			}
+		})
+	}
+}
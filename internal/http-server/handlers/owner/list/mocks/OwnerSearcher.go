@@ -0,0 +1,60 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// OwnerSearcher is an autogenerated mock type for the OwnerSearcher type
+type OwnerSearcher struct {
+	mock.Mock
+}
+
+// GetOwnersBySearchRequest provides a mock function with given fields: ctx, searchRequest
+func (_m *OwnerSearcher) GetOwnersBySearchRequest(ctx context.Context, searchRequest postgres.OwnerSearchRequest) ([]postgres.Owner, error) {
+	ret := _m.Called(ctx, searchRequest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetOwnersBySearchRequest")
+	}
+
+	var r0 []postgres.Owner
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.OwnerSearchRequest) ([]postgres.Owner, error)); ok {
+		return rf(ctx, searchRequest)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.OwnerSearchRequest) []postgres.Owner); ok {
+		r0 = rf(ctx, searchRequest)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.Owner)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.OwnerSearchRequest) error); ok {
+		r1 = rf(ctx, searchRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOwnerSearcher creates a new instance of OwnerSearcher. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnerSearcher(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnerSearcher {
+	mock := &OwnerSearcher{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
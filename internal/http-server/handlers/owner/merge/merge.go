@@ -0,0 +1,110 @@
+package merge
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Request struct {
+	OwnerId       int `json:"-"`
+	SourceOwnerId int `json:"sourceOwnerId"`
+}
+
+type Response struct {
+	response.Response
+	Reassigned int `json:"reassigned"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerMerger
+type OwnerMerger interface {
+	MergeOwners(ctx context.Context, targetID, sourceID int) (int, error)
+}
+
+//	@Summary		Merge owners
+//	@Description	Move all cars of the source owner to the owner {id} and delete the source owner
+//	@Tags			Owner
+//	@Accept			json
+//	@Produce		json
+//	@Param			id				path		int	true	"OwnerId"
+//	@Param			sourceOwnerId	body		int	true	"SourceOwnerId"
+//	@Success		200				{object}	Response
+//	@Failure		400				{object}	response.Response
+//	@Failure		404				{object}	response.Response
+//	@Router			/owners/{id}/merge [post]
+func New(log *slog.Logger, ownerMerger OwnerMerger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.owner.merge.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		req.OwnerId, _ = strconv.Atoi(chi.URLParam(r, "id"))
+
+		log.Info("request body decoded", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		reassigned, err := ownerMerger.MergeOwners(r.Context(), req.OwnerId, req.SourceOwnerId)
+		if errors.Is(err, storage.ErrOwnerNotFound) {
+			log.Info("owner not found", slog.Int("owner_id", req.OwnerId), slog.Int("source_owner_id", req.SourceOwnerId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("owner not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to merge owners", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to merge owners"))
+
+			return
+		}
+
+		log.Info("owners merged", slog.Int("owner_id", req.OwnerId), slog.Int("source_owner_id", req.SourceOwnerId))
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			reassigned,
+		})
+	}
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if req.OwnerId < 1 {
+		return false, slog.String("field", "id"), "field id is not valid"
+	}
+	if req.SourceOwnerId < 1 || req.SourceOwnerId == req.OwnerId {
+		return false, slog.String("field", "sourceOwnerId"), "field sourceOwnerId is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
@@ -0,0 +1,112 @@
+package merge_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/merge"
+	"effective_mobile_test/internal/http-server/handlers/owner/merge/mocks"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMergeHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		body       string
+		callMerge  bool
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "merged",
+			id:         "1",
+			body:       `{"sourceOwnerId": 2}`,
+			callMerge:  true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "owner not found",
+			id:         "1",
+			body:       `{"sourceOwnerId": 2}`,
+			callMerge:  true,
+			mockErr:    storage.ErrOwnerNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "owner not found",
+		},
+		{
+			name:       "storage failure",
+			id:         "1",
+			body:       `{"sourceOwnerId": 2}`,
+			callMerge:  true,
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to merge owners",
+		},
+		{
+			name:       "owner merged into itself",
+			id:         "1",
+			body:       `{"sourceOwnerId": 1}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field sourceOwnerId is not valid",
+		},
+		{
+			name:       "missing source owner",
+			id:         "1",
+			body:       `{}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field sourceOwnerId is not valid",
+		},
+		{
+			name:       "invalid id",
+			id:         "first",
+			body:       `{"sourceOwnerId": 2}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+		{
+			name:       "malformed body",
+			id:         "1",
+			body:       `{"sourceOwnerId": "2"`,
+			wantStatus: http.StatusOK,
+			wantError:  "failed to decode request",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerMerger := mocks.NewOwnerMerger(t)
+			if tc.callMerge {
+				ownerMerger.On("MergeOwners", mock.Anything, 1, 2).Return(3, tc.mockErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Post("/owners/{id}/merge", merge.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerMerger))
+
+			req := httptest.NewRequest(http.MethodPost, "/owners/"+tc.id+"/merge", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp merge.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Equal(t, 3, resp.Reassigned)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OwnerMerger is an autogenerated mock type for the OwnerMerger type
+type OwnerMerger struct {
+	mock.Mock
+}
+
+// MergeOwners provides a mock function with given fields: ctx, targetID, sourceID
+func (_m *OwnerMerger) MergeOwners(ctx context.Context, targetID int, sourceID int) (int, error) {
+	ret := _m.Called(ctx, targetID, sourceID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for MergeOwners")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) (int, error)); ok {
+		return rf(ctx, targetID, sourceID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, int) int); ok {
+		r0 = rf(ctx, targetID, sourceID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, int) error); ok {
+		r1 = rf(ctx, targetID, sourceID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOwnerMerger creates a new instance of OwnerMerger. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnerMerger(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnerMerger {
+	mock := &OwnerMerger{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,46 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OwnerRestorer is an autogenerated mock type for the OwnerRestorer type
+type OwnerRestorer struct {
+	mock.Mock
+}
+
+// RestoreOwner provides a mock function with given fields: ctx, ownerID
+func (_m *OwnerRestorer) RestoreOwner(ctx context.Context, ownerID int) error {
+	ret := _m.Called(ctx, ownerID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RestoreOwner")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, ownerID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewOwnerRestorer creates a new instance of OwnerRestorer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnerRestorer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnerRestorer {
+	mock := &OwnerRestorer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
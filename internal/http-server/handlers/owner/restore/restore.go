@@ -0,0 +1,86 @@
+package restore
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Response struct {
+	response.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerRestorer
+type OwnerRestorer interface {
+	RestoreOwner(ctx context.Context, ownerID int) error
+}
+
+//	@Summary		Restore owner
+//	@Description	Restore a soft-deleted owner by id
+//	@Tags			Owner
+//	@Produce		json
+//	@Param			id	path		int	true	"OwnerId"
+//	@Success		200	{object}	Response
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Failure		409	{object}	response.Response
+//	@Router			/owners/{id}/restore [post]
+func New(log *slog.Logger, ownerRestorer OwnerRestorer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.owner.restore.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		ownerId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || ownerId < 1 {
+			log.Error("invalid request", slog.String("field", "id"))
+
+			render.JSON(w, r, response.Error("field id is not valid"))
+
+			return
+		}
+
+		err = ownerRestorer.RestoreOwner(r.Context(), ownerId)
+		if errors.Is(err, storage.ErrOwnerNotFound) {
+			log.Info("deleted owner not found", slog.Int("owner_id", ownerId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("deleted owner not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrOwnerExists) {
+			log.Info("owner with the same full name already exists", slog.Int("owner_id", ownerId))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("owner with the same full name already exists"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to restore owner", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to restore owner"))
+
+			return
+		}
+
+		log.Info("owner restored", slog.Int("owner_id", ownerId))
+
+		render.JSON(w, r, Response{
+			response.OK(),
+		})
+	}
+}
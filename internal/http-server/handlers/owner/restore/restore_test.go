@@ -0,0 +1,81 @@
+package restore_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/restore"
+	"effective_mobile_test/internal/http-server/handlers/owner/restore/mocks"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRestoreHandler(t *testing.T) {
+	cases := []struct {
+		name        string
+		id          string
+		callRestore bool
+		mockErr     error
+		wantStatus  int
+		wantError   string
+	}{
+		{
+			name:        "restored",
+			id:          "3",
+			callRestore: true,
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "owner is not deleted",
+			id:          "3",
+			callRestore: true,
+			mockErr:     storage.ErrOwnerNotFound,
+			wantStatus:  http.StatusNotFound,
+			wantError:   "deleted owner not found",
+		},
+		{
+			name:        "identity taken by another owner",
+			id:          "3",
+			callRestore: true,
+			mockErr:     storage.ErrOwnerExists,
+			wantStatus:  http.StatusConflict,
+			wantError:   "owner with the same full name already exists",
+		},
+		{
+			name:       "invalid id",
+			id:         "three",
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerRestorer := mocks.NewOwnerRestorer(t)
+			if tc.callRestore {
+				ownerRestorer.On("RestoreOwner", mock.Anything, 3).Return(tc.mockErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Post("/owners/{id}/restore", restore.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerRestorer))
+
+			req := httptest.NewRequest(http.MethodPost, "/owners/"+tc.id+"/restore", nil)
+			rr := httptest.NewRecorder()
+
+			router.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp restore.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+		})
+	}
+}
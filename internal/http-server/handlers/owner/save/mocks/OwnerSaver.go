@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// OwnerSaver is an autogenerated mock type for the OwnerSaver type
+type OwnerSaver struct {
+	mock.Mock
+}
+
+// SaveOwner provides a mock function with given fields: ctx, owner
+func (_m *OwnerSaver) SaveOwner(ctx context.Context, owner postgres.Owner) (int, error) {
+	ret := _m.Called(ctx, owner)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveOwner")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Owner) (int, error)); ok {
+		return rf(ctx, owner)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Owner) int); ok {
+		r0 = rf(ctx, owner)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.Owner) error); ok {
+		r1 = rf(ctx, owner)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOwnerSaver creates a new instance of OwnerSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOwnerSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OwnerSaver {
+	mock := &OwnerSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -1,9 +1,13 @@
 package save
 
 import (
+	"context"
 	"effective_mobile_test/internal/lib/api/response"
 	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage"
 	"effective_mobile_test/internal/storage/postgres"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
@@ -21,19 +25,27 @@ type Response struct {
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerSaver
 type OwnerSaver interface {
-	SaveOwner(owner postgres.Owner) (int, error)
+	SaveOwner(ctx context.Context, owner postgres.Owner) (int, error)
 }
 
 // @Summary		Save a new owner
-// @Description	Save a new owner by name, surname, patronymic
+// @Description	Save a new owner by name, surname and optional patronymic, birth date, phone, email and document number.
+// @Description	A request with an Idempotency-Key header can be retried safely, see /car/save.
 // @Tags			Owner
 // @Accept			json
 // @Produce		json
-// @Param			name		body		string	true	"Name"
-// @Param			surname		body		string	true	"Surname"
-// @Param			patronymic	body		string	true	"Patronymic"
+// @Param			name			body		string	true	"Name"
+// @Param			surname			body		string	true	"Surname"
+// @Param			patronymic		body		string	false	"Patronymic"
+// @Param			birthDate		body		string	false	"BirthDate (YYYY-MM-DD)"
+// @Param			phone			body		string	false	"Phone (E.164)"
+// @Param			email			body		string	false	"Email"
+// @Param			documentNumber	body		string	false	"DocumentNumber"
+// @Param			Idempotency-Key	header		string	false	"Idempotency key"
 // @Success		200			{object}	Response
 // @Failure		400			{object}	response.Response
+// @Failure		409			{object}	response.Response
+// @Failure		422			{object}	response.Response
 // @Router			/owner/save [post]
 func New(log *slog.Logger, ownerSaver OwnerSaver) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -42,6 +54,7 @@ func New(log *slog.Logger, ownerSaver OwnerSaver) http.HandlerFunc {
 		log = log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
 		)
 
 		var req Request
@@ -65,7 +78,15 @@ func New(log *slog.Logger, ownerSaver OwnerSaver) http.HandlerFunc {
 			return
 		}
 
-		ownerId, err := ownerSaver.SaveOwner(req.Owner)
+		ownerId, err := ownerSaver.SaveOwner(r.Context(), req.Owner)
+		if errors.Is(err, storage.ErrOwnerExists) {
+			log.Info("owner already exists", slog.Any("owner", req.Owner))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("owner already exists"))
+
+			return
+		}
 		if err != nil {
 			log.Error("failed to save owner", sl.Err(err))
 
@@ -82,14 +103,5 @@ func New(log *slog.Logger, ownerSaver OwnerSaver) http.HandlerFunc {
 }
 
 func validateRequest(req Request) (bool, slog.Attr, string) {
-	if req.Name == "" {
-		return false, slog.String("field", "name"), "field name is not valid"
-	}
-	if req.Surname == "" {
-		return false, slog.String("field", "surname"), "field surname is not valid"
-	}
-	if req.Patronymic == "" {
-		return false, slog.String("field", "patronymic"), "field patronymic is not valid"
-	}
-	return true, slog.Attr{}, ""
+	return validate.Owner(req.Owner, "")
 }
@@ -0,0 +1,113 @@
+package save_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/owner/save"
+	"effective_mobile_test/internal/http-server/handlers/owner/save/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSaveHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		body       string
+		callSave   bool
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "without patronymic",
+			body:       `{"name": "Ivan", "surname": "Ivanov"}`,
+			callSave:   true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "with every field",
+			body: `{"name": "Ivan", "surname": "Ivanov", "patronymic": "Ivanovich", "birthDate": "1990-05-17",
+					"phone": "+79991234567", "email": "ivan@example.com", "documentNumber": "4510 123456"}`,
+			callSave:   true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "owner exists",
+			body:       `{"name": "Ivan", "surname": "Ivanov"}`,
+			callSave:   true,
+			mockErr:    storage.ErrOwnerExists,
+			wantStatus: http.StatusConflict,
+			wantError:  "owner already exists",
+		},
+		{
+			name:       "blank surname",
+			body:       `{"name": "Ivan", "surname": "  "}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field surname is not valid",
+		},
+		{
+			name:       "phone is not in E.164",
+			body:       `{"name": "Ivan", "surname": "Ivanov", "phone": "8 999 123-45-67"}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field phone is not valid",
+		},
+		{
+			name:       "birth date in the future",
+			body:       `{"name": "Ivan", "surname": "Ivanov", "birthDate": "2999-01-01"}`,
+			wantStatus: http.StatusOK,
+			wantError:  "field birthDate is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ownerSaver := mocks.NewOwnerSaver(t)
+			if tc.callSave {
+				ownerSaver.On("SaveOwner", mock.Anything, mock.AnythingOfType("postgres.Owner")).
+					Return(1, tc.mockErr).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerSaver)
+
+			req := httptest.NewRequest(http.MethodPost, "/owner/save", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError == "" {
+				assert.Equal(t, 1, resp.OwnerId)
+			}
+		})
+	}
+}
+
+func TestSaveHandlerPassesOptionalFields(t *testing.T) {
+	ownerSaver := mocks.NewOwnerSaver(t)
+	ownerSaver.On("SaveOwner", mock.Anything, mock.MatchedBy(func(owner postgres.Owner) bool {
+		return owner.Patronymic == nil && owner.Email != nil && *owner.Email == "ivan@example.com"
+	})).Return(1, nil).Once()
+
+	handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), ownerSaver)
+
+	req := httptest.NewRequest(http.MethodPost, "/owner/save",
+		strings.NewReader(`{"name": "Ivan", "surname": "Ivanov", "email": "ivan@example.com"}`))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
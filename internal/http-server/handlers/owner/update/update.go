@@ -1,43 +1,66 @@
 package update
 
 import (
+	"context"
+	"effective_mobile_test/internal/lib/api/etag"
 	"effective_mobile_test/internal/lib/api/response"
 	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/render"
 	"log/slog"
 	"net/http"
 )
 
+// Request holds the fields to change. An empty optional field clears its value.
 type Request struct {
-	OwnerId    int     `json:"ownerId"`
-	Name       *string `json:"name"`
-	Surname    *string `json:"surname"`
-	Patronymic *string `json:"patronymic"`
+	OwnerId        int     `json:"ownerId"`
+	Name           *string `json:"name"`
+	Surname        *string `json:"surname"`
+	Patronymic     *string `json:"patronymic"`
+	BirthDate      *string `json:"birthDate"`
+	Phone          *string `json:"phone"`
+	Email          *string `json:"email"`
+	DocumentNumber *string `json:"documentNumber"`
+	// Version is an alternative to the If-Match header
+	Version *int `json:"version,omitempty"`
 }
 
 type Response struct {
 	response.Response
+	Version int `json:"version"`
 }
 
 //go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OwnerUpdater
 type OwnerUpdater interface {
-	UpdateOwnerName(ownerID int, newName string) error
-	UpdateOwnerSurname(ownerID int, newSurname string) error
-	UpdateOwnerPatronymic(ownerID int, newPatronymic string) error
+	PatchOwner(ctx context.Context, ownerID int, patch postgres.OwnerPatch, versions []int) (int, error)
 }
 
 // @Summary		Update owner
-// @Description	Update owner by ownerId and new data
+// @Description	Update owner by ownerId and new data in one change. The current version can be required
+// @Description	with the If-Match header or the version field, a stale one is refused with 412.
 // @Tags			Owner
 // @Accept			json
 // @Produce		json
-// @Param			ownerId		body		int		true	"OwnerId"
-// @Param			name		body		string	false	"Name"
-// @Param			surname		body		string	false	"Surname"
-// @Param			patronymic	body		string	false	"Patronymic"
+// @Param			ownerId			body		int		true	"OwnerId"
+// @Param			name			body		string	false	"Name"
+// @Param			surname			body		string	false	"Surname"
+// @Param			patronymic		body		string	false	"Patronymic"
+// @Param			birthDate		body		string	false	"BirthDate (YYYY-MM-DD)"
+// @Param			phone			body		string	false	"Phone (E.164)"
+// @Param			email			body		string	false	"Email"
+// @Param			documentNumber	body		string	false	"DocumentNumber"
+// @Param			version			body		int		false	"Version"
+// @Param			If-Match		header		string	false	"ETag of the owner"
 // @Success		200			{object}	Response
+// @Header			200			{string}	ETag	"ETag of the updated owner"
 // @Failure		400			{object}	response.Response
+// @Failure		404			{object}	response.Response
+// @Failure		409			{object}	response.Response
+// @Failure		412			{object}	response.Response
 // @Router			/owner/update [put]
 func New(log *slog.Logger, ownerUpdater OwnerUpdater) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -46,6 +69,7 @@ func New(log *slog.Logger, ownerUpdater OwnerUpdater) http.HandlerFunc {
 		log = log.With(
 			slog.String("op", op),
 			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
 		)
 
 		var req Request
@@ -69,41 +93,69 @@ func New(log *slog.Logger, ownerUpdater OwnerUpdater) http.HandlerFunc {
 			return
 		}
 
-		if req.Name != nil {
-			err = ownerUpdater.UpdateOwnerName(req.OwnerId, *req.Name)
-			if err != nil {
-				log.Error("failed to update owner name", sl.Err(err))
+		versions, err := etag.Expected(r, req.Version)
+		if errors.Is(err, etag.ErrPreconditionFailed) {
+			log.Info("owner version precondition failed", slog.Int("owner_id", req.OwnerId))
 
-				render.JSON(w, r, response.Error("failed to update owner name"))
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("owner was changed by someone else"))
 
-				return
-			}
+			return
+		}
+		if err != nil {
+			log.Error("invalid request", slog.String("field", "If-Match"), sl.Err(err))
+
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, response.Error("header If-Match is not valid"))
+
+			return
 		}
 
-		if req.Surname != nil {
-			err = ownerUpdater.UpdateOwnerSurname(req.OwnerId, *req.Surname)
-			if err != nil {
-				log.Error("failed to update owner surname", sl.Err(err))
+		newVersion, err := ownerUpdater.PatchOwner(r.Context(), req.OwnerId, postgres.OwnerPatch{
+			Name:           req.Name,
+			Surname:        req.Surname,
+			Patronymic:     req.Patronymic,
+			BirthDate:      req.BirthDate,
+			Phone:          req.Phone,
+			Email:          req.Email,
+			DocumentNumber: req.DocumentNumber,
+		}, versions)
+		if errors.Is(err, storage.ErrOwnerNotFound) {
+			log.Info("owner not found", slog.Int("owner_id", req.OwnerId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("owner not found"))
 
-				render.JSON(w, r, response.Error("failed to update owner surname"))
+			return
+		}
+		if errors.Is(err, storage.ErrVersionMismatch) {
+			log.Info("owner version mismatch", slog.Int("owner_id", req.OwnerId))
 
-				return
-			}
+			render.Status(r, http.StatusPreconditionFailed)
+			render.JSON(w, r, response.Error("owner was changed by someone else"))
+
+			return
 		}
+		if errors.Is(err, storage.ErrOwnerExists) {
+			log.Info("owner with the same full name already exists", slog.Int("owner_id", req.OwnerId))
 
-		if req.Patronymic != nil {
-			err = ownerUpdater.UpdateOwnerPatronymic(req.OwnerId, *req.Patronymic)
-			if err != nil {
-				log.Error("failed to update owner patronymic", sl.Err(err))
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("owner with the same full name already exists"))
 
-				render.JSON(w, r, response.Error("failed to update owner patronymic"))
+			return
+		}
+		if err != nil {
+			log.Error("failed to update owner", sl.Err(err))
 
-				return
-			}
+			render.JSON(w, r, response.Error("failed to update owner"))
+
+			return
 		}
 
+		etag.Set(w, newVersion)
 		render.JSON(w, r, Response{
 			response.OK(),
+			newVersion,
 		})
 	}
 }
@@ -112,14 +164,29 @@ func validateRequest(req Request) (bool, slog.Attr, string) {
 	if req.OwnerId < 1 {
 		return false, slog.String("field", "owner_id"), "field owner_id is not valid"
 	}
-	if req.Name != nil && len(*req.Name) < 1 {
+	if req.Name != nil && !validate.Name(*req.Name) {
 		return false, slog.String("field", "name"), "field name is not valid"
 	}
-	if req.Surname != nil && len(*req.Surname) < 1 {
+	if req.Surname != nil && !validate.Name(*req.Surname) {
 		return false, slog.String("field", "surname"), "field surname is not valid"
 	}
-	if req.Patronymic != nil && len(*req.Patronymic) < 1 {
+	if req.Patronymic != nil && !validate.Patronymic(*req.Patronymic) {
 		return false, slog.String("field", "patronymic"), "field patronymic is not valid"
 	}
+	if !validate.Optional(req.BirthDate, validate.BirthDate) {
+		return false, slog.String("field", "birthDate"), "field birthDate is not valid"
+	}
+	if !validate.Optional(req.Phone, validate.Phone) {
+		return false, slog.String("field", "phone"), "field phone is not valid"
+	}
+	if !validate.Optional(req.Email, validate.Email) {
+		return false, slog.String("field", "email"), "field email is not valid"
+	}
+	if !validate.Optional(req.DocumentNumber, validate.DocumentNumber) {
+		return false, slog.String("field", "documentNumber"), "field documentNumber is not valid"
+	}
+	if req.Version != nil && *req.Version < 1 {
+		return false, slog.String("field", "version"), "field version is not valid"
+	}
 	return true, slog.Attr{}, ""
 }
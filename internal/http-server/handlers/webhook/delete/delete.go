@@ -0,0 +1,77 @@
+package delete
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Response struct {
+	response.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=WebhookDeleter
+type WebhookDeleter interface {
+	DeleteWebhook(ctx context.Context, webhookID int) error
+}
+
+//	@Summary		Delete webhook
+//	@Description	Unsubscribe a webhook; its pending deliveries are dropped together with the delivery log
+//	@Tags			Webhook
+//	@Produce		json
+//	@Param			id	path		int	true	"WebhookId"
+//	@Success		200	{object}	Response
+//	@Failure		400	{object}	response.Response
+//	@Failure		404	{object}	response.Response
+//	@Router			/webhooks/{id} [delete]
+func New(log *slog.Logger, webhookDeleter WebhookDeleter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.delete.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		webhookId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || webhookId < 1 {
+			log.Error("invalid request", slog.String("field", "id"))
+
+			render.JSON(w, r, response.Error("field id is not valid"))
+
+			return
+		}
+
+		err = webhookDeleter.DeleteWebhook(r.Context(), webhookId)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Info("webhook not found", slog.Int("webhook_id", webhookId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("webhook not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to delete webhook", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to delete webhook"))
+
+			return
+		}
+
+		log.Info("webhook deleted", slog.Int("webhook_id", webhookId))
+
+		render.JSON(w, r, Response{
+			response.OK(),
+		})
+	}
+}
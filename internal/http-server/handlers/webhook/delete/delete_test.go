@@ -0,0 +1,80 @@
+package delete_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/webhook/delete"
+	"effective_mobile_test/internal/http-server/handlers/webhook/delete/mocks"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		id         string
+		callDelete bool
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "deleted",
+			id:         "3",
+			callDelete: true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not found",
+			id:         "3",
+			callDelete: true,
+			mockErr:    storage.ErrWebhookNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "webhook not found",
+		},
+		{
+			name:       "storage failure",
+			id:         "3",
+			callDelete: true,
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to delete webhook",
+		},
+		{
+			name:       "invalid id",
+			id:         "0",
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			webhookDeleter := mocks.NewWebhookDeleter(t)
+			if tc.callDelete {
+				webhookDeleter.On("DeleteWebhook", mock.Anything, 3).Return(tc.mockErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Delete("/webhooks/{id}", delete.New(slog.New(slog.NewTextHandler(io.Discard, nil)), webhookDeleter))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodDelete, "/webhooks/"+tc.id, nil))
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp delete.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+		})
+	}
+}
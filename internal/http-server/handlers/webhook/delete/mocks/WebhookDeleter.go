@@ -0,0 +1,46 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// WebhookDeleter is an autogenerated mock type for the WebhookDeleter type
+type WebhookDeleter struct {
+	mock.Mock
+}
+
+// DeleteWebhook provides a mock function with given fields: ctx, webhookID
+func (_m *WebhookDeleter) DeleteWebhook(ctx context.Context, webhookID int) error {
+	ret := _m.Called(ctx, webhookID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for DeleteWebhook")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) error); ok {
+		r0 = rf(ctx, webhookID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewWebhookDeleter creates a new instance of WebhookDeleter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhookDeleter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhookDeleter {
+	mock := &WebhookDeleter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,146 @@
+package deliveries
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+const (
+	defaultPageNum  = 1
+	defaultPageSize = 50
+	maxPageSize     = 1000
+)
+
+type Request struct {
+	postgres.DeliverySearchRequest
+}
+
+type Response struct {
+	response.Response
+	Deliveries []postgres.WebhookDelivery `json:"deliveries"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=DeliveryGetter
+type DeliveryGetter interface {
+	GetWebhookDeliveries(ctx context.Context, searchRequest postgres.DeliverySearchRequest) ([]postgres.WebhookDelivery, error)
+}
+
+//	@Summary		List webhook deliveries
+//	@Description	List the deliveries of a webhook, newest first, with the outcome of their last attempt
+//	@Tags			Webhook
+//	@Produce		json
+//	@Param			id			path		int		true	"WebhookId"
+//	@Param			status		query		string	false	"Status"	Enums(pending, delivered, dead)
+//	@Param			pageNum		query		int		false	"PageNum"
+//	@Param			pageSize	query		int		false	"PageSize"
+//	@Success		200			{object}	Response
+//	@Failure		400			{object}	response.Response
+//	@Failure		404			{object}	response.Response
+//	@Router			/webhooks/{id}/deliveries [get]
+func New(log *slog.Logger, deliveryGetter DeliveryGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.deliveries.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		req, err := parseRequest(r)
+		if err != nil {
+			log.Error("failed to parse request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to parse request"))
+
+			return
+		}
+
+		log.Info("request parsed", slog.Any("request", req))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		deliveries, err := deliveryGetter.GetWebhookDeliveries(r.Context(), req.DeliverySearchRequest)
+		if errors.Is(err, storage.ErrWebhookNotFound) {
+			log.Info("webhook not found", slog.Int("webhook_id", req.WebhookID))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("webhook not found"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to get webhook deliveries", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get webhook deliveries"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			deliveries,
+		})
+	}
+}
+
+func parseRequest(r *http.Request) (Request, error) {
+	query := r.URL.Query()
+
+	req := Request{postgres.DeliverySearchRequest{
+		Status:   query.Get("status"),
+		PageNum:  defaultPageNum,
+		PageSize: defaultPageSize,
+	}}
+
+	var err error
+	if req.WebhookID, err = strconv.Atoi(chi.URLParam(r, "id")); err != nil {
+		return Request{}, err
+	}
+	if pageNum := query.Get("pageNum"); pageNum != "" {
+		if req.PageNum, err = strconv.Atoi(pageNum); err != nil {
+			return Request{}, err
+		}
+	}
+	if pageSize := query.Get("pageSize"); pageSize != "" {
+		if req.PageSize, err = strconv.Atoi(pageSize); err != nil {
+			return Request{}, err
+		}
+	}
+
+	return req, nil
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if req.WebhookID < 1 {
+		return false, slog.String("field", "id"), "field id is not valid"
+	}
+	switch req.Status {
+	case "", postgres.DeliveryPending, postgres.DeliveryDelivered, postgres.DeliveryDead:
+	default:
+		return false, slog.String("field", "status"), "field status is not valid"
+	}
+	if req.PageSize < 1 || req.PageSize > maxPageSize {
+		return false, slog.String("field", "pageSize"), "field pageSize is not valid"
+	}
+	if req.PageNum < 1 {
+		return false, slog.String("field", "pageNum"), "field pageNum is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
@@ -0,0 +1,97 @@
+package deliveries_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/webhook/deliveries"
+	"effective_mobile_test/internal/http-server/handlers/webhook/deliveries/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeliveriesHandler(t *testing.T) {
+	found := []postgres.WebhookDelivery{{ID: 11, WebhookID: 3, EventID: 42, EventType: "car.updated", Status: postgres.DeliveryDead}}
+
+	cases := []struct {
+		name string
+		path string
+		// request is the search expected to be run, nil if none must be
+		request        *postgres.DeliverySearchRequest
+		mockErr        error
+		wantStatus     int
+		wantError      string
+		wantDeliveries []postgres.WebhookDelivery
+	}{
+		{
+			name:           "first page by default",
+			path:           "/webhooks/3/deliveries",
+			request:        &postgres.DeliverySearchRequest{WebhookID: 3, PageNum: 1, PageSize: 50},
+			wantStatus:     http.StatusOK,
+			wantDeliveries: found,
+		},
+		{
+			name:           "dead deliveries",
+			path:           "/webhooks/3/deliveries?status=dead&pageNum=2&pageSize=10",
+			request:        &postgres.DeliverySearchRequest{WebhookID: 3, Status: postgres.DeliveryDead, PageNum: 2, PageSize: 10},
+			wantStatus:     http.StatusOK,
+			wantDeliveries: found,
+		},
+		{
+			name:       "webhook not found",
+			path:       "/webhooks/3/deliveries",
+			request:    &postgres.DeliverySearchRequest{WebhookID: 3, PageNum: 1, PageSize: 50},
+			mockErr:    storage.ErrWebhookNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "webhook not found",
+		},
+		{
+			name:       "unknown status",
+			path:       "/webhooks/3/deliveries?status=lost",
+			wantStatus: http.StatusOK,
+			wantError:  "field status is not valid",
+		},
+		{
+			name:       "page too large",
+			path:       "/webhooks/3/deliveries?pageSize=1001",
+			wantStatus: http.StatusOK,
+			wantError:  "field pageSize is not valid",
+		},
+		{
+			name:       "invalid id",
+			path:       "/webhooks/three/deliveries",
+			wantStatus: http.StatusOK,
+			wantError:  "failed to parse request",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			deliveryGetter := mocks.NewDeliveryGetter(t)
+			if tc.request != nil {
+				deliveryGetter.On("GetWebhookDeliveries", mock.Anything, *tc.request).Return(found, tc.mockErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Get("/webhooks/{id}/deliveries", deliveries.New(slog.New(slog.NewTextHandler(io.Discard, nil)), deliveryGetter))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp deliveries.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			assert.Equal(t, tc.wantDeliveries, resp.Deliveries)
+		})
+	}
+}
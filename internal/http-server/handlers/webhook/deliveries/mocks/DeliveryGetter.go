@@ -0,0 +1,60 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// DeliveryGetter is an autogenerated mock type for the DeliveryGetter type
+type DeliveryGetter struct {
+	mock.Mock
+}
+
+// GetWebhookDeliveries provides a mock function with given fields: ctx, searchRequest
+func (_m *DeliveryGetter) GetWebhookDeliveries(ctx context.Context, searchRequest postgres.DeliverySearchRequest) ([]postgres.WebhookDelivery, error) {
+	ret := _m.Called(ctx, searchRequest)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetWebhookDeliveries")
+	}
+
+	var r0 []postgres.WebhookDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.DeliverySearchRequest) ([]postgres.WebhookDelivery, error)); ok {
+		return rf(ctx, searchRequest)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.DeliverySearchRequest) []postgres.WebhookDelivery); ok {
+		r0 = rf(ctx, searchRequest)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.WebhookDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.DeliverySearchRequest) error); ok {
+		r1 = rf(ctx, searchRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewDeliveryGetter creates a new instance of DeliveryGetter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDeliveryGetter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DeliveryGetter {
+	mock := &DeliveryGetter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
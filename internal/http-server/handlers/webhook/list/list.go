@@ -0,0 +1,54 @@
+package list
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage/postgres"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+type Response struct {
+	response.Response
+	Webhooks []postgres.Webhook `json:"webhooks"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=WebhookGetter
+type WebhookGetter interface {
+	GetWebhooks(ctx context.Context) ([]postgres.Webhook, error)
+}
+
+//	@Summary		List webhooks
+//	@Description	List the webhook subscriptions without their secrets
+//	@Tags			Webhook
+//	@Produce		json
+//	@Success		200	{object}	Response
+//	@Router			/webhooks [get]
+func New(log *slog.Logger, webhookGetter WebhookGetter) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.list.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		webhooks, err := webhookGetter.GetWebhooks(r.Context())
+		if err != nil {
+			log.Error("failed to get webhooks", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to get webhooks"))
+
+			return
+		}
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			webhooks,
+		})
+	}
+}
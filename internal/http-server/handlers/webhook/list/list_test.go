@@ -0,0 +1,60 @@
+package list_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/webhook/list"
+	"effective_mobile_test/internal/http-server/handlers/webhook/list/mocks"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListHandler(t *testing.T) {
+	webhooks := []postgres.Webhook{{ID: 3, URL: "https://partner.example.com/hook", EventTypes: []string{"car.*"}}}
+
+	cases := []struct {
+		name         string
+		webhooks     []postgres.Webhook
+		mockErr      error
+		wantError    string
+		wantWebhooks []postgres.Webhook
+	}{
+		{
+			name:         "webhooks",
+			webhooks:     webhooks,
+			wantWebhooks: webhooks,
+		},
+		{
+			name:      "storage failure",
+			mockErr:   errors.New("unexpected error"),
+			wantError: "failed to get webhooks",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			webhookGetter := mocks.NewWebhookGetter(t)
+			webhookGetter.On("GetWebhooks", mock.Anything).Return(tc.webhooks, tc.mockErr).Once()
+
+			handler := list.New(slog.New(slog.NewTextHandler(io.Discard, nil)), webhookGetter)
+
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/webhooks", nil))
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp list.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			assert.Equal(t, tc.wantWebhooks, resp.Webhooks)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// DeliveryRetrier is an autogenerated mock type for the DeliveryRetrier type
+type DeliveryRetrier struct {
+	mock.Mock
+}
+
+// RetryWebhookDelivery provides a mock function with given fields: ctx, webhookID, deliveryID
+func (_m *DeliveryRetrier) RetryWebhookDelivery(ctx context.Context, webhookID int, deliveryID int64) error {
+	ret := _m.Called(ctx, webhookID, deliveryID)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RetryWebhookDelivery")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, int64) error); ok {
+		r0 = rf(ctx, webhookID, deliveryID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewDeliveryRetrier creates a new instance of DeliveryRetrier. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDeliveryRetrier(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DeliveryRetrier {
+	mock := &DeliveryRetrier{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
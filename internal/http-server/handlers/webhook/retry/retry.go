@@ -0,0 +1,96 @@
+package retry
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+type Response struct {
+	response.Response
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=DeliveryRetrier
+type DeliveryRetrier interface {
+	RetryWebhookDelivery(ctx context.Context, webhookID int, deliveryID int64) error
+}
+
+//	@Summary		Retry webhook delivery
+//	@Description	Send a dead delivery again, with a fresh number of attempts
+//	@Tags			Webhook
+//	@Produce		json
+//	@Param			id			path		int	true	"WebhookId"
+//	@Param			deliveryId	path		int	true	"DeliveryId"
+//	@Success		200			{object}	Response
+//	@Failure		400			{object}	response.Response
+//	@Failure		404			{object}	response.Response
+//	@Failure		409			{object}	response.Response
+//	@Router			/webhooks/{id}/deliveries/{deliveryId}/retry [post]
+func New(log *slog.Logger, deliveryRetrier DeliveryRetrier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.retry.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		webhookId, err := strconv.Atoi(chi.URLParam(r, "id"))
+		if err != nil || webhookId < 1 {
+			log.Error("invalid request", slog.String("field", "id"))
+
+			render.JSON(w, r, response.Error("field id is not valid"))
+
+			return
+		}
+
+		deliveryId, err := strconv.ParseInt(chi.URLParam(r, "deliveryId"), 10, 64)
+		if err != nil || deliveryId < 1 {
+			log.Error("invalid request", slog.String("field", "deliveryId"))
+
+			render.JSON(w, r, response.Error("field deliveryId is not valid"))
+
+			return
+		}
+
+		err = deliveryRetrier.RetryWebhookDelivery(r.Context(), webhookId, deliveryId)
+		if errors.Is(err, storage.ErrDeliveryNotFound) {
+			log.Info("webhook delivery not found", slog.Int64("delivery_id", deliveryId))
+
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, response.Error("webhook delivery not found"))
+
+			return
+		}
+		if errors.Is(err, storage.ErrDeliveryNotDead) {
+			log.Info("webhook delivery is not dead", slog.Int64("delivery_id", deliveryId))
+
+			render.Status(r, http.StatusConflict)
+			render.JSON(w, r, response.Error("webhook delivery is not dead"))
+
+			return
+		}
+		if err != nil {
+			log.Error("failed to retry webhook delivery", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to retry webhook delivery"))
+
+			return
+		}
+
+		log.Info("webhook delivery retried", slog.Int64("delivery_id", deliveryId))
+
+		render.JSON(w, r, Response{
+			response.OK(),
+		})
+	}
+}
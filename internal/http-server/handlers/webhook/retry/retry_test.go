@@ -0,0 +1,95 @@
+package retry_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/webhook/retry"
+	"effective_mobile_test/internal/http-server/handlers/webhook/retry/mocks"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRetryHandler(t *testing.T) {
+	cases := []struct {
+		name       string
+		path       string
+		callRetry  bool
+		mockErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "retried",
+			path:       "/webhooks/3/deliveries/11/retry",
+			callRetry:  true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "delivery of another webhook",
+			path:       "/webhooks/3/deliveries/11/retry",
+			callRetry:  true,
+			mockErr:    storage.ErrDeliveryNotFound,
+			wantStatus: http.StatusNotFound,
+			wantError:  "webhook delivery not found",
+		},
+		{
+			name:       "delivery is not dead",
+			path:       "/webhooks/3/deliveries/11/retry",
+			callRetry:  true,
+			mockErr:    storage.ErrDeliveryNotDead,
+			wantStatus: http.StatusConflict,
+			wantError:  "webhook delivery is not dead",
+		},
+		{
+			name:       "storage failure",
+			path:       "/webhooks/3/deliveries/11/retry",
+			callRetry:  true,
+			mockErr:    errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantError:  "failed to retry webhook delivery",
+		},
+		{
+			name:       "invalid webhook id",
+			path:       "/webhooks/x/deliveries/11/retry",
+			wantStatus: http.StatusOK,
+			wantError:  "field id is not valid",
+		},
+		{
+			name:       "invalid delivery id",
+			path:       "/webhooks/3/deliveries/-11/retry",
+			wantStatus: http.StatusOK,
+			wantError:  "field deliveryId is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			deliveryRetrier := mocks.NewDeliveryRetrier(t)
+			if tc.callRetry {
+				deliveryRetrier.On("RetryWebhookDelivery", mock.Anything, 3, int64(11)).Return(tc.mockErr).Once()
+			}
+
+			router := chi.NewRouter()
+			router.Post("/webhooks/{id}/deliveries/{deliveryId}/retry",
+				retry.New(slog.New(slog.NewTextHandler(io.Discard, nil)), deliveryRetrier))
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, tc.path, nil))
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+
+			var resp retry.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+		})
+	}
+}
@@ -0,0 +1,57 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	mock "github.com/stretchr/testify/mock"
+)
+
+// WebhookSaver is an autogenerated mock type for the WebhookSaver type
+type WebhookSaver struct {
+	mock.Mock
+}
+
+// SaveWebhook provides a mock function with given fields: ctx, webhook
+func (_m *WebhookSaver) SaveWebhook(ctx context.Context, webhook postgres.Webhook) (int, error) {
+	ret := _m.Called(ctx, webhook)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveWebhook")
+	}
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Webhook) (int, error)); ok {
+		return rf(ctx, webhook)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.Webhook) int); ok {
+		r0 = rf(ctx, webhook)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.Webhook) error); ok {
+		r1 = rf(ctx, webhook)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewWebhookSaver creates a new instance of WebhookSaver. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewWebhookSaver(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *WebhookSaver {
+	mock := &WebhookSaver{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
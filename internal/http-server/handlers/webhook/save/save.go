@@ -0,0 +1,159 @@
+package save
+
+import (
+	"context"
+	"crypto/rand"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/hex"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+)
+
+const (
+	minSecretLen = 16
+	// secretSize is the number of random bytes of a generated secret.
+	secretSize = 32
+)
+
+type Request struct {
+	URL string `json:"url"`
+	// EventTypes are types like car.updated or patterns like owner.*; empty subscribes to every event
+	EventTypes []string `json:"eventTypes,omitempty"`
+	// Secret signs the payloads; a random one is generated if it is empty
+	Secret string  `json:"secret,omitempty"`
+	RegNum *string `json:"regNum,omitempty"`
+	Mark   *string `json:"mark,omitempty"`
+}
+
+type Response struct {
+	response.Response
+	WebhookId int    `json:"webhookId"`
+	Secret    string `json:"secret"`
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=WebhookSaver
+type WebhookSaver interface {
+	SaveWebhook(ctx context.Context, webhook postgres.Webhook) (int, error)
+}
+
+//	@Summary		Subscribe a webhook
+//	@Description	Subscribe a URL to catalog events, optionally only to events of the given types
+//	@Description	(e.g. car.updated, owner.*) and of cars with the given regNum or mark.
+//	@Description	Every event is POSTed as JSON with the headers X-Webhook-Id, X-Webhook-Delivery, X-Webhook-Event,
+//	@Description	X-Webhook-Timestamp (Unix seconds) and X-Webhook-Signature: "sha256=" and the hex-encoded
+//	@Description	HMAC-SHA256 of "<timestamp>.<body>" keyed with the secret. Any 2xx response acknowledges it;
+//	@Description	failed deliveries are retried with exponential backoff and end up dead after 10 attempts.
+//	@Description	Redirects are not followed, and URLs of the loopback, private or link-local networks are refused.
+//	@Description	The secret is returned only in this response.
+//	@Tags			Webhook
+//	@Accept			json
+//	@Produce		json
+//	@Param			url			body		string		true	"URL"
+//	@Param			eventTypes	body		[]string	false	"EventTypes"
+//	@Param			secret		body		string		false	"Secret"
+//	@Param			regNum		body		string		false	"RegNum"
+//	@Param			mark		body		string		false	"Mark"
+//	@Success		200			{object}	Response
+//	@Failure		400			{object}	response.Response
+//	@Router			/webhooks [post]
+func New(log *slog.Logger, webhookSaver WebhookSaver) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		const op = "handlers.webhook.save.New"
+
+		log = log.With(
+			slog.String("op", op),
+			slog.String("request_id", middleware.GetReqID(r.Context())),
+			sl.TraceID(r.Context()),
+		)
+
+		var req Request
+
+		err := render.DecodeJSON(r.Body, &req)
+		if err != nil {
+			log.Error("failed to decode request", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to decode request"))
+
+			return
+		}
+
+		// the secret must not end up in the logs
+		log.Info("request body decoded", slog.String("url", req.URL), slog.Any("event_types", req.EventTypes))
+
+		if ok, field, msg := validateRequest(req); !ok {
+			log.Error("invalid request", field)
+
+			render.JSON(w, r, response.Error(msg))
+
+			return
+		}
+
+		if req.Secret == "" {
+			if req.Secret, err = generateSecret(); err != nil {
+				log.Error("failed to generate secret", sl.Err(err))
+
+				render.JSON(w, r, response.Error("failed to save webhook"))
+
+				return
+			}
+		}
+
+		webhookId, err := webhookSaver.SaveWebhook(r.Context(), postgres.Webhook{
+			URL:        req.URL,
+			EventTypes: req.EventTypes,
+			Secret:     req.Secret,
+			RegNum:     req.RegNum,
+			Mark:       req.Mark,
+		})
+		if err != nil {
+			log.Error("failed to save webhook", sl.Err(err))
+
+			render.JSON(w, r, response.Error("failed to save webhook"))
+
+			return
+		}
+
+		log.Info("webhook saved", slog.Int("webhook_id", webhookId))
+
+		render.JSON(w, r, Response{
+			response.OK(),
+			webhookId,
+			req.Secret,
+		})
+	}
+}
+
+func generateSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+func validateRequest(req Request) (bool, slog.Attr, string) {
+	if !validate.WebhookURL(req.URL) {
+		return false, slog.String("field", "url"), "field url is not valid"
+	}
+	for _, t := range req.EventTypes {
+		if !validate.EventType(t) {
+			return false, slog.String("field", "eventTypes"), "field eventTypes is not valid"
+		}
+	}
+	if req.Secret != "" && len(req.Secret) < minSecretLen {
+		return false, slog.String("field", "secret"), "field secret is not valid"
+	}
+	if req.RegNum != nil && !validate.RegNum(*req.RegNum) {
+		return false, slog.String("field", "regNum"), "field regNum is not valid"
+	}
+	if req.Mark != nil && *req.Mark == "" {
+		return false, slog.String("field", "mark"), "field mark is not valid"
+	}
+	return true, slog.Attr{}, ""
+}
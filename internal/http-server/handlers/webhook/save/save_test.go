@@ -0,0 +1,118 @@
+package save_test
+
+import (
+	"effective_mobile_test/internal/http-server/handlers/webhook/save"
+	"effective_mobile_test/internal/http-server/handlers/webhook/save/mocks"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSaveHandler(t *testing.T) {
+	const secret = "0123456789abcdef"
+	mark := "Lada"
+
+	cases := []struct {
+		name string
+		body string
+		// webhook is the webhook expected to be saved, nil if none must be
+		webhook *postgres.Webhook
+		mockErr error
+		// wantSecret is the returned secret, empty if a generated one is expected
+		wantSecret string
+		wantError  string
+	}{
+		{
+			name: "subscribed with a secret",
+			body: `{"url":"https://partner.example.com/hook","eventTypes":["car.updated","owner.*"],"secret":"` + secret + `","mark":"Lada"}`,
+			webhook: &postgres.Webhook{URL: "https://partner.example.com/hook", EventTypes: []string{"car.updated", "owner.*"},
+				Secret: secret, Mark: &mark},
+			wantSecret: secret,
+		},
+		{
+			name:    "secret is generated",
+			body:    `{"url":"https://partner.example.com/hook"}`,
+			webhook: &postgres.Webhook{URL: "https://partner.example.com/hook"},
+		},
+		{
+			name:      "storage failure",
+			body:      `{"url":"https://partner.example.com/hook","secret":"` + secret + `"}`,
+			webhook:   &postgres.Webhook{URL: "https://partner.example.com/hook", Secret: secret},
+			mockErr:   errors.New("unexpected error"),
+			wantError: "failed to save webhook",
+		},
+		{
+			name:      "internal receiver",
+			body:      `{"url":"http://10.0.0.1/hook"}`,
+			wantError: "field url is not valid",
+		},
+		{
+			name:      "unknown event type",
+			body:      `{"url":"https://partner.example.com/hook","eventTypes":["car.sold"]}`,
+			wantError: "field eventTypes is not valid",
+		},
+		{
+			name:      "short secret",
+			body:      `{"url":"https://partner.example.com/hook","secret":"123"}`,
+			wantError: "field secret is not valid",
+		},
+		{
+			name:      "empty mark",
+			body:      `{"url":"https://partner.example.com/hook","mark":""}`,
+			wantError: "field mark is not valid",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			webhookSaver := mocks.NewWebhookSaver(t)
+			if tc.webhook != nil {
+				webhookSaver.On("SaveWebhook", mock.Anything, mock.MatchedBy(func(w postgres.Webhook) bool {
+					if tc.webhook.Secret == "" {
+						// a generated secret is 32 random bytes in hex
+						if len(w.Secret) != 64 {
+							return false
+						}
+						w.Secret = ""
+					}
+					return assert.ObjectsAreEqual(*tc.webhook, w)
+				})).Return(3, tc.mockErr).Once()
+			}
+
+			handler := save.New(slog.New(slog.NewTextHandler(io.Discard, nil)), webhookSaver)
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			var resp save.Response
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+			assert.Equal(t, tc.wantError, resp.Error)
+			if tc.wantError != "" {
+				assert.Empty(t, resp.Secret)
+
+				return
+			}
+
+			assert.Equal(t, 3, resp.WebhookId)
+			if tc.wantSecret != "" {
+				assert.Equal(t, tc.wantSecret, resp.Secret)
+			} else {
+				assert.Len(t, resp.Secret, 64)
+			}
+		})
+	}
+}
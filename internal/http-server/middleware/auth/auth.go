@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/actor"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// APIKeyHeader carries an API key, as an alternative to an Authorization: Bearer header.
+const APIKeyHeader = "X-API-Key"
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=APIKeyGetter
+type APIKeyGetter interface {
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (postgres.APIKey, error)
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=TokenVerifier
+type TokenVerifier interface {
+	Verify(token string) (auth.Principal, error)
+}
+
+// New rejects requests without a valid API key or bearer token with 401. The principal of an
+// authenticated request is attached to its context and its ID is recorded as the actor of its changes.
+func New(log *slog.Logger, keys APIKeyGetter, tokens TokenVerifier) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/auth"),
+		)
+
+		log.Info("auth middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				sl.TraceID(r.Context()),
+			)
+
+			credential := credentials(r)
+			if credential == "" {
+				log.Info("request is not authenticated")
+
+				unauthorized(w, r, "authentication required")
+
+				return
+			}
+
+			var principal auth.Principal
+			if auth.IsAPIKey(credential) {
+				key, err := keys.GetAPIKeyByHash(r.Context(), auth.HashAPIKey(credential))
+				if errors.Is(err, storage.ErrAPIKeyNotFound) {
+					log.Info("invalid api key")
+
+					unauthorized(w, r, "invalid api key")
+
+					return
+				}
+				if err != nil {
+					log.Error("failed to get api key", sl.Err(err))
+
+					render.JSON(w, r, response.Error("failed to authenticate"))
+
+					return
+				}
+
+				principal = auth.Principal{Name: key.Name, Method: auth.MethodAPIKey, Role: key.Role, MultiTenant: key.MultiTenant}
+				if key.Tenant != nil {
+					principal.Tenant = *key.Tenant
+				}
+			} else {
+				var err error
+				if principal, err = tokens.Verify(credential); err != nil {
+					log.Info("invalid token", sl.Err(err))
+
+					unauthorized(w, r, "invalid token")
+
+					return
+				}
+			}
+
+			log.Debug("request authenticated",
+				slog.String("principal", principal.Name),
+				slog.String("method", principal.Method),
+				slog.String("role", principal.Role),
+			)
+
+			ctx := auth.WithPrincipal(r.Context(), principal)
+			ctx = actor.WithActor(ctx, principal.ID())
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// credentials returns the API key or token of the request, or an empty string if there is none.
+func credentials(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); key != "" {
+		return key
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+
+	return strings.TrimSpace(token)
+}
+
+func unauthorized(w http.ResponseWriter, r *http.Request, msg string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="cars-catalog"`)
+
+	render.Status(r, http.StatusUnauthorized)
+	render.JSON(w, r, response.Error(msg))
+}
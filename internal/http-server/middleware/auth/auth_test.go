@@ -0,0 +1,193 @@
+package auth_test
+
+import (
+	"effective_mobile_test/internal/http-server/middleware/auth"
+	"effective_mobile_test/internal/http-server/middleware/auth/mocks"
+	"effective_mobile_test/internal/lib/actor"
+	libauth "effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuth(t *testing.T) {
+	const apiKey = libauth.APIKeyPrefix + "secret"
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		// lookup tells whether the API key is looked up, with keyErr as the result
+		lookup bool
+		keyErr error
+		// verify tells whether the token is verified, with verifyErr as the result
+		verify        bool
+		verifyErr     error
+		wantStatus    int
+		wantBody      string
+		wantPrincipal libauth.Principal
+	}{
+		{
+			name:          "api key header",
+			header:        auth.APIKeyHeader,
+			value:         apiKey,
+			lookup:        true,
+			wantStatus:    http.StatusOK,
+			wantPrincipal: libauth.Principal{Name: "ci", Method: libauth.MethodAPIKey, Role: libauth.RoleEditor},
+		},
+		{
+			name:          "api key as a bearer token",
+			header:        "Authorization",
+			value:         "Bearer " + apiKey,
+			lookup:        true,
+			wantStatus:    http.StatusOK,
+			wantPrincipal: libauth.Principal{Name: "ci", Method: libauth.MethodAPIKey, Role: libauth.RoleEditor},
+		},
+		{
+			name:          "jwt",
+			header:        "Authorization",
+			value:         "bearer header.payload.signature",
+			verify:        true,
+			wantStatus:    http.StatusOK,
+			wantPrincipal: libauth.Principal{Name: "alice", Method: libauth.MethodJWT, Role: libauth.RoleViewer},
+		},
+		{
+			name:       "without credentials",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "authentication required",
+		},
+		{
+			name:       "other scheme",
+			header:     "Authorization",
+			value:      "Basic YWxpY2U6c2VjcmV0",
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "authentication required",
+		},
+		{
+			name:       "unknown or revoked api key",
+			header:     auth.APIKeyHeader,
+			value:      apiKey,
+			lookup:     true,
+			keyErr:     fmt.Errorf("get: %w", storage.ErrAPIKeyNotFound),
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "invalid api key",
+		},
+		{
+			name:       "storage failure",
+			header:     auth.APIKeyHeader,
+			value:      apiKey,
+			lookup:     true,
+			keyErr:     errors.New("unexpected error"),
+			wantStatus: http.StatusOK,
+			wantBody:   "failed to authenticate",
+		},
+		{
+			name:       "invalid token",
+			header:     "Authorization",
+			value:      "Bearer header.payload.signature",
+			verify:     true,
+			verifyErr:  errors.New("token is expired"),
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   "invalid token",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys := mocks.NewAPIKeyGetter(t)
+			if tc.lookup {
+				keys.On("GetAPIKeyByHash", mock.Anything, libauth.HashAPIKey(apiKey)).
+					Return(postgres.APIKey{ID: 1, Name: "ci", Role: libauth.RoleEditor}, tc.keyErr).Once()
+			}
+			tokens := mocks.NewTokenVerifier(t)
+			if tc.verify {
+				tokens.On("Verify", "header.payload.signature").
+					Return(libauth.Principal{Name: "alice", Method: libauth.MethodJWT, Role: libauth.RoleViewer}, tc.verifyErr).Once()
+			}
+
+			var principal libauth.Principal
+			var gotActor string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var ok bool
+				principal, ok = libauth.FromContext(r.Context())
+				require.True(t, ok)
+				gotActor = actor.FromContext(r.Context())
+			})
+
+			handler := auth.New(slog.New(slog.NewTextHandler(io.Discard, nil)), keys, tokens)(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/car/search", nil)
+			if tc.header != "" {
+				req.Header.Set(tc.header, tc.value)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Contains(t, rr.Body.String(), tc.wantBody)
+			assert.Equal(t, tc.wantPrincipal, principal)
+			if tc.wantStatus == http.StatusUnauthorized {
+				assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"))
+			}
+			if tc.wantPrincipal.Name != "" {
+				// changes are attributed to the principal, whatever way it authenticated
+				assert.Equal(t, tc.wantPrincipal.ID(), gotActor)
+			}
+		})
+	}
+}
+
+func TestAuthAPIKeyTenant(t *testing.T) {
+	const apiKey = libauth.APIKeyPrefix + "secret"
+	acme := "acme"
+
+	cases := []struct {
+		name          string
+		key           postgres.APIKey
+		wantPrincipal libauth.Principal
+	}{
+		{
+			name:          "bound to a tenant",
+			key:           postgres.APIKey{ID: 1, Name: "ci", Role: libauth.RoleEditor, Tenant: &acme},
+			wantPrincipal: libauth.Principal{Name: "ci", Method: libauth.MethodAPIKey, Role: libauth.RoleEditor, Tenant: "acme"},
+		},
+		{
+			name:          "multi-tenant",
+			key:           postgres.APIKey{ID: 1, Name: "ci", Role: libauth.RoleViewer, MultiTenant: true},
+			wantPrincipal: libauth.Principal{Name: "ci", Method: libauth.MethodAPIKey, Role: libauth.RoleViewer, MultiTenant: true},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys := mocks.NewAPIKeyGetter(t)
+			keys.On("GetAPIKeyByHash", mock.Anything, libauth.HashAPIKey(apiKey)).Return(tc.key, nil).Once()
+
+			var principal libauth.Principal
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				principal, _ = libauth.FromContext(r.Context())
+			})
+
+			handler := auth.New(slog.New(slog.NewTextHandler(io.Discard, nil)), keys, mocks.NewTokenVerifier(t))(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/car/search", nil)
+			req.Header.Set(auth.APIKeyHeader, apiKey)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tc.wantPrincipal, principal)
+		})
+	}
+}
@@ -0,0 +1,266 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/go-chi/render"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// Header carries the client-chosen key of a POST request that may be retried.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses replayed from an earlier request with the same key.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxKeyLength = 255
+	// maxBodySize limits both the request bodies hashed and the response bodies kept for replay
+	maxBodySize = 1 << 20
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=KeyStore
+type KeyStore interface {
+	AcquireIdempotencyKey(ctx context.Context, key postgres.IdempotencyKey, ttl time.Duration) (*postgres.StoredResponse, error)
+	SaveIdempotentResponse(ctx context.Context, key postgres.IdempotencyKey, resp postgres.StoredResponse) error
+	ReleaseIdempotencyKey(ctx context.Context, key postgres.IdempotencyKey) error
+}
+
+// New makes POST requests carrying an Idempotency-Key header safe to retry: the first response
+// for a key is stored for ttl and replayed to retries with the same body. Only definite outcomes
+// are stored, see replayable; after any other failure a retry runs the request again.
+// Keys are scoped by caller, so callers picking the same key don't get each other's responses.
+// Reusing a key with a different body is refused with 422, a retry racing the first request with 409.
+//
+// Requests streaming a body other than JSON, such as CSV imports, are passed through as if they had no key.
+// A response larger than maxBodySize, such as a file export, is not kept: retries are refused with 409
+// instead of running the request again.
+func New(log *slog.Logger, store KeyStore, ttl time.Duration) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/idempotency"),
+		)
+
+		log.Info("idempotency middleware enabled", slog.String("ttl", ttl.String()))
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			keyValue := r.Header.Get(Header)
+			if r.Method != http.MethodPost || keyValue == "" {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				sl.TraceID(r.Context()),
+				slog.String("idempotency_key", keyValue),
+			)
+
+			if len(keyValue) > maxKeyLength {
+				log.Error("invalid request", slog.String("field", Header))
+
+				render.JSON(w, r, response.Error("header "+Header+" is not valid"))
+
+				return
+			}
+
+			if !isJSON(r) {
+				log.Info("idempotency key ignored for a streamed request body",
+					slog.String("content_type", r.Header.Get("Content-Type")))
+
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxBodySize+1))
+			if err != nil {
+				log.Error("failed to read request body", sl.Err(err))
+
+				render.JSON(w, r, response.Error("failed to read request body"))
+
+				return
+			}
+			if len(body) > maxBodySize {
+				log.Error("request body is too large", slog.Int("limit", maxBodySize))
+
+				render.Status(r, http.StatusRequestEntityTooLarge)
+				render.JSON(w, r, response.Error("request body is too large"))
+
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			var principal string
+			if p, ok := auth.FromContext(r.Context()); ok {
+				principal = p.ID()
+			}
+
+			hash := sha256.Sum256(body)
+			key := postgres.IdempotencyKey{
+				Principal:   principal,
+				Key:         keyValue,
+				Method:      r.Method,
+				Path:        r.URL.Path,
+				RequestHash: hash[:],
+			}
+
+			stored, err := store.AcquireIdempotencyKey(r.Context(), key, ttl)
+			if errors.Is(err, storage.ErrIdempotencyKeyReused) {
+				log.Info("idempotency key reused with a different request")
+
+				render.Status(r, http.StatusUnprocessableEntity)
+				render.JSON(w, r, response.Error("idempotency key was already used with a different request"))
+
+				return
+			}
+			if errors.Is(err, storage.ErrIdempotencyKeyInProgress) {
+				log.Info("idempotency key is in use")
+
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, response.Error("a request with the same idempotency key is in progress"))
+
+				return
+			}
+			if err != nil {
+				log.Error("failed to acquire idempotency key", sl.Err(err))
+
+				render.JSON(w, r, response.Error("failed to acquire idempotency key"))
+
+				return
+			}
+
+			if stored != nil && stored.Truncated {
+				log.Info("stored response is too large to replay", slog.Int("status", stored.Status))
+
+				render.Status(r, http.StatusConflict)
+				render.JSON(w, r, response.Error("the request with the same idempotency key was already processed, "+
+					"its response is too large to replay"))
+
+				return
+			}
+			if stored != nil {
+				log.Info("replaying stored response", slog.Int("status", stored.Status))
+
+				if stored.ContentType != "" {
+					w.Header().Set("Content-Type", stored.ContentType)
+				}
+				w.Header().Set(ReplayedHeader, strconv.FormatBool(true))
+				w.WriteHeader(stored.Status)
+				_, _ = w.Write(stored.Body)
+
+				return
+			}
+
+			// the response is stored even if the client has gone away in the meantime
+			ctx := context.WithoutCancel(r.Context())
+
+			saved := false
+			defer func() {
+				if saved {
+					return
+				}
+				if err := store.ReleaseIdempotencyKey(ctx, key); err != nil {
+					log.Error("failed to release idempotency key", sl.Err(err))
+				}
+			}()
+
+			buf := &limitedBuffer{limit: maxBodySize}
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			ww.Tee(buf)
+
+			next.ServeHTTP(ww, r)
+
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			if !replayable(status, buf) {
+				return
+			}
+
+			err = store.SaveIdempotentResponse(ctx, key, postgres.StoredResponse{
+				Status:      status,
+				ContentType: ww.Header().Get("Content-Type"),
+				Body:        buf.Bytes(),
+				Truncated:   buf.overflow,
+			})
+			if err != nil {
+				log.Error("failed to save idempotent response", sl.Err(err))
+
+				return
+			}
+			saved = true
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// isJSON reports whether the request has no body type or a JSON one.
+func isJSON(r *http.Request) bool {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	return err == nil && mediaType == "application/json"
+}
+
+// replayable reports whether a response is a definite outcome of the request, worth replaying to retries:
+// a success, or a client error the same request would get again. Server errors and the failures the handlers
+// report with 200 and an error status in the body, such as a timeout of the info API, are transient.
+func replayable(status int, body *limitedBuffer) bool {
+	if status >= http.StatusInternalServerError {
+		return false
+	}
+	if status >= http.StatusBadRequest || body.overflow {
+		return true
+	}
+
+	var resp response.Response
+	if err := json.Unmarshal(body.Bytes(), &resp); err != nil {
+		// not a response of the API, e.g. an exported file
+		return true
+	}
+
+	return resp.Status != response.StatusError
+}
+
+// limitedBuffer keeps what is written to it until more than limit bytes are written,
+// then drops everything and marks itself as overflown.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.overflow {
+		return len(p), nil
+	}
+	if b.Len()+len(p) > b.limit {
+		b.overflow = true
+		b.Buffer = bytes.Buffer{}
+
+		return len(p), nil
+	}
+
+	return b.Buffer.Write(p)
+}
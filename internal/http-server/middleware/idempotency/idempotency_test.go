@@ -0,0 +1,285 @@
+package idempotency_test
+
+import (
+	"crypto/sha256"
+	"effective_mobile_test/internal/http-server/middleware/idempotency"
+	"effective_mobile_test/internal/http-server/middleware/idempotency/mocks"
+	"effective_mobile_test/internal/lib/api/response"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"fmt"
+	"github.com/go-chi/render"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const ttl = time.Hour
+
+func TestIdempotency(t *testing.T) {
+	body := `{"regNums":["X123XX150"]}`
+	hash := sha256.Sum256([]byte(body))
+	key := postgres.IdempotencyKey{
+		Key:         "key-1",
+		Method:      http.MethodPost,
+		Path:        "/car/save",
+		RequestHash: hash[:],
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		contentType string
+		key         string
+		// handler is the response of the wrapped handler, nil if it must not be called
+		handler http.HandlerFunc
+		// acquire tells whether the key is acquired, with acquired and acquireErr as the result
+		acquire    bool
+		acquired   *postgres.StoredResponse
+		acquireErr error
+		// save tells whether the response is stored, release whether the key is released instead
+		save          bool
+		saveTruncated bool
+		release       bool
+		wantStatus    int
+		wantBody      string
+		replayed      bool
+	}{
+		{
+			name:       "without a key",
+			method:     http.MethodPost,
+			handler:    ok,
+			wantStatus: http.StatusOK,
+			wantBody:   `"status":"OK"`,
+		},
+		{
+			name:       "not a post",
+			method:     http.MethodPut,
+			key:        "key-1",
+			handler:    ok,
+			wantStatus: http.StatusOK,
+			wantBody:   `"status":"OK"`,
+		},
+		{
+			name:        "streamed request body",
+			method:      http.MethodPost,
+			contentType: "text/csv",
+			key:         "key-1",
+			handler:     ok,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"status":"OK"`,
+		},
+		{
+			name:       "key too long",
+			method:     http.MethodPost,
+			key:        strings.Repeat("k", 256),
+			wantStatus: http.StatusOK,
+			wantBody:   "header Idempotency-Key is not valid",
+		},
+		{
+			name:        "first request is stored",
+			method:      http.MethodPost,
+			contentType: "application/json; charset=utf-8",
+			key:         "key-1",
+			handler:     ok,
+			acquire:     true,
+			save:        true,
+			wantStatus:  http.StatusOK,
+			wantBody:    `"status":"OK"`,
+		},
+		{
+			name:       "client error is stored",
+			method:     http.MethodPost,
+			key:        "key-1",
+			handler:    status(http.StatusBadRequest, response.Error("field RegNums is required")),
+			acquire:    true,
+			save:       true,
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "field RegNums is required",
+		},
+		{
+			name:          "large response is marked as not replayable",
+			method:        http.MethodPost,
+			key:           "key-1",
+			handler:       large,
+			acquire:       true,
+			save:          true,
+			saveTruncated: true,
+			wantStatus:    http.StatusOK,
+			wantBody:      "reg_num,mark",
+		},
+		{
+			name:       "server error is not stored",
+			method:     http.MethodPost,
+			key:        "key-1",
+			handler:    status(http.StatusInternalServerError, response.Error("internal error")),
+			acquire:    true,
+			release:    true,
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   "internal error",
+		},
+		{
+			name:       "failure reported with 200 is not stored",
+			method:     http.MethodPost,
+			key:        "key-1",
+			handler:    status(http.StatusOK, response.Error("failed to find car")),
+			acquire:    true,
+			release:    true,
+			wantStatus: http.StatusOK,
+			wantBody:   "failed to find car",
+		},
+		{
+			name:    "stored response is replayed",
+			method:  http.MethodPost,
+			key:     "key-1",
+			acquire: true,
+			acquired: &postgres.StoredResponse{
+				Status:      http.StatusCreated,
+				ContentType: "application/json",
+				Body:        []byte(`{"status":"OK","replay":true}`),
+			},
+			wantStatus: http.StatusCreated,
+			wantBody:   `"replay":true`,
+			replayed:   true,
+		},
+		{
+			name:       "truncated response is not replayed",
+			method:     http.MethodPost,
+			key:        "key-1",
+			acquire:    true,
+			acquired:   &postgres.StoredResponse{Status: http.StatusOK, Truncated: true},
+			wantStatus: http.StatusConflict,
+			wantBody:   "too large to replay",
+		},
+		{
+			name:       "key reused with another body",
+			method:     http.MethodPost,
+			key:        "key-1",
+			acquire:    true,
+			acquireErr: fmt.Errorf("acquire: %w", storage.ErrIdempotencyKeyReused),
+			wantStatus: http.StatusUnprocessableEntity,
+			wantBody:   "already used with a different request",
+		},
+		{
+			name:       "key in progress",
+			method:     http.MethodPost,
+			key:        "key-1",
+			acquire:    true,
+			acquireErr: fmt.Errorf("acquire: %w", storage.ErrIdempotencyKeyInProgress),
+			wantStatus: http.StatusConflict,
+			wantBody:   "is in progress",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := mocks.NewKeyStore(t)
+
+			if tc.acquire {
+				store.On("AcquireIdempotencyKey", mock.Anything, key, ttl).
+					Return(tc.acquired, tc.acquireErr).Once()
+			}
+			if tc.save {
+				store.On("SaveIdempotentResponse", mock.Anything, key, mock.MatchedBy(func(resp postgres.StoredResponse) bool {
+					if tc.saveTruncated {
+						return resp.Status == tc.wantStatus && resp.Truncated && len(resp.Body) == 0
+					}
+
+					return resp.Status == tc.wantStatus && !resp.Truncated && strings.Contains(string(resp.Body), tc.wantBody)
+				})).Return(nil).Once()
+			}
+			if tc.release {
+				store.On("ReleaseIdempotencyKey", mock.Anything, key).Return(nil).Once()
+			}
+
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+
+				// the handler reads the body the middleware may have read already
+				got, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Equal(t, body, string(got))
+
+				tc.handler(w, r)
+			})
+
+			handler := idempotency.New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, ttl)(next)
+
+			req := httptest.NewRequest(tc.method, "/car/save", strings.NewReader(body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.key != "" {
+				req.Header.Set(idempotency.Header, tc.key)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.handler != nil, called)
+			assert.Equal(t, tc.wantStatus, rr.Code)
+			assert.Contains(t, rr.Body.String(), tc.wantBody)
+			if tc.replayed {
+				assert.Equal(t, "true", rr.Header().Get(idempotency.ReplayedHeader))
+			} else {
+				assert.Empty(t, rr.Header().Get(idempotency.ReplayedHeader))
+			}
+		})
+	}
+}
+
+func TestIdempotencyScopedByPrincipal(t *testing.T) {
+	body := `{"regNums":["X123XX150"]}`
+	hash := sha256.Sum256([]byte(body))
+	key := postgres.IdempotencyKey{
+		Principal:   "api_key:ci",
+		Key:         "key-1",
+		Method:      http.MethodPost,
+		Path:        "/car/save",
+		RequestHash: hash[:],
+	}
+
+	store := mocks.NewKeyStore(t)
+	store.On("AcquireIdempotencyKey", mock.Anything, key, ttl).Return(nil, nil).Once()
+	store.On("SaveIdempotentResponse", mock.Anything, key, mock.Anything).Return(nil).Once()
+
+	handler := idempotency.New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, ttl)(ok)
+
+	req := httptest.NewRequest(http.MethodPost, "/car/save", strings.NewReader(body))
+	req.Header.Set(idempotency.Header, "key-1")
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "ci", Method: auth.MethodAPIKey}))
+	rr := httptest.NewRecorder()
+
+	handler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+var ok http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, response.OK())
+}
+
+// large streams a file of several megabytes in chunks.
+var large http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/csv")
+	_, _ = io.WriteString(w, "reg_num,mark\n")
+	for i := 0; i < 100_000; i++ {
+		_, _ = fmt.Fprintf(w, "X%06dXX150,Lada%s\n", i, strings.Repeat(" ", 20))
+	}
+}
+
+func status(code int, resp response.Response) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		render.Status(r, code)
+		render.JSON(w, r, resp)
+	}
+}
@@ -0,0 +1,98 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	time "time"
+)
+
+// KeyStore is an autogenerated mock type for the KeyStore type
+type KeyStore struct {
+	mock.Mock
+}
+
+// AcquireIdempotencyKey provides a mock function with given fields: ctx, key, ttl
+func (_m *KeyStore) AcquireIdempotencyKey(ctx context.Context, key postgres.IdempotencyKey, ttl time.Duration) (*postgres.StoredResponse, error) {
+	ret := _m.Called(ctx, key, ttl)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcquireIdempotencyKey")
+	}
+
+	var r0 *postgres.StoredResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.IdempotencyKey, time.Duration) (*postgres.StoredResponse, error)); ok {
+		return rf(ctx, key, ttl)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.IdempotencyKey, time.Duration) *postgres.StoredResponse); ok {
+		r0 = rf(ctx, key, ttl)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*postgres.StoredResponse)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, postgres.IdempotencyKey, time.Duration) error); ok {
+		r1 = rf(ctx, key, ttl)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ReleaseIdempotencyKey provides a mock function with given fields: ctx, key
+func (_m *KeyStore) ReleaseIdempotencyKey(ctx context.Context, key postgres.IdempotencyKey) error {
+	ret := _m.Called(ctx, key)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ReleaseIdempotencyKey")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.IdempotencyKey) error); ok {
+		r0 = rf(ctx, key)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// SaveIdempotentResponse provides a mock function with given fields: ctx, key, resp
+func (_m *KeyStore) SaveIdempotentResponse(ctx context.Context, key postgres.IdempotencyKey, resp postgres.StoredResponse) error {
+	ret := _m.Called(ctx, key, resp)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SaveIdempotentResponse")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, postgres.IdempotencyKey, postgres.StoredResponse) error); ok {
+		r0 = rf(ctx, key, resp)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewKeyStore creates a new instance of KeyStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewKeyStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *KeyStore {
+	mock := &KeyStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
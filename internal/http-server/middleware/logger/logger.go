@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"time"
 
+	"effective_mobile_test/internal/lib/logger/sl"
 	"github.com/go-chi/chi/v5/middleware"
 	"log/slog"
 )
@@ -23,6 +24,7 @@ func New(log *slog.Logger) func(next http.Handler) http.Handler {
 				slog.String("remote_addr", r.RemoteAddr),
 				slog.String("user_agent", r.UserAgent()),
 				slog.String("request_id", middleware.GetReqID(r.Context())),
+				sl.TraceID(r.Context()),
 			)
 			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
 
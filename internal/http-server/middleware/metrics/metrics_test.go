@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"effective_mobile_test/internal/lib/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	router := chi.NewRouter()
+	router.Use(New(log))
+	router.Get("/car/{id}", func(w http.ResponseWriter, r *http.Request) {})
+	router.Route("/owners", func(r chi.Router) {
+		r.Post("/", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusCreated)
+		})
+	})
+
+	cases := []struct {
+		name       string
+		method     string
+		path       string
+		wantRoute  string
+		wantStatus string
+	}{
+		{
+			name:       "route pattern instead of path",
+			method:     http.MethodGet,
+			path:       "/car/42",
+			wantRoute:  "/car/{id}",
+			wantStatus: "200",
+		},
+		{
+			name:       "full pattern of a subrouter",
+			method:     http.MethodPost,
+			path:       "/owners/",
+			wantRoute:  "/owners",
+			wantStatus: "201",
+		},
+		{
+			name:       "unknown path",
+			method:     http.MethodGet,
+			path:       "/nope/123",
+			wantRoute:  unmatchedRoute,
+			wantStatus: "404",
+		},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			before := httpRequests(t, tc.method, tc.wantRoute, tc.wantStatus)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.wantStatus, strconv.Itoa(rr.Code))
+			assert.Equal(t, before+1, httpRequests(t, tc.method, tc.wantRoute, tc.wantStatus))
+		})
+	}
+}
+
+// httpRequests returns the number of requests observed with the labels.
+func httpRequests(t *testing.T, method, route, status string) uint64 {
+	t.Helper()
+
+	var m dto.Metric
+	err := metrics.HTTPRequestDuration.WithLabelValues(method, route, status).(prometheus.Metric).Write(&m)
+	require.NoError(t, err)
+
+	return m.GetHistogram().GetSampleCount()
+}
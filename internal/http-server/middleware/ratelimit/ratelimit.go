@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/auth"
+	"github.com/go-chi/chi/v5/middleware"
+	"golang.org/x/time/rate"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that filled up again are dropped.
+const sweepInterval = time.Minute
+
+// Limit is a token bucket: Rate requests per second on average, up to Burst at once.
+// A zero Rate disables the limit and a Burst below one is taken as one.
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// limiter keeps a bucket per client.
+type limiter struct {
+	limit Limit
+
+	mu        sync.Mutex
+	buckets   map[string]*rate.Limiter
+	lastSweep time.Time
+}
+
+// New limits the requests of every client to the route group it is used on, with a token bucket
+// of its own per group. Clients are told apart by their API key or token subject, or by their IP
+// if the request isn't authenticated, so a limiter used before the auth middleware limits every IP.
+// Refused requests get 429 with Retry-After; every response carries the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers of the last limiter it passed.
+func New(log *slog.Logger, group string, limit Limit) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/ratelimit"),
+			slog.String("group", group),
+		)
+
+		if limit.Rate <= 0 {
+			log.Info("ratelimit middleware disabled")
+
+			return next
+		}
+
+		if limit.Burst < 1 {
+			limit.Burst = 1
+		}
+
+		log.Info("ratelimit middleware enabled",
+			slog.Float64("rate", limit.Rate),
+			slog.Int("burst", limit.Burst),
+		)
+
+		l := &limiter{limit: limit, buckets: make(map[string]*rate.Limiter)}
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			client := clientKey(r)
+
+			now := time.Now()
+			allowed, remaining, reset, retryAfter := l.take(client, now)
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(limit.Burst))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(reset)))
+
+			if !allowed {
+				log.Info("rate limit exceeded",
+					slog.String("request_id", middleware.GetReqID(r.Context())),
+					slog.String("client", client),
+				)
+
+				w.Header().Set("Retry-After", strconv.Itoa(seconds(retryAfter)))
+				problem.Write(w, r, http.StatusTooManyRequests, "rate limit exceeded, retry later")
+
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// take spends a token of the client's bucket if there is one. It returns the tokens left,
+// how long the bucket takes to fill up again and, for refused requests, when a token is available.
+func (l *limiter) take(client string, now time.Time) (bool, int, time.Duration, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, ok := l.buckets[client]
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(l.limit.Rate), l.limit.Burst)
+		l.buckets[client] = bucket
+	}
+
+	reservation := bucket.ReserveN(now, 1)
+	allowed := reservation.OK() && reservation.DelayFrom(now) == 0
+
+	var retryAfter time.Duration
+	if !allowed {
+		retryAfter = reservation.DelayFrom(now)
+		if !reservation.OK() {
+			retryAfter = time.Duration(float64(time.Second) / l.limit.Rate)
+		}
+		reservation.CancelAt(now)
+	}
+
+	tokens := bucket.TokensAt(now)
+	reset := time.Duration((float64(l.limit.Burst) - tokens) / l.limit.Rate * float64(time.Second))
+
+	return allowed, int(math.Max(0, math.Floor(tokens))), reset, retryAfter
+}
+
+// sweep drops the buckets that are full, which are the same as new ones, so that the number
+// of buckets follows the number of active clients.
+func (l *limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for client, bucket := range l.buckets {
+		if bucket.TokensAt(now) >= float64(l.limit.Burst) {
+			delete(l.buckets, client)
+		}
+	}
+}
+
+// clientKey identifies the caller: by the principal if the request is authenticated, by IP otherwise.
+func clientKey(r *http.Request) string {
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		return principal.ID()
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// seconds rounds d up to whole seconds, as the headers expect.
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
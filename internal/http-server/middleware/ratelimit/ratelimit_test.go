@@ -0,0 +1,190 @@
+package ratelimit
+
+import (
+	"effective_mobile_test/internal/lib/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTake(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	type step struct {
+		after          time.Duration
+		wantAllowed    bool
+		wantRemaining  int
+		wantReset      time.Duration
+		wantRetryAfter time.Duration
+	}
+
+	cases := []struct {
+		name  string
+		limit Limit
+		steps []step
+	}{
+		{
+			name:  "burst then refused until a token is back",
+			limit: Limit{Rate: 2, Burst: 3},
+			steps: []step{
+				{wantAllowed: true, wantRemaining: 2, wantReset: 500 * time.Millisecond},
+				{wantAllowed: true, wantRemaining: 1, wantReset: time.Second},
+				{wantAllowed: true, wantRemaining: 0, wantReset: 1500 * time.Millisecond},
+				{wantAllowed: false, wantRemaining: 0, wantReset: 1500 * time.Millisecond, wantRetryAfter: 500 * time.Millisecond},
+				{after: 500 * time.Millisecond, wantAllowed: true, wantRemaining: 0, wantReset: 1500 * time.Millisecond},
+			},
+		},
+		{
+			name:  "refill up to the burst",
+			limit: Limit{Rate: 1, Burst: 2},
+			steps: []step{
+				{wantAllowed: true, wantRemaining: 1, wantReset: time.Second},
+				{wantAllowed: true, wantRemaining: 0, wantReset: 2 * time.Second},
+				{after: time.Hour, wantAllowed: true, wantRemaining: 1, wantReset: time.Second},
+			},
+		},
+		{
+			name:  "slow rate",
+			limit: Limit{Rate: 0.5, Burst: 1},
+			steps: []step{
+				{wantAllowed: true, wantRemaining: 0, wantReset: 2 * time.Second},
+				{after: time.Second, wantAllowed: false, wantRemaining: 0, wantReset: time.Second, wantRetryAfter: time.Second},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			l := &limiter{limit: tc.limit, buckets: make(map[string]*rate.Limiter)}
+
+			at := now
+			for i, s := range tc.steps {
+				at = at.Add(s.after)
+				allowed, remaining, reset, retryAfter := l.take("api_key:billing", at)
+
+				assert.Equal(t, s.wantAllowed, allowed, "step %d", i)
+				assert.Equal(t, s.wantRemaining, remaining, "step %d", i)
+				assert.InDelta(t, s.wantReset, reset, float64(time.Millisecond), "step %d", i)
+				assert.InDelta(t, s.wantRetryAfter, retryAfter, float64(time.Millisecond), "step %d", i)
+			}
+		})
+	}
+}
+
+func TestTakeKeepsClientsApart(t *testing.T) {
+	now := time.Now()
+	l := &limiter{limit: Limit{Rate: 1, Burst: 1}, buckets: make(map[string]*rate.Limiter)}
+
+	allowed, _, _, _ := l.take("ip:192.0.2.1", now)
+	require.True(t, allowed)
+	allowed, _, _, _ = l.take("ip:192.0.2.1", now)
+	require.False(t, allowed)
+
+	allowed, _, _, _ = l.take("ip:192.0.2.2", now)
+	assert.True(t, allowed)
+}
+
+func TestSweep(t *testing.T) {
+	now := time.Now()
+	l := &limiter{limit: Limit{Rate: 1, Burst: 5}, buckets: make(map[string]*rate.Limiter), lastSweep: now}
+
+	l.take("idle", now)
+	for i := 0; i < 5; i++ {
+		l.take("busy", now.Add(sweepInterval))
+	}
+
+	// the idle bucket is full again and dropped, the busy one is kept
+	l.take("other", now.Add(sweepInterval+time.Second))
+
+	assert.NotContains(t, l.buckets, "idle")
+	assert.Contains(t, l.buckets, "busy")
+	assert.Contains(t, l.buckets, "other")
+}
+
+func TestClientKey(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/cars/1", nil)
+	req.RemoteAddr = "192.0.2.1:51234"
+
+	assert.Equal(t, "ip:192.0.2.1", clientKey(req))
+
+	req = req.WithContext(auth.WithPrincipal(req.Context(), auth.Principal{Name: "billing", Method: auth.MethodAPIKey}))
+
+	assert.Equal(t, "api_key:billing", clientKey(req))
+}
+
+func TestRateLimit(t *testing.T) {
+	log := slog.New(slog.NewTextHandler(io.Discard, nil))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// the IP limiter runs before the principal is known, the API limiter after it
+	byIP := New(log, "ip", Limit{Rate: 0.01, Burst: 2})
+	byPrincipal := New(log, "api", Limit{Rate: 0.01, Burst: 1})
+	authenticate := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := r.Header.Get("X-Test-Principal")
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Principal{Name: name, Method: auth.MethodAPIKey})))
+		})
+	}
+	handler := byIP(authenticate(byPrincipal(next)))
+
+	cases := []struct {
+		name       string
+		remoteAddr string
+		principal  string
+		wantStatus int
+		// wantLimit is the burst of the last limiter the request passed
+		wantLimit string
+	}{
+		{name: "first request", remoteAddr: "192.0.2.1:1000", principal: "billing", wantStatus: http.StatusNoContent, wantLimit: "1"},
+		{name: "principal limit", remoteAddr: "192.0.2.2:1000", principal: "billing", wantStatus: http.StatusTooManyRequests, wantLimit: "1"},
+		{name: "other principal on the same IP", remoteAddr: "192.0.2.1:1001", principal: "reports", wantStatus: http.StatusNoContent, wantLimit: "1"},
+		{name: "IP limit applies to every principal", remoteAddr: "192.0.2.1:1002", principal: "audit", wantStatus: http.StatusTooManyRequests, wantLimit: "2"},
+		{name: "other IP", remoteAddr: "192.0.2.3:1000", principal: "audit", wantStatus: http.StatusNoContent, wantLimit: "1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/cars/1", nil)
+			req.RemoteAddr = tc.remoteAddr
+			req.Header.Set("X-Test-Principal", tc.principal)
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantLimit, rr.Header().Get("RateLimit-Limit"))
+			assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+			assert.NotEmpty(t, rr.Header().Get("RateLimit-Reset"))
+			if tc.wantStatus == http.StatusTooManyRequests {
+				assert.Equal(t, "100", rr.Header().Get("Retry-After"))
+				assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	handler := New(slog.New(slog.NewTextHandler(io.Discard, nil)), "api", Limit{})(next)
+
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cars/1", nil))
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+	}
+}
@@ -0,0 +1,46 @@
+package rbac
+
+import (
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/auth"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// New refuses requests with 403 unless their principal has all the permissions.
+// It must run after the auth middleware.
+func New(log *slog.Logger, permissions ...string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/rbac"),
+			slog.String("permissions", strings.Join(permissions, ",")),
+		)
+
+		log.Info("rbac middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.FromContext(r.Context())
+
+			for _, permission := range permissions {
+				if !principal.Can(permission) {
+					log.Info("permission denied",
+						slog.String("request_id", middleware.GetReqID(r.Context())),
+						slog.String("principal", principal.Name),
+						slog.String("role", principal.Role),
+						slog.String("permission", permission),
+					)
+
+					problem.Write(w, r, http.StatusForbidden, "permission "+permission+" is required")
+
+					return
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,81 @@
+package rbac_test
+
+import (
+	"effective_mobile_test/internal/http-server/middleware/rbac"
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/auth"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRBAC(t *testing.T) {
+	cases := []struct {
+		name        string
+		principal   *auth.Principal
+		permissions []string
+		wantStatus  int
+		wantDetail  string
+	}{
+		{
+			name:        "granted",
+			principal:   &auth.Principal{Name: "ci", Role: auth.RoleEditor},
+			permissions: []string{auth.PermCarRead, auth.PermCarWrite},
+			wantStatus:  http.StatusOK,
+		},
+		{
+			name:        "one permission missing",
+			principal:   &auth.Principal{Name: "ci", Role: auth.RoleEditor},
+			permissions: []string{auth.PermCarWrite, auth.PermCarDelete},
+			wantStatus:  http.StatusForbidden,
+			wantDetail:  "permission car:delete is required",
+		},
+		{
+			name:        "without a principal",
+			permissions: []string{auth.PermCarRead},
+			wantStatus:  http.StatusForbidden,
+			wantDetail:  "permission car:read is required",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var called bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+			handler := rbac.New(slog.New(slog.NewTextHandler(io.Discard, nil)), tc.permissions...)(next)
+
+			req := httptest.NewRequest(http.MethodDelete, "/cars/5", nil)
+			if tc.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), *tc.principal))
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantStatus == http.StatusOK, called)
+			if tc.wantStatus == http.StatusOK {
+				return
+			}
+
+			assert.Equal(t, problem.ContentType, rr.Header().Get("Content-Type"))
+
+			var p problem.Problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &p))
+			assert.Equal(t, problem.Problem{
+				Type:     "about:blank",
+				Title:    "Forbidden",
+				Status:   http.StatusForbidden,
+				Detail:   tc.wantDetail,
+				Instance: "/cars/5",
+			}, p)
+		})
+	}
+}
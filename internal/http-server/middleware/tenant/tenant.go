@@ -0,0 +1,68 @@
+package tenant
+
+import (
+	"effective_mobile_test/internal/lib/api/problem"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/tenant"
+	"github.com/go-chi/chi/v5/middleware"
+	"log/slog"
+	"net/http"
+)
+
+// Header chooses the tenant of a request whose principal may choose it, see auth.Principal.ChoosesTenant.
+const Header = "X-Tenant-ID"
+
+// New stores the tenant of the request in its context. A principal bound to a tenant always
+// works in it, principals that may choose the tenant name it with the header (tenant.Default
+// if it is missing) and all others work in tenant.Default. A header naming a tenant the
+// principal can't work in is refused with 403. It must run after the auth middleware.
+func New(log *slog.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		log := log.With(
+			slog.String("component", "middleware/tenant"),
+		)
+
+		log.Info("tenant middleware enabled")
+
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			principal, _ := auth.FromContext(r.Context())
+			requested := r.Header.Get(Header)
+
+			log := log.With(
+				slog.String("request_id", middleware.GetReqID(r.Context())),
+				slog.String("principal", principal.Name),
+			)
+
+			if requested != "" && !tenant.Valid(requested) {
+				log.Info("invalid tenant", slog.String("tenant", requested))
+
+				problem.Write(w, r, http.StatusBadRequest, "invalid "+Header+" header")
+
+				return
+			}
+
+			id := principal.Tenant
+			if id == "" {
+				id = tenant.Default
+			}
+			if requested != "" && requested != id {
+				if !principal.ChoosesTenant() {
+					log.Info("tenant denied",
+						slog.String("tenant", requested),
+						slog.String("allowed_tenant", id),
+					)
+
+					problem.Write(w, r, http.StatusForbidden, "access to tenant "+requested+" is denied")
+
+					return
+				}
+
+				id = requested
+			}
+
+			next.ServeHTTP(w, r.WithContext(tenant.WithTenant(r.Context(), id)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
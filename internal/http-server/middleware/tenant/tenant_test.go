@@ -0,0 +1,67 @@
+package tenant_test
+
+import (
+	mwTenant "effective_mobile_test/internal/http-server/middleware/tenant"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTenant(t *testing.T) {
+	viewer := auth.Principal{Name: "reports", Method: auth.MethodAPIKey, Role: auth.RoleViewer}
+	editor := auth.Principal{Name: "billing", Method: auth.MethodAPIKey, Role: auth.RoleEditor}
+	admin := auth.Principal{Name: "ops", Method: auth.MethodJWT, Role: auth.RoleAdmin}
+	multi := auth.Principal{Name: "sync", Method: auth.MethodAPIKey, Role: auth.RoleEditor, MultiTenant: true}
+	bound := auth.Principal{Name: "acme", Method: auth.MethodAPIKey, Role: auth.RoleAdmin, Tenant: "acme"}
+
+	cases := []struct {
+		name       string
+		principal  *auth.Principal
+		header     string
+		wantStatus int
+		wantTenant string
+	}{
+		{name: "unbound key works in the default tenant", principal: &editor, wantStatus: http.StatusOK, wantTenant: tenant.Default},
+		{name: "unbound key may name the default tenant", principal: &viewer, header: tenant.Default, wantStatus: http.StatusOK, wantTenant: tenant.Default},
+		{name: "unbound key can't choose another tenant", principal: &editor, header: "acme", wantStatus: http.StatusForbidden},
+		{name: "multi-tenant key chooses the tenant", principal: &multi, header: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "multi-tenant key defaults to the default tenant", principal: &multi, wantStatus: http.StatusOK, wantTenant: tenant.Default},
+		{name: "admin chooses the tenant", principal: &admin, header: "fleet-north", wantStatus: http.StatusOK, wantTenant: "fleet-north"},
+		{name: "bound key works in its tenant", principal: &bound, wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "bound key may name its tenant", principal: &bound, header: "acme", wantStatus: http.StatusOK, wantTenant: "acme"},
+		{name: "bound admin can't choose another tenant", principal: &bound, header: tenant.Default, wantStatus: http.StatusForbidden},
+		{name: "without principal", header: "acme", wantStatus: http.StatusForbidden},
+		{name: "invalid header", principal: &admin, header: "Acme Inc", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = tenant.FromContext(r.Context())
+			})
+
+			handler := mwTenant.New(slog.New(slog.NewTextHandler(io.Discard, nil)))(next)
+
+			req := httptest.NewRequest(http.MethodGet, "/cars/1", nil)
+			if tc.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), *tc.principal))
+			}
+			if tc.header != "" {
+				req.Header.Set(mwTenant.Header, tc.header)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.wantStatus, rr.Code)
+			assert.Equal(t, tc.wantTenant, got)
+		})
+	}
+}
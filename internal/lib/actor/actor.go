@@ -0,0 +1,25 @@
+package actor
+
+import "context"
+
+// Anonymous is the actor of requests that don't identify themselves.
+const Anonymous = "anonymous"
+
+// System is the actor of changes made by the service itself, e.g. background workers.
+const System = "system"
+
+type ctxKey struct{}
+
+// WithActor returns a copy of ctx carrying the actor responsible for the changes made with it.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, actor)
+}
+
+// FromContext returns the actor stored in ctx, or Anonymous if there is none.
+func FromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(ctxKey{}).(string); ok && actor != "" {
+		return actor
+	}
+
+	return Anonymous
+}
@@ -0,0 +1,92 @@
+package etag
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidTag is returned for a malformed If-Match header.
+	ErrInvalidTag = errors.New("invalid entity tag")
+	// ErrPreconditionFailed is returned when the precondition can't hold for any version.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// Format returns the strong entity tag of the given version.
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Set sets the ETag header of the response to the entity tag of the given version.
+func Set(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", Format(version))
+}
+
+// IfMatch returns the versions listed in the If-Match header of the request,
+// or nil if the header is absent or is "*", which matches any existing resource.
+// If-Match uses the strong comparison, so weak tags and tags of no version never match;
+// ErrPreconditionFailed is returned if the header lists nothing else.
+func IfMatch(r *http.Request) ([]int, error) {
+	values := r.Header.Values("If-Match")
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	header := strings.TrimSpace(strings.Join(values, ","))
+	if header == "*" {
+		return nil, nil
+	}
+
+	versions := []int{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" {
+			// empty list elements are allowed by the list syntax
+			continue
+		}
+
+		weak := strings.HasPrefix(tag, "W/")
+		tag = strings.TrimPrefix(tag, "W/")
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' || strings.ContainsRune(tag[1:len(tag)-1], '"') {
+			return nil, ErrInvalidTag
+		}
+		if weak {
+			continue
+		}
+
+		version, err := strconv.Atoi(tag[1 : len(tag)-1])
+		if err != nil || version < 1 {
+			continue
+		}
+		versions = append(versions, version)
+	}
+
+	if len(versions) == 0 {
+		return nil, ErrPreconditionFailed
+	}
+
+	return versions, nil
+}
+
+// Expected returns the versions the request accepts, taken from the If-Match header
+// or, for clients that don't handle headers, from the version field of the body.
+// If both are given, the body version must be listed by the header.
+// nil means that any version is accepted.
+func Expected(r *http.Request, body *int) ([]int, error) {
+	header, err := IfMatch(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if body == nil {
+		return header, nil
+	}
+	if header != nil && !slices.Contains(header, *body) {
+		return nil, ErrPreconditionFailed
+	}
+
+	return []int{*body}, nil
+}
@@ -0,0 +1,145 @@
+package etag_test
+
+import (
+	"effective_mobile_test/internal/lib/api/etag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIfMatch(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers []string
+		want    []int
+		wantErr error
+	}{
+		{
+			name: "absent",
+		},
+		{
+			name:    "any existing resource",
+			headers: []string{"*"},
+		},
+		{
+			name:    "single tag",
+			headers: []string{`"3"`},
+			want:    []int{3},
+		},
+		{
+			name:    "list of tags",
+			headers: []string{` "3", "4" ,`},
+			want:    []int{3, 4},
+		},
+		{
+			name:    "list split over several headers",
+			headers: []string{`"3"`, `"4"`},
+			want:    []int{3, 4},
+		},
+		{
+			name:    "weak tags never match",
+			headers: []string{`W/"3", "4"`},
+			want:    []int{4},
+		},
+		{
+			name:    "only weak tags",
+			headers: []string{`W/"3"`},
+			wantErr: etag.ErrPreconditionFailed,
+		},
+		{
+			name:    "tag of no version",
+			headers: []string{`"abc"`},
+			wantErr: etag.ErrPreconditionFailed,
+		},
+		{
+			name:    "unquoted tag",
+			headers: []string{"3"},
+			wantErr: etag.ErrInvalidTag,
+		},
+		{
+			name:    "star within a list",
+			headers: []string{`*, "3"`},
+			wantErr: etag.ErrInvalidTag,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/car/update", nil)
+			for _, h := range tc.headers {
+				req.Header.Add("If-Match", h)
+			}
+
+			got, err := etag.IfMatch(req)
+			require.ErrorIs(t, err, tc.wantErr)
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestExpected(t *testing.T) {
+	version := func(v int) *int { return &v }
+
+	cases := []struct {
+		name    string
+		header  string
+		body    *int
+		want    []int
+		wantErr error
+	}{
+		{
+			name: "no precondition",
+		},
+		{
+			name: "body only",
+			body: version(3),
+			want: []int{3},
+		},
+		{
+			name:   "header only",
+			header: `"3", "4"`,
+			want:   []int{3, 4},
+		},
+		{
+			name:   "body listed by the header",
+			header: `"3", "4"`,
+			body:   version(4),
+			want:   []int{4},
+		},
+		{
+			name:    "body not listed by the header",
+			header:  `"3"`,
+			body:    version(4),
+			wantErr: etag.ErrPreconditionFailed,
+		},
+		{
+			name:   "body with any existing resource",
+			header: "*",
+			body:   version(4),
+			want:   []int{4},
+		},
+		{
+			name:    "malformed header",
+			header:  `"3`,
+			body:    version(3),
+			wantErr: etag.ErrInvalidTag,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/car/update", nil)
+			if tc.header != "" {
+				req.Header.Set("If-Match", tc.header)
+			}
+
+			got, err := etag.Expected(req, tc.body)
+			require.ErrorIs(t, err, tc.wantErr)
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
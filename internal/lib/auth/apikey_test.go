@@ -0,0 +1,30 @@
+package auth_test
+
+import (
+	"effective_mobile_test/internal/lib/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	key, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+
+	other, err := auth.GenerateAPIKey()
+	require.NoError(t, err)
+
+	assert.True(t, auth.IsAPIKey(key))
+	// 32 random bytes in unpadded base64
+	assert.Len(t, key, len(auth.APIKeyPrefix)+43)
+	assert.NotEqual(t, key, other)
+}
+
+func TestIsAPIKey(t *testing.T) {
+	assert.True(t, auth.IsAPIKey(auth.APIKeyPrefix+"abc"))
+	assert.False(t, auth.IsAPIKey("eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiJhbGljZSJ9.sig"))
+}
+
+func TestHashAPIKey(t *testing.T) {
+	assert.Equal(t, "d42ae5a0dbaa68f055ac027c0b37b89666ecfabbf3344e2b155ce18513e04207", auth.HashAPIKey("cat_test"))
+}
@@ -0,0 +1,47 @@
+package auth
+
+import "context"
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	// Name identifies the caller: the name of its API key or the subject of its token
+	Name string
+	// Method is how the caller authenticated, MethodAPIKey or MethodJWT
+	Method string
+	// Role decides what the principal may do, see Can
+	Role string
+	// Tenant is the only tenant the principal may access
+	Tenant string
+	// MultiTenant lets a principal without a tenant choose it, see ChoosesTenant
+	MultiTenant bool
+}
+
+// ID identifies the principal among the callers authenticated by any method.
+func (p Principal) ID() string {
+	return p.Method + ":" + p.Name
+}
+
+// ChoosesTenant reports whether the principal may choose the tenant of its requests:
+// it isn't bound to one and is either flagged multi-tenant or an admin.
+func (p Principal) ChoosesTenant() bool {
+	return p.Tenant == "" && (p.MultiTenant || p.Can(PermAdminTenants))
+}
+
+type ctxKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the authenticated caller.
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, ctxKey{}, principal)
+}
+
+// FromContext returns the principal stored in ctx, if the request was authenticated.
+func FromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(ctxKey{}).(Principal)
+
+	return principal, ok
+}
@@ -0,0 +1,36 @@
+package auth_test
+
+import (
+	"effective_mobile_test/internal/lib/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrincipalID(t *testing.T) {
+	key := auth.Principal{Name: "ci", Method: auth.MethodAPIKey}
+	token := auth.Principal{Name: "ci", Method: auth.MethodJWT}
+
+	// an API key and a token subject with the same name are different callers
+	assert.Equal(t, "api_key:ci", key.ID())
+	assert.NotEqual(t, key.ID(), token.ID())
+}
+
+func TestChoosesTenant(t *testing.T) {
+	cases := []struct {
+		name      string
+		principal auth.Principal
+		want      bool
+	}{
+		{name: "viewer", principal: auth.Principal{Role: auth.RoleViewer}},
+		{name: "editor", principal: auth.Principal{Role: auth.RoleEditor}},
+		{name: "admin", principal: auth.Principal{Role: auth.RoleAdmin}, want: true},
+		{name: "multi-tenant viewer", principal: auth.Principal{Role: auth.RoleViewer, MultiTenant: true}, want: true},
+		{name: "bound admin", principal: auth.Principal{Role: auth.RoleAdmin, Tenant: "acme"}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.principal.ChoosesTenant())
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"effective_mobile_test/internal/lib/tenant"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"math/big"
+	"os"
+	"time"
+)
+
+// leeway tolerates clock skew between the token issuer and the service.
+const leeway = 30 * time.Second
+
+var ErrJWTDisabled = errors.New("no keys are configured to verify tokens")
+
+// JWTConfig holds the keys tokens are verified with. Any of them may be left empty.
+type JWTConfig struct {
+	// HS256Secret verifies HS256 tokens
+	HS256Secret string
+	// RS256PublicKeyFile is a PEM file with the RSA public key verifying RS256 tokens
+	RS256PublicKeyFile string
+	// JWKSFile is a JSON Web Key Set file with RSA public keys verifying RS256 tokens by their kid
+	JWKSFile string
+	// Issuer and Audience, if set, must match the iss and aud claims of tokens
+	Issuer   string
+	Audience string
+}
+
+// JWTVerifier checks bearer tokens. Tokens must be signed by one of the configured keys,
+// not be expired and carry a subject, which names the principal. The role claim gives
+// the role of the principal, viewer if it is missing, the tenant claim binds it to a tenant
+// and the multi_tenant claim lets it choose one instead.
+type JWTVerifier struct {
+	secret    []byte
+	publicKey *rsa.PublicKey
+	keySet    map[string]*rsa.PublicKey
+	parser    *jwt.Parser
+}
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Role        string `json:"role,omitempty"`
+	Tenant      string `json:"tenant,omitempty"`
+	MultiTenant bool   `json:"multi_tenant,omitempty"`
+}
+
+// NewJWTVerifier loads the keys of cfg. If none are configured, every token is rejected with ErrJWTDisabled.
+func NewJWTVerifier(cfg JWTConfig) (*JWTVerifier, error) {
+	v := &JWTVerifier{}
+
+	var methods []string
+	if cfg.HS256Secret != "" {
+		v.secret = []byte(cfg.HS256Secret)
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if cfg.RS256PublicKeyFile != "" {
+		pem, err := os.ReadFile(cfg.RS256PublicKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read public key: %w", err)
+		}
+		if v.publicKey, err = jwt.ParseRSAPublicKeyFromPEM(pem); err != nil {
+			return nil, fmt.Errorf("failed to parse public key: %w", err)
+		}
+	}
+	if cfg.JWKSFile != "" {
+		var err error
+		if v.keySet, err = loadKeySet(cfg.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	if v.publicKey != nil || len(v.keySet) > 0 {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+
+	opts := []jwt.ParserOption{jwt.WithValidMethods(methods), jwt.WithExpirationRequired(), jwt.WithLeeway(leeway)}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	if len(methods) > 0 {
+		v.parser = jwt.NewParser(opts...)
+	}
+
+	return v, nil
+}
+
+// Verify checks the token and returns the principal it was issued to.
+func (v *JWTVerifier) Verify(token string) (Principal, error) {
+	if v.parser == nil {
+		return Principal{}, ErrJWTDisabled
+	}
+
+	var claims tokenClaims
+	if _, err := v.parser.ParseWithClaims(token, &claims, v.key); err != nil {
+		return Principal{}, err
+	}
+	if claims.Subject == "" {
+		return Principal{}, errors.New("token has no subject")
+	}
+
+	if claims.Role == "" {
+		claims.Role = RoleViewer
+	}
+	if !ValidRole(claims.Role) {
+		return Principal{}, fmt.Errorf("token has unknown role %q", claims.Role)
+	}
+	if claims.Tenant != "" && !tenant.Valid(claims.Tenant) {
+		return Principal{}, fmt.Errorf("token has invalid tenant %q", claims.Tenant)
+	}
+	if claims.Tenant != "" && claims.MultiTenant {
+		return Principal{}, fmt.Errorf("token has both a tenant and multi_tenant")
+	}
+
+	return Principal{
+		Name:        claims.Subject,
+		Method:      MethodJWT,
+		Role:        claims.Role,
+		Tenant:      claims.Tenant,
+		MultiTenant: claims.MultiTenant,
+	}, nil
+}
+
+// key returns the key the token must be signed with; its algorithm is already checked by the parser.
+func (v *JWTVerifier) key(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() == jwt.SigningMethodHS256.Alg() {
+		return v.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid != "" && v.keySet != nil {
+		key, ok := v.keySet[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key id %q", kid)
+		}
+
+		return key, nil
+	}
+	if v.publicKey != nil {
+		return v.publicKey, nil
+	}
+	if kid == "" && len(v.keySet) == 1 {
+		for _, key := range v.keySet {
+			return key, nil
+		}
+	}
+
+	return nil, errors.New("token has no key id")
+}
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// loadKeySet reads the RSA signing keys of a JSON Web Key Set, by kid. Other keys are skipped.
+func loadKeySet(path string) (map[string]*rsa.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jwks: %w", err)
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err = json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != jwt.SigningMethodRS256.Alg()) {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwks key %q: %w", k.Kid, err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse jwks key %q: %w", k.Kid, err)
+		}
+		if len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("failed to parse jwks key %q: invalid exponent", k.Kid)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("jwks %s has no RSA signing keys", path)
+	}
+
+	return keys, nil
+}
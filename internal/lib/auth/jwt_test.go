@@ -0,0 +1,256 @@
+package auth_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"effective_mobile_test/internal/lib/auth"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const secret = "test-secret"
+
+func TestJWTVerifier(t *testing.T) {
+	rsaKey := generateKey(t)
+	jwksKey := generateKey(t)
+	otherKey := generateKey(t)
+
+	dir := t.TempDir()
+	pemFile := writePublicKey(t, dir, &rsaKey.PublicKey)
+	jwksFile := writeJWKS(t, dir, map[string]*rsa.PublicKey{"key-1": &jwksKey.PublicKey})
+
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{
+		HS256Secret:        secret,
+		RS256PublicKeyFile: pemFile,
+		JWKSFile:           jwksFile,
+		Issuer:             "https://issuer.example.com",
+		Audience:           "cars-catalog",
+	})
+	require.NoError(t, err)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"sub": "alice",
+			"iss": "https://issuer.example.com",
+			"aud": "cars-catalog",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		}
+	}
+	with := func(changes map[string]any) jwt.MapClaims {
+		claims := valid()
+		for k, v := range changes {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+
+		return claims
+	}
+
+	cases := []struct {
+		name    string
+		token   string
+		want    auth.Principal
+		wantErr bool
+	}{
+		{
+			name:  "HS256 defaults to viewer",
+			token: hs256(t, valid()),
+			want:  auth.Principal{Name: "alice", Method: auth.MethodJWT, Role: auth.RoleViewer},
+		},
+		{
+			name:  "role and tenant claims",
+			token: hs256(t, with(map[string]any{"role": auth.RoleEditor, "tenant": "fleet-north"})),
+			want:  auth.Principal{Name: "alice", Method: auth.MethodJWT, Role: auth.RoleEditor, Tenant: "fleet-north"},
+		},
+		{
+			name:  "multi-tenant claim",
+			token: hs256(t, with(map[string]any{"multi_tenant": true})),
+			want:  auth.Principal{Name: "alice", Method: auth.MethodJWT, Role: auth.RoleViewer, MultiTenant: true},
+		},
+		{
+			name:  "RS256 with the PEM key",
+			token: rs256(t, rsaKey, "", valid()),
+			want:  auth.Principal{Name: "alice", Method: auth.MethodJWT, Role: auth.RoleViewer},
+		},
+		{
+			name:  "RS256 with a key of the set",
+			token: rs256(t, jwksKey, "key-1", with(map[string]any{"role": auth.RoleAdmin})),
+			want:  auth.Principal{Name: "alice", Method: auth.MethodJWT, Role: auth.RoleAdmin},
+		},
+		{name: "unknown key id", token: rs256(t, jwksKey, "key-2", valid()), wantErr: true},
+		{name: "signed by another key", token: rs256(t, otherKey, "", valid()), wantErr: true},
+		{name: "wrong secret", token: sign(t, jwt.SigningMethodHS256, []byte("other"), valid()), wantErr: true},
+		{name: "unsigned", token: sign(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, valid()), wantErr: true},
+		{name: "HS384 is not accepted", token: sign(t, jwt.SigningMethodHS384, []byte(secret), valid()), wantErr: true},
+		{name: "expired", token: hs256(t, with(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})), wantErr: true},
+		{
+			name:  "expired within leeway",
+			token: hs256(t, with(map[string]any{"exp": time.Now().Add(-10 * time.Second).Unix()})),
+			want:  auth.Principal{Name: "alice", Method: auth.MethodJWT, Role: auth.RoleViewer},
+		},
+		{name: "without expiry", token: hs256(t, with(map[string]any{"exp": nil})), wantErr: true},
+		{name: "not yet valid", token: hs256(t, with(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})), wantErr: true},
+		{name: "other issuer", token: hs256(t, with(map[string]any{"iss": "https://evil.example.com"})), wantErr: true},
+		{name: "other audience", token: hs256(t, with(map[string]any{"aud": "other-service"})), wantErr: true},
+		{name: "without subject", token: hs256(t, with(map[string]any{"sub": nil})), wantErr: true},
+		{name: "unknown role", token: hs256(t, with(map[string]any{"role": "root"})), wantErr: true},
+		{name: "invalid tenant", token: hs256(t, with(map[string]any{"tenant": "*"})), wantErr: true},
+		{name: "tenant and multi-tenant", token: hs256(t, with(map[string]any{"tenant": "acme", "multi_tenant": true})), wantErr: true},
+		{name: "malformed", token: "not.a.token", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			principal, err := verifier.Verify(tc.token)
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, principal)
+		})
+	}
+}
+
+func TestJWTVerifierWithoutKeys(t *testing.T) {
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{})
+	require.NoError(t, err)
+
+	_, err = verifier.Verify(hs256(t, jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}))
+
+	assert.ErrorIs(t, err, auth.ErrJWTDisabled)
+}
+
+func TestJWTVerifierRejectsHS256WithoutSecret(t *testing.T) {
+	key := generateKey(t)
+	pemFile := writePublicKey(t, t.TempDir(), &key.PublicKey)
+
+	verifier, err := auth.NewJWTVerifier(auth.JWTConfig{RS256PublicKeyFile: pemFile})
+	require.NoError(t, err)
+
+	// the public key must not be usable as an HMAC secret
+	pemData, err := os.ReadFile(pemFile)
+	require.NoError(t, err)
+	token := sign(t, jwt.SigningMethodHS256, pemData, jwt.MapClaims{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()})
+
+	_, err = verifier.Verify(token)
+
+	assert.Error(t, err)
+}
+
+func TestNewJWTVerifierFailures(t *testing.T) {
+	dir := t.TempDir()
+
+	invalidPEM := filepath.Join(dir, "invalid.pem")
+	require.NoError(t, os.WriteFile(invalidPEM, []byte("not a key"), 0o600))
+
+	emptySet := filepath.Join(dir, "empty.json")
+	require.NoError(t, os.WriteFile(emptySet, []byte(`{"keys":[{"kty":"EC","kid":"ec"}]}`), 0o600))
+
+	cases := []struct {
+		name string
+		cfg  auth.JWTConfig
+	}{
+		{name: "missing public key file", cfg: auth.JWTConfig{RS256PublicKeyFile: filepath.Join(dir, "missing.pem")}},
+		{name: "invalid public key", cfg: auth.JWTConfig{RS256PublicKeyFile: invalidPEM}},
+		{name: "missing key set", cfg: auth.JWTConfig{JWKSFile: filepath.Join(dir, "missing.json")}},
+		{name: "key set without RSA keys", cfg: auth.JWTConfig{JWKSFile: emptySet}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := auth.NewJWTVerifier(tc.cfg)
+
+			assert.Error(t, err)
+		})
+	}
+}
+
+func generateKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	return key
+}
+
+func writePublicKey(t *testing.T, dir string, key *rsa.PublicKey) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKIXPublicKey(key)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "public.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	return path
+}
+
+func writeJWKS(t *testing.T, dir string, keys map[string]*rsa.PublicKey) string {
+	t.Helper()
+
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, map[string]string{
+			"kty": "RSA",
+			"kid": kid,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "jwks.json")
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	return path
+}
+
+func hs256(t *testing.T, claims jwt.MapClaims) string {
+	return sign(t, jwt.SigningMethodHS256, []byte(secret), claims)
+}
+
+func rs256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	signed, err := jwt.NewWithClaims(method, claims).SignedString(key)
+	require.NoError(t, err)
+
+	return signed
+}
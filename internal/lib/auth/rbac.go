@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"strings"
+)
+
+const (
+	// RoleViewer reads cars and owners
+	RoleViewer = "viewer"
+	// RoleEditor also creates and changes them
+	RoleEditor = "editor"
+	// RoleAdmin also deletes them, sees the deleted ones, chooses the tenant and manages webhooks and the audit log
+	RoleAdmin = "admin"
+)
+
+const (
+	PermCarRead       = "car:read"
+	PermCarWrite      = "car:write"
+	PermCarDelete     = "car:delete"
+	PermOwnerRead     = "owner:read"
+	PermOwnerWrite    = "owner:write"
+	PermOwnerDelete   = "owner:delete"
+	PermAdminAudit    = "admin:audit"
+	PermAdminWebhooks = "admin:webhooks"
+	PermAdminDeleted  = "admin:deleted"
+	PermAdminTenants  = "admin:tenants"
+)
+
+// grants holds the permissions of every role; a permission ending with * grants all
+// the permissions with the same prefix.
+var grants = map[string][]string{
+	RoleViewer: {PermCarRead, PermOwnerRead},
+	RoleEditor: {PermCarRead, PermCarWrite, PermOwnerRead, PermOwnerWrite},
+	RoleAdmin:  {"car:*", "owner:*", "admin:*"},
+}
+
+// ValidRole reports whether role is one of the known roles.
+func ValidRole(role string) bool {
+	_, ok := grants[role]
+
+	return ok
+}
+
+// Can reports whether the role of the principal grants the permission.
+func (p Principal) Can(permission string) bool {
+	for _, grant := range grants[p.Role] {
+		if grant == permission || strings.HasSuffix(grant, "*") && strings.HasPrefix(permission, strings.TrimSuffix(grant, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Can reports whether the request of ctx was authenticated with the permission.
+func Can(ctx context.Context, permission string) bool {
+	principal, ok := FromContext(ctx)
+
+	return ok && principal.Can(permission)
+}
@@ -0,0 +1,63 @@
+package auth_test
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/auth"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPrincipalCan(t *testing.T) {
+	cases := []struct {
+		role    string
+		allowed []string
+		denied  []string
+	}{
+		{
+			role:    auth.RoleViewer,
+			allowed: []string{auth.PermCarRead, auth.PermOwnerRead},
+			denied:  []string{auth.PermCarWrite, auth.PermOwnerWrite, auth.PermCarDelete, auth.PermAdminDeleted},
+		},
+		{
+			role:    auth.RoleEditor,
+			allowed: []string{auth.PermCarRead, auth.PermCarWrite, auth.PermOwnerRead, auth.PermOwnerWrite},
+			denied:  []string{auth.PermCarDelete, auth.PermOwnerDelete, auth.PermAdminAudit, auth.PermAdminWebhooks},
+		},
+		{
+			role: auth.RoleAdmin,
+			allowed: []string{auth.PermCarRead, auth.PermCarWrite, auth.PermCarDelete, auth.PermOwnerDelete,
+				auth.PermAdminAudit, auth.PermAdminWebhooks, auth.PermAdminDeleted},
+		},
+		{
+			role:   "root",
+			denied: []string{auth.PermCarRead},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.role, func(t *testing.T) {
+			principal := auth.Principal{Name: "alice", Role: tc.role}
+
+			for _, permission := range tc.allowed {
+				assert.True(t, principal.Can(permission), permission)
+			}
+			for _, permission := range tc.denied {
+				assert.False(t, principal.Can(permission), permission)
+			}
+		})
+	}
+}
+
+func TestCan(t *testing.T) {
+	ctx := auth.WithPrincipal(context.Background(), auth.Principal{Name: "alice", Role: auth.RoleAdmin})
+
+	assert.True(t, auth.Can(ctx, auth.PermAdminDeleted))
+	// requests without a principal are granted nothing
+	assert.False(t, auth.Can(context.Background(), auth.PermCarRead))
+}
+
+func TestValidRole(t *testing.T) {
+	assert.True(t, auth.ValidRole(auth.RoleEditor))
+	assert.False(t, auth.ValidRole(""))
+	assert.False(t, auth.ValidRole("Admin"))
+}
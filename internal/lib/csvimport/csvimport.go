@@ -0,0 +1,201 @@
+package csvimport
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// setter stores the value of a column in the car.
+type setter func(car *postgres.Car, value string) error
+
+// columns are the imported columns, named the way the export names them.
+var columns = map[string]setter{
+	"regNum": func(c *postgres.Car, v string) error { c.RegNum = v; return nil },
+	"mark":   func(c *postgres.Car, v string) error { c.Mark = v; return nil },
+	"model":  func(c *postgres.Car, v string) error { c.Model = v; return nil },
+	"year": func(c *postgres.Car, v string) error {
+		year, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New("field year is not valid")
+		}
+		c.Year = year
+		return nil
+	},
+	"ownerName":           func(c *postgres.Car, v string) error { c.Owner.Name = v; return nil },
+	"ownerSurname":        func(c *postgres.Car, v string) error { c.Owner.Surname = v; return nil },
+	"ownerPatronymic":     func(c *postgres.Car, v string) error { c.Owner.Patronymic = optional(v); return nil },
+	"ownerBirthDate":      func(c *postgres.Car, v string) error { c.Owner.BirthDate = optional(v); return nil },
+	"ownerPhone":          func(c *postgres.Car, v string) error { c.Owner.Phone = optional(v); return nil },
+	"ownerEmail":          func(c *postgres.Car, v string) error { c.Owner.Email = optional(v); return nil },
+	"ownerDocumentNumber": func(c *postgres.Car, v string) error { c.Owner.DocumentNumber = optional(v); return nil },
+}
+
+// ignored are the exported columns that are assigned by the catalog, so an exported file can be imported.
+var ignored = map[string]bool{"id": true, "version": true, "deletedAt": true, "ownerId": true}
+
+// ErrInvalidFile is returned when the file as a whole can't be imported, e.g. because of a bad header.
+var ErrInvalidFile = errors.New("invalid file")
+
+// Required are the columns every imported file must have.
+var Required = []string{"regNum", "mark", "model", "year", "ownerName", "ownerSurname"}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=CarImporter
+type CarImporter interface {
+	ImportCars(ctx context.Context, rows []postgres.ImportRow, opts postgres.ImportOptions) (postgres.ImportResult, error)
+}
+
+// Import parses the CSV read from r and imports the valid rows with carImporter.
+// The errors of both the parsing and the import are returned in the result ordered by line.
+// Unless opts.SkipInvalid is set, nothing is imported if any row is invalid.
+func Import(ctx context.Context, carImporter CarImporter, r io.Reader, opts postgres.ImportOptions) (postgres.ImportResult, error) {
+	rows, errs, err := Parse(r)
+	if err != nil {
+		return postgres.ImportResult{}, err
+	}
+
+	if len(errs) > 0 && !opts.SkipInvalid {
+		// the rows that parsed still get checked against the catalog
+		opts.DryRun = true
+	}
+
+	result := postgres.ImportResult{Errors: []postgres.ImportError{}}
+	if len(rows) > 0 {
+		result, err = carImporter.ImportCars(ctx, rows, opts)
+		if err != nil {
+			return postgres.ImportResult{}, err
+		}
+	}
+
+	result.Errors = append(result.Errors, errs...)
+	sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+
+	if len(result.Errors) > 0 && !opts.SkipInvalid {
+		result.Imported = 0
+	}
+
+	return result, nil
+}
+
+// Parse reads cars from CSV with a header row naming the columns.
+// It returns the valid rows and the errors of the invalid ones; the error is
+// returned only when the file can't be read at all.
+// A regNum repeated in the file is an error on every line but the first one.
+func Parse(r io.Reader) ([]postgres.ImportRow, []postgres.ImportError, error) {
+	reader := csv.NewReader(r)
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, fmt.Errorf("%w: file is empty", ErrInvalidFile)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: failed to read header: %w", ErrInvalidFile, err)
+	}
+
+	setters, err := headerSetters(header)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %w", ErrInvalidFile, err)
+	}
+
+	rows := []postgres.ImportRow{}
+	errs := []postgres.ImportError{}
+	firstSeen := make(map[string]int)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			errs = append(errs, postgres.ImportError{Line: parseErr.StartLine, Error: parseErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		car, err := parseCar(setters, record)
+		if err != nil {
+			errs = append(errs, postgres.ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+
+		if first, ok := firstSeen[car.RegNum]; ok {
+			errs = append(errs, postgres.ImportError{Line: line, Error: fmt.Sprintf("duplicate regNum, first seen on line %d", first)})
+			continue
+		}
+		firstSeen[car.RegNum] = line
+
+		rows = append(rows, postgres.ImportRow{Line: line, Car: car})
+	}
+
+	return rows, errs, nil
+}
+
+// headerSetters returns the setter of every column of the header, nil for ignored columns.
+func headerSetters(header []string) ([]setter, error) {
+	setters := make([]setter, len(header))
+	seen := make(map[string]bool, len(header))
+	for i, name := range header {
+		// spreadsheet programs like to start the file with a byte order mark
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate column %q", name)
+		}
+		seen[name] = true
+
+		if ignored[name] {
+			continue
+		}
+		set, ok := columns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		setters[i] = set
+	}
+
+	for _, name := range Required {
+		if !seen[name] {
+			return nil, fmt.Errorf("missing column %q", name)
+		}
+	}
+
+	return setters, nil
+}
+
+// parseCar builds a car from a record and validates it.
+func parseCar(setters []setter, record []string) (postgres.Car, error) {
+	var car postgres.Car
+	for i, set := range setters {
+		if set == nil {
+			continue
+		}
+		if err := set(&car, strings.TrimSpace(record[i])); err != nil {
+			return postgres.Car{}, err
+		}
+	}
+
+	if ok, _, msg := validate.Car(car, ""); !ok {
+		return postgres.Car{}, errors.New(msg)
+	}
+
+	return car, nil
+}
+
+func optional(v string) *string {
+	if v == "" {
+		return nil
+	}
+
+	return &v
+}
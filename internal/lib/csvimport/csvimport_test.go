@@ -0,0 +1,130 @@
+package csvimport_test
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/csvimport"
+	"effective_mobile_test/internal/lib/csvimport/mocks"
+	"effective_mobile_test/internal/storage/postgres"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const header = "regNum,mark,model,year,ownerName,ownerSurname\n"
+
+func TestParse(t *testing.T) {
+	t.Run("valid rows with their lines", func(t *testing.T) {
+		rows, errs, err := csvimport.Parse(strings.NewReader("\ufeffid,regNum,mark,model,year,ownerName,ownerSurname,ownerPatronymic\n" +
+			"1,X123XX150,Lada,Vesta,2002,Ivan,Ivanov,Ivanovich\n" +
+			"2, X124XX150 ,Lada,Granta,2015,Petr,Petrov,\n"))
+		require.NoError(t, err)
+
+		assert.Empty(t, errs)
+		require.Len(t, rows, 2)
+
+		assert.Equal(t, 2, rows[0].Line)
+		assert.Equal(t, "X123XX150", rows[0].Car.RegNum)
+		require.NotNil(t, rows[0].Car.Owner.Patronymic)
+		assert.Equal(t, "Ivanovich", *rows[0].Car.Owner.Patronymic)
+		// ids of an exported file are assigned by the catalog
+		assert.Zero(t, rows[0].Car.ID)
+
+		assert.Equal(t, 3, rows[1].Line)
+		assert.Equal(t, "X124XX150", rows[1].Car.RegNum)
+		assert.Nil(t, rows[1].Car.Owner.Patronymic)
+	})
+
+	t.Run("row errors with their lines", func(t *testing.T) {
+		rows, errs, err := csvimport.Parse(strings.NewReader(header +
+			"X123XX150,Lada,Vesta,2002,Ivan,Ivanov\n" +
+			"X124XX150,Lada,Vesta,two thousand,Ivan,Ivanov\n" +
+			"X125XX150,Lada,Vesta,1800,Ivan,Ivanov\n" +
+			"X126XX150,Lada,Vesta\n" +
+			"X123XX150,Lada,Vesta,2002,Ivan,Ivanov\n"))
+		require.NoError(t, err)
+
+		require.Len(t, rows, 1)
+		assert.Equal(t, []postgres.ImportError{
+			{Line: 3, Error: "field year is not valid"},
+			{Line: 4, Error: "field year is not valid"},
+			{Line: 5, Error: "wrong number of fields"},
+			{Line: 6, Error: "duplicate regNum, first seen on line 2"},
+		}, errs)
+	})
+
+	cases := []struct {
+		name    string
+		file    string
+		wantErr string
+	}{
+		{name: "empty file", file: "", wantErr: "invalid file: file is empty"},
+		{name: "missing column", file: "regNum,mark,model,year,ownerName\n", wantErr: `invalid file: missing column "ownerSurname"`},
+		{name: "unknown column", file: "regNum,price\n", wantErr: `invalid file: unknown column "price"`},
+		{name: "duplicate column", file: "regNum,regNum\n", wantErr: `invalid file: duplicate column "regNum"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := csvimport.Parse(strings.NewReader(tc.file))
+
+			assert.ErrorIs(t, err, csvimport.ErrInvalidFile)
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	file := header +
+		"X123XX150,Lada,Vesta,2002,Ivan,Ivanov\n" +
+		"X124XX150,Lada,Vesta,1800,Ivan,Ivanov\n" +
+		"X125XX150,Lada,Vesta,2002,Ivan,Ivanov\n"
+
+	// isRows matches the rows parsed from the valid lines of file
+	isRows := mock.MatchedBy(func(rows []postgres.ImportRow) bool {
+		return len(rows) == 2 && rows[0].Line == 2 && rows[1].Line == 4
+	})
+
+	t.Run("invalid rows turn the import into a dry run", func(t *testing.T) {
+		carImporter := mocks.NewCarImporter(t)
+		carImporter.On("ImportCars", mock.Anything, isRows, postgres.ImportOptions{DryRun: true}).
+			Return(postgres.ImportResult{Imported: 1, Errors: []postgres.ImportError{{Line: 4, Error: "car already exists"}}}, nil).
+			Once()
+
+		result, err := csvimport.Import(context.Background(), carImporter, strings.NewReader(file), postgres.ImportOptions{})
+		require.NoError(t, err)
+
+		assert.Zero(t, result.Imported)
+		// the errors of the catalog are merged with the parse errors by line
+		assert.Equal(t, []postgres.ImportError{
+			{Line: 3, Error: "field year is not valid"},
+			{Line: 4, Error: "car already exists"},
+		}, result.Errors)
+	})
+
+	t.Run("skip invalid imports the valid rows", func(t *testing.T) {
+		opts := postgres.ImportOptions{SkipInvalid: true}
+
+		carImporter := mocks.NewCarImporter(t)
+		carImporter.On("ImportCars", mock.Anything, isRows, opts).
+			Return(postgres.ImportResult{Imported: 2, Errors: []postgres.ImportError{}}, nil).Once()
+
+		result, err := csvimport.Import(context.Background(), carImporter, strings.NewReader(file), opts)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.Imported)
+		assert.Equal(t, []postgres.ImportError{{Line: 3, Error: "field year is not valid"}}, result.Errors)
+	})
+
+	t.Run("nothing valid to import", func(t *testing.T) {
+		carImporter := mocks.NewCarImporter(t)
+
+		result, err := csvimport.Import(context.Background(), carImporter,
+			strings.NewReader(header+"X123XX150,Lada,Vesta,1800,Ivan,Ivanov\n"), postgres.ImportOptions{})
+		require.NoError(t, err)
+
+		assert.Zero(t, result.Imported)
+		assert.Equal(t, []postgres.ImportError{{Line: 2, Error: "field year is not valid"}}, result.Errors)
+	})
+}
@@ -0,0 +1,58 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// CarImporter is an autogenerated mock type for the CarImporter type
+type CarImporter struct {
+	mock.Mock
+}
+
+// ImportCars provides a mock function with given fields: ctx, rows, opts
+func (_m *CarImporter) ImportCars(ctx context.Context, rows []postgres.ImportRow, opts postgres.ImportOptions) (postgres.ImportResult, error) {
+	ret := _m.Called(ctx, rows, opts)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ImportCars")
+	}
+
+	var r0 postgres.ImportResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []postgres.ImportRow, postgres.ImportOptions) (postgres.ImportResult, error)); ok {
+		return rf(ctx, rows, opts)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []postgres.ImportRow, postgres.ImportOptions) postgres.ImportResult); ok {
+		r0 = rf(ctx, rows, opts)
+	} else {
+		r0 = ret.Get(0).(postgres.ImportResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []postgres.ImportRow, postgres.ImportOptions) error); ok {
+		r1 = rf(ctx, rows, opts)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewCarImporter creates a new instance of CarImporter. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewCarImporter(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *CarImporter {
+	mock := &CarImporter{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,94 @@
+package export
+
+import (
+	"effective_mobile_test/internal/storage/postgres"
+	"fmt"
+	"time"
+)
+
+// Column is an exported field of a car.
+type Column struct {
+	Name  string
+	Value func(car postgres.Car) any
+}
+
+var columns = []Column{
+	{"id", func(c postgres.Car) any { return c.ID }},
+	{"regNum", func(c postgres.Car) any { return c.RegNum }},
+	{"mark", func(c postgres.Car) any { return c.Mark }},
+	{"model", func(c postgres.Car) any { return c.Model }},
+	{"year", func(c postgres.Car) any { return c.Year }},
+	{"version", func(c postgres.Car) any { return c.Version }},
+	{"deletedAt", func(c postgres.Car) any { return optionalTime(c.DeletedAt) }},
+	{"ownerId", func(c postgres.Car) any { return c.Owner.ID }},
+	{"ownerName", func(c postgres.Car) any { return c.Owner.Name }},
+	{"ownerSurname", func(c postgres.Car) any { return c.Owner.Surname }},
+	{"ownerPatronymic", func(c postgres.Car) any { return optional(c.Owner.Patronymic) }},
+	{"ownerBirthDate", func(c postgres.Car) any { return optional(c.Owner.BirthDate) }},
+	{"ownerPhone", func(c postgres.Car) any { return optional(c.Owner.Phone) }},
+	{"ownerEmail", func(c postgres.Car) any { return optional(c.Owner.Email) }},
+	{"ownerDocumentNumber", func(c postgres.Car) any { return optional(c.Owner.DocumentNumber) }},
+}
+
+// DefaultColumns are exported when no columns are requested.
+var DefaultColumns = []string{"id", "regNum", "mark", "model", "year", "ownerName", "ownerSurname", "ownerPatronymic"}
+
+// Columns returns the columns with the given names in the given order.
+func Columns(names []string) ([]Column, error) {
+	result := make([]Column, 0, len(names))
+	for _, name := range names {
+		column, ok := columnByName(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown column %q", name)
+		}
+		result = append(result, column)
+	}
+
+	return result, nil
+}
+
+func columnByName(name string) (Column, bool) {
+	for _, column := range columns {
+		if column.Name == name {
+			return column, true
+		}
+	}
+
+	return Column{}, false
+}
+
+// Header returns the names of the columns.
+func Header(columns []Column) []any {
+	header := make([]any, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+
+	return header
+}
+
+// Row returns the values of the columns for the car.
+func Row(columns []Column, car postgres.Car) []any {
+	row := make([]any, len(columns))
+	for i, column := range columns {
+		row[i] = column.Value(car)
+	}
+
+	return row
+}
+
+func optional(v *string) string {
+	if v == nil {
+		return ""
+	}
+
+	return *v
+}
+
+func optionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return t.Format(time.RFC3339)
+}
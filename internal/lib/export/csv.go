@@ -0,0 +1,37 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+// CSV writes rows as comma-separated values.
+type CSV struct {
+	w   *csv.Writer
+	row []string
+}
+
+func NewCSV(w io.Writer) *CSV {
+	return &CSV{w: csv.NewWriter(w)}
+}
+
+func (c *CSV) WriteRow(values []any) error {
+	c.row = c.row[:0]
+	for _, v := range values {
+		c.row = append(c.row, fmt.Sprint(v))
+	}
+
+	return c.w.Write(c.row)
+}
+
+// Flush writes the buffered rows to the underlying writer.
+func (c *CSV) Flush() error {
+	c.w.Flush()
+
+	return c.w.Error()
+}
+
+func (c *CSV) Close() error {
+	return c.Flush()
+}
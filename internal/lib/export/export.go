@@ -0,0 +1,40 @@
+package export
+
+import (
+	"fmt"
+	"io"
+)
+
+// Supported formats.
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Writer writes rows of a table as they come. Values are strings or ints.
+type Writer interface {
+	WriteRow(values []any) error
+	// Close writes whatever the format needs after the last row; it doesn't close the underlying writer
+	Close() error
+}
+
+// NewWriter returns a Writer of the given format writing to w.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSV(w), nil
+	case FormatXLSX:
+		return NewXLSX(w, "Cars")
+	default:
+		return nil, fmt.Errorf("unknown format %q", format)
+	}
+}
+
+// ContentType returns the MIME type of the format.
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+
+	return "text/csv; charset=utf-8"
+}
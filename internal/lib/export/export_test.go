@@ -0,0 +1,156 @@
+package export_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"effective_mobile_test/internal/lib/export"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestColumns(t *testing.T) {
+	patronymic := "Ivanovich"
+	deletedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	car := postgres.Car{
+		ID: 5, RegNum: "X123XX150", Mark: "Lada", Model: "Vesta", Year: 2002, Version: 3, DeletedAt: &deletedAt,
+		Owner: postgres.Owner{ID: 7, Name: "Ivan", Surname: "Ivanov", Patronymic: &patronymic},
+	}
+
+	t.Run("values in the requested order", func(t *testing.T) {
+		columns, err := export.Columns([]string{"ownerPatronymic", "id", "deletedAt", "ownerEmail", "year"})
+		require.NoError(t, err)
+
+		assert.Equal(t, []any{"ownerPatronymic", "id", "deletedAt", "ownerEmail", "year"}, export.Header(columns))
+		// missing optional fields are empty strings
+		assert.Equal(t, []any{"Ivanovich", 5, "2024-03-01T12:00:00Z", "", 2002}, export.Row(columns, car))
+	})
+
+	t.Run("default columns are known", func(t *testing.T) {
+		columns, err := export.Columns(export.DefaultColumns)
+		require.NoError(t, err)
+
+		assert.Len(t, columns, len(export.DefaultColumns))
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		_, err := export.Columns([]string{"id", "price"})
+		assert.EqualError(t, err, `unknown column "price"`)
+	})
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := export.NewWriter(export.FormatCSV, &buf)
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteRow([]any{"id", "mark"}))
+	require.NoError(t, w.WriteRow([]any{5, `Lada "Vesta", 2002`}))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "id,mark\n5,\"Lada \"\"Vesta\"\", 2002\"\n", buf.String())
+}
+
+// sheet is the part of a worksheet read back by TestXLSX.
+type sheet struct {
+	Rows []struct {
+		Ref   string `xml:"r,attr"`
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline string `xml:"is>t"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readSheet checks that the workbook holds all of its parts and returns its sheet.
+func readSheet(t *testing.T, workbook []byte) sheet {
+	t.Helper()
+
+	z, err := zip.NewReader(bytes.NewReader(workbook), int64(len(workbook)))
+	require.NoError(t, err)
+
+	parts := make(map[string]*zip.File)
+	for _, f := range z.File {
+		parts[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/_rels/workbook.xml.rels", "xl/workbook.xml"} {
+		assert.Contains(t, parts, name)
+	}
+	require.Contains(t, parts, "xl/worksheets/sheet1.xml")
+
+	f, err := parts["xl/worksheets/sheet1.xml"].Open()
+	require.NoError(t, err)
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	require.NoError(t, err)
+
+	var s sheet
+	require.NoError(t, xml.Unmarshal(content, &s))
+
+	return s
+}
+
+func TestXLSX(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := export.NewWriter(export.FormatXLSX, &buf)
+	require.NoError(t, err)
+
+	require.NoError(t, w.WriteRow([]any{"id", "mark"}))
+	require.NoError(t, w.WriteRow([]any{5, "Lada & <Kia>"}))
+	require.NoError(t, w.Close())
+
+	s := readSheet(t, buf.Bytes())
+
+	require.Len(t, s.Rows, 2)
+	assert.Equal(t, "2", s.Rows[1].Ref)
+
+	require.Len(t, s.Rows[1].Cells, 2)
+	assert.Equal(t, "A2", s.Rows[1].Cells[0].Ref)
+	assert.Equal(t, "5", s.Rows[1].Cells[0].Value)
+	assert.Equal(t, "B2", s.Rows[1].Cells[1].Ref)
+	assert.Equal(t, "inlineStr", s.Rows[1].Cells[1].Type)
+	assert.Equal(t, "Lada & <Kia>", s.Rows[1].Cells[1].Inline)
+}
+
+func TestXLSXColumnNames(t *testing.T) {
+	var buf bytes.Buffer
+
+	w, err := export.NewXLSX(&buf, "Cars")
+	require.NoError(t, err)
+
+	row := make([]any, 28)
+	for i := range row {
+		row[i] = i
+	}
+	require.NoError(t, w.WriteRow(row))
+	require.NoError(t, w.Close())
+
+	s := readSheet(t, buf.Bytes())
+
+	require.Len(t, s.Rows, 1)
+	require.Len(t, s.Rows[0].Cells, 28)
+	for i, want := range map[int]string{0: "A1", 25: "Z1", 26: "AA1", 27: "AB1"} {
+		assert.Equal(t, want, s.Rows[0].Cells[i].Ref)
+	}
+}
+
+func TestXLSXUnsupportedValue(t *testing.T) {
+	w, err := export.NewXLSX(io.Discard, "Cars")
+	require.NoError(t, err)
+
+	assert.EqualError(t, w.WriteRow([]any{1.5}), "unsupported value of type float64")
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	_, err := export.NewWriter("ods", io.Discard)
+	assert.EqualError(t, err, `unknown format "ods"`)
+}
@@ -0,0 +1,17 @@
+package health
+
+import "sync/atomic"
+
+// State tells whether the service accepts traffic. It starts not ready.
+type State struct {
+	ready atomic.Bool
+}
+
+// SetReady marks the service as ready to accept traffic, or not, e.g. while it shuts down.
+func (s *State) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+func (s *State) Ready() bool {
+	return s.ready.Load()
+}
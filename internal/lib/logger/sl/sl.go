@@ -1,6 +1,10 @@
 package sl
 
-import "log/slog"
+import (
+	"context"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+)
 
 func Err(err error) slog.Attr {
 	return slog.Attr{
@@ -8,3 +12,14 @@ func Err(err error) slog.Attr {
 		Value: slog.StringValue(err.Error()),
 	}
 }
+
+// TraceID returns the id of the trace the span in ctx belongs to, or an empty attribute,
+// which log handlers skip, if there is no span.
+func TraceID(ctx context.Context) slog.Attr {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return slog.Attr{}
+	}
+
+	return slog.String("trace_id", spanContext.TraceID().String())
+}
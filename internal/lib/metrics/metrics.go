@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"database/sql"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const namespace = "cars_catalog"
+
+// Outcomes of the calls to the info API.
+const (
+	OutcomeOK          = "ok"
+	OutcomeTimeout     = "timeout"
+	OutcomeConnError   = "conn_error"
+	OutcomeServerError = "server_error"
+	OutcomeBadRequest  = "bad_request"
+	OutcomeBadResponse = "bad_response"
+)
+
+var (
+	// HTTPRequestDuration observes the handled requests by method, chi route pattern and status.
+	HTTPRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the handled HTTP requests.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// InfoRequestDuration observes the calls to the info API by outcome.
+	InfoRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "info_api",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of the calls to the info API.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	// CarsSaved counts the cars saved with POST /car/save.
+	CarsSaved = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cars_saved_total",
+		Help:      "Cars saved with the details found by the info API.",
+	})
+
+	// EnrichmentFailures counts the saves that failed because the info API found no details.
+	EnrichmentFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "car_enrichment_failures_total",
+		Help:      "Car saves that failed because the details of the cars couldn't be fetched from the info API.",
+	})
+)
+
+// RegisterDBStats exposes the connection pool statistics returned by stats.
+func RegisterDBStats(stats func() sql.DBStats) {
+	gauge := func(name, help string, value func(s sql.DBStats) float64) {
+		promauto.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "db", Name: name, Help: help,
+		}, func() float64 { return value(stats()) })
+	}
+	counter := func(name, help string, value func(s sql.DBStats) float64) {
+		promauto.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "db", Name: name, Help: help,
+		}, func() float64 { return value(stats()) })
+	}
+
+	gauge("max_open_connections", "Maximum number of open connections to the database.",
+		func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) })
+	gauge("open_connections", "Number of established connections, both in use and idle.",
+		func(s sql.DBStats) float64 { return float64(s.OpenConnections) })
+	gauge("in_use_connections", "Number of connections currently in use.",
+		func(s sql.DBStats) float64 { return float64(s.InUse) })
+	gauge("idle_connections", "Number of idle connections.",
+		func(s sql.DBStats) float64 { return float64(s.Idle) })
+	counter("wait_count_total", "Total number of connections waited for.",
+		func(s sql.DBStats) float64 { return float64(s.WaitCount) })
+	counter("wait_duration_seconds_total", "Total time blocked waiting for a new connection.",
+		func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() })
+	counter("max_idle_closed_total", "Total number of connections closed due to SetMaxIdleConns.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) })
+	counter("max_idle_time_closed_total", "Total number of connections closed due to SetConnMaxIdleTime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) })
+	counter("max_lifetime_closed_total", "Total number of connections closed due to SetConnMaxLifetime.",
+		func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) })
+}
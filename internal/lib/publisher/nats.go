@@ -0,0 +1,63 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"strconv"
+	"time"
+)
+
+// NATS publishes messages to NATS JetStream, so a stream has to capture their subjects, e.g. catalog.>.
+// Every message carries a Nats-Msg-Id header with its id, which lets the stream drop the duplicates
+// of a message published again, and a Catalog-Key header with its key. Publish returns only when
+// the stream has acknowledged the message, so an accepted message was stored by the server.
+type NATS struct {
+	conn    *nats.Conn
+	js      jetstream.JetStream
+	timeout time.Duration
+}
+
+// NewNATS returns a publisher to the server at url. It connects in the background and reconnects
+// after failures, so the server may be unavailable when the publisher is created.
+func NewNATS(url string, timeout time.Duration) (*NATS, error) {
+	conn, err := nats.Connect(url, nats.Name("cars-catalog"), nats.Timeout(timeout),
+		nats.RetryOnFailedConnect(true), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+
+		return nil, fmt.Errorf("failed to connect to nats: %w", err)
+	}
+
+	return &NATS{conn: conn, js: js, timeout: timeout}, nil
+}
+
+func (n *NATS) Publish(ctx context.Context, msg Message) error {
+	ctx, cancel := context.WithTimeout(ctx, n.timeout)
+	defer cancel()
+
+	m := &nats.Msg{
+		Subject: msg.Subject,
+		Header:  nats.Header{"Catalog-Key": []string{msg.Key}},
+		Data:    msg.Payload,
+	}
+
+	// a duplicate is acknowledged too: the stream has the message already
+	if _, err := n.js.PublishMsg(ctx, m, jetstream.WithMsgID(strconv.FormatInt(msg.ID, 10))); err != nil {
+		return fmt.Errorf("failed to publish to nats: %w", err)
+	}
+
+	return nil
+}
+
+func (n *NATS) Close() error {
+	n.conn.Close()
+
+	return nil
+}
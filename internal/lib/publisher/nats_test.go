@@ -0,0 +1,140 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// runNATSServer runs an in-process NATS server with JetStream on the given port, a free one if it is -1.
+func runNATSServer(t *testing.T, port int) *server.Server {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host: "127.0.0.1", Port: port, JetStream: true, StoreDir: t.TempDir(), NoLog: true, NoSigs: true,
+	})
+	require.NoError(t, err)
+
+	go srv.Start()
+	t.Cleanup(srv.Shutdown)
+	require.True(t, srv.ReadyForConnections(5*time.Second), "nats server is not ready")
+
+	return srv
+}
+
+// createStream creates a stream capturing the catalog subjects on the server.
+func createStream(t *testing.T, srv *server.Server) jetstream.Stream {
+	t.Helper()
+
+	conn, err := nats.Connect(srv.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(conn.Close)
+
+	js, err := jetstream.New(conn)
+	require.NoError(t, err)
+
+	stream, err := js.CreateStream(context.Background(), jetstream.StreamConfig{Name: "CATALOG", Subjects: []string{"catalog.>"}})
+	require.NoError(t, err)
+
+	return stream
+}
+
+func TestNATSPublish(t *testing.T) {
+	srv := runNATSServer(t, -1)
+	stream := createStream(t, srv)
+
+	pub, err := NewNATS(srv.ClientURL(), 5*time.Second)
+	require.NoError(t, err)
+	defer pub.Close()
+
+	messages := []Message{
+		{ID: 1, Subject: "catalog.car.created", Key: "car.7", Payload: []byte(`{"id":1}`)},
+		{ID: 2, Subject: "catalog.car.updated", Key: "car.7", Payload: []byte(`{"id":2}`)},
+		{ID: 3, Subject: "catalog.owner.deleted", Key: "owner.3", Payload: []byte(`{"id":3}`)},
+	}
+	for _, msg := range messages {
+		require.NoError(t, pub.Publish(context.Background(), msg))
+	}
+	// a message relayed again after a lost acknowledgement is dropped by the stream
+	require.NoError(t, pub.Publish(context.Background(), messages[1]))
+
+	// Publish returns once the stream has stored the message
+	info, err := stream.Info(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(len(messages)), info.State.Msgs)
+
+	for i, msg := range messages {
+		stored, err := stream.GetMsg(context.Background(), uint64(i+1))
+		require.NoError(t, err)
+
+		assert.Equal(t, msg.Subject, stored.Subject)
+		assert.Equal(t, strconv.FormatInt(msg.ID, 10), stored.Header.Get(jetstream.MsgIDHeader))
+		assert.Equal(t, msg.Key, stored.Header.Get("Catalog-Key"))
+		assert.Equal(t, string(msg.Payload), string(stored.Data))
+	}
+}
+
+func TestNATSPublishFailures(t *testing.T) {
+	t.Run("no stream captures the subject", func(t *testing.T) {
+		srv := runNATSServer(t, -1)
+
+		pub, err := NewNATS(srv.ClientURL(), 5*time.Second)
+		require.NoError(t, err)
+		defer pub.Close()
+
+		err = pub.Publish(context.Background(), Message{ID: 1, Subject: "catalog.car.created", Key: "car.1"})
+		assert.ErrorIs(t, err, jetstream.ErrNoStreamResponse)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		srv := runNATSServer(t, -1)
+		stream := createStream(t, srv)
+
+		pub, err := NewNATS(srv.ClientURL(), 5*time.Second)
+		require.NoError(t, err)
+		defer pub.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.Error(t, pub.Publish(ctx, Message{ID: 1, Subject: "catalog.car.created", Key: "car.1"}))
+
+		info, err := stream.Info(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, info.State.Msgs)
+	})
+}
+
+func TestNATSConnectsInBackground(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+	require.NoError(t, ln.Close())
+
+	// the server is down when the publisher is created, so messages are not acknowledged
+	pub, err := NewNATS(fmt.Sprintf("nats://127.0.0.1:%d", port), 200*time.Millisecond)
+	require.NoError(t, err)
+	defer pub.Close()
+
+	msg := Message{ID: 1, Subject: "catalog.car.created", Key: "car.1", Payload: []byte(`{"id":1}`)}
+	require.Error(t, pub.Publish(context.Background(), msg))
+
+	stream := createStream(t, runNATSServer(t, port))
+
+	// the relay publishes the message again until the server is back
+	require.Eventually(t, func() bool {
+		return pub.Publish(context.Background(), msg) == nil
+	}, 10*time.Second, 100*time.Millisecond)
+
+	info, err := stream.Info(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), info.State.Msgs)
+}
@@ -0,0 +1,64 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+)
+
+// Message is an event published to a message broker.
+type Message struct {
+	// ID identifies the message, so that consumers can drop the duplicates of at-least-once delivery
+	ID int64
+	// Subject is the topic of the message, e.g. catalog.car.updated
+	Subject string
+	// Key is the entity the message is about, e.g. car.42; messages with the same key are published in order
+	Key     string
+	Payload []byte
+}
+
+// Publisher sends messages to a broker. Publish returns only when the broker has accepted the message.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Publisher
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// New returns the publisher configured by rawURL:
+//
+//	stderr                    writes messages to the standard error, one JSON object per line
+//	file:///path/events.jsonl appends messages to a file the same way
+//	nats://host:4222          publishes messages to NATS JetStream
+//
+// The standard output is rejected: it carries the logs, which would be mixed with the messages.
+func New(rawURL string, timeout time.Duration) (Publisher, error) {
+	switch rawURL {
+	case "stderr":
+		return NewWriter(os.Stderr), nil
+	case "stdout":
+		return nil, errors.New("publisher stdout is not supported as it carries the logs, use stderr")
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid publisher url: %w", err)
+	}
+
+	switch u.Scheme {
+	case "file":
+		f, err := os.OpenFile(u.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewWriter(f), nil
+	case "nats":
+		return NewNATS(rawURL, timeout)
+	default:
+		return nil, fmt.Errorf("unknown publisher %q", rawURL)
+	}
+}
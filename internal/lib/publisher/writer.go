@@ -0,0 +1,59 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+)
+
+// Writer publishes messages as JSON lines to an io.Writer, e.g. a file or the standard error.
+type Writer struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+type line struct {
+	ID      int64           `json:"id"`
+	Subject string          `json:"subject"`
+	Key     string          `json:"key"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w, enc: json.NewEncoder(w)}
+}
+
+func (p *Writer) Publish(_ context.Context, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.enc.Encode(line{msg.ID, msg.Subject, msg.Key, msg.Payload}); err != nil {
+		return err
+	}
+
+	// a file is synced, so that a message is on disk before it leaves the outbox
+	if f, ok := p.w.(*os.File); ok && !isStdStream(f) {
+		return f.Sync()
+	}
+
+	return nil
+}
+
+// Close closes the underlying writer if it is a closer other than the standard streams.
+func (p *Writer) Close() error {
+	if f, ok := p.w.(*os.File); ok && isStdStream(f) {
+		return nil
+	}
+	if c, ok := p.w.(io.Closer); ok {
+		return c.Close()
+	}
+
+	return nil
+}
+
+func isStdStream(f *os.File) bool {
+	return f == os.Stdout || f == os.Stderr
+}
@@ -0,0 +1,80 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWriterPublish(t *testing.T) {
+	var buf bytes.Buffer
+	pub := NewWriter(&buf)
+
+	require.NoError(t, pub.Publish(context.Background(), Message{ID: 1, Subject: "catalog.car.created", Key: "car.7", Payload: []byte(`{"id":7}`)}))
+	require.NoError(t, pub.Publish(context.Background(), Message{ID: 2, Subject: "catalog.car.deleted", Key: "car.7", Payload: []byte(`{"id":7}`)}))
+
+	assert.Equal(t, `{"id":1,"subject":"catalog.car.created","key":"car.7","payload":{"id":7}}`+"\n"+
+		`{"id":2,"subject":"catalog.car.deleted","key":"car.7","payload":{"id":7}}`+"\n", buf.String())
+}
+
+func TestNew(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+
+	cases := []struct {
+		name    string
+		url     string
+		wantErr bool
+		check   func(t *testing.T, pub Publisher)
+	}{
+		{
+			name: "standard error, leaving the standard output to the logs",
+			url:  "stderr",
+			check: func(t *testing.T, pub Publisher) {
+				assert.Same(t, os.Stderr, pub.(*Writer).w)
+			},
+		},
+		{name: "standard output carrying the logs", url: "stdout", wantErr: true},
+		{
+			name: "file",
+			url:  "file://" + path,
+			check: func(t *testing.T, pub Publisher) {
+				require.NoError(t, pub.Publish(context.Background(), Message{ID: 1, Subject: "catalog.car.created", Key: "car.1", Payload: []byte("{}")}))
+				require.NoError(t, pub.Close())
+
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				assert.Contains(t, string(data), `"subject":"catalog.car.created"`)
+			},
+		},
+		{
+			name: "nats",
+			url:  "nats://localhost:4222",
+			check: func(t *testing.T, pub Publisher) {
+				// the server is connected to in the background
+				assert.IsType(t, &NATS{}, pub)
+				require.NoError(t, pub.Close())
+			},
+		},
+		{name: "unknown scheme", url: "kafka://localhost:9092", wantErr: true},
+		{name: "invalid url", url: "nats://%zz", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			pub, err := New(tc.url, time.Second)
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+
+			tc.check(t, pub)
+		})
+	}
+}
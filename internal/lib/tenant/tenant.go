@@ -0,0 +1,36 @@
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Default is the tenant of requests that don't choose one, and of the data created before tenants.
+const Default = "default"
+
+// All gives access to the data of every tenant. It is meant for background jobs of the service
+// and is never accepted from clients.
+const All = "*"
+
+var idPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type ctxKey struct{}
+
+// WithTenant returns a copy of ctx carrying the tenant whose data is accessed with it.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, ctxKey{}, tenant)
+}
+
+// FromContext returns the tenant stored in ctx, or Default if there is none.
+func FromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(ctxKey{}).(string); ok && tenant != "" {
+		return tenant
+	}
+
+	return Default
+}
+
+// Valid reports whether id can name a tenant: lowercase letters, digits, _ and -, up to 64 characters.
+func Valid(id string) bool {
+	return idPattern.MatchString(id)
+}
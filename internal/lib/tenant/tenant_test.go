@@ -0,0 +1,37 @@
+package tenant_test
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, tenant.Default, tenant.FromContext(context.Background()))
+	assert.Equal(t, tenant.Default, tenant.FromContext(tenant.WithTenant(context.Background(), "")))
+	assert.Equal(t, "acme", tenant.FromContext(tenant.WithTenant(context.Background(), "acme")))
+}
+
+func TestValid(t *testing.T) {
+	cases := []struct {
+		id   string
+		want bool
+	}{
+		{id: "acme", want: true},
+		{id: "fleet-north_2", want: true},
+		{id: strings.Repeat("a", 64), want: true},
+		{id: strings.Repeat("a", 65)},
+		{id: ""},
+		{id: "Acme"},
+		{id: "-acme"},
+		{id: tenant.All},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.id, func(t *testing.T) {
+			assert.Equal(t, tc.want, tenant.Valid(tc.id))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package tracing
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSetup(t *testing.T) {
+	cases := []struct {
+		name     string
+		exporter string
+		wantErr  bool
+	}{
+		{name: "default", exporter: ""},
+		{name: "none", exporter: ExporterNone},
+		{name: "stdout", exporter: ExporterStdout},
+		{name: "unknown", exporter: "zipkin", wantErr: true},
+	}
+
+	for _, tc := range cases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tc.exporter)
+			if tc.wantErr {
+				assert.ErrorContains(t, err, `unknown trace exporter "zipkin"`)
+				return
+			}
+			require.NoError(t, err)
+
+			assert.NoError(t, shutdown(context.Background()))
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package validate
+
+import (
+	"effective_mobile_test/internal/storage/postgres"
+	"log/slog"
+)
+
+const (
+	maxRegNumLen = 255
+	minYear      = 1900
+)
+
+// RegNum reports whether s fits the reg_num column.
+func RegNum(s string) bool {
+	return len(s) > 0 && len(s) <= maxRegNumLen
+}
+
+// Year reports whether year is a plausible manufacturing year.
+func Year(year int) bool {
+	return year >= minYear
+}
+
+// Car validates every field of car including its owner.
+// prefix is prepended to the field name in the returned attribute and message.
+func Car(car postgres.Car, prefix string) (bool, slog.Attr, string) {
+	if !RegNum(car.RegNum) {
+		return invalid(prefix + "regNum")
+	}
+	if len(car.Mark) < 1 {
+		return invalid(prefix + "mark")
+	}
+	if len(car.Model) < 1 {
+		return invalid(prefix + "model")
+	}
+	if !Year(car.Year) {
+		return invalid(prefix + "year")
+	}
+	return Owner(car.Owner, prefix+"owner.")
+}
+
+// CarPatch validates the fields present in patch.
+func CarPatch(patch postgres.CarPatch, prefix string) (bool, slog.Attr, string) {
+	if patch.RegNum != nil && !RegNum(*patch.RegNum) {
+		return invalid(prefix + "regNum")
+	}
+	if patch.Mark != nil && len(*patch.Mark) < 1 {
+		return invalid(prefix + "mark")
+	}
+	if patch.Model != nil && len(*patch.Model) < 1 {
+		return invalid(prefix + "model")
+	}
+	if patch.Year != nil && !Year(*patch.Year) {
+		return invalid(prefix + "year")
+	}
+	if patch.Owner != nil {
+		return Owner(*patch.Owner, prefix+"owner.")
+	}
+	return true, slog.Attr{}, ""
+}
@@ -0,0 +1,102 @@
+package validate
+
+import (
+	"effective_mobile_test/internal/storage/postgres"
+	"log/slog"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxNameLen  = 255
+	maxEmailLen = 254
+	dateLayout  = "2006-01-02"
+)
+
+var (
+	// phoneRe matches phone numbers in E.164 format
+	phoneRe          = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	documentNumberRe = regexp.MustCompile(`^[0-9A-Za-zА-Яа-яЁё][0-9A-Za-zА-Яа-яЁё -]{2,30}[0-9A-Za-zА-Яа-яЁё]$`)
+	minBirthDate     = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// Name reports whether s is a valid name or surname.
+func Name(s string) bool {
+	return strings.TrimSpace(s) != "" && utf8.RuneCountInString(s) <= maxNameLen
+}
+
+// Patronymic reports whether s is a valid patronymic. It is optional, so an empty one is valid.
+func Patronymic(s string) bool {
+	return utf8.RuneCountInString(s) <= maxNameLen
+}
+
+// BirthDate reports whether s is a date formatted as YYYY-MM-DD between 1900-01-01 and today.
+func BirthDate(s string) bool {
+	t, err := time.Parse(dateLayout, s)
+	if err != nil {
+		return false
+	}
+
+	return !t.Before(minBirthDate) && !t.After(time.Now())
+}
+
+// Phone reports whether s is a phone number in E.164 format, e.g. +79991234567.
+func Phone(s string) bool {
+	return phoneRe.MatchString(s)
+}
+
+// Email reports whether s is a bare email address.
+func Email(s string) bool {
+	if len(s) > maxEmailLen {
+		return false
+	}
+
+	addr, err := mail.ParseAddress(s)
+
+	return err == nil && addr.Address == s
+}
+
+// DocumentNumber reports whether s looks like an identity document number:
+// 4 to 32 letters and digits, optionally separated by spaces or dashes.
+func DocumentNumber(s string) bool {
+	return documentNumberRe.MatchString(s)
+}
+
+// Owner validates every field of owner. Optional fields may be absent or empty.
+// prefix is prepended to the field name in the returned attribute and message.
+func Owner(owner postgres.Owner, prefix string) (bool, slog.Attr, string) {
+	if !Name(owner.Name) {
+		return invalid(prefix + "name")
+	}
+	if !Name(owner.Surname) {
+		return invalid(prefix + "surname")
+	}
+	if owner.Patronymic != nil && !Patronymic(*owner.Patronymic) {
+		return invalid(prefix + "patronymic")
+	}
+	if !Optional(owner.BirthDate, BirthDate) {
+		return invalid(prefix + "birthDate")
+	}
+	if !Optional(owner.Phone, Phone) {
+		return invalid(prefix + "phone")
+	}
+	if !Optional(owner.Email, Email) {
+		return invalid(prefix + "email")
+	}
+	if !Optional(owner.DocumentNumber, DocumentNumber) {
+		return invalid(prefix + "documentNumber")
+	}
+	return true, slog.Attr{}, ""
+}
+
+// Optional applies check to v unless v is absent or empty.
+func Optional(v *string, check func(string) bool) bool {
+	return v == nil || *v == "" || check(*v)
+}
+
+func invalid(field string) (bool, slog.Attr, string) {
+	return false, slog.String("field", field), "field " + field + " is not valid"
+}
@@ -0,0 +1,63 @@
+package validate_test
+
+import (
+	"effective_mobile_test/internal/lib/validate"
+	"effective_mobile_test/internal/storage/postgres"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFields(t *testing.T) {
+	cases := []struct {
+		name  string
+		check func(string) bool
+		value string
+		want  bool
+	}{
+		{"name", validate.Name, "Ivan", true},
+		{"blank name", validate.Name, " \t", false},
+		{"too long name", validate.Name, strings.Repeat("я", 256), false},
+		{"longest name", validate.Name, strings.Repeat("я", 255), true},
+		{"empty patronymic", validate.Patronymic, "", true},
+		{"too long patronymic", validate.Patronymic, strings.Repeat("a", 256), false},
+		{"birth date", validate.BirthDate, "1990-05-17", true},
+		{"birth date before 1900", validate.BirthDate, "1899-12-31", false},
+		{"birth date in the future", validate.BirthDate, time.Now().AddDate(0, 0, 2).Format("2006-01-02"), false},
+		{"birth date in another layout", validate.BirthDate, "17.05.1990", false},
+		{"phone", validate.Phone, "+79991234567", true},
+		{"phone without plus", validate.Phone, "79991234567", false},
+		{"phone with separators", validate.Phone, "+7 999 123-45-67", false},
+		{"phone too short", validate.Phone, "+123", false},
+		{"email", validate.Email, "ivan@example.com", true},
+		{"email with display name", validate.Email, "Ivan <ivan@example.com>", false},
+		{"email without domain", validate.Email, "ivan@", false},
+		{"document number", validate.DocumentNumber, "4510 123456", true},
+		{"document number with cyrillic letters", validate.DocumentNumber, "АБ-123456", true},
+		{"document number too short", validate.DocumentNumber, "123", false},
+		{"document number with a trailing space", validate.DocumentNumber, "4510 123456 ", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.check(tc.value))
+		})
+	}
+}
+
+func TestOwner(t *testing.T) {
+	empty := ""
+	badEmail := "ivan"
+
+	ok, _, _ := validate.Owner(postgres.Owner{Name: "Ivan", Surname: "Ivanov", Patronymic: &empty, Phone: &empty}, "")
+	assert.True(t, ok)
+
+	ok, _, msg := validate.Owner(postgres.Owner{Name: "Ivan", Surname: "Ivanov", Email: &badEmail}, "owner.")
+	assert.False(t, ok)
+	assert.Equal(t, "field owner.email is not valid", msg)
+
+	ok, _, msg = validate.Owner(postgres.Owner{Surname: "Ivanov"}, "")
+	assert.False(t, ok)
+	assert.Equal(t, "field name is not valid", msg)
+}
@@ -0,0 +1,39 @@
+package validate
+
+import (
+	"effective_mobile_test/internal/lib/webhook"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// eventTypeRe matches event types like car.updated and patterns like owner.*
+var eventTypeRe = regexp.MustCompile(`^(car|owner)\.(\*|created|updated|deleted|restored|purged)$`)
+
+// WebhookURL reports whether s is an absolute http or https URL whose host is a domain name
+// or a public address. Names resolving to other addresses are refused when the webhooks are sent.
+func WebhookURL(s string) bool {
+	u, err := url.Parse(s)
+	if err != nil {
+		return false
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return webhook.PublicAddress(addr)
+	}
+
+	return true
+}
+
+// EventType reports whether s is an event type or a pattern of types like owner.*.
+func EventType(s string) bool {
+	return eventTypeRe.MatchString(s)
+}
@@ -0,0 +1,60 @@
+package validate_test
+
+import (
+	"effective_mobile_test/internal/lib/validate"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestWebhookURL(t *testing.T) {
+	cases := []struct {
+		url  string
+		want bool
+	}{
+		{url: "https://example.com/hooks/cars", want: true},
+		{url: "http://hooks.example.com:8080/", want: true},
+		{url: "https://93.184.216.34/hook", want: true},
+		{url: "https://[2606:2800:220:1:248:1893:25c8:1946]/hook", want: true},
+		{url: "ftp://example.com/hook"},
+		{url: "/relative/hook"},
+		{url: "https:///hook"},
+		{url: "http://localhost:8082/hook"},
+		{url: "http://api.localhost/hook"},
+		{url: "http://LOCALHOST./hook"},
+		{url: "http://127.0.0.1/hook"},
+		{url: "http://[::1]/hook"},
+		{url: "http://0.0.0.0/hook"},
+		{url: "http://10.1.2.3/hook"},
+		{url: "http://192.168.0.10/hook"},
+		{url: "http://169.254.169.254/latest/meta-data/"},
+		{url: "http://[fe80::1]/hook"},
+		{url: "http://[::ffff:10.0.0.1]/hook"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.url, func(t *testing.T) {
+			assert.Equal(t, tc.want, validate.WebhookURL(tc.url))
+		})
+	}
+}
+
+func TestEventType(t *testing.T) {
+	cases := []struct {
+		eventType string
+		want      bool
+	}{
+		{eventType: "car.updated", want: true},
+		{eventType: "owner.*", want: true},
+		{eventType: "car.purged", want: true},
+		{eventType: "*"},
+		{eventType: "car."},
+		{eventType: "webhook.created"},
+		{eventType: "car.updated.now"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.eventType, func(t *testing.T) {
+			assert.Equal(t, tc.want, validate.EventType(tc.eventType))
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package webhook_test
+
+import (
+	"effective_mobile_test/internal/lib/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":1}`)
+
+	assert.Equal(t, "sha256=3dd1b9aef568d75f6790a84bd2e5dfa1f44409eef3cbdbd3f10b837376100c11",
+		webhook.Sign("secret", 1700000000, body))
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	signature := webhook.Sign("secret", 1700000000, body)
+
+	cases := []struct {
+		name      string
+		secret    string
+		timestamp int64
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", timestamp: 1700000000, body: body, signature: signature, want: true},
+		{name: "other secret", secret: "other", timestamp: 1700000000, body: body, signature: signature},
+		{name: "other timestamp", secret: "secret", timestamp: 1700000001, body: body, signature: signature},
+		{name: "other body", secret: "secret", timestamp: 1700000000, body: []byte(`{"id":2}`), signature: signature},
+		{name: "without prefix", secret: "secret", timestamp: 1700000000, body: body, signature: signature[len("sha256="):]},
+		{name: "empty", secret: "secret", timestamp: 1700000000, body: body},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, webhook.Verify(tc.secret, tc.timestamp, tc.body, tc.signature))
+		})
+	}
+}
+
+func TestPublicAddress(t *testing.T) {
+	cases := []struct {
+		addr string
+		want bool
+	}{
+		{addr: "93.184.216.34", want: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", want: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "0.0.0.0"},
+		{addr: "10.0.0.1"},
+		{addr: "172.16.5.4"},
+		{addr: "192.168.1.1"},
+		{addr: "169.254.169.254"},
+		{addr: "fe80::1"},
+		{addr: "fd00::1"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "224.0.0.1"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			assert.Equal(t, tc.want, webhook.PublicAddress(netip.MustParseAddr(tc.addr)))
+		})
+	}
+}
+
+func TestClientRefusesInternalAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached the server")
+	}))
+	defer srv.Close()
+
+	_, err := webhook.Client(time.Second).Post(srv.URL, "application/json", nil)
+
+	require.ErrorIs(t, err, webhook.ErrForbiddenAddress)
+}
+
+func TestClientDoesNotFollowRedirects(t *testing.T) {
+	client := webhook.Client(time.Second)
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/hook", nil)
+
+	assert.ErrorIs(t, client.CheckRedirect(req, []*http.Request{req}), http.ErrUseLastResponse)
+}
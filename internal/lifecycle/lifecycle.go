@@ -0,0 +1,146 @@
+package lifecycle
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// step is a stage of the shutdown, bounded by timeout unless it is zero.
+type step struct {
+	name    string
+	timeout time.Duration
+	fn      func(ctx context.Context) error
+}
+
+// Manager runs the background workers of the service and shuts the service down
+// in the order the shutdown steps were registered.
+type Manager struct {
+	log *slog.Logger
+
+	workersCtx  context.Context
+	stopWorkers context.CancelFunc
+	workers     sync.WaitGroup
+
+	steps []step
+}
+
+func New(log *slog.Logger) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &Manager{
+		log:         log.With(slog.String("component", "lifecycle")),
+		workersCtx:  ctx,
+		stopWorkers: cancel,
+	}
+}
+
+// Go runs a background worker until StopWorkers is called. run must return once its ctx is done;
+// a job it is in the middle of may finish first, as StopWorkers waits for it.
+func (m *Manager) Go(name string, run func(ctx context.Context)) {
+	m.workers.Add(1)
+
+	go func() {
+		defer m.workers.Done()
+
+		run(m.workersCtx)
+
+		m.log.Debug("worker returned", slog.String("worker", name))
+	}()
+}
+
+// StopWorkers stops the workers started with Go and waits for them to return, or for ctx to be done.
+func (m *Manager) StopWorkers(ctx context.Context) error {
+	m.stopWorkers()
+
+	done := make(chan struct{})
+	go func() {
+		m.workers.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers still running: %w", ctx.Err())
+	}
+}
+
+// OnShutdown adds a step to the shutdown. Steps run one after another in the order they were added,
+// each with a context that expires after timeout, or never if timeout is zero.
+func (m *Manager) OnShutdown(name string, timeout time.Duration, fn func(ctx context.Context) error) {
+	m.steps = append(m.steps, step{name: name, timeout: timeout, fn: fn})
+}
+
+// Run calls serve and waits for SIGINT or SIGTERM, or for serve to fail, then shuts the service down.
+// serve must block while the service is serving and return nil once it was stopped by a shutdown step.
+// Run returns the exit code of the process: 0 after a clean shutdown, 1 if serve or a shutdown step failed.
+func (m *Manager) Run(serve func() error) int {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- serve()
+	}()
+
+	exitCode := 0
+
+	select {
+	case sig := <-signals:
+		m.log.Info("shutting down", slog.String("signal", sig.String()))
+	case err := <-serveErr:
+		if err == nil {
+			err = errors.New("stopped serving unexpectedly")
+		}
+		m.log.Error("server failed, shutting down", sl.Err(err))
+		exitCode = 1
+	}
+
+	if err := m.Shutdown(); err != nil {
+		exitCode = 1
+	}
+
+	m.log.Info("service stopped", slog.Int("exit_code", exitCode))
+
+	return exitCode
+}
+
+// Shutdown runs the shutdown steps. A step that fails or times out is logged and the next one runs anyway,
+// so that, e.g., storage is closed even if some requests couldn't be drained in time.
+func (m *Manager) Shutdown() error {
+	var errs []error
+
+	for _, s := range m.steps {
+		log := m.log.With(slog.String("step", s.name))
+
+		ctx, cancel := context.Background(), context.CancelFunc(func() {})
+		if s.timeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		}
+
+		started := time.Now()
+		err := s.fn(ctx)
+		cancel()
+
+		if err != nil {
+			log.Error("shutdown step failed", sl.Err(err))
+			errs = append(errs, fmt.Errorf("%s: %w", s.name, err))
+
+			continue
+		}
+
+		log.Info("shutdown step done", slog.String("took", time.Since(started).String()))
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,144 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func newManager() *Manager {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)))
+}
+
+func TestShutdownOrder(t *testing.T) {
+	m := newManager()
+
+	var order []string
+	step := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+
+	m.OnShutdown("readiness", 0, step("readiness", nil))
+	m.OnShutdown("http server", time.Second, step("http server", errors.New("requests still in flight")))
+	m.OnShutdown("workers", time.Second, step("workers", nil))
+	m.OnShutdown("storage", 0, step("storage", nil))
+
+	err := m.Shutdown()
+
+	// a failed step doesn't stop the next ones, e.g. storage is closed anyway
+	assert.Equal(t, []string{"readiness", "http server", "workers", "storage"}, order)
+	assert.EqualError(t, err, "http server: requests still in flight")
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	m := newManager()
+
+	m.OnShutdown("bounded", 10*time.Millisecond, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		require.True(t, ok)
+
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	m.OnShutdown("unbounded", 0, func(ctx context.Context) error {
+		_, ok := ctx.Deadline()
+		assert.False(t, ok)
+
+		return nil
+	})
+
+	err := m.Shutdown()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestStopWorkers(t *testing.T) {
+	m := newManager()
+
+	var mu sync.Mutex
+	var finished []string
+	for _, name := range []string{"webhooks", "outbox"} {
+		name := name
+		m.Go(name, func(ctx context.Context) {
+			<-ctx.Done()
+
+			// the job in progress finishes before the worker returns
+			time.Sleep(10 * time.Millisecond)
+
+			mu.Lock()
+			finished = append(finished, name)
+			mu.Unlock()
+		})
+	}
+
+	require.NoError(t, m.StopWorkers(context.Background()))
+
+	assert.ElementsMatch(t, []string{"webhooks", "outbox"}, finished)
+}
+
+func TestStopWorkersTimeout(t *testing.T) {
+	m := newManager()
+
+	release := make(chan struct{})
+	defer close(release)
+	m.Go("stuck", func(ctx context.Context) {
+		<-release
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := m.StopWorkers(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestRun(t *testing.T) {
+	t.Run("serve fails", func(t *testing.T) {
+		m := newManager()
+
+		var shutDown bool
+		m.OnShutdown("storage", 0, func(ctx context.Context) error {
+			shutDown = true
+			return nil
+		})
+
+		code := m.Run(func() error { return errors.New("address already in use") })
+
+		assert.Equal(t, 1, code)
+		assert.True(t, shutDown)
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		m := newManager()
+
+		stop := make(chan struct{})
+		m.OnShutdown("http server", time.Second, func(ctx context.Context) error {
+			close(stop)
+			return nil
+		})
+
+		serving := make(chan struct{})
+		go func() {
+			<-serving
+			// Run listens for signals before it calls serve
+			require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+		}()
+
+		code := m.Run(func() error {
+			close(serving)
+			<-stop
+			return nil
+		})
+
+		assert.Equal(t, 0, code)
+	})
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// @Schema
+type APIKey struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+	// Tenant binds the key to a tenant; keys without one work in the default tenant
+	Tenant *string `json:"tenant,omitempty"`
+	// MultiTenant lets a key without a tenant choose it with the X-Tenant-ID header
+	MultiTenant bool       `json:"multiTenant,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	RevokedAt   *time.Time `json:"revokedAt,omitempty"`
+}
+
+// SaveAPIKey stores the hash of a new API key with its role and its tenant, if any, or whether it
+// may choose the tenant. It fails with storage.ErrAPIKeyExists if an active key has the same name.
+func (s *Storage) SaveAPIKey(ctx context.Context, name string, role string, tenant *string, multiTenant bool, keyHash string) (int, error) {
+	const op = "storage.postgres.SaveAPIKey"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `INSERT INTO api_keys(name, role, tenant_id, multi_tenant, key_hash)
+								VALUES ($1, $2, $3, $4, $5) RETURNING key_id`,
+		name, role, nullable(tenant), multiTenant, keyHash).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyExists)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetAPIKeyByHash returns the active API key with the hash, or fails with storage.ErrAPIKeyNotFound.
+func (s *Storage) GetAPIKeyByHash(ctx context.Context, keyHash string) (APIKey, error) {
+	const op = "storage.postgres.GetAPIKeyByHash"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var key APIKey
+	err := s.db.QueryRowContext(ctx, `SELECT key_id, name, role, tenant_id, multi_tenant, created_at FROM api_keys
+								WHERE key_hash = $1 AND revoked_at IS NULL`, keyHash).
+		Scan(&key.ID, &key.Name, &key.Role, &key.Tenant, &key.MultiTenant, &key.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return APIKey{}, fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return key, nil
+}
+
+// GetAPIKeys returns all API keys, including the revoked ones.
+func (s *Storage) GetAPIKeys(ctx context.Context) ([]APIKey, error) {
+	const op = "storage.postgres.GetAPIKeys"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT key_id, name, role, tenant_id, multi_tenant, created_at, revoked_at FROM api_keys
+								ORDER BY key_id`)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var key APIKey
+		if err = rows.Scan(&key.ID, &key.Name, &key.Role, &key.Tenant, &key.MultiTenant, &key.CreatedAt, &key.RevokedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		keys = append(keys, key)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey makes an API key unusable. It fails with storage.ErrAPIKeyNotFound
+// unless the key exists and is active.
+func (s *Storage) RevokeAPIKey(ctx context.Context, keyID int) error {
+	const op = "storage.postgres.RevokeAPIKey"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = now() WHERE key_id = $1 AND revoked_at IS NULL",
+		keyID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
+
+// SetAPIKeyRole changes the role of an API key. It fails with storage.ErrAPIKeyNotFound
+// unless the key exists and is active.
+func (s *Storage) SetAPIKeyRole(ctx context.Context, keyID int, role string) error {
+	const op = "storage.postgres.SetAPIKeyRole"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	res, err := s.db.ExecContext(ctx, "UPDATE api_keys SET role = $2 WHERE key_id = $1 AND revoked_at IS NULL",
+		keyID, role)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrAPIKeyNotFound)
+	}
+
+	return nil
+}
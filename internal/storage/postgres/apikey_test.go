@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSaveAPIKey(t *testing.T) {
+	t.Run("saved", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO api_keys(name, role, tenant_id, multi_tenant, key_hash)")).
+			WithArgs("ci", "editor", nil, true, "hash").
+			WillReturnRows(sqlmock.NewRows([]string{"key_id"}).AddRow(4))
+
+		id, err := s.SaveAPIKey(context.Background(), "ci", "editor", nil, true, "hash")
+		require.NoError(t, err)
+
+		assert.Equal(t, 4, id)
+	})
+
+	t.Run("bound to a tenant", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		tenant := "acme"
+		mock.ExpectQuery("INSERT INTO api_keys").WithArgs("ci", "editor", "acme", false, "hash").
+			WillReturnRows(sqlmock.NewRows([]string{"key_id"}).AddRow(4))
+
+		_, err := s.SaveAPIKey(context.Background(), "ci", "editor", &tenant, false, "hash")
+		require.NoError(t, err)
+	})
+
+	t.Run("name taken by an active key", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery("INSERT INTO api_keys").WithArgs("ci", "editor", nil, false, "hash").
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+
+		_, err := s.SaveAPIKey(context.Background(), "ci", "editor", nil, false, "hash")
+		assert.ErrorIs(t, err, storage.ErrAPIKeyExists)
+	})
+}
+
+func TestGetAPIKeyByHash(t *testing.T) {
+	query := regexp.QuoteMeta("WHERE key_hash = $1 AND revoked_at IS NULL")
+	columns := []string{"key_id", "name", "role", "tenant_id", "multi_tenant", "created_at"}
+
+	t.Run("active key", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(query).WithArgs("hash").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow(4, "ci", "editor", "acme", false, createdAt))
+
+		key, err := s.GetAPIKeyByHash(context.Background(), "hash")
+		require.NoError(t, err)
+
+		tenant := "acme"
+		assert.Equal(t, APIKey{ID: 4, Name: "ci", Role: "editor", Tenant: &tenant, CreatedAt: createdAt}, key)
+	})
+
+	t.Run("unknown or revoked key", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery(query).WithArgs("hash").WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := s.GetAPIKeyByHash(context.Background(), "hash")
+		assert.ErrorIs(t, err, storage.ErrAPIKeyNotFound)
+	})
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	s, mock := newMock(t)
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE api_keys SET revoked_at = now() WHERE key_id = $1 AND revoked_at IS NULL")).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.ErrorIs(t, s.RevokeAPIKey(context.Background(), 4), storage.ErrAPIKeyNotFound)
+}
@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/actor"
+	"effective_mobile_test/internal/lib/tenant"
+	"encoding/json"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"strings"
+	"time"
+)
+
+// Entities recorded in the audit log.
+const (
+	EntityCar   = "car"
+	EntityOwner = "owner"
+)
+
+// Actions recorded in the audit log.
+const (
+	actionCreate  = "create"
+	actionUpdate  = "update"
+	actionDelete  = "delete"
+	actionRestore = "restore"
+	actionPurge   = "purge"
+)
+
+// @Schema
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entityId"`
+	Action    string          `json:"action"`
+	Before    json.RawMessage `json:"before,omitempty" swaggertype:"object"`
+	After     json.RawMessage `json:"after,omitempty" swaggertype:"object"`
+	Actor     string          `json:"actor"`
+	RequestID string          `json:"requestId,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// @Schema
+type AuditSearchRequest struct {
+	Entity string `json:"entity"`
+	// EntityID of zero matches any entity
+	EntityID int        `json:"entityId"`
+	Actor    string     `json:"actor"`
+	From     *time.Time `json:"from,omitempty"`
+	To       *time.Time `json:"to,omitempty"`
+	PageNum  int        `json:"pageNum"`
+	PageSize int        `json:"pageSize"`
+}
+
+// change describes a single modification of an entity. before is empty for
+// created entities and after is empty for purged ones.
+type change struct {
+	entity string
+	id     int
+	action string
+	before []byte
+	after  []byte
+}
+
+// recordBatchSize is the maximum number of changes inserted into the audit log with one
+// statement, which keeps the number of query parameters below the protocol limit.
+const recordBatchSize = 1000
+
+// record appends changes to the audit log within tx, attributing them to the actor
+// and request stored in ctx.
+func record(ctx context.Context, tx *sql.Tx, changes ...change) error {
+	for len(changes) > recordBatchSize {
+		if err := record(ctx, tx, changes[:recordBatchSize]...); err != nil {
+			return err
+		}
+		changes = changes[recordBatchSize:]
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	var c conditions
+	who, requestID := c.arg(actor.FromContext(ctx)), c.arg(nullable(ptr(middleware.GetReqID(ctx))))
+
+	values := make([]string, len(changes))
+	for i, ch := range changes {
+		values[i] = "(" + c.arg(ch.entity) + ", " + c.arg(ch.id) + ", " + c.arg(ch.action) + ", " +
+			c.arg(jsonb(ch.before)) + "::jsonb, " + c.arg(jsonb(ch.after)) + "::jsonb, " + who + ", " + requestID + ")"
+	}
+
+	_, err := tx.ExecContext(ctx, "INSERT INTO audit_log(entity, entity_id, action, before, after, actor, request_id) VALUES "+
+		strings.Join(values, ", "), c.args...)
+
+	return err
+}
+
+// jsonb maps an empty snapshot to NULL.
+func jsonb(snapshot []byte) any {
+	if len(snapshot) == 0 {
+		return nil
+	}
+
+	return string(snapshot)
+}
+
+func ptr(v string) *string {
+	return &v
+}
+
+// carSnapshotQuery selects the car rows together with their current owner ids as JSON.
+const carSnapshotQuery = `SELECT c.car_id, to_jsonb(c) || jsonb_build_object('owner_id', co.owner_id)
+								FROM cars c
+								LEFT JOIN cars_owners co ON co.car_id = c.car_id AND co.valid_to IS NULL`
+
+// carSnapshot returns the car row together with its current owner id as JSON.
+func carSnapshot(ctx context.Context, tx *sql.Tx, carID int) ([]byte, error) {
+	var id int
+	var snapshot []byte
+	err := tx.QueryRowContext(ctx, carSnapshotQuery+" WHERE c.car_id = $1", carID).Scan(&id, &snapshot)
+
+	return snapshot, err
+}
+
+// carSnapshots returns the snapshots of several cars by their ids.
+func carSnapshots(ctx context.Context, tx *sql.Tx, carIDs []int) (map[int][]byte, error) {
+	return snapshots(ctx, tx, carSnapshotQuery+" WHERE c.car_id = ANY($1)", carIDs)
+}
+
+// ownerSnapshotQuery selects the owner rows as JSON.
+const ownerSnapshotQuery = "SELECT o.owner_id, to_jsonb(o) - 'identity_key' FROM owners o"
+
+// ownerSnapshot returns the owner row as JSON.
+func ownerSnapshot(ctx context.Context, tx *sql.Tx, ownerID int) ([]byte, error) {
+	var id int
+	var snapshot []byte
+	err := tx.QueryRowContext(ctx, ownerSnapshotQuery+" WHERE o.owner_id = $1", ownerID).Scan(&id, &snapshot)
+
+	return snapshot, err
+}
+
+// ownerSnapshots returns the snapshots of several owners by their ids.
+func ownerSnapshots(ctx context.Context, tx *sql.Tx, ownerIDs []int) (map[int][]byte, error) {
+	return snapshots(ctx, tx, ownerSnapshotQuery+" WHERE o.owner_id = ANY($1)", ownerIDs)
+}
+
+func snapshots(ctx context.Context, tx *sql.Tx, query string, ids []int) (map[int][]byte, error) {
+	result := make(map[int][]byte, len(ids))
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, query, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var snapshot []byte
+		if err = rows.Scan(&id, &snapshot); err != nil {
+			return nil, err
+		}
+		result[id] = snapshot
+	}
+
+	return result, rows.Err()
+}
+
+// purge runs deleteQuery, which must take the purge threshold as $1 and return the tenant, the id
+// and the JSON snapshot of every deleted row as tenant_id, id and before, and records the deletions
+// in the tenants of the rows.
+// It returns the number of deleted rows.
+func purge(ctx context.Context, tx *sql.Tx, entity, deleteQuery string, before time.Time) (int64, error) {
+	res, err := tx.ExecContext(ctx, `WITH purged AS (`+deleteQuery+`)
+								INSERT INTO audit_log(tenant_id, entity, entity_id, action, before, actor, request_id)
+								SELECT tenant_id, $2::varchar, id, $3::varchar, before, $4::varchar, $5::varchar FROM purged`,
+		before, entity, actionPurge, actor.FromContext(ctx), nullable(ptr(middleware.GetReqID(ctx))))
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// GetAuditLog returns audit entries of the tenant matching the request, oldest first.
+func (s *Storage) GetAuditLog(ctx context.Context, searchRequest AuditSearchRequest) ([]AuditEntry, error) {
+	const op = "storage.postgres.GetAuditLog"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var c conditions
+	c.add("tenant_id = " + c.arg(tenant.FromContext(ctx)))
+	if searchRequest.Entity != "" {
+		c.add("entity = " + c.arg(searchRequest.Entity))
+	}
+	if searchRequest.EntityID != 0 {
+		c.add("entity_id = " + c.arg(searchRequest.EntityID))
+	}
+	if searchRequest.Actor != "" {
+		c.add("actor = " + c.arg(searchRequest.Actor))
+	}
+	if searchRequest.From != nil {
+		c.add("created_at >= " + c.arg(*searchRequest.From))
+	}
+	if searchRequest.To != nil {
+		c.add("created_at < " + c.arg(*searchRequest.To))
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT audit_id, entity, entity_id, action, before, after, actor,
+								   COALESCE(request_id, ''), created_at
+								   FROM audit_log`+
+		c.where()+" ORDER BY audit_id"+c.page(searchRequest.PageNum, searchRequest.PageSize), c.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		var before, after []byte
+		err = rows.Scan(&e.ID, &e.Entity, &e.EntityID, &e.Action, &before, &after, &e.Actor, &e.RequestID, &e.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		e.Before, e.After = before, after
+
+		entries = append(entries, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return entries, nil
+}
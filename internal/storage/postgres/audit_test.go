@@ -0,0 +1,186 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/actor"
+	"effective_mobile_test/internal/lib/tenant"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// expectCarLocked expects the car to be locked in the state given by deleted and returns version 1 as its version.
+func expectCarLocked(mock sqlmock.Sqlmock, carID int, deleted bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM cars c WHERE c.car_id = $1 AND (c.deleted_at IS NOT NULL) = $2 AND tenant_visible(c.tenant_id) FOR UPDATE")).
+		WithArgs(carID, deleted).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+}
+
+// expectOwnerLocked expects the owner to be locked in the state given by deleted and returns version 1 as their version.
+func expectOwnerLocked(mock sqlmock.Sqlmock, ownerID int, deleted bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM owners o WHERE o.owner_id = $1 AND (o.deleted_at IS NOT NULL) = $2 AND tenant_visible(o.tenant_id) FOR UPDATE")).
+		WithArgs(ownerID, deleted).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+}
+
+// expectCarSnapshot expects a snapshot of the car to be taken.
+func expectCarSnapshot(mock sqlmock.Sqlmock, carID int) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT c.car_id, to_jsonb(c) || jsonb_build_object('owner_id', co.owner_id)")).
+		WithArgs(carID).
+		WillReturnRows(sqlmock.NewRows([]string{"car_id", "snapshot"}).AddRow(carID, fmt.Sprintf(`{"car_id": %d}`, carID)))
+}
+
+// expectOwnerSnapshot expects a snapshot of the owner to be taken.
+func expectOwnerSnapshot(mock sqlmock.Sqlmock, ownerID int) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT o.owner_id, to_jsonb(o) - 'identity_key' FROM owners o WHERE o.owner_id = $1")).
+		WithArgs(ownerID).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id", "snapshot"}).AddRow(ownerID, fmt.Sprintf(`{"owner_id": %d}`, ownerID)))
+}
+
+// expectCarChanged expects the version of a changed car to be incremented and the change to be recorded.
+func expectCarChanged(mock sqlmock.Sqlmock, carID int) {
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET version = version + 1 WHERE car_id = $1")).
+		WithArgs(carID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectCarSnapshot(mock, carID)
+	expectRecorded(mock, 1)
+}
+
+// expectOwnerChanged expects the version of a changed owner to be incremented and the change to be recorded.
+func expectOwnerChanged(mock sqlmock.Sqlmock, ownerID int) {
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE owners SET version = version + 1 WHERE owner_id = $1")).
+		WithArgs(ownerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectOwnerSnapshot(mock, ownerID)
+	expectRecorded(mock, 1)
+}
+
+// expectRecorded expects the given number of changes to be appended to the audit log.
+func expectRecorded(mock sqlmock.Sqlmock, changes int) {
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log(entity, entity_id, action, before, after, actor, request_id) VALUES")).
+		WillReturnResult(sqlmock.NewResult(0, int64(changes)))
+}
+
+func TestRecord(t *testing.T) {
+	t.Run("attributes changes to the actor and request", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		ctx := actor.WithActor(context.WithValue(context.Background(), middleware.RequestIDKey, "req-1"), "alice")
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log(entity, entity_id, action, before, after, actor, request_id) VALUES "+
+			"($3, $4, $5, $6::jsonb, $7::jsonb, $1, $2), ($8, $9, $10, $11::jsonb, $12::jsonb, $1, $2)")).
+			WithArgs("alice", "req-1",
+				EntityOwner, 3, actionCreate, nil, `{"owner_id": 3}`,
+				EntityCar, 5, actionUpdate, `{"owner_id": 1}`, `{"owner_id": 3}`).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectCommit()
+
+		err := s.withTx(ctx, func(tx *sql.Tx) error {
+			return record(ctx, tx,
+				change{entity: EntityOwner, id: 3, action: actionCreate, after: []byte(`{"owner_id": 3}`)},
+				change{entity: EntityCar, id: 5, action: actionUpdate, before: []byte(`{"owner_id": 1}`), after: []byte(`{"owner_id": 3}`)},
+			)
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("anonymous request without id", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectExec("INSERT INTO audit_log").
+			WithArgs(actor.Anonymous, nil, EntityCar, 5, actionDelete, `{"car_id": 5}`, `{"car_id": 5}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ctx := context.Background()
+		err := s.withTx(ctx, func(tx *sql.Tx) error {
+			return record(ctx, tx, change{entity: EntityCar, id: 5, action: actionDelete,
+				before: []byte(`{"car_id": 5}`), after: []byte(`{"car_id": 5}`)})
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("large change sets are split into batches", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		changes := make([]change, recordBatchSize+1)
+		for i := range changes {
+			changes[i] = change{entity: EntityCar, id: i + 1, action: actionCreate, after: []byte(`{}`)}
+		}
+
+		expectBegin(mock, tenant.Default)
+		expectRecorded(mock, recordBatchSize)
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO audit_log(entity, entity_id, action, before, after, actor, request_id) VALUES "+
+			"($3, $4, $5, $6::jsonb, $7::jsonb, $1, $2)")).
+			WithArgs(actor.Anonymous, nil, EntityCar, recordBatchSize+1, actionCreate, nil, `{}`).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		ctx := context.Background()
+		require.NoError(t, s.withTx(ctx, func(tx *sql.Tx) error {
+			return record(ctx, tx, changes...)
+		}))
+	})
+
+	t.Run("nothing to record", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectCommit()
+
+		ctx := context.Background()
+		require.NoError(t, s.withTx(ctx, func(tx *sql.Tx) error {
+			return record(ctx, tx)
+		}))
+	})
+}
+
+func TestGetAuditLog(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"audit_id", "entity", "entity_id", "action", "before", "after", "actor", "request_id", "created_at"}
+
+	t.Run("without filters", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta("FROM audit_log WHERE tenant_id = $1 ORDER BY audit_id LIMIT $2 OFFSET $3")).
+			WithArgs(tenant.Default, 50, 0).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		entries, err := s.GetAuditLog(context.Background(), AuditSearchRequest{PageNum: 1, PageSize: 50})
+		require.NoError(t, err)
+
+		assert.NotNil(t, entries)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		from, to := createdAt.Add(-time.Hour), createdAt.Add(time.Hour)
+
+		mock.ExpectQuery(regexp.QuoteMeta("FROM audit_log WHERE tenant_id = $1 AND entity = $2 AND entity_id = $3 AND actor = $4 "+
+			"AND created_at >= $5 AND created_at < $6 ORDER BY audit_id LIMIT $7 OFFSET $8")).
+			WithArgs("acme", EntityCar, 5, "alice", from, to, 10, 10).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, EntityCar, 5, actionUpdate, []byte(`{"mark": "Lada"}`), []byte(`{"mark": "Kia"}`), "alice", "req-1", createdAt).
+				AddRow(2, EntityCar, 5, actionPurge, []byte(`{"mark": "Kia"}`), nil, "alice", "", createdAt))
+
+		entries, err := s.GetAuditLog(tenant.WithTenant(context.Background(), "acme"), AuditSearchRequest{
+			Entity: EntityCar, EntityID: 5, Actor: "alice", From: &from, To: &to, PageNum: 2, PageSize: 10,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, entries, 2)
+		assert.JSONEq(t, `{"mark": "Kia"}`, string(entries[0].After))
+		assert.Equal(t, "req-1", entries[0].RequestID)
+		assert.Nil(t, entries[1].After)
+	})
+}
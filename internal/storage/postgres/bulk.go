@@ -0,0 +1,611 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"fmt"
+)
+
+// BulkAction is the kind of a bulk operation on cars.
+type BulkAction string
+
+const (
+	BulkCreate BulkAction = "create"
+	BulkPatch  BulkAction = "patch"
+	BulkDelete BulkAction = "delete"
+)
+
+// @Schema
+type BulkOperation struct {
+	Action BulkAction `json:"action"`
+	// ID or RegNum identifies the car to patch or delete
+	ID     int    `json:"id,omitempty"`
+	RegNum string `json:"regNum,omitempty"`
+	// Car is the car to create
+	Car *Car `json:"car,omitempty"`
+	// Patch holds the fields to change
+	Patch *CarPatch `json:"patch,omitempty"`
+	// Version must match the current version of the car to patch or delete if set
+	Version *int `json:"version,omitempty"`
+}
+
+// versions returns the versions the car may be at for the operation to apply, nil if any version will do.
+func (o BulkOperation) versions() []int {
+	if o.Version == nil {
+		return nil
+	}
+
+	return []int{*o.Version}
+}
+
+// Statuses of bulk operation results.
+const (
+	BulkStatusOK     = "ok"
+	BulkStatusFailed = "failed"
+	// BulkStatusRolledBack marks operations undone because another operation of an atomic bulk failed
+	BulkStatusRolledBack = "rolledBack"
+)
+
+// @Schema
+type BulkResult struct {
+	Status string `json:"status"`
+	// ID and Version describe the created or patched car
+	ID      int    `json:"id,omitempty"`
+	Version int    `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// errBulkFailed aborts the transaction of an atomic bulk after an operation failed.
+var errBulkFailed = errors.New("bulk operation failed")
+
+// BulkCars applies the operations with a fixed number of statements and returns a result per operation.
+// If atomic is set, the operations are applied all or none; otherwise the failing ones are skipped.
+func (s *Storage) BulkCars(ctx context.Context, ops []BulkOperation, atomic bool) ([]BulkResult, error) {
+	const op = "storage.postgres.BulkCars"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	results := make([]BulkResult, len(ops))
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return bulkCars(ctx, tx, ops, atomic, results)
+	})
+	if errors.Is(err, errBulkFailed) {
+		for i := range results {
+			if results[i].Status != BulkStatusFailed {
+				results[i] = BulkResult{Status: BulkStatusRolledBack}
+			}
+		}
+
+		return results, nil
+	}
+	// a conflict that can't be told apart from the batch, e.g. two cars swapping their regNums
+	if isUniqueViolation(err) && !atomic {
+		return s.bulkCarsOneByOne(ctx, ops), nil
+	}
+	if isUniqueViolation(err) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrCarExists)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return results, nil
+}
+
+func bulkCars(ctx context.Context, tx *sql.Tx, ops []BulkOperation, atomic bool, results []BulkResult) error {
+	failed := false
+	fail := func(i int, err error) {
+		results[i] = BulkResult{Status: BulkStatusFailed, Error: err.Error()}
+		failed = true
+	}
+
+	targets, versions, err := bulkTargets(ctx, tx, ops, fail)
+	if err != nil {
+		return err
+	}
+
+	if err = bulkCheckRegNums(ctx, tx, ops, targets, fail); err != nil {
+		return err
+	}
+	if failed && atomic {
+		return errBulkFailed
+	}
+
+	var deleted, patched, owned, owners []int
+	var patches []CarPatch
+	var newOwners []Owner
+	for i, o := range ops {
+		switch {
+		case results[i].Status == BulkStatusFailed:
+		case o.Action == BulkDelete:
+			deleted = append(deleted, targets[i])
+		case o.Action == BulkPatch:
+			patched = append(patched, targets[i])
+			patches = append(patches, *o.Patch)
+			if o.Patch.Owner != nil {
+				owned = append(owned, targets[i])
+				newOwners = append(newOwners, *o.Patch.Owner)
+			}
+		}
+	}
+
+	before, err := carSnapshots(ctx, tx, append(append([]int{}, deleted...), patched...))
+	if err != nil {
+		return err
+	}
+
+	var changes []change
+
+	if len(deleted) > 0 {
+		if err = deleteCars(ctx, tx, deleted); err != nil {
+			return err
+		}
+	}
+
+	if len(patched) > 0 {
+		if err = patchCars(ctx, tx, patched, patches); err != nil {
+			return err
+		}
+	}
+
+	// cars are inserted after the patches, which may free the regNums they take
+	var created []int
+	var cars []Car
+	for i, o := range ops {
+		if o.Action == BulkCreate && results[i].Status != BulkStatusFailed {
+			cars = append(cars, *o.Car)
+		}
+	}
+
+	if len(cars) > 0 {
+		created, err = insertCars(ctx, tx, cars)
+		if err != nil {
+			return err
+		}
+	}
+
+	n := 0
+	for i, o := range ops {
+		if o.Action == BulkCreate && results[i].Status != BulkStatusFailed {
+			if created[n] == 0 {
+				fail(i, storage.ErrCarExists)
+			}
+			targets[i] = created[n]
+			n++
+		}
+	}
+	if failed && atomic {
+		return errBulkFailed
+	}
+
+	// owners are only upserted for the cars that were created, so that a failed create
+	// doesn't leave an owner without cars behind
+	var createdIDs []int
+	for i, o := range ops {
+		if o.Action == BulkCreate && results[i].Status != BulkStatusFailed {
+			createdIDs = append(createdIDs, targets[i])
+			newOwners = append(newOwners, o.Car.Owner)
+		}
+	}
+
+	if len(newOwners) > 0 {
+		var ownerChanges []change
+		owners, ownerChanges, err = upsertOwners(ctx, tx, newOwners)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, ownerChanges...)
+	}
+
+	if len(owned) > 0 {
+		if err = setCarOwners(ctx, tx, owned, owners[:len(owned)]); err != nil {
+			return err
+		}
+	}
+
+	if len(createdIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `INSERT INTO cars_owners(car_id, owner_id)
+									SELECT * FROM unnest($1::int[], $2::int[])`, createdIDs, owners[len(owned):])
+		if err != nil {
+			return err
+		}
+	}
+
+	ids := append(append([]int{}, deleted...), patched...)
+	for i := range ops {
+		if ops[i].Action == BulkCreate && results[i].Status != BulkStatusFailed {
+			ids = append(ids, targets[i])
+		}
+	}
+
+	after, err := carSnapshots(ctx, tx, ids)
+	if err != nil {
+		return err
+	}
+
+	for i, o := range ops {
+		if results[i].Status == BulkStatusFailed {
+			continue
+		}
+
+		id := targets[i]
+		switch o.Action {
+		case BulkCreate:
+			changes = append(changes, change{entity: EntityCar, id: id, action: actionCreate, after: after[id]})
+			results[i] = BulkResult{Status: BulkStatusOK, ID: id, Version: 1}
+		case BulkPatch:
+			changes = append(changes, change{entity: EntityCar, id: id, action: actionUpdate, before: before[id], after: after[id]})
+			results[i] = BulkResult{Status: BulkStatusOK, ID: id, Version: versions[i] + 1}
+		case BulkDelete:
+			changes = append(changes, change{entity: EntityCar, id: id, action: actionDelete, before: before[id], after: after[id]})
+			results[i] = BulkResult{Status: BulkStatusOK, ID: id}
+		}
+	}
+
+	return record(ctx, tx, changes...)
+}
+
+// bulkTargets resolves and locks the cars to patch or delete. It returns their ids and versions
+// by operation index and reports operations whose car can't be changed to fail.
+func bulkTargets(ctx context.Context, tx *sql.Tx, ops []BulkOperation, fail func(int, error)) ([]int, []int, error) {
+	var ids []int
+	var regNums []string
+	for _, o := range ops {
+		switch {
+		case o.Action == BulkCreate:
+		case o.ID != 0:
+			ids = append(ids, o.ID)
+		default:
+			regNums = append(regNums, o.RegNum)
+		}
+	}
+
+	targets := make([]int, len(ops))
+	versions := make([]int, len(ops))
+	if len(ids) == 0 && len(regNums) == 0 {
+		return targets, versions, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT c.car_id, c.reg_num, c.version FROM cars c
+								 WHERE (c.car_id = ANY($1) OR c.reg_num = ANY($2)) AND c.deleted_at IS NULL AND `+inTenant("c")+`
+								 ORDER BY c.car_id
+								 FOR UPDATE`, ids, regNums)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	current := make(map[int]int)
+	byRegNum := make(map[string]int)
+	for rows.Next() {
+		var id, version int
+		var regNum string
+		if err = rows.Scan(&id, &regNum, &version); err != nil {
+			return nil, nil, err
+		}
+		current[id] = version
+		byRegNum[regNum] = id
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[int]bool)
+	for i, o := range ops {
+		if o.Action == BulkCreate {
+			continue
+		}
+
+		id := o.ID
+		if id == 0 {
+			id = byRegNum[o.RegNum]
+		}
+
+		version, ok := current[id]
+		switch {
+		case !ok:
+			fail(i, storage.ErrCarNotFound)
+		case seen[id]:
+			fail(i, storage.ErrDuplicateOperation)
+		case matchVersion(o.versions(), version) != nil:
+			fail(i, storage.ErrVersionMismatch)
+		default:
+			targets[i], versions[i] = id, version
+			seen[id] = true
+		}
+	}
+
+	return targets, versions, nil
+}
+
+// bulkCheckRegNums reports patches to fail whose new regNum is taken by a car that stays alive
+// and keeps its regNum, or by an earlier patch of the same bulk.
+func bulkCheckRegNums(ctx context.Context, tx *sql.Tx, ops []BulkOperation, targets []int, fail func(int, error)) error {
+	var regNums []string
+	released := []int{}
+	for i, o := range ops {
+		if targets[i] == 0 {
+			continue
+		}
+		if o.Action == BulkDelete || o.Patch.RegNum != nil {
+			released = append(released, targets[i])
+		}
+		if o.Action == BulkPatch && o.Patch.RegNum != nil {
+			regNums = append(regNums, *o.Patch.RegNum)
+		}
+	}
+	if len(regNums) == 0 {
+		return nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `SELECT c.reg_num FROM cars c
+								 WHERE c.reg_num = ANY($1) AND c.deleted_at IS NULL AND c.car_id <> ALL($2) AND `+inTenant("c"), regNums, released)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	taken := make(map[string]bool)
+	for rows.Next() {
+		var regNum string
+		if err = rows.Scan(&regNum); err != nil {
+			return err
+		}
+		taken[regNum] = true
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+
+	for i, o := range ops {
+		if targets[i] == 0 || o.Action != BulkPatch || o.Patch.RegNum == nil {
+			continue
+		}
+
+		if taken[*o.Patch.RegNum] {
+			targets[i] = 0
+			fail(i, storage.ErrCarExists)
+
+			continue
+		}
+		taken[*o.Patch.RegNum] = true
+	}
+
+	return nil
+}
+
+// upsertOwners resolves owners the way ownerID does, with a single statement. It returns the owner ids
+// in the order of owners together with the audit changes of the created owners.
+func upsertOwners(ctx context.Context, tx *sql.Tx, owners []Owner) ([]int, []change, error) {
+	names := make([]string, len(owners))
+	surnames := make([]string, len(owners))
+	patronymics := make([]*string, len(owners))
+	birthDates := make([]*string, len(owners))
+	phones := make([]*string, len(owners))
+	emails := make([]*string, len(owners))
+	documentNumbers := make([]*string, len(owners))
+	for i, o := range owners {
+		names[i], surnames[i] = o.Name, o.Surname
+		patronymics[i] = nonEmpty(o.Patronymic)
+		birthDates[i] = nonEmpty(o.BirthDate)
+		phones[i] = nonEmpty(o.Phone)
+		emails[i] = nonEmpty(o.Email)
+		documentNumbers[i] = nonEmpty(o.DocumentNumber)
+	}
+
+	// the same owner may come several times, but a row can be upserted only once per statement
+	rows, err := tx.QueryContext(ctx, `WITH input AS (
+									 SELECT n.*,
+										 owner_name_part(n.surname) || '|' || owner_name_part(n.name) || '|' ||
+										 owner_name_part(n.patronymic) AS identity_key
+									 FROM unnest($1::text[], $2::text[], $3::text[], $4::text[], $5::text[], $6::text[], $7::text[])
+										 WITH ORDINALITY AS n(name, surname, patronymic, birth_date, phone, email, document_number, ord)
+								 ), upserted AS (
+									 INSERT INTO owners(name, surname, patronymic, birth_date, phone, email, document_number)
+									 SELECT DISTINCT ON (identity_key) name, surname, patronymic, birth_date::date, phone, email, document_number
+									 FROM input
+									 ORDER BY identity_key, ord
+									 ON CONFLICT (tenant_id, identity_key) WHERE deleted_at IS NULL DO UPDATE SET name = owners.name
+									 RETURNING owner_id, identity_key, xmax = 0 AS inserted
+								 )
+								 SELECT u.owner_id, u.inserted
+								 FROM input i
+								 JOIN upserted u USING (identity_key)
+								 ORDER BY i.ord`,
+		names, surnames, patronymics, birthDates, phones, emails, documentNumbers)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	ids := make([]int, 0, len(owners))
+	var inserted []int
+	seen := make(map[int]bool)
+	for rows.Next() {
+		var id int
+		var isNew bool
+		if err = rows.Scan(&id, &isNew); err != nil {
+			return nil, nil, err
+		}
+		ids = append(ids, id)
+		if isNew && !seen[id] {
+			inserted = append(inserted, id)
+		}
+		seen[id] = true
+	}
+	if err = rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	after, err := ownerSnapshots(ctx, tx, inserted)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	changes := make([]change, len(inserted))
+	for i, id := range inserted {
+		changes[i] = change{entity: EntityOwner, id: id, action: actionCreate, after: after[id]}
+	}
+
+	return ids, changes, nil
+}
+
+// nonEmpty maps an absent or empty optional value to nil.
+func nonEmpty(v *string) *string {
+	if v == nil || *v == "" {
+		return nil
+	}
+
+	return v
+}
+
+// deleteCars soft-deletes the cars with the given ids, with a single statement.
+func deleteCars(ctx context.Context, tx *sql.Tx, carIDs []int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE cars SET deleted_at = now(), version = version + 1 WHERE car_id = ANY($1)", carIDs)
+
+	return err
+}
+
+// patchCars applies the patches to the cars with the given ids, with a single statement.
+func patchCars(ctx context.Context, tx *sql.Tx, carIDs []int, patches []CarPatch) error {
+	regNums := make([]*string, len(patches))
+	marks := make([]*string, len(patches))
+	models := make([]*string, len(patches))
+	years := make([]*int, len(patches))
+	for i, p := range patches {
+		regNums[i], marks[i], models[i], years[i] = p.RegNum, p.Mark, p.Model, p.Year
+	}
+
+	_, err := tx.ExecContext(ctx, `UPDATE cars c
+								SET reg_num = COALESCE(p.reg_num, c.reg_num),
+									mark = COALESCE(p.mark, c.mark),
+									model = COALESCE(p.model, c.model),
+									year = COALESCE(p.year, c.year),
+									version = c.version + 1
+								FROM unnest($1::int[], $2::text[], $3::text[], $4::text[], $5::int[]) AS p(car_id, reg_num, mark, model, year)
+								WHERE c.car_id = p.car_id`,
+		carIDs, regNums, marks, models, years)
+
+	return err
+}
+
+// setCarOwners does what setCarOwner does for several cars at once.
+func setCarOwners(ctx context.Context, tx *sql.Tx, carIDs, ownerIDs []int) error {
+	_, err := tx.ExecContext(ctx, "UPDATE cars_owners SET valid_to = now() WHERE car_id = ANY($1) AND valid_to IS NULL", carIDs)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO cars_owners(car_id, owner_id)
+								SELECT * FROM unnest($1::int[], $2::int[])`, carIDs, ownerIDs)
+
+	return err
+}
+
+// insertCars inserts the cars, without their owners, with a single statement.
+// It returns the ids of the new cars in the order of cars, with zero for a car whose regNum is taken.
+func insertCars(ctx context.Context, tx *sql.Tx, cars []Car) ([]int, error) {
+	regNums := make([]string, len(cars))
+	marks := make([]string, len(cars))
+	models := make([]string, len(cars))
+	years := make([]int, len(cars))
+	for i, c := range cars {
+		regNums[i], marks[i], models[i], years[i] = c.RegNum, c.Mark, c.Model, c.Year
+	}
+
+	rows, err := tx.QueryContext(ctx, `INSERT INTO cars(reg_num, mark, model, year)
+								 SELECT reg_num, mark, model, year
+								 FROM unnest($1::text[], $2::text[], $3::text[], $4::int[]) WITH ORDINALITY AS n(reg_num, mark, model, year, ord)
+								 ORDER BY ord
+								 ON CONFLICT (tenant_id, reg_num) WHERE deleted_at IS NULL DO NOTHING
+								 RETURNING car_id, reg_num`, regNums, marks, models, years)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := make(map[string]int)
+	for rows.Next() {
+		var id int
+		var regNum string
+		if err = rows.Scan(&id, &regNum); err != nil {
+			return nil, err
+		}
+		inserted[regNum] = id
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// of several cars with the same regNum only the first one is inserted
+	ids := make([]int, len(cars))
+	for i, c := range cars {
+		if id, ok := inserted[c.RegNum]; ok {
+			ids[i] = id
+			delete(inserted, c.RegNum)
+		}
+	}
+
+	return ids, nil
+}
+
+// bulkCarsOneByOne applies the operations one by one, each in its own transaction.
+func (s *Storage) bulkCarsOneByOne(ctx context.Context, ops []BulkOperation) []BulkResult {
+	results := make([]BulkResult, len(ops))
+	for i, o := range ops {
+		id, version, err := s.bulkCar(ctx, o)
+		if err != nil {
+			results[i] = BulkResult{Status: BulkStatusFailed, Error: bulkError(err)}
+
+			continue
+		}
+
+		results[i] = BulkResult{Status: BulkStatusOK, ID: id, Version: version}
+	}
+
+	return results
+}
+
+func (s *Storage) bulkCar(ctx context.Context, o BulkOperation) (int, int, error) {
+	if o.Action == BulkCreate {
+		id, err := s.SaveCar(ctx, *o.Car)
+
+		return id, 1, err
+	}
+
+	id := o.ID
+	if id == 0 {
+		err := s.withTx(ctx, func(tx *sql.Tx) error {
+			return tx.QueryRowContext(ctx, "SELECT c.car_id FROM cars c WHERE c.reg_num = $1 AND c.deleted_at IS NULL AND "+inTenant("c"), o.RegNum).
+				Scan(&id)
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, 0, storage.ErrCarNotFound
+		}
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if o.Action == BulkDelete {
+		return id, 0, s.DeleteCar(ctx, id, o.versions())
+	}
+
+	version, err := s.PatchCar(ctx, id, *o.Patch, o.versions())
+
+	return id, version, err
+}
+
+// bulkError describes the failure of a single operation without exposing internal errors.
+func bulkError(err error) string {
+	for _, known := range []error{storage.ErrCarNotFound, storage.ErrCarExists, storage.ErrVersionMismatch} {
+		if errors.Is(err, known) {
+			return known.Error()
+		}
+	}
+
+	return "failed to apply operation"
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"fmt"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+// expectBulkTargets expects the cars to patch or delete to be locked and returns the given rows of id, regNum and version.
+func expectBulkTargets(mock sqlmock.Sqlmock, ids []int, regNums []string, rows *sqlmock.Rows) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT c.car_id, c.reg_num, c.version FROM cars c")).
+		WithArgs(ids, regNums).
+		WillReturnRows(rows)
+}
+
+func TestBulkCars(t *testing.T) {
+	targetColumns := []string{"car_id", "reg_num", "version"}
+
+	t.Run("transaction is rolled back by a failed operation", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		stale := 2
+
+		expectBegin(mock, tenant.Default)
+		expectBulkTargets(mock, []int{1}, []string{"B002BB77"},
+			sqlmock.NewRows(targetColumns).AddRow(1, "A001AA77", 1).AddRow(2, "B002BB77", 1))
+		mock.ExpectRollback()
+
+		results, err := s.BulkCars(context.Background(), []BulkOperation{
+			{Action: BulkDelete, ID: 1, Version: &stale},
+			{Action: BulkDelete, RegNum: "B002BB77"},
+		}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []BulkResult{
+			{Status: BulkStatusFailed, Error: storage.ErrVersionMismatch.Error()},
+			{Status: BulkStatusRolledBack},
+		}, results)
+	})
+
+	t.Run("best effort skips failed operations", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectBulkTargets(mock, []int{1, 9, 1}, []string(nil), sqlmock.NewRows(targetColumns).AddRow(1, "A001AA77", 1))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE c.car_id = ANY($1)")).WithArgs([]int{1}).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id", "snapshot"}).AddRow(1, `{"car_id": 1}`))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET deleted_at = now(), version = version + 1 WHERE car_id = ANY($1)")).
+			WithArgs([]int{1}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE c.car_id = ANY($1)")).WithArgs([]int{1}).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id", "snapshot"}).AddRow(1, `{"car_id": 1, "version": 2}`))
+		expectRecorded(mock, 1)
+		mock.ExpectCommit()
+
+		results, err := s.BulkCars(context.Background(), []BulkOperation{
+			{Action: BulkDelete, ID: 1},
+			{Action: BulkDelete, ID: 9},
+			{Action: BulkDelete, ID: 1},
+		}, false)
+		require.NoError(t, err)
+
+		assert.Equal(t, []BulkResult{
+			{Status: BulkStatusOK, ID: 1},
+			{Status: BulkStatusFailed, Error: storage.ErrCarNotFound.Error()},
+			{Status: BulkStatusFailed, Error: storage.ErrDuplicateOperation.Error()},
+		}, results)
+	})
+
+	t.Run("patch to a taken regNum", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		taken := "B002BB77"
+
+		expectBegin(mock, tenant.Default)
+		expectBulkTargets(mock, []int{1}, []string(nil), sqlmock.NewRows(targetColumns).AddRow(1, "A001AA77", 1))
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT c.reg_num FROM cars c")).WithArgs([]string{taken}, []int{1}).
+			WillReturnRows(sqlmock.NewRows([]string{"reg_num"}).AddRow(taken))
+		mock.ExpectRollback()
+
+		results, err := s.BulkCars(context.Background(), []BulkOperation{
+			{Action: BulkPatch, ID: 1, Patch: &CarPatch{RegNum: &taken}},
+		}, true)
+		require.NoError(t, err)
+
+		assert.Equal(t, []BulkResult{{Status: BulkStatusFailed, Error: storage.ErrCarExists.Error()}}, results)
+	})
+}
+
+func TestBulkError(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "car not found", err: fmt.Errorf("op: %w", storage.ErrCarNotFound), want: storage.ErrCarNotFound.Error()},
+		{name: "car exists", err: fmt.Errorf("op: %w", storage.ErrCarExists), want: storage.ErrCarExists.Error()},
+		{name: "version mismatch", err: fmt.Errorf("op: %w", storage.ErrVersionMismatch), want: storage.ErrVersionMismatch.Error()},
+		{name: "internal errors are hidden", err: errors.New("connection reset by peer"), want: "failed to apply operation"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, bulkError(tc.err))
+		})
+	}
+}
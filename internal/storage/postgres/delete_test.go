@@ -0,0 +1,119 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"testing"
+)
+
+// expectOwnerCars expects the owner to be locked and returns carIDs as the cars they hold.
+func expectOwnerCars(mock sqlmock.Sqlmock, ownerID int, carIDs ...int) {
+	expectOwnerLocked(mock, ownerID, false)
+	expectOwnerSnapshot(mock, ownerID)
+
+	rows := sqlmock.NewRows([]string{"car_id"})
+	for _, carID := range carIDs {
+		rows.AddRow(carID)
+	}
+	mock.ExpectQuery("(?s)SELECT co.car_id FROM cars_owners co.*c.deleted_at IS NULL.*co.valid_to IS NULL").
+		WithArgs(ownerID).
+		WillReturnRows(rows)
+}
+
+// expectOwnerDeleted expects the owner to be soft-deleted and the deletion to be recorded.
+func expectOwnerDeleted(mock sqlmock.Sqlmock, ownerID int) {
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE owners SET deleted_at = now() WHERE owner_id = $1")).
+		WithArgs(ownerID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectOwnerChanged(mock, ownerID)
+}
+
+func TestDeleteOwner(t *testing.T) {
+	t.Run("restrict deletes an owner without cars", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerCars(mock, 1)
+		expectOwnerDeleted(mock, 1)
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.DeleteOwner(context.Background(), 1, OwnerDeleteRestrict, 0, nil))
+	})
+
+	t.Run("restrict refuses to delete an owner with cars", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerCars(mock, 1, 10)
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.DeleteOwner(context.Background(), 1, OwnerDeleteRestrict, 0, nil), storage.ErrOwnerHasCars)
+	})
+
+	t.Run("cascade deletes the cars", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerCars(mock, 1, 10, 11)
+		for _, carID := range []int{10, 11} {
+			expectCarLocked(mock, carID, false)
+			expectCarSnapshot(mock, carID)
+			mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET deleted_at = now() WHERE car_id = $1")).
+				WithArgs(carID).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+			expectCarChanged(mock, carID)
+		}
+		expectOwnerDeleted(mock, 1)
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.DeleteOwner(context.Background(), 1, OwnerDeleteCascade, 0, nil))
+	})
+
+	t.Run("reassign hands the cars over", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerCars(mock, 1, 10)
+		expectOwnerLocked(mock, 2, false)
+		expectCarLocked(mock, 10, false)
+		expectCarSnapshot(mock, 10)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars_owners SET valid_to = now() WHERE car_id = $1 AND valid_to IS NULL")).
+			WithArgs(10).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO cars_owners(car_id, owner_id) VALUES ($1, $2)")).
+			WithArgs(10, 2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarChanged(mock, 10)
+		expectOwnerDeleted(mock, 1)
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.DeleteOwner(context.Background(), 1, OwnerDeleteReassign, 2, nil))
+	})
+
+	t.Run("reassign to an unknown owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerCars(mock, 1, 10)
+		mock.ExpectQuery("FOR UPDATE").WithArgs(2, false).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.DeleteOwner(context.Background(), 1, OwnerDeleteReassign, 2, nil), storage.ErrOwnerNotFound)
+	})
+
+	t.Run("unknown owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("FOR UPDATE").WithArgs(1, false).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.DeleteOwner(context.Background(), 1, OwnerDeleteCascade, 0, nil), storage.ErrOwnerNotFound)
+	})
+}
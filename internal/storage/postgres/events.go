@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v5/stdlib"
+	"time"
+)
+
+// eventsChannel is notified by every transaction that publishes events.
+const eventsChannel = "catalog_events"
+
+// visibleEvents matches the events of transactions older than any transaction still in progress.
+// Events are read in (xid, event_id) order: a transaction that is still running may hold
+// smaller event ids than a committed one, but never a smaller xid than the visible events.
+const visibleEvents = "xid < pg_snapshot_xmin(pg_current_snapshot())"
+
+// @Schema
+type Event struct {
+	ID     int64  `json:"id"`
+	Tenant string `json:"tenant"`
+	// Type is the entity and the action, e.g. car.created, car.updated, owner.deleted
+	Type      string          `json:"type"`
+	Entity    string          `json:"entity"`
+	EntityID  int             `json:"entityId"`
+	Data      json.RawMessage `json:"data,omitempty" swaggertype:"object"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+// GetEvents returns up to limit events of the tenant published after the event with id after,
+// or from the first one if after is zero. Ids identify events but don't order them:
+// only the ids returned by GetEvents or LastEventID should be passed as after.
+func (s *Storage) GetEvents(ctx context.Context, after int64, limit int) ([]Event, error) {
+	const op = "storage.postgres.GetEvents"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var c conditions
+	c.add(visibleEvents)
+	c.add("tenant_id = " + c.arg(tenant.FromContext(ctx)))
+	if after != 0 {
+		var xid string
+		err := s.db.QueryRowContext(ctx, "SELECT xid::text FROM events WHERE event_id = $1 AND tenant_id = $2",
+			after, tenant.FromContext(ctx)).Scan(&xid)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%s: %w", op, storage.ErrEventNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		c.add("(xid, event_id) > (" + c.arg(xid) + "::xid8, " + c.arg(after) + ")")
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT event_id, tenant_id, type, entity, entity_id, data, created_at FROM events"+
+		c.where()+" ORDER BY xid, event_id LIMIT "+c.arg(limit), c.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		var e Event
+		var data []byte
+		if err = rows.Scan(&e.ID, &e.Tenant, &e.Type, &e.Entity, &e.EntityID, &data, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		e.Data = data
+
+		events = append(events, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return events, nil
+}
+
+// LastEventID returns the id of the latest event of the tenant GetEvents can return, or zero if there are none yet.
+func (s *Storage) LastEventID(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.LastEventID"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var id int64
+	err := s.db.QueryRowContext(ctx, "SELECT event_id FROM events WHERE "+visibleEvents+" AND tenant_id = $1"+
+		" ORDER BY xid DESC, event_id DESC LIMIT 1", tenant.FromContext(ctx)).Scan(&id)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// ListenEvents calls fn every time a transaction that published events commits.
+// It holds a connection of its own until ctx is done or the connection fails,
+// and returns the error that stopped it.
+func (s *Storage) ListenEvents(ctx context.Context, fn func()) error {
+	const op = "storage.postgres.ListenEvents"
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	err = conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("unexpected driver connection")
+		}
+		pgxConn := c.Conn()
+
+		// the connection stays subscribed, so it must not go back to the pool
+		defer func() { _ = pgxConn.Close(context.Background()) }()
+
+		if _, err := pgxConn.Exec(ctx, "LISTEN "+eventsChannel); err != nil {
+			return err
+		}
+
+		for {
+			if _, err := pgxConn.WaitForNotification(ctx); err != nil {
+				return err
+			}
+			fn()
+		}
+	})
+
+	return fmt.Errorf("%s: %w", op, err)
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestGetEvents(t *testing.T) {
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	columns := []string{"event_id", "tenant_id", "type", "entity", "entity_id", "data", "created_at"}
+
+	t.Run("from the first event", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta("FROM events WHERE " + visibleEvents + " AND tenant_id = $1 ORDER BY xid, event_id LIMIT $2")).
+			WithArgs("acme", 100).
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow(1, "acme", "car.created", EntityCar, 5, []byte(`{"car_id": 5}`), createdAt).
+				AddRow(2, "acme", "car.purged", EntityCar, 5, nil, createdAt))
+
+		events, err := s.GetEvents(tenant.WithTenant(context.Background(), "acme"), 0, 100)
+		require.NoError(t, err)
+
+		require.Len(t, events, 2)
+		assert.Equal(t, "acme", events[0].Tenant)
+		assert.Equal(t, "car.created", events[0].Type)
+		assert.JSONEq(t, `{"car_id": 5}`, string(events[0].Data))
+		assert.Nil(t, events[1].Data)
+	})
+
+	t.Run("after an event in the order of transactions", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT xid::text FROM events WHERE event_id = $1 AND tenant_id = $2")).
+			WithArgs(int64(7), tenant.Default).
+			WillReturnRows(sqlmock.NewRows([]string{"xid"}).AddRow("1042"))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE "+visibleEvents+" AND tenant_id = $1 AND (xid, event_id) > ($2::xid8, $3) "+
+			"ORDER BY xid, event_id LIMIT $4")).
+			WithArgs(tenant.Default, "1042", int64(7), 100).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		events, err := s.GetEvents(context.Background(), 7, 100)
+		require.NoError(t, err)
+
+		assert.NotNil(t, events)
+		assert.Empty(t, events)
+	})
+
+	t.Run("unknown event", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		// events of other tenants are unknown as well
+		mock.ExpectQuery("SELECT xid::text FROM events").WithArgs(int64(7), tenant.Default).WillReturnError(sql.ErrNoRows)
+
+		_, err := s.GetEvents(context.Background(), 7, 100)
+		assert.ErrorIs(t, err, storage.ErrEventNotFound)
+	})
+}
+
+func TestLastEventID(t *testing.T) {
+	t.Run("latest visible event", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY xid DESC, event_id DESC LIMIT 1")).
+			WillReturnRows(sqlmock.NewRows([]string{"event_id"}).AddRow(9))
+
+		id, err := s.LastEventID(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(9), id)
+	})
+
+	t.Run("no events yet", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery("FROM events").WillReturnRows(sqlmock.NewRows([]string{"event_id"}))
+
+		id, err := s.LastEventID(context.Background())
+		require.NoError(t, err)
+
+		assert.Zero(t, id)
+	})
+}
@@ -0,0 +1,83 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// exportBatchSize is the number of cars fetched from the export cursor at once.
+const exportBatchSize = 1000
+
+// ExportCars calls fn for every car matching the filter in the order of ids, ignoring pagination.
+// The cars are read through a server-side cursor in batches, so memory use doesn't grow with their number.
+// An error returned by fn stops the export and is returned as is.
+func (s *Storage) ExportCars(ctx context.Context, filter CarFilter, fn func(car Car) error) error {
+	const op = "storage.postgres.ExportCars"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err = setTenant(ctx, tx); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	var c conditions
+	_, err = tx.ExecContext(ctx, "DECLARE cars_export NO SCROLL CURSOR FOR SELECT "+carColumns+", "+ownerColumns("o")+
+		filterCars(ctx, &c, filter)+" ORDER BY c.car_id", c.args...)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	for {
+		fetched, err := fetchCars(ctx, tx, fn)
+		if err != nil {
+			return err
+		}
+		if fetched < exportBatchSize {
+			break
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// fetchCars passes the next batch of cars from the export cursor to fn and returns the batch size.
+func fetchCars(ctx context.Context, tx *sql.Tx, fn func(car Car) error) (int, error) {
+	const op = "storage.postgres.fetchCars"
+
+	rows, err := tx.QueryContext(ctx, "FETCH "+strconv.Itoa(exportBatchSize)+" FROM cars_export")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	fetched := 0
+	for rows.Next() {
+		var car Car
+		if err = rows.Scan(carFields(&car)...); err != nil {
+			return 0, fmt.Errorf("%s: %w", op, err)
+		}
+		fetched++
+
+		if err = fn(car); err != nil {
+			return 0, err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return fetched, nil
+}
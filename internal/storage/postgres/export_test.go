@@ -0,0 +1,68 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// expectFetch expects the next batch to be fetched from the export cursor and returns cars with ids from..to.
+func expectFetch(mock sqlmock.Sqlmock, from, to int) {
+	rows := sqlmock.NewRows(carColumnNames)
+	for id := from; id <= to; id++ {
+		rows.AddRow(carRow(id, "X123XX150", "Lada", "Vesta", 2002, ownerRow(1, "Ivan", "Ivanov", nil))...)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta("FETCH 1000 FROM cars_export")).WillReturnRows(rows)
+}
+
+func TestExportCars(t *testing.T) {
+	t.Run("reads the cursor in batches until it is exhausted", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectExec(regexp.QuoteMeta("DECLARE cars_export NO SCROLL CURSOR FOR SELECT c.car_id")).
+			WithArgs((*time.Time)(nil), "Lada").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		expectFetch(mock, 1, exportBatchSize)
+		expectFetch(mock, exportBatchSize+1, exportBatchSize+2)
+		mock.ExpectCommit()
+
+		var ids []int
+		err := s.ExportCars(context.Background(), CarFilter{Mark: "Lada"}, func(car Car) error {
+			ids = append(ids, car.ID)
+
+			return nil
+		})
+		require.NoError(t, err)
+
+		require.Len(t, ids, exportBatchSize+2)
+		assert.Equal(t, exportBatchSize+2, ids[len(ids)-1])
+	})
+
+	t.Run("error of fn stops the export", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		errClosed := errors.New("client went away")
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectExec("DECLARE cars_export").WillReturnResult(sqlmock.NewResult(0, 0))
+		expectFetch(mock, 1, 3)
+		mock.ExpectRollback()
+
+		calls := 0
+		err := s.ExportCars(context.Background(), CarFilter{}, func(car Car) error {
+			calls++
+
+			return errClosed
+		})
+		assert.ErrorIs(t, err, errClosed)
+		assert.Equal(t, 1, calls)
+	})
+}
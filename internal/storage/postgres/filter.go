@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/storage"
+	"fmt"
+	"time"
+)
+
+// @Schema
+type CarFilter struct {
+	// Query matches a substring of the regNum, mark, model or owner names
+	Query string `json:"query"`
+	// RegNum, Mark and Model match exactly
+	RegNum string `json:"regNum,omitempty"`
+	Mark   string `json:"mark,omitempty"`
+	Model  string `json:"model,omitempty"`
+	// YearFrom and YearTo bound the year inclusively
+	YearFrom int `json:"yearFrom,omitempty"`
+	YearTo   int `json:"yearTo,omitempty"`
+	OwnerID  int `json:"ownerId,omitempty"`
+	// AsOf selects the owner valid at the given instant instead of the current one
+	AsOf *time.Time `json:"asOf,omitempty"`
+	// IncludeDeleted also returns soft-deleted cars, to admins only
+	IncludeDeleted bool `json:"includeDeleted,omitempty"`
+}
+
+// Empty reports whether the filter matches every alive car.
+func (f CarFilter) Empty() bool {
+	return f.Query == "" && f.RegNum == "" && f.Mark == "" && f.Model == "" &&
+		f.YearFrom == 0 && f.YearTo == 0 && f.OwnerID == 0
+}
+
+// filterCars adds the conditions of the filter to c and returns the FROM and WHERE clauses
+// selecting the matching cars of the tenant as c together with their owners as o.
+// Soft-deleted cars are only selected for callers allowed to see them.
+func filterCars(ctx context.Context, c *conditions, f CarFilter) string {
+	asOf := c.arg(f.AsOf)
+	c.add(inTenant("c"))
+	if !showDeleted(ctx, f.IncludeDeleted) {
+		c.add("c.deleted_at IS NULL")
+	}
+	if f.Query != "" {
+		q := c.arg("%" + f.Query + "%")
+		c.add("(c.reg_num LIKE " + q + " OR c.mark LIKE " + q + " OR c.model LIKE " + q +
+			" OR o.name LIKE " + q + " OR o.surname LIKE " + q + " OR o.patronymic LIKE " + q + ")")
+	}
+	if f.RegNum != "" {
+		c.add("c.reg_num = " + c.arg(f.RegNum))
+	}
+	if f.Mark != "" {
+		c.add("c.mark = " + c.arg(f.Mark))
+	}
+	if f.Model != "" {
+		c.add("c.model = " + c.arg(f.Model))
+	}
+	if f.YearFrom != 0 {
+		c.add("c.year >= " + c.arg(f.YearFrom))
+	}
+	if f.YearTo != 0 {
+		c.add("c.year <= " + c.arg(f.YearTo))
+	}
+	if f.OwnerID != 0 {
+		c.add("o.owner_id = " + c.arg(f.OwnerID))
+	}
+
+	return ` FROM cars c
+		JOIN cars_owners co ON c.car_id = co.car_id AND ` + ownedAt(asOf) + `
+		JOIN owners o ON co.owner_id = o.owner_id` + c.where()
+}
+
+// CountCarsByFilter returns the number of cars matching the filter and up to sampleSize of them.
+func (s *Storage) CountCarsByFilter(ctx context.Context, filter CarFilter, sampleSize int) (int, []Car, error) {
+	const op = "storage.postgres.CountCarsByFilter"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var count int
+	sample := []Car{}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var c conditions
+		err := tx.QueryRowContext(ctx, "SELECT count(*)"+filterCars(ctx, &c, filter), c.args...).Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		c = conditions{}
+		rows, err := tx.QueryContext(ctx, "SELECT "+carColumns+", "+ownerColumns("o")+filterCars(ctx, &c, filter)+
+			" ORDER BY c.car_id LIMIT "+c.arg(sampleSize), c.args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var car Car
+			if err = rows.Scan(carFields(&car)...); err != nil {
+				return err
+			}
+
+			sample = append(sample, car)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return 0, nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return count, sample, nil
+}
+
+// PatchCarsByFilter applies the patch to every alive car matching the filter in one transaction
+// and returns the number of patched cars. It fails with storage.ErrTooManyMatches without changing
+// anything if more than limit cars match.
+func (s *Storage) PatchCarsByFilter(ctx context.Context, filter CarFilter, patch CarPatch, limit int) (int, error) {
+	const op = "storage.postgres.PatchCarsByFilter"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var affected int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		carIDs, err := lockCarsByFilter(ctx, tx, filter, limit)
+		if err != nil {
+			return err
+		}
+		affected = len(carIDs)
+
+		return changeCars(ctx, tx, carIDs, actionUpdate, func() error {
+			patches := make([]CarPatch, len(carIDs))
+			for i := range patches {
+				patches[i] = patch
+			}
+
+			if err := patchCars(ctx, tx, carIDs, patches); err != nil {
+				return err
+			}
+			if patch.Owner == nil {
+				return nil
+			}
+
+			owners, changes, err := upsertOwners(ctx, tx, []Owner{*patch.Owner})
+			if err != nil {
+				return err
+			}
+
+			ownerIDs := make([]int, len(carIDs))
+			for i := range ownerIDs {
+				ownerIDs[i] = owners[0]
+			}
+
+			if err = setCarOwners(ctx, tx, carIDs, ownerIDs); err != nil {
+				return err
+			}
+
+			return record(ctx, tx, changes...)
+		})
+	})
+	if isUniqueViolation(err) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrCarExists)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// DeleteCarsByFilter soft-deletes every alive car matching the filter in one transaction
+// and returns the number of deleted cars. It fails with storage.ErrTooManyMatches without changing
+// anything if more than limit cars match.
+func (s *Storage) DeleteCarsByFilter(ctx context.Context, filter CarFilter, limit int) (int, error) {
+	const op = "storage.postgres.DeleteCarsByFilter"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var affected int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		carIDs, err := lockCarsByFilter(ctx, tx, filter, limit)
+		if err != nil {
+			return err
+		}
+		affected = len(carIDs)
+
+		return changeCars(ctx, tx, carIDs, actionDelete, func() error {
+			return deleteCars(ctx, tx, carIDs)
+		})
+	})
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return affected, nil
+}
+
+// showDeleted reports whether soft-deleted rows were requested by a caller with auth.PermAdminDeleted.
+func showDeleted(ctx context.Context, requested bool) bool {
+	return requested && auth.Can(ctx, auth.PermAdminDeleted)
+}
+
+// lockCarsByFilter locks the alive cars matching the filter and returns their ids,
+// or fails with storage.ErrTooManyMatches if there are more than limit of them.
+func lockCarsByFilter(ctx context.Context, tx *sql.Tx, filter CarFilter, limit int) ([]int, error) {
+	filter.IncludeDeleted = false
+
+	var c conditions
+	rows, err := tx.QueryContext(ctx, "SELECT c.car_id"+filterCars(ctx, &c, filter)+
+		" ORDER BY c.car_id LIMIT "+c.arg(limit+1)+" FOR UPDATE OF c", c.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var carIDs []int
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		carIDs = append(carIDs, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(carIDs) > limit {
+		return nil, storage.ErrTooManyMatches
+	}
+
+	return carIDs, nil
+}
+
+// changeCars applies fn to the locked cars and records the changes in the audit log.
+// fn is responsible for incrementing the versions of the cars.
+func changeCars(ctx context.Context, tx *sql.Tx, carIDs []int, action string, fn func() error) error {
+	if len(carIDs) == 0 {
+		return nil
+	}
+
+	before, err := carSnapshots(ctx, tx, carIDs)
+	if err != nil {
+		return err
+	}
+
+	if err = fn(); err != nil {
+		return err
+	}
+
+	after, err := carSnapshots(ctx, tx, carIDs)
+	if err != nil {
+		return err
+	}
+
+	changes := make([]change, len(carIDs))
+	for i, id := range carIDs {
+		changes[i] = change{entity: EntityCar, id: id, action: action, before: before[id], after: after[id]}
+	}
+
+	return record(ctx, tx, changes...)
+}
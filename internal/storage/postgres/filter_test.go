@@ -0,0 +1,139 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/auth"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFilterCars(t *testing.T) {
+	asOf := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name string
+		// role is the role of the caller, who is anonymous if it is empty
+		role      string
+		filter    CarFilter
+		wantConds []string
+		wantArgs  []any
+		noConds   []string
+	}{
+		{
+			name:      "empty filter selects alive cars of the current owners",
+			filter:    CarFilter{},
+			wantConds: []string{"c.deleted_at IS NULL"},
+			wantArgs:  []any{(*time.Time)(nil)},
+		},
+		{
+			name:      "as of selects the owners of that instant",
+			filter:    CarFilter{AsOf: &asOf},
+			wantConds: []string{"c.deleted_at IS NULL"},
+			wantArgs:  []any{&asOf},
+		},
+		{
+			name:     "include deleted",
+			role:     auth.RoleAdmin,
+			filter:   CarFilter{IncludeDeleted: true},
+			wantArgs: []any{(*time.Time)(nil)},
+			noConds:  []string{"c.deleted_at IS NULL"},
+		},
+		{
+			name:      "include deleted is ignored for non-admins",
+			role:      auth.RoleEditor,
+			filter:    CarFilter{IncludeDeleted: true},
+			wantConds: []string{"c.deleted_at IS NULL"},
+			wantArgs:  []any{(*time.Time)(nil)},
+		},
+		{
+			name:      "include deleted is ignored without a principal",
+			filter:    CarFilter{IncludeDeleted: true},
+			wantConds: []string{"c.deleted_at IS NULL"},
+			wantArgs:  []any{(*time.Time)(nil)},
+		},
+		{
+			name:      "exact fields and year range",
+			filter:    CarFilter{RegNum: "X123XX150", Mark: "Lada", YearFrom: 2000, YearTo: 2010, OwnerID: 7},
+			wantConds: []string{"c.reg_num = $2", "c.mark = $3", "c.year >= $4", "c.year <= $5", "o.owner_id = $6"},
+			wantArgs:  []any{(*time.Time)(nil), "X123XX150", "Lada", 2000, 2010, 7},
+		},
+		{
+			name:      "query matches cars and owners with one argument",
+			filter:    CarFilter{Query: "Ves"},
+			wantConds: []string{"c.model LIKE $2", "o.surname LIKE $2"},
+			wantArgs:  []any{(*time.Time)(nil), "%Ves%"},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.role != "" {
+				ctx = auth.WithPrincipal(ctx, auth.Principal{Name: "test", Role: tc.role})
+			}
+
+			var c conditions
+			query := filterCars(ctx, &c, tc.filter)
+
+			// every car is joined with the single ownership period valid at AsOf, so that
+			// a car that changed hands is returned once and not once per past owner
+			require.Contains(t, query, "JOIN cars_owners co ON c.car_id = co.car_id AND "+ownedAt("$1"))
+			require.Equal(t, 1, strings.Count(query, "JOIN cars_owners"))
+			// and only the cars of the tenant are selected, deleted or not
+			require.Contains(t, query, inTenant("c"))
+
+			for _, cond := range tc.wantConds {
+				assert.Contains(t, query, cond)
+			}
+			for _, cond := range tc.noConds {
+				assert.NotContains(t, query, cond)
+			}
+			assert.Equal(t, tc.wantArgs, c.args)
+		})
+	}
+}
+
+func TestDeleteCarsByFilter(t *testing.T) {
+	filter := CarFilter{Mark: "Lado"}
+
+	t.Run("deletes the matching cars", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY c.car_id LIMIT $3 FOR UPDATE OF c")).
+			WithArgs((*time.Time)(nil), "Lado", 3).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id"}).AddRow(5).AddRow(6))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE c.car_id = ANY($1)")).WithArgs([]int{5, 6}).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id", "snapshot"}).AddRow(5, `{"car_id": 5}`).AddRow(6, `{"car_id": 6}`))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET deleted_at = now()")).WithArgs([]int{5, 6}).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE c.car_id = ANY($1)")).WithArgs([]int{5, 6}).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id", "snapshot"}).AddRow(5, `{"car_id": 5}`).AddRow(6, `{"car_id": 6}`))
+		expectRecorded(mock, 2)
+		mock.ExpectCommit()
+
+		affected, err := s.DeleteCarsByFilter(context.Background(), filter, 2)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, affected)
+	})
+
+	t.Run("more matches than limit", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("FOR UPDATE OF c").WithArgs((*time.Time)(nil), "Lado", 2).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id"}).AddRow(5).AddRow(6))
+		mock.ExpectRollback()
+
+		_, err := s.DeleteCarsByFilter(context.Background(), filter, 1)
+		assert.ErrorIs(t, err, storage.ErrTooManyMatches)
+	})
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	const query = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	t.Run("migrated", func(t *testing.T) {
+		s, mock := newMock(t)
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(14, false))
+
+		version, dirty, err := s.SchemaVersion(context.Background())
+		require.NoError(t, err)
+		assert.EqualValues(t, 14, version)
+		assert.False(t, dirty)
+	})
+
+	t.Run("dirty", func(t *testing.T) {
+		s, mock := newMock(t)
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(15, true))
+
+		version, dirty, err := s.SchemaVersion(context.Background())
+		require.NoError(t, err)
+		assert.EqualValues(t, 15, version)
+		assert.True(t, dirty)
+	})
+
+	t.Run("never migrated", func(t *testing.T) {
+		s, mock := newMock(t)
+		mock.ExpectQuery(query).WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+
+		version, dirty, err := s.SchemaVersion(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, version)
+		assert.False(t, dirty)
+	})
+
+	t.Run("query failure", func(t *testing.T) {
+		s, mock := newMock(t)
+		mock.ExpectQuery(query).WillReturnError(errors.New("relation \"schema_migrations\" does not exist"))
+
+		_, _, err := s.SchemaVersion(context.Background())
+		assert.ErrorContains(t, err, "storage.postgres.SchemaVersion")
+	})
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// IdempotencyKey identifies a request made with an Idempotency-Key header.
+// The same key may be used independently on different endpoints and by different callers and tenants;
+// the tenant is the one stored in the context of the calls.
+type IdempotencyKey struct {
+	// Principal identifies the caller that chose the key
+	Principal   string
+	Key         string
+	Method      string
+	Path        string
+	RequestHash []byte
+}
+
+// StoredResponse is the response recorded for an idempotency key.
+type StoredResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+	// Truncated is set if the response was too large to keep, so that only its status is known
+	Truncated bool
+}
+
+// AcquireIdempotencyKey reserves the key for the request until ttl passes. It returns nil if the caller
+// must process the request and then call SaveIdempotentResponse or ReleaseIdempotencyKey, or the response
+// recorded for the key otherwise. It fails with storage.ErrIdempotencyKeyReused if the key was used
+// for a different request, and with storage.ErrIdempotencyKeyInProgress if that request is not finished yet.
+func (s *Storage) AcquireIdempotencyKey(ctx context.Context, key IdempotencyKey, ttl time.Duration) (*StoredResponse, error) {
+	const op = "storage.postgres.AcquireIdempotencyKey"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	// an expired key is taken over as if it was never used
+	var acquired bool
+	err := s.db.QueryRowContext(ctx, `INSERT INTO idempotency_keys(tenant_id, principal, key, method, path, request_hash, expires_at)
+								VALUES ($6, $7, $1, $2, $3, $4, now() + make_interval(secs => $5))
+								ON CONFLICT (tenant_id, principal, key, method, path) DO UPDATE
+								SET request_hash = EXCLUDED.request_hash, status = NULL, content_type = NULL, body = NULL,
+									truncated = false, created_at = now(), expires_at = EXCLUDED.expires_at
+								WHERE idempotency_keys.expires_at <= now()
+								RETURNING true`,
+		key.Key, key.Method, key.Path, key.RequestHash, ttl.Seconds(), tenant.FromContext(ctx), key.Principal).Scan(&acquired)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var hash []byte
+	var status sql.NullInt64
+	var contentType sql.NullString
+	var body []byte
+	var truncated bool
+	err = s.db.QueryRowContext(ctx, `SELECT request_hash, status, content_type, body, truncated FROM idempotency_keys
+								WHERE tenant_id = $4 AND principal = $5 AND key = $1 AND method = $2 AND path = $3`,
+		key.Key, key.Method, key.Path, tenant.FromContext(ctx), key.Principal).
+		Scan(&hash, &status, &contentType, &body, &truncated)
+	if errors.Is(err, sql.ErrNoRows) {
+		// the key was released in the meantime, the client has to retry
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyInProgress)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	if !bytes.Equal(hash, key.RequestHash) {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyReused)
+	}
+	if !status.Valid {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrIdempotencyKeyInProgress)
+	}
+
+	return &StoredResponse{
+		Status:      int(status.Int64),
+		ContentType: contentType.String,
+		Body:        body,
+		Truncated:   truncated,
+	}, nil
+}
+
+// SaveIdempotentResponse records the response to the request that acquired the key.
+func (s *Storage) SaveIdempotentResponse(ctx context.Context, key IdempotencyKey, resp StoredResponse) error {
+	const op = "storage.postgres.SaveIdempotentResponse"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, `UPDATE idempotency_keys SET status = $4, content_type = $5, body = $6, truncated = $7
+								WHERE tenant_id = $8 AND principal = $9 AND key = $1 AND method = $2 AND path = $3`,
+		key.Key, key.Method, key.Path, resp.Status, resp.ContentType, resp.Body, resp.Truncated, tenant.FromContext(ctx), key.Principal)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ReleaseIdempotencyKey frees a key acquired for a request that failed without a response worth replaying.
+func (s *Storage) ReleaseIdempotencyKey(ctx context.Context, key IdempotencyKey) error {
+	const op = "storage.postgres.ReleaseIdempotencyKey"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	_, err := s.db.ExecContext(ctx, `DELETE FROM idempotency_keys
+								WHERE tenant_id = $4 AND principal = $5 AND key = $1 AND method = $2 AND path = $3 AND status IS NULL`,
+		key.Key, key.Method, key.Path, tenant.FromContext(ctx), key.Principal)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// PurgeIdempotencyKeys deletes expired idempotency keys and returns their number.
+func (s *Storage) PurgeIdempotencyKeys(ctx context.Context) (int64, error) {
+	const op = "storage.postgres.PurgeIdempotencyKeys"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= now()")
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	purged, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,138 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestAcquireIdempotencyKey(t *testing.T) {
+	key := IdempotencyKey{Principal: "api_key:ci", Key: "key-1", Method: "POST", Path: "/car/save", RequestHash: []byte{1, 2, 3}}
+	columns := []string{"request_hash", "status", "content_type", "body", "truncated"}
+
+	// expectTaken expects the key to be held by an earlier request
+	expectTaken := func(mock sqlmock.Sqlmock) {
+		mock.ExpectQuery(regexp.QuoteMeta("INSERT INTO idempotency_keys(tenant_id, principal, key, method, path, request_hash, expires_at)")).
+			WithArgs("key-1", "POST", "/car/save", []byte{1, 2, 3}, float64(3600), tenant.Default, "api_key:ci").
+			WillReturnError(sql.ErrNoRows)
+	}
+
+	t.Run("new key", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery("INSERT INTO idempotency_keys").
+			WithArgs("key-1", "POST", "/car/save", []byte{1, 2, 3}, float64(3600), tenant.Default, "api_key:ci").
+			WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+
+		stored, err := s.AcquireIdempotencyKey(context.Background(), key, time.Hour)
+		require.NoError(t, err)
+
+		assert.Nil(t, stored)
+	})
+
+	t.Run("same key in another tenant", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery("INSERT INTO idempotency_keys").
+			WithArgs("key-1", "POST", "/car/save", []byte{1, 2, 3}, float64(3600), "acme", "api_key:ci").
+			WillReturnRows(sqlmock.NewRows([]string{"bool"}).AddRow(true))
+
+		stored, err := s.AcquireIdempotencyKey(tenant.WithTenant(context.Background(), "acme"), key, time.Hour)
+		require.NoError(t, err)
+
+		assert.Nil(t, stored)
+	})
+
+	t.Run("stored response", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectTaken(mock)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT request_hash, status, content_type, body, truncated FROM idempotency_keys")).
+			WithArgs("key-1", "POST", "/car/save", tenant.Default, "api_key:ci").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow([]byte{1, 2, 3}, 200, "application/json", []byte(`{"status":"OK"}`), false))
+
+		stored, err := s.AcquireIdempotencyKey(context.Background(), key, time.Hour)
+		require.NoError(t, err)
+
+		assert.Equal(t, &StoredResponse{Status: 200, ContentType: "application/json", Body: []byte(`{"status":"OK"}`)}, stored)
+	})
+
+	t.Run("truncated response", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectTaken(mock)
+		mock.ExpectQuery("SELECT request_hash").WithArgs("key-1", "POST", "/car/save", tenant.Default, "api_key:ci").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte{1, 2, 3}, 200, "text/csv", nil, true))
+
+		stored, err := s.AcquireIdempotencyKey(context.Background(), key, time.Hour)
+		require.NoError(t, err)
+
+		assert.True(t, stored.Truncated)
+	})
+
+	t.Run("different request", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectTaken(mock)
+		mock.ExpectQuery("SELECT request_hash").WithArgs("key-1", "POST", "/car/save", tenant.Default, "api_key:ci").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte{4, 5, 6}, 200, nil, nil, false))
+
+		_, err := s.AcquireIdempotencyKey(context.Background(), key, time.Hour)
+		assert.ErrorIs(t, err, storage.ErrIdempotencyKeyReused)
+	})
+
+	t.Run("in progress", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectTaken(mock)
+		mock.ExpectQuery("SELECT request_hash").WithArgs("key-1", "POST", "/car/save", tenant.Default, "api_key:ci").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow([]byte{1, 2, 3}, nil, nil, nil, false))
+
+		_, err := s.AcquireIdempotencyKey(context.Background(), key, time.Hour)
+		assert.ErrorIs(t, err, storage.ErrIdempotencyKeyInProgress)
+	})
+
+	t.Run("released in the meantime", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectTaken(mock)
+		mock.ExpectQuery("SELECT request_hash").WithArgs("key-1", "POST", "/car/save", tenant.Default, "api_key:ci").
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		_, err := s.AcquireIdempotencyKey(context.Background(), key, time.Hour)
+		assert.ErrorIs(t, err, storage.ErrIdempotencyKeyInProgress)
+	})
+}
+
+func TestSaveIdempotentResponse(t *testing.T) {
+	s, mock := newMock(t)
+
+	key := IdempotencyKey{Principal: "api_key:ci", Key: "key-1", Method: "POST", Path: "/cars/export"}
+
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE idempotency_keys SET status = $4, content_type = $5, body = $6, truncated = $7")).
+		WithArgs("key-1", "POST", "/cars/export", 200, "text/csv", []byte(nil), true, tenant.Default, "api_key:ci").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.SaveIdempotentResponse(context.Background(), key,
+		StoredResponse{Status: 200, ContentType: "text/csv", Truncated: true}))
+}
+
+func TestReleaseIdempotencyKey(t *testing.T) {
+	s, mock := newMock(t)
+
+	// only the key of the caller is released, and only while its request is in progress
+	mock.ExpectExec(regexp.QuoteMeta("WHERE tenant_id = $4 AND principal = $5 AND key = $1 AND method = $2 AND path = $3 AND status IS NULL")).
+		WithArgs("key-1", "POST", "/car/save", tenant.Default, "api_key:ci").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, s.ReleaseIdempotencyKey(context.Background(),
+		IdempotencyKey{Principal: "api_key:ci", Key: "key-1", Method: "POST", Path: "/car/save"}))
+}
@@ -0,0 +1,232 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/actor"
+	"errors"
+	"fmt"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// importBatchSize is the number of cars whose owners are upserted and which are copied
+// into the staging table at once.
+const importBatchSize = 5000
+
+// ImportRow is a car read from line Line of an imported file.
+type ImportRow struct {
+	Line int
+	Car  Car
+}
+
+// @Schema
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportOptions control how ImportCars treats the imported rows.
+type ImportOptions struct {
+	// DryRun only reports the rows that can't be imported, nothing is written
+	DryRun bool
+	// SkipInvalid imports the rows that can be imported even if others can't;
+	// otherwise nothing is imported if any row fails
+	SkipInvalid bool
+}
+
+// @Schema
+type ImportResult struct {
+	// Imported is the number of imported cars, or the number of cars that would be imported on a dry run
+	Imported int           `json:"imported"`
+	Errors   []ImportError `json:"errors"`
+}
+
+// importErrCarExists is reported for rows whose regNum is taken by a car in the catalog.
+const importErrCarExists = "car already exists"
+
+// ImportCars creates the cars together with their owners, which are upserted the way
+// GetOwnerID finds them. The regNums of the rows must be unique.
+// The cars are loaded with COPY into a staging table and inserted from there with a single
+// statement, so the number of round trips doesn't grow with the number of rows.
+// Rows whose regNum is taken are reported as errors.
+func (s *Storage) ImportCars(ctx context.Context, rows []ImportRow, opts ImportOptions) (ImportResult, error) {
+	const op = "storage.postgres.ImportCars"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	if opts.DryRun {
+		result, err := s.checkImport(ctx, rows)
+		if err != nil {
+			return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+		if len(result.Errors) > 0 && !opts.SkipInvalid {
+			result.Imported = 0
+		}
+
+		return result, nil
+	}
+
+	// COPY needs the driver connection, so the transaction is kept on a dedicated one
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err = setTenant(ctx, tx); err != nil {
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	_, err = tx.ExecContext(ctx, `CREATE TEMP TABLE import_cars (
+									line INT NOT NULL,
+									reg_num TEXT NOT NULL,
+									mark TEXT NOT NULL,
+									model TEXT NOT NULL,
+									year INT NOT NULL,
+									owner_id INT NOT NULL
+								) ON COMMIT DROP`)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	for start := 0; start < len(rows); start += importBatchSize {
+		batch := rows[start:min(start+importBatchSize, len(rows))]
+		if err = stageImport(ctx, conn, tx, batch); err != nil {
+			return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+		}
+	}
+
+	failed, err := insertImported(ctx, tx)
+	if err != nil {
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	result := ImportResult{Imported: len(rows) - len(failed), Errors: failed}
+	if len(failed) > 0 && !opts.SkipInvalid {
+		result.Imported = 0
+
+		return result, nil
+	}
+
+	if err = tx.Commit(); err != nil {
+		return ImportResult{}, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return result, nil
+}
+
+// checkImport reports the rows that ImportCars would refuse without writing anything.
+func (s *Storage) checkImport(ctx context.Context, rows []ImportRow) (ImportResult, error) {
+	lines := make(map[string]int, len(rows))
+	regNums := make([]string, len(rows))
+	for i, r := range rows {
+		lines[r.Car.RegNum] = r.Line
+		regNums[i] = r.Car.RegNum
+	}
+
+	result := ImportResult{Errors: []ImportError{}}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		taken, err := tx.QueryContext(ctx, "SELECT c.reg_num FROM cars c WHERE c.reg_num = ANY($1) AND c.deleted_at IS NULL AND "+inTenant("c"), regNums)
+		if err != nil {
+			return err
+		}
+		defer taken.Close()
+
+		for taken.Next() {
+			var regNum string
+			if err = taken.Scan(&regNum); err != nil {
+				return err
+			}
+			result.Errors = append(result.Errors, ImportError{Line: lines[regNum], Error: importErrCarExists})
+		}
+
+		return taken.Err()
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	result.Imported = len(rows) - len(result.Errors)
+
+	return result, nil
+}
+
+// stageImport upserts the owners of the rows and copies the rows into the staging table.
+func stageImport(ctx context.Context, conn *sql.Conn, tx *sql.Tx, rows []ImportRow) error {
+	owners := make([]Owner, len(rows))
+	for i, r := range rows {
+		owners[i] = r.Car.Owner
+	}
+
+	ownerIDs, changes, err := upsertOwners(ctx, tx, owners)
+	if err != nil {
+		return err
+	}
+	if err = record(ctx, tx, changes...); err != nil {
+		return err
+	}
+
+	// the transaction is open on this connection, so the copy is a part of it
+	return conn.Raw(func(driverConn any) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("unexpected driver connection")
+		}
+
+		_, err := c.Conn().CopyFrom(ctx, pgx.Identifier{"import_cars"},
+			[]string{"line", "reg_num", "mark", "model", "year", "owner_id"},
+			pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+				car := rows[i].Car
+				return []any{rows[i].Line, car.RegNum, car.Mark, car.Model, car.Year, ownerIDs[i]}, nil
+			}))
+
+		return err
+	})
+}
+
+// insertImported inserts the staged cars whose regNums are free, assigns them their owners
+// and records their creation. It returns the errors of the staged rows that weren't inserted.
+func insertImported(ctx context.Context, tx *sql.Tx) ([]ImportError, error) {
+	rows, err := tx.QueryContext(ctx, `WITH inserted AS (
+									 INSERT INTO cars(reg_num, mark, model, year)
+									 SELECT reg_num, mark, model, year FROM import_cars ORDER BY line
+									 ON CONFLICT (tenant_id, reg_num) WHERE deleted_at IS NULL DO NOTHING
+									 RETURNING car_id, reg_num, to_jsonb(cars) AS snapshot
+								 ), owned AS (
+									 INSERT INTO cars_owners(car_id, owner_id)
+									 SELECT n.car_id, i.owner_id FROM inserted n JOIN import_cars i USING (reg_num)
+								 ), audited AS (
+									 INSERT INTO audit_log(entity, entity_id, action, after, actor, request_id)
+									 SELECT $1::varchar, n.car_id, $2::varchar,
+										 n.snapshot || jsonb_build_object('owner_id', i.owner_id), $3::varchar, $4::varchar
+									 FROM inserted n JOIN import_cars i USING (reg_num)
+								 )
+								 SELECT i.line FROM import_cars i
+								 WHERE NOT EXISTS (SELECT 1 FROM inserted n WHERE n.reg_num = i.reg_num)
+								 ORDER BY i.line`,
+		EntityCar, actionCreate, actor.FromContext(ctx), nullable(ptr(middleware.GetReqID(ctx))))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	failed := []ImportError{}
+	for rows.Next() {
+		var line int
+		if err = rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		failed = append(failed, ImportError{Line: line, Error: importErrCarExists})
+	}
+
+	return failed, rows.Err()
+}
@@ -0,0 +1,52 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestImportCarsDryRun(t *testing.T) {
+	rows := []ImportRow{
+		{Line: 2, Car: Car{RegNum: "X123XX150"}},
+		{Line: 4, Car: Car{RegNum: "X124XX150"}},
+		{Line: 5, Car: Car{RegNum: "X125XX150"}},
+	}
+
+	expectTaken := func(mock sqlmock.Sqlmock) {
+		// only the regNums of the tenant are taken
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT c.reg_num FROM cars c WHERE c.reg_num = ANY($1) AND c.deleted_at IS NULL AND tenant_visible(c.tenant_id)")).
+			WithArgs([]string{"X123XX150", "X124XX150", "X125XX150"}).
+			WillReturnRows(sqlmock.NewRows([]string{"reg_num"}).AddRow("X124XX150"))
+		mock.ExpectCommit()
+	}
+
+	t.Run("reports taken regNums by line", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectTaken(mock)
+
+		result, err := s.ImportCars(context.Background(), rows, ImportOptions{DryRun: true})
+		require.NoError(t, err)
+
+		assert.Zero(t, result.Imported)
+		assert.Equal(t, []ImportError{{Line: 4, Error: importErrCarExists}}, result.Errors)
+	})
+
+	t.Run("counts the rows that would be imported when skipping invalid ones", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectTaken(mock)
+
+		result, err := s.ImportCars(context.Background(), rows, ImportOptions{DryRun: true, SkipInvalid: true})
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.Imported)
+		assert.Equal(t, []ImportError{{Line: 4, Error: importErrCarExists}}, result.Errors)
+	})
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestSaveOwnerExists(t *testing.T) {
+	s, mock := newMock(t)
+
+	expectBegin(mock, tenant.Default)
+	mock.ExpectQuery("INSERT INTO owners").
+		WithArgs("Ivan", "Ivanov", nil, nil, nil, nil, nil).
+		WillReturnError(&pgconn.PgError{Code: "23505", ConstraintName: "idx_owners_identity"})
+	mock.ExpectRollback()
+
+	_, err := s.SaveOwner(context.Background(), Owner{Name: "Ivan", Surname: "Ivanov", Patronymic: new(string)})
+	assert.ErrorIs(t, err, storage.ErrOwnerExists)
+}
+
+func TestMergeOwners(t *testing.T) {
+	t.Run("reassigns the periods and joins the consecutive ones", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		from := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerLocked(mock, 1, false)
+		expectOwnerLocked(mock, 2, false)
+		expectOwnerSnapshot(mock, 2)
+		mock.ExpectQuery("SELECT co.car_id FROM cars_owners co").WithArgs(2).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id"}).AddRow(10))
+		expectCarSnapshot(mock, 10)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars_owners SET owner_id = $1 WHERE owner_id = $2")).
+			WithArgs(1, 2).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		// car 10 was held by the source and then by the target, which is now one open period
+		mock.ExpectQuery("islands").WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"car_id", "min", "array_agg"}).AddRow(10, from, nil))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM cars_owners")).
+			WithArgs(10, from, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars_owners SET valid_to = $3 WHERE car_id = $1 AND valid_from = $2")).
+			WithArgs(10, from, nil).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET version = version + 1 WHERE car_id = $1")).
+			WithArgs(10).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarSnapshot(mock, 10)
+		// the car the source held is recorded as handed over
+		expectRecorded(mock, 1)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE owners SET deleted_at = now() WHERE owner_id = $1")).
+			WithArgs(2).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOwnerChanged(mock, 2)
+		mock.ExpectCommit()
+
+		reassigned, err := s.MergeOwners(context.Background(), 1, 2)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, reassigned)
+	})
+
+	t.Run("unknown owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerLocked(mock, 1, false)
+		mock.ExpectQuery("FOR UPDATE").WithArgs(2, false).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		_, err := s.MergeOwners(context.Background(), 1, 2)
+		assert.ErrorIs(t, err, storage.ErrOwnerNotFound)
+	})
+}
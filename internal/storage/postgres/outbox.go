@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/storage"
+	"fmt"
+)
+
+// OutboxMessage is an event waiting in the outbox to be published.
+type OutboxMessage struct {
+	ID    int64
+	Event Event
+}
+
+// Outbox is the exclusive right to publish the outbox. It is held by a single relay
+// across all replicas, so that messages are published in the order they were queued.
+type Outbox struct {
+	conn *sql.Conn
+}
+
+// AcquireOutbox takes the outbox lock with a connection of its own and fails with
+// storage.ErrOutboxBusy if another relay holds it. The lock is held until Release is
+// called or the connection fails, which makes the methods of the Outbox fail too.
+func (s *Storage) AcquireOutbox(ctx context.Context) (*Outbox, error) {
+	const op = "storage.postgres.AcquireOutbox"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	var locked bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext('outbox'))").Scan(&locked); err != nil {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !locked {
+		_ = conn.Close()
+
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrOutboxBusy)
+	}
+
+	return &Outbox{conn: conn}, nil
+}
+
+// Messages returns up to limit of the oldest messages in the outbox.
+func (o *Outbox) Messages(ctx context.Context, limit int) ([]OutboxMessage, error) {
+	const op = "storage.postgres.Outbox.Messages"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	rows, err := o.conn.QueryContext(ctx, `SELECT m.message_id, e.event_id, e.tenant_id, e.type, e.entity, e.entity_id, e.data, e.created_at
+									 FROM outbox m
+									 JOIN events e ON e.event_id = m.event_id
+									 ORDER BY m.message_id
+									 LIMIT $1`, limit)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var messages []OutboxMessage
+	for rows.Next() {
+		var m OutboxMessage
+		var data []byte
+		err = rows.Scan(&m.ID, &m.Event.ID, &m.Event.Tenant, &m.Event.Type, &m.Event.Entity, &m.Event.EntityID, &data, &m.Event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		m.Event.Data = data
+
+		messages = append(messages, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return messages, nil
+}
+
+// Ack removes published messages from the outbox.
+func (o *Outbox) Ack(ctx context.Context, messageIDs []int64) error {
+	const op = "storage.postgres.Outbox.Ack"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	if len(messageIDs) == 0 {
+		return nil
+	}
+
+	if _, err := o.conn.ExecContext(ctx, "DELETE FROM outbox WHERE message_id = ANY($1)", messageIDs); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// Release gives the outbox lock up and returns the connection to the pool.
+func (o *Outbox) Release() error {
+	const op = "storage.postgres.Outbox.Release"
+
+	_, err := o.conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext('outbox'))")
+	if closeErr := o.conn.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
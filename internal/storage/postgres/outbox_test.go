@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func expectOutboxLock(mock sqlmock.Sqlmock, locked bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT pg_try_advisory_lock(hashtext('outbox'))")).
+		WillReturnRows(sqlmock.NewRows([]string{"locked"}).AddRow(locked))
+}
+
+func TestAcquireOutbox(t *testing.T) {
+	t.Run("busy", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectOutboxLock(mock, false)
+
+		_, err := s.AcquireOutbox(context.Background())
+		assert.ErrorIs(t, err, storage.ErrOutboxBusy)
+	})
+
+	t.Run("messages are read and acked on the locked connection", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		expectOutboxLock(mock, true)
+		mock.ExpectQuery("(?s)FROM outbox m.*ORDER BY m.message_id.*LIMIT \\$1").WithArgs(100).
+			WillReturnRows(sqlmock.NewRows([]string{"message_id", "event_id", "tenant_id", "type", "entity", "entity_id", "data", "created_at"}).
+				AddRow(1, 42, "acme", "car.updated", EntityCar, 5, []byte(`{"mark": "Lada"}`), createdAt))
+		mock.ExpectExec(regexp.QuoteMeta("DELETE FROM outbox WHERE message_id = ANY($1)")).WithArgs([]int64{1}).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_unlock(hashtext('outbox'))")).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		outbox, err := s.AcquireOutbox(context.Background())
+		require.NoError(t, err)
+
+		messages, err := outbox.Messages(context.Background(), 100)
+		require.NoError(t, err)
+		assert.Equal(t, []OutboxMessage{{ID: 1, Event: Event{ID: 42, Tenant: "acme", Type: "car.updated", Entity: EntityCar, EntityID: 5,
+			Data: json.RawMessage(`{"mark": "Lada"}`), CreatedAt: createdAt}}}, messages)
+
+		require.NoError(t, outbox.Ack(context.Background(), []int64{1}))
+		// nothing published, nothing to remove
+		require.NoError(t, outbox.Ack(context.Background(), nil))
+
+		require.NoError(t, outbox.Release())
+	})
+}
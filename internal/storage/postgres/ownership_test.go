@@ -0,0 +1,235 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// valueConverter passes slices through as pgx does for array parameters
+// and converts everything else the way database/sql does by default.
+type valueConverter struct{}
+
+func (valueConverter) ConvertValue(v any) (driver.Value, error) {
+	if reflect.ValueOf(v).Kind() == reflect.Slice {
+		return v, nil
+	}
+
+	return driver.DefaultParameterConverter.ConvertValue(v)
+}
+
+// expectBegin expects a transaction to be started and bound to the tenant.
+func expectBegin(mock sqlmock.Sqlmock, tenantID string) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT set_config('app.tenant_id', $1, true)")).
+		WithArgs(tenantID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+// newMock returns a Storage backed by sqlmock, checking on cleanup that every expectation was met.
+func newMock(t *testing.T) (*Storage, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New(sqlmock.ValueConverterOption(valueConverter{}))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		assert.NoError(t, mock.ExpectationsWereMet())
+		_ = db.Close()
+	})
+
+	return &Storage{db: db}, mock
+}
+
+var (
+	// ownerColumnNames and carColumnNames name the columns of ownerColumns and carColumns
+	ownerColumnNames = []string{"owner_id", "name", "surname", "patronymic", "birth_date", "phone", "email",
+		"document_number", "deleted_at", "version"}
+	carColumnNames = append([]string{"car_id", "reg_num", "mark", "model", "year", "deleted_at", "version"}, ownerColumnNames...)
+)
+
+// ownerRow returns the values of an owner row of version 1 scanned by ownerFields,
+// with the optional columns left NULL.
+func ownerRow(id int, name, surname string, patronymic any) []driver.Value {
+	row := make([]driver.Value, len(ownerColumnNames))
+	copy(row, []driver.Value{id, name, surname, patronymic})
+	row[len(row)-1] = 1
+
+	return row
+}
+
+// carRow returns the values of a car row of version 1 scanned by carFields, with the optional columns left NULL.
+func carRow(id int, regNum, mark, model string, year int, owner []driver.Value) []driver.Value {
+	row := make([]driver.Value, len(carColumnNames)-len(ownerColumnNames))
+	copy(row, []driver.Value{id, regNum, mark, model, year})
+	row[len(row)-1] = 1
+
+	return append(row, owner...)
+}
+
+func TestOwnedAt(t *testing.T) {
+	assert.Equal(t,
+		"co.valid_from <= COALESCE($2::timestamptz, now()) AND (co.valid_to IS NULL OR co.valid_to > COALESCE($2::timestamptz, now()))",
+		ownedAt("$2"))
+}
+
+func TestGetOwnerCars(t *testing.T) {
+	asOf := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("cars held at the instant", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS(SELECT 1 FROM owners o WHERE o.owner_id = $1 AND o.deleted_at IS NULL AND tenant_visible(o.tenant_id))")).
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery(regexp.QuoteMeta(ownedAt("$2"))).
+			WithArgs(7, asOf).
+			WillReturnRows(sqlmock.NewRows(carColumnNames).
+				AddRow(carRow(10, "X123XX150", "Lada", "Vesta", 2002, ownerRow(7, "Ivan", "Ivanov", "Ivanovich"))...).
+				AddRow(carRow(11, "A001AA77", "Lada", "Niva", 1999, ownerRow(7, "Ivan", "Ivanov", "Ivanovich"))...))
+		mock.ExpectCommit()
+
+		cars, err := s.GetOwnerCars(context.Background(), 7, asOf)
+		require.NoError(t, err)
+
+		require.Len(t, cars, 2)
+		assert.Equal(t, "X123XX150", cars[0].RegNum)
+		assert.Equal(t, "Ivanov", cars[1].Owner.Surname)
+	})
+
+	t.Run("no cars at the instant", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("FROM cars c").WithArgs(7, asOf).
+			WillReturnRows(sqlmock.NewRows(carColumnNames))
+		mock.ExpectCommit()
+
+		cars, err := s.GetOwnerCars(context.Background(), 7, asOf)
+		require.NoError(t, err)
+
+		assert.NotNil(t, cars)
+		assert.Empty(t, cars)
+	})
+
+	t.Run("unknown owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		// owners of other tenants are unknown as well
+		expectBegin(mock, "acme")
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+		mock.ExpectRollback()
+
+		_, err := s.GetOwnerCars(tenant.WithTenant(context.Background(), "acme"), 7, asOf)
+		assert.ErrorIs(t, err, storage.ErrOwnerNotFound)
+	})
+}
+
+func TestGetCarByRegNum(t *testing.T) {
+	asOf := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("owner at the instant", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(regexp.QuoteMeta(ownedAt("$2"))).
+			WithArgs("X123XX150", asOf).
+			WillReturnRows(sqlmock.NewRows(carColumnNames).
+				AddRow(carRow(10, "X123XX150", "Lada", "Vesta", 2002, ownerRow(2, "Petr", "Petrov", nil))...))
+		mock.ExpectCommit()
+
+		car, err := s.GetCarByRegNum(context.Background(), "X123XX150", asOf)
+		require.NoError(t, err)
+
+		assert.Equal(t, "Petr", car.Owner.Name)
+	})
+
+	t.Run("no owner at the instant", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("FROM cars c").WithArgs("X123XX150", asOf).
+			WillReturnRows(sqlmock.NewRows(carColumnNames))
+		mock.ExpectRollback()
+
+		_, err := s.GetCarByRegNum(context.Background(), "X123XX150", asOf)
+		assert.ErrorIs(t, err, storage.ErrCarNotFound)
+	})
+}
+
+func TestPatchCar(t *testing.T) {
+	phone := "+79991234567"
+	newOwner := Owner{Name: "Petr", Surname: "Petrov", Phone: &phone}
+
+	t.Run("closes the current period and opens a new one", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("INSERT INTO owners").
+			WithArgs("Petr", "Petrov", nil, nil, phone, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_id", "inserted"}).AddRow(3, true))
+		// the new owner is recorded as created
+		expectOwnerSnapshot(mock, 3)
+		expectRecorded(mock, 1)
+		expectCarLocked(mock, 5, false)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars_owners SET valid_to = now() WHERE car_id = $1 AND valid_to IS NULL")).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(regexp.QuoteMeta("INSERT INTO cars_owners(car_id, owner_id) VALUES ($1, $2)")).
+			WithArgs(5, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarChanged(mock, 5)
+		mock.ExpectCommit()
+
+		version, err := s.PatchCar(context.Background(), 5, CarPatch{Owner: &newOwner}, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, version)
+	})
+
+	t.Run("keeps the current period if the new one can't be opened", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("INSERT INTO owners").
+			WithArgs("Petr", "Petrov", nil, nil, phone, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"owner_id", "inserted"}).AddRow(3, false))
+		expectCarLocked(mock, 5, false)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec("UPDATE cars_owners").WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO cars_owners").WithArgs(5, 3).
+			WillReturnError(errors.New("connection lost"))
+		mock.ExpectRollback()
+
+		_, err := s.PatchCar(context.Background(), 5, CarPatch{Owner: &newOwner}, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("deleted car", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("INSERT INTO owners").
+			WillReturnRows(sqlmock.NewRows([]string{"owner_id", "inserted"}).AddRow(3, false))
+		mock.ExpectQuery("FROM cars").WithArgs(5, false).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		_, err := s.PatchCar(context.Background(), 5, CarPatch{Owner: &newOwner}, nil)
+		assert.ErrorIs(t, err, storage.ErrCarNotFound)
+	})
+}
@@ -1,9 +1,18 @@
 package postgres
 
 import (
+	"context"
 	"database/sql"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"errors"
 	"fmt"
-	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/stdlib"
+	"slices"
+	"strings"
+	"time"
 )
 
 /*
@@ -22,27 +31,91 @@ import (
 
 // @Schema
 type Owner struct {
-	Name       string `json:"name"`
-	Surname    string `json:"surname"`
-	Patronymic string `json:"patronymic"`
+	ID         int     `json:"id,omitempty"`
+	Name       string  `json:"name"`
+	Surname    string  `json:"surname"`
+	Patronymic *string `json:"patronymic,omitempty"`
+	// BirthDate is formatted as YYYY-MM-DD
+	BirthDate      *string    `json:"birthDate,omitempty"`
+	Phone          *string    `json:"phone,omitempty"`
+	Email          *string    `json:"email,omitempty"`
+	DocumentNumber *string    `json:"documentNumber,omitempty"`
+	DeletedAt      *time.Time `json:"deletedAt,omitempty"`
+	// Version is incremented on every change of the owner
+	Version int `json:"version,omitempty"`
 }
 
 // @Schema
 type Car struct {
-	RegNum string `json:"regNum"`
-	Mark   string `json:"mark"`
-	Model  string `json:"model"`
-	Year   int    `json:"year"`
-	Owner  Owner  `json:"owner"`
+	ID        int        `json:"id,omitempty"`
+	RegNum    string     `json:"regNum"`
+	Mark      string     `json:"mark"`
+	Model     string     `json:"model"`
+	Year      int        `json:"year"`
+	Owner     Owner      `json:"owner"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// Version is incremented on every change of the car, including a change of its owner
+	Version int `json:"version,omitempty"`
+}
+
+// CarPatch holds the car fields to change; nil fields are left as they are.
+type CarPatch struct {
+	RegNum *string `json:"regNum,omitempty"`
+	Mark   *string `json:"mark,omitempty"`
+	Model  *string `json:"model,omitempty"`
+	Year   *int    `json:"year,omitempty"`
+	Owner  *Owner  `json:"owner,omitempty"`
+}
+
+// OwnerPatch holds the owner fields to change; nil fields are left as they are
+// and empty optional fields are cleared.
+type OwnerPatch struct {
+	Name           *string
+	Surname        *string
+	Patronymic     *string
+	BirthDate      *string
+	Phone          *string
+	Email          *string
+	DocumentNumber *string
 }
 
 // @Schema
 type SearchRequest struct {
-	Query    string `json:"query"`
-	PageNum  int    `json:"pageNum"`
-	PageSize int    `json:"pageSize"`
+	CarFilter
+	PageNum  int `json:"pageNum"`
+	PageSize int `json:"pageSize"`
+}
+
+// @Schema
+type OwnerDuplicate struct {
+	Owner     Owner   `json:"owner"`
+	Candidate Owner   `json:"candidate"`
+	Score     float64 `json:"score"`
+}
+
+// @Schema
+type OwnerSearchRequest struct {
+	Name       string `json:"name"`
+	Surname    string `json:"surname"`
+	Patronymic string `json:"patronymic"`
+	PageNum    int    `json:"pageNum"`
+	PageSize   int    `json:"pageSize"`
+	// IncludeDeleted also returns soft-deleted owners, to admins only
+	IncludeDeleted bool `json:"includeDeleted,omitempty"`
 }
 
+// OwnerDeletePolicy defines what happens to the cars of a deleted owner.
+type OwnerDeletePolicy string
+
+const (
+	// OwnerDeleteRestrict refuses to delete an owner who still holds cars
+	OwnerDeleteRestrict OwnerDeletePolicy = "restrict"
+	// OwnerDeleteCascade deletes the cars the owner holds together with the owner
+	OwnerDeleteCascade OwnerDeletePolicy = "cascade"
+	// OwnerDeleteReassign hands the cars the owner holds over to another owner
+	OwnerDeleteReassign OwnerDeletePolicy = "reassign"
+)
+
 type Storage struct {
 	db *sql.DB
 }
@@ -50,20 +123,280 @@ type Storage struct {
 func New(dbUrl string) (*Storage, error) {
 	const op = "storage.postgres.New"
 
-	db, err := sql.Open("pgx", dbUrl)
+	config, err := pgx.ParseConfig(dbUrl)
 	if err != nil {
 		return nil, fmt.Errorf("%s : %w", op, err)
 	}
+	config.Tracer = queryTracer{}
+
+	db := stdlib.OpenDB(*config)
 
 	return &Storage{db: db}, nil
 }
 
-func (s *Storage) SaveOwner(owner Owner) (int, error) {
+// Stats returns the statistics of the connection pool.
+func (s *Storage) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// Close closes the connection pool, waiting for the queries in progress to finish.
+func (s *Storage) Close() error {
+	const op = "storage.postgres.Close"
+
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// withTx runs fn in a transaction of the tenant stored in ctx, committing it if fn succeeds
+// and rolling it back otherwise. Cars, owners and their ownership are only accessed this way.
+func (s *Storage) withTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if err = setTenant(ctx, tx); err != nil {
+		return err
+	}
+
+	if err = fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// setTenant binds tx to the tenant stored in ctx: rows are created in the tenant and
+// row-level security hides the rows of other tenants.
+func setTenant(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT set_config('app.tenant_id', $1, true)", tenant.FromContext(ctx))
+
+	return err
+}
+
+// inTenant returns a condition matching the rows of the table aliased as alias that belong to the tenant
+// of the transaction. Row-level security enforces the same, unless the database user bypasses it,
+// so every query starting from cars, owners or cars_owners has this condition.
+func inTenant(alias string) string {
+	return "tenant_visible(" + alias + ".tenant_id)"
+}
+
+// carColumns lists the car columns of the cars table aliased as c, in the order expected by carFields.
+const carColumns = "c.car_id, c.reg_num, c.mark, c.model, c.year, c.deleted_at, c.version"
+
+// carFields returns the scan destinations for carColumns followed by ownerColumns.
+func carFields(car *Car) []any {
+	return append([]any{&car.ID, &car.RegNum, &car.Mark, &car.Model, &car.Year, &car.DeletedAt, &car.Version}, ownerFields(&car.Owner)...)
+}
+
+// ownerColumns lists the owner columns of the owners table aliased as alias,
+// in the order expected by ownerFields.
+func ownerColumns(alias string) string {
+	return alias + ".owner_id, " + alias + ".name, " + alias + ".surname, " + alias + ".patronymic, " +
+		"to_char(" + alias + ".birth_date, 'YYYY-MM-DD'), " + alias + ".phone, " + alias + ".email, " +
+		alias + ".document_number, " + alias + ".deleted_at, " + alias + ".version"
+}
+
+// ownerFields returns the scan destinations for ownerColumns.
+func ownerFields(owner *Owner) []any {
+	return []any{&owner.ID, &owner.Name, &owner.Surname, &owner.Patronymic,
+		&owner.BirthDate, &owner.Phone, &owner.Email, &owner.DocumentNumber, &owner.DeletedAt, &owner.Version}
+}
+
+// nullable maps an absent or empty optional value to NULL.
+func nullable(v *string) any {
+	if v == nil || *v == "" {
+		return nil
+	}
+
+	return *v
+}
+
+// isUniqueViolation reports whether err is caused by a unique constraint.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// lockCar locks the car until the end of tx and returns its version. It fails with storage.ErrCarNotFound
+// unless the car exists and is soft-deleted exactly when deleted is true.
+func lockCar(ctx context.Context, tx *sql.Tx, carID int, deleted bool) (int, error) {
+	var version int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM cars c WHERE c.car_id = $1 AND (c.deleted_at IS NOT NULL) = $2 AND "+inTenant("c")+" FOR UPDATE",
+		carID, deleted).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrCarNotFound
+	}
+
+	return version, err
+}
+
+// lockOwner locks the owner until the end of tx and returns their version. It fails with storage.ErrOwnerNotFound
+// unless the owner exists and is soft-deleted exactly when deleted is true.
+func lockOwner(ctx context.Context, tx *sql.Tx, ownerID int, deleted bool) (int, error) {
+	var version int
+	err := tx.QueryRowContext(ctx, "SELECT version FROM owners o WHERE o.owner_id = $1 AND (o.deleted_at IS NOT NULL) = $2 AND "+inTenant("o")+" FOR UPDATE",
+		ownerID, deleted).Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, storage.ErrOwnerNotFound
+	}
+
+	return version, err
+}
+
+// matchVersion fails with storage.ErrVersionMismatch unless expected is nil or contains actual.
+func matchVersion(expected []int, actual int) error {
+	if expected != nil && !slices.Contains(expected, actual) {
+		return storage.ErrVersionMismatch
+	}
+
+	return nil
+}
+
+// changeCar locks the car, applies fn to it, increments its version and records the change
+// in the audit log. It returns the new version. deleted tells whether fn expects a soft-deleted car,
+// a non-nil versions must contain the current version.
+func changeCar(ctx context.Context, tx *sql.Tx, carID int, action string, deleted bool, versions []int, fn func() error) (int, error) {
+	current, err := lockCar(ctx, tx, carID, deleted)
+	if err != nil {
+		return 0, err
+	}
+	if err = matchVersion(versions, current); err != nil {
+		return 0, err
+	}
+
+	before, err := carSnapshot(ctx, tx, carID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = fn(); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE cars SET version = version + 1 WHERE car_id = $1", carID)
+	if err != nil {
+		return 0, err
+	}
+
+	after, err := carSnapshot(ctx, tx, carID)
+	if err != nil {
+		return 0, err
+	}
+
+	return current + 1, record(ctx, tx, change{entity: EntityCar, id: carID, action: action, before: before, after: after})
+}
+
+// changeOwner locks the owner, applies fn to them, increments their version and records the change
+// in the audit log. It returns the new version. deleted tells whether fn expects a soft-deleted owner,
+// a non-nil versions must contain the current version.
+func changeOwner(ctx context.Context, tx *sql.Tx, ownerID int, action string, deleted bool, versions []int, fn func() error) (int, error) {
+	current, err := lockOwner(ctx, tx, ownerID, deleted)
+	if err != nil {
+		return 0, err
+	}
+	if err = matchVersion(versions, current); err != nil {
+		return 0, err
+	}
+
+	before, err := ownerSnapshot(ctx, tx, ownerID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err = fn(); err != nil {
+		return 0, err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE owners SET version = version + 1 WHERE owner_id = $1", ownerID)
+	if err != nil {
+		return 0, err
+	}
+
+	after, err := ownerSnapshot(ctx, tx, ownerID)
+	if err != nil {
+		return 0, err
+	}
+
+	return current + 1, record(ctx, tx, change{entity: EntityOwner, id: ownerID, action: action, before: before, after: after})
+}
+
+// insertOwner inserts the owner and records its creation.
+func insertOwner(ctx context.Context, tx *sql.Tx, owner Owner) (int, error) {
+	var id int
+	err := tx.QueryRowContext(ctx, `INSERT INTO owners(name, surname, patronymic, birth_date, phone, email, document_number)
+								VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING owner_id`,
+		owner.Name, owner.Surname, nullable(owner.Patronymic), nullable(owner.BirthDate), nullable(owner.Phone),
+		nullable(owner.Email), nullable(owner.DocumentNumber)).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+
+	after, err := ownerSnapshot(ctx, tx, id)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, record(ctx, tx, change{entity: EntityOwner, id: id, action: actionCreate, after: after})
+}
+
+// ownerID returns the id of the alive owner with the same normalized identity
+// (see owner_name_part in the schema), creating the owner if there is none.
+func ownerID(ctx context.Context, tx *sql.Tx, owner Owner) (int, error) {
+	var id int
+	var inserted bool
+	err := tx.QueryRowContext(ctx, `INSERT INTO owners(name, surname, patronymic, birth_date, phone, email, document_number)
+								VALUES ($1, $2, $3, $4, $5, $6, $7)
+								ON CONFLICT (tenant_id, identity_key) WHERE deleted_at IS NULL DO UPDATE SET name = owners.name
+								RETURNING owner_id, xmax = 0`,
+		owner.Name, owner.Surname, nullable(owner.Patronymic), nullable(owner.BirthDate), nullable(owner.Phone),
+		nullable(owner.Email), nullable(owner.DocumentNumber)).Scan(&id, &inserted)
+	if err != nil || !inserted {
+		return id, err
+	}
+
+	after, err := ownerSnapshot(ctx, tx, id)
+	if err != nil {
+		return -1, err
+	}
+
+	return id, record(ctx, tx, change{entity: EntityOwner, id: id, action: actionCreate, after: after})
+}
+
+// setCarOwner closes the current ownership period of the car and opens a new one for the owner.
+func setCarOwner(ctx context.Context, tx *sql.Tx, carID, ownerID int) error {
+	// the previous ownership is closed rather than deleted so that point-in-time queries keep working
+	_, err := tx.ExecContext(ctx, "UPDATE cars_owners SET valid_to = now() WHERE car_id = $1 AND valid_to IS NULL", carID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "INSERT INTO cars_owners(car_id, owner_id) VALUES ($1, $2)", carID, ownerID)
+
+	return err
+}
+
+func (s *Storage) SaveOwner(ctx context.Context, owner Owner) (int, error) {
 	const op = "storage.postgres.SaveOwner"
 
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
 	var id int
-	err := s.db.QueryRow("INSERT INTO owners(name, surname, patronymic) VALUES ($1, $2, $3) RETURNING owner_id",
-		owner.Name, owner.Surname, owner.Patronymic).Scan(&id)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = insertOwner(ctx, tx, owner)
+
+		return err
+	})
+	if isUniqueViolation(err) {
+		return -1, fmt.Errorf("%s: %w", op, storage.ErrOwnerExists)
+	}
 	if err != nil {
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
@@ -71,134 +404,528 @@ func (s *Storage) SaveOwner(owner Owner) (int, error) {
 	return id, nil
 }
 
-func (s *Storage) GetOwnerID(owner Owner) (int, error) {
+// GetOwnerID returns the id of the alive owner with the same normalized identity
+// (see owner_name_part in the schema), creating the owner if there is none.
+func (s *Storage) GetOwnerID(ctx context.Context, owner Owner) (int, error) {
 	const op = "storage.postgres.GetOwnerID"
 
-	rows, err := s.db.Query("SELECT id FROM owners WHERE name = $1 AND surname = $2 AND patronymic = $3",
-		owner.Name, owner.Surname, owner.Patronymic)
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var id int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var err error
+		id, err = ownerID(ctx, tx, owner)
+
+		return err
+	})
 	if err != nil {
 		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
+	return id, nil
+}
+
+func (s *Storage) SaveCar(ctx context.Context, car Car) (int, error) {
+	const op = "storage.postgres.SaveCar"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
 	var id int
-	if rows.Next() {
-		err = rows.Scan(&id)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(ctx, "INSERT INTO cars(reg_num, mark, model, year) VALUES ($1, $2, $3, $4) RETURNING car_id",
+			car.RegNum, car.Mark, car.Model, car.Year).Scan(&id)
+		if isUniqueViolation(err) {
+			return storage.ErrCarExists
+		}
+		if err != nil {
+			return err
+		}
+
+		ownerID, err := ownerID(ctx, tx, car.Owner)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "INSERT INTO cars_owners(car_id, owner_id) VALUES ($1, $2)", id, ownerID)
 		if err != nil {
-			return -1, fmt.Errorf("%s: %w", op, err)
+			return err
 		}
-	} else {
-		id, err = s.SaveOwner(owner)
+
+		after, err := carSnapshot(ctx, tx, id)
 		if err != nil {
-			return -1, fmt.Errorf("%s: %w", op, err)
+			return err
 		}
+
+		return record(ctx, tx, change{entity: EntityCar, id: id, action: actionCreate, after: after})
+	})
+	if err != nil {
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
 	return id, nil
 }
 
-func (s *Storage) SaveCar(car Car) (int, error) {
-	const op = "storage.postgres.SaveCar"
+func (s *Storage) GetCarsBySearchRequest(ctx context.Context, searchRequest SearchRequest) ([]Car, error) {
+	var cars []Car
+	err := s.EachCarBySearchRequest(ctx, searchRequest, func(car Car) error {
+		cars = append(cars, car)
 
-	var id int
-	err := s.db.QueryRow("INSERT INTO cars(reg_num, mark, model, year) VALUES ($1, $2, $3, $4) RETURNING car_id",
-		car.RegNum, car.Mark, car.Model, car.Year).Scan(&id)
+		return nil
+	})
 	if err != nil {
-		return -1, fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
 
-	ownerID, err := s.GetOwnerID(car.Owner)
+	return cars, nil
+}
+
+// EachCarBySearchRequest calls fn for every car of the requested page as it is read from the database,
+// without holding the page in memory. A zero PageSize returns all matching cars.
+// An error returned by fn stops the iteration and is returned as is.
+func (s *Storage) EachCarBySearchRequest(ctx context.Context, searchRequest SearchRequest, fn func(car Car) error) error {
+	const op = "storage.postgres.EachCarBySearchRequest"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var c conditions
+	query := "SELECT " + carColumns + ", " + ownerColumns("o") + filterCars(ctx, &c, searchRequest.CarFilter) + " ORDER BY c.car_id"
+	if searchRequest.PageSize > 0 {
+		query += c.page(searchRequest.PageNum, searchRequest.PageSize)
+	}
+
+	return s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, query, c.args...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var car Car
+			err = rows.Scan(carFields(&car)...)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op, err)
+			}
+
+			if err = fn(car); err != nil {
+				return err
+			}
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+
+		return nil
+	})
+}
+
+// ownedAt returns a condition on cars_owners co matching the ownership period
+// that contains the instant bound to param. A NULL instant means now.
+func ownedAt(param string) string {
+	at := "COALESCE(" + param + "::timestamptz, now())"
+
+	return "co.valid_from <= " + at + " AND (co.valid_to IS NULL OR co.valid_to > " + at + ")"
+}
+
+func (s *Storage) GetCarByRegNum(ctx context.Context, regNum string, asOf time.Time) (Car, error) {
+	const op = "storage.postgres.GetCarByRegNum"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var car Car
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `SELECT `+carColumns+`, `+ownerColumns("o")+`
+								FROM cars c
+								JOIN cars_owners co ON c.car_id = co.car_id AND `+ownedAt("$2")+`
+								JOIN owners o ON co.owner_id = o.owner_id
+								WHERE c.reg_num = $1 AND c.deleted_at IS NULL AND `+inTenant("c"), regNum, asOf).
+			Scan(carFields(&car)...)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Car{}, fmt.Errorf("%s: %w", op, storage.ErrCarNotFound)
+	}
 	if err != nil {
-		return -1, fmt.Errorf("%s: %w", op, err)
+		return Car{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = s.db.Exec("INSERT INTO cars_owners(car_reg_num, owner_id) VALUES ($1, $2)",
-		car.RegNum, ownerID)
+	return car, nil
+}
+
+// GetCar returns an alive car together with its current owner.
+func (s *Storage) GetCar(ctx context.Context, carID int) (Car, error) {
+	const op = "storage.postgres.GetCar"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var car Car
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, `SELECT `+carColumns+`, `+ownerColumns("o")+`
+								FROM cars c
+								JOIN cars_owners co ON c.car_id = co.car_id AND co.valid_to IS NULL
+								JOIN owners o ON co.owner_id = o.owner_id
+								WHERE c.car_id = $1 AND c.deleted_at IS NULL AND `+inTenant("c"), carID).
+			Scan(carFields(&car)...)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Car{}, fmt.Errorf("%s: %w", op, storage.ErrCarNotFound)
+	}
 	if err != nil {
-		return -1, fmt.Errorf("%s: %w", op, err)
+		return Car{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return id, nil
+	return car, nil
 }
 
-func (s *Storage) GetCarsBySearchRequest(searchRequest SearchRequest) ([]Car, error) {
-	const op = "storage.postgres.GetCarsBySearchRequest"
+func (s *Storage) GetOwner(ctx context.Context, ownerID int) (Owner, error) {
+	const op = "storage.postgres.GetOwner"
 
-	var cars []Car
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
 
-	rows, err := s.db.Query(`SELECT DISTINCT c.reg_num, c.mark, c.model, c.year, o.name, o.surname, o.patronymic 
-								   FROM cars c 
-    							   JOIN cars_owners co ON c.car_id = co.car_id 
-								   JOIN owners o ON co.owner_id = o.owner_id
-								   WHERE c.reg_num LIKE $1 or c.mark LIKE $1 or c.model LIKE $1 or o.name LIKE $1
-								      or o.surname LIKE $1 or o.patronymic LIKE $1
-								   LIMIT $2 OFFSET $2*($3-1)`, "%"+searchRequest.Query+"%", searchRequest.PageSize, searchRequest.PageNum)
+	var owner Owner
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return tx.QueryRowContext(ctx, "SELECT "+ownerColumns("o")+" FROM owners o WHERE o.owner_id = $1 AND o.deleted_at IS NULL AND "+inTenant("o"), ownerID).
+			Scan(ownerFields(&owner)...)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return Owner{}, fmt.Errorf("%s: %w", op, storage.ErrOwnerNotFound)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("%s: %w", op, err)
+		return Owner{}, fmt.Errorf("%s: %w", op, err)
 	}
 
-	for rows.Next() {
-		var car Car
-		var owner Owner
-		err = rows.Scan(&car.RegNum, &car.Mark, &car.Model, &car.Year, &owner.Name, &owner.Surname, &owner.Patronymic)
+	return owner, nil
+}
+
+func (s *Storage) GetOwnersBySearchRequest(ctx context.Context, searchRequest OwnerSearchRequest) ([]Owner, error) {
+	const op = "storage.postgres.GetOwnersBySearchRequest"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var c conditions
+	c.add(inTenant("o"))
+	if !showDeleted(ctx, searchRequest.IncludeDeleted) {
+		c.add("o.deleted_at IS NULL")
+	}
+	if searchRequest.Name != "" {
+		c.add("o.name ILIKE " + c.arg("%"+searchRequest.Name+"%"))
+	}
+	if searchRequest.Surname != "" {
+		c.add("o.surname ILIKE " + c.arg("%"+searchRequest.Surname+"%"))
+	}
+	if searchRequest.Patronymic != "" {
+		c.add("o.patronymic ILIKE " + c.arg("%"+searchRequest.Patronymic+"%"))
+	}
+
+	owners := []Owner{}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, "SELECT "+ownerColumns("o")+" FROM owners o"+
+			c.where()+" ORDER BY o.owner_id"+c.page(searchRequest.PageNum, searchRequest.PageSize), c.args...)
 		if err != nil {
-			return nil, fmt.Errorf("%s: %w", op, err)
+			return err
 		}
+		defer rows.Close()
 
-		car.Owner = owner
-		cars = append(cars, car)
+		for rows.Next() {
+			var owner Owner
+			if err = rows.Scan(ownerFields(&owner)...); err != nil {
+				return err
+			}
+
+			owners = append(owners, owner)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return cars, nil
+	return owners, nil
 }
 
-func (s *Storage) DeleteCar(carID int) error {
-	const op = "storage.postgres.DeleteCar"
+// GetOwnerDuplicates returns pairs of owners whose normalized identities are similar
+// enough to be the same person. A missing patronymic on either side is compared
+// by surname and name only.
+func (s *Storage) GetOwnerDuplicates(ctx context.Context, threshold float64, limit int) ([]OwnerDuplicate, error) {
+	const op = "storage.postgres.GetOwnerDuplicates"
 
-	_, err := s.db.Exec("DELETE FROM cars_owners WHERE car_id = $1", carID)
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	duplicates := []OwnerDuplicate{}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, `SELECT `+ownerColumns("a")+`, `+ownerColumns("b")+`, p.score
+								   FROM owners a
+								   JOIN owners b ON a.owner_id < b.owner_id AND b.deleted_at IS NULL AND b.tenant_id = a.tenant_id
+								   CROSS JOIN LATERAL (SELECT GREATEST(
+									   similarity(a.identity_key, b.identity_key),
+									   CASE WHEN owner_name_part(a.patronymic) = '' OR owner_name_part(b.patronymic) = ''
+											THEN similarity(split_part(a.identity_key, '|', 1) || ' ' || split_part(a.identity_key, '|', 2),
+															split_part(b.identity_key, '|', 1) || ' ' || split_part(b.identity_key, '|', 2))
+											ELSE 0 END
+								   ) AS score) p
+								   WHERE a.deleted_at IS NULL AND `+inTenant("a")+` AND p.score >= $1
+								   ORDER BY p.score DESC, a.owner_id, b.owner_id
+								   LIMIT $2`, threshold, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var d OwnerDuplicate
+			fields := append(ownerFields(&d.Owner), ownerFields(&d.Candidate)...)
+			if err = rows.Scan(append(fields, &d.Score)...); err != nil {
+				return err
+			}
+
+			duplicates = append(duplicates, d)
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = s.db.Exec("DELETE FROM cars WHERE car_id = $1", carID)
+	return duplicates, nil
+}
+
+// currentCarIDs returns the alive cars the owner currently holds, locking their ownership periods.
+func currentCarIDs(ctx context.Context, tx *sql.Tx, ownerID int) ([]int, error) {
+	rows, err := tx.QueryContext(ctx, `SELECT co.car_id FROM cars_owners co
+								 JOIN cars c ON c.car_id = co.car_id AND c.deleted_at IS NULL
+								 WHERE co.owner_id = $1 AND co.valid_to IS NULL
+								 ORDER BY co.car_id
+								 FOR UPDATE OF co`, ownerID)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	var carIDs []int
+	for rows.Next() {
+		var carID int
+		if err = rows.Scan(&carID); err != nil {
+			return nil, err
+		}
+		carIDs = append(carIDs, carID)
+	}
+
+	return carIDs, rows.Err()
 }
 
-func (s *Storage) DeleteOwner(ownerID int) error {
-	const op = "storage.postgres.DeleteOwner"
+// MergeOwners moves every ownership period of the source owner to the target owner
+// and soft-deletes the source owner. It returns the number of reassigned periods.
+// A merge states that both owners are the same person, so the past is rewritten as well:
+// as-of queries report the target as the owner of the source's cars before the merge.
+// Consecutive periods of a car held by both owners one after the other are joined into one.
+func (s *Storage) MergeOwners(ctx context.Context, targetID, sourceID int) (int, error) {
+	const op = "storage.postgres.MergeOwners"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var reassigned int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if _, err := lockOwner(ctx, tx, targetID, false); err != nil {
+			return err
+		}
+
+		_, err := changeOwner(ctx, tx, sourceID, actionDelete, false, nil, func() error {
+			carIDs, err := currentCarIDs(ctx, tx, sourceID)
+			if err != nil {
+				return err
+			}
 
-	_, err := s.db.Exec("DELETE FROM cars_owners WHERE owner_id = $1", ownerID)
+			changes := make([]change, len(carIDs))
+			for i, carID := range carIDs {
+				changes[i] = change{entity: EntityCar, id: carID, action: actionUpdate}
+				if changes[i].before, err = carSnapshot(ctx, tx, carID); err != nil {
+					return err
+				}
+			}
+
+			res, err := tx.ExecContext(ctx, "UPDATE cars_owners SET owner_id = $1 WHERE owner_id = $2", targetID, sourceID)
+			if err != nil {
+				return err
+			}
+
+			affected, err := res.RowsAffected()
+			if err != nil {
+				return err
+			}
+			reassigned = int(affected)
+
+			if err = joinPeriods(ctx, tx, targetID); err != nil {
+				return err
+			}
+
+			for i, carID := range carIDs {
+				_, err = tx.ExecContext(ctx, "UPDATE cars SET version = version + 1 WHERE car_id = $1", carID)
+				if err != nil {
+					return err
+				}
+
+				if changes[i].after, err = carSnapshot(ctx, tx, carID); err != nil {
+					return err
+				}
+			}
+
+			if err = record(ctx, tx, changes...); err != nil {
+				return err
+			}
+
+			_, err = tx.ExecContext(ctx, "UPDATE owners SET deleted_at = now() WHERE owner_id = $1", sourceID)
+
+			return err
+		})
+
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return -1, fmt.Errorf("%s: %w", op, err)
 	}
 
-	_, err = s.db.Exec("DELETE FROM owners WHERE owner_id = $1", ownerID)
+	return reassigned, nil
+}
+
+// joinPeriods joins the ownership periods of the owner's cars that follow one another
+// into a single period, so that a car is never held twice in a row by the same owner.
+func joinPeriods(ctx context.Context, tx *sql.Tx, ownerID int) error {
+	rows, err := tx.QueryContext(ctx, `WITH periods AS (
+								SELECT car_id, owner_id, valid_from, valid_to,
+									   CASE WHEN lag(owner_id) OVER w = owner_id AND lag(valid_to) OVER w = valid_from
+											THEN 0 ELSE 1 END AS starts
+								FROM cars_owners
+								WHERE car_id IN (SELECT car_id FROM cars_owners WHERE owner_id = $1)
+								WINDOW w AS (PARTITION BY car_id ORDER BY valid_from)
+							), islands AS (
+								SELECT *, sum(starts) OVER (PARTITION BY car_id ORDER BY valid_from) AS island
+								FROM periods
+							)
+							SELECT car_id, min(valid_from), (array_agg(valid_to ORDER BY valid_from DESC))[1]
+							FROM islands
+							WHERE owner_id = $1
+							GROUP BY car_id, island
+							HAVING count(*) > 1`, ownerID)
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return err
 	}
+	defer rows.Close()
 
-	return nil
+	type period struct {
+		carID     int
+		validFrom time.Time
+		validTo   *time.Time
+	}
+	var joined []period
+	for rows.Next() {
+		var p period
+		if err = rows.Scan(&p.carID, &p.validFrom, &p.validTo); err != nil {
+			return err
+		}
+		joined = append(joined, p)
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
 
+	for _, p := range joined {
+		// the periods after the first one of the run are folded into it
+		_, err = tx.ExecContext(ctx, `DELETE FROM cars_owners
+							WHERE car_id = $1 AND valid_from > $2 AND ($3::timestamptz IS NULL OR valid_from < $3)`,
+			p.carID, p.validFrom, p.validTo)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "UPDATE cars_owners SET valid_to = $3 WHERE car_id = $1 AND valid_from = $2",
+			p.carID, p.validFrom, p.validTo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-func (s *Storage) UpdateRegNum(carID int, newRegNum string) error {
-	const op = "storage.postgres.UpdateRegNum"
+func (s *Storage) GetOwnerCars(ctx context.Context, ownerID int, asOf time.Time) ([]Car, error) {
+	const op = "storage.postgres.GetOwnerCars"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	cars := []Car{}
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM owners o WHERE o.owner_id = $1 AND o.deleted_at IS NULL AND "+inTenant("o")+")", ownerID).
+			Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return storage.ErrOwnerNotFound
+		}
+
+		rows, err := tx.QueryContext(ctx, `SELECT `+carColumns+`, `+ownerColumns("o")+`
+								   FROM cars c
+								   JOIN cars_owners co ON c.car_id = co.car_id AND `+ownedAt("$2")+`
+								   JOIN owners o ON co.owner_id = o.owner_id
+								   WHERE o.owner_id = $1 AND c.deleted_at IS NULL AND `+inTenant("o")+`
+								   ORDER BY c.car_id`, ownerID, asOf)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var car Car
+			if err = rows.Scan(carFields(&car)...); err != nil {
+				return err
+			}
 
-	_, err := s.db.Exec("UPDATE cars SET reg_num = $1 WHERE car_id = $2", newRegNum, carID)
+			cars = append(cars, car)
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return nil, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return cars, nil
+}
+
+// deleteCar soft-deletes an alive car within tx. A non-nil versions must contain the current version.
+func deleteCar(ctx context.Context, tx *sql.Tx, carID int, versions []int) error {
+	_, err := changeCar(ctx, tx, carID, actionDelete, false, versions, func() error {
+		_, err := tx.ExecContext(ctx, "UPDATE cars SET deleted_at = now() WHERE car_id = $1", carID)
+
+		return err
+	})
+
+	return err
 }
 
-func (s *Storage) UpdateMark(carID int, newMark string) error {
-	const op = "storage.postgres.UpdateMark"
+// DeleteCar soft-deletes the car. Its ownership history is kept so that it can be restored.
+// A non-nil versions must contain the current version.
+func (s *Storage) DeleteCar(ctx context.Context, carID int, versions []int) error {
+	const op = "storage.postgres.DeleteCar"
 
-	_, err := s.db.Exec("UPDATE cars SET mark = $1 WHERE car_id = $2", newMark, carID)
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return deleteCar(ctx, tx, carID, versions)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -206,10 +933,41 @@ func (s *Storage) UpdateMark(carID int, newMark string) error {
 	return nil
 }
 
-func (s *Storage) UpdateModel(carID int, newModel string) error {
-	const op = "storage.postgres.UpdateModel"
+// RestoreCar restores a soft-deleted car together with its current owner if that was deleted too.
+func (s *Storage) RestoreCar(ctx context.Context, carID int) error {
+	const op = "storage.postgres.RestoreCar"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := changeCar(ctx, tx, carID, actionRestore, true, nil, func() error {
+			_, err := tx.ExecContext(ctx, "UPDATE cars SET deleted_at = NULL WHERE car_id = $1", carID)
+			if isUniqueViolation(err) {
+				return storage.ErrCarExists
+			}
+
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		var ownerID int
+		var ownerDeleted bool
+		err = tx.QueryRowContext(ctx, `SELECT o.owner_id, o.deleted_at IS NOT NULL
+								FROM cars_owners co
+								JOIN owners o ON o.owner_id = co.owner_id
+								WHERE co.car_id = $1 AND co.valid_to IS NULL AND `+inTenant("co"), carID).Scan(&ownerID, &ownerDeleted)
+		if errors.Is(err, sql.ErrNoRows) || err == nil && !ownerDeleted {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
 
-	_, err := s.db.Exec("UPDATE cars SET model = $1 WHERE car_id = $2", newModel, carID)
+		return restoreOwner(ctx, tx, ownerID)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -217,10 +975,30 @@ func (s *Storage) UpdateModel(carID int, newModel string) error {
 	return nil
 }
 
-func (s *Storage) UpdateYear(carID int, newYear int) error {
-	const op = "storage.postgres.UpdateYear"
+// restoreOwner restores a soft-deleted owner within tx.
+func restoreOwner(ctx context.Context, tx *sql.Tx, ownerID int) error {
+	_, err := changeOwner(ctx, tx, ownerID, actionRestore, true, nil, func() error {
+		_, err := tx.ExecContext(ctx, "UPDATE owners SET deleted_at = NULL WHERE owner_id = $1", ownerID)
+		if isUniqueViolation(err) {
+			return storage.ErrOwnerExists
+		}
+
+		return err
+	})
+
+	return err
+}
+
+// RestoreOwner restores a soft-deleted owner. Cars deleted together with the owner stay deleted.
+func (s *Storage) RestoreOwner(ctx context.Context, ownerID int) error {
+	const op = "storage.postgres.RestoreOwner"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
 
-	_, err := s.db.Exec("UPDATE cars SET year = $1 WHERE car_id = $2", newYear, carID)
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		return restoreOwner(ctx, tx, ownerID)
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -228,31 +1006,96 @@ func (s *Storage) UpdateYear(carID int, newYear int) error {
 	return nil
 }
 
-func (s *Storage) UpdateOwner(carID int, newOwner Owner) error {
-	const op = "storage.postgres.UpdateOwner"
+// PurgeDeleted permanently deletes cars and owners soft-deleted before the given instant,
+// together with their ownership history. It returns the number of purged cars and owners.
+func (s *Storage) PurgeDeleted(ctx context.Context, before time.Time) (int64, int64, error) {
+	const op = "storage.postgres.PurgeDeleted"
 
-	ownerID, err := s.GetOwnerID(newOwner)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
 
-	_, err = s.db.Exec("DELETE FROM cars_owners WHERE car_id = $1", carID)
-	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
-	}
+	var cars, owners int64
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `DELETE FROM cars_owners co
+								USING cars c
+								WHERE co.car_id = c.car_id AND c.deleted_at < $1 AND `+inTenant("c"), before)
+		if err != nil {
+			return err
+		}
+
+		cars, err = purge(ctx, tx, EntityCar, `DELETE FROM cars c WHERE c.deleted_at < $1 AND `+inTenant("c")+`
+								RETURNING c.tenant_id, c.car_id AS id, to_jsonb(c) AS before`, before)
+		if err != nil {
+			return err
+		}
 
-	_, err = s.db.Exec("INSERT INTO cars_owners(car_id, owner_id) VALUES ($1, $2)", carID, ownerID)
+		_, err = tx.ExecContext(ctx, `DELETE FROM cars_owners co
+								USING owners o
+								WHERE co.owner_id = o.owner_id AND o.deleted_at < $1 AND `+inTenant("o"), before)
+		if err != nil {
+			return err
+		}
+
+		owners, err = purge(ctx, tx, EntityOwner, `DELETE FROM owners o WHERE o.deleted_at < $1 AND `+inTenant("o")+`
+								RETURNING o.tenant_id, o.owner_id AS id, to_jsonb(o) - 'identity_key' AS before`, before)
+
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return cars, owners, nil
 }
 
-func (s *Storage) UpdateOwnerName(ownerID int, newName string) error {
-	const op = "storage.postgres.UpdateOwnerName"
+// DeleteOwner soft-deletes the owner and applies policy to the cars they currently hold.
+// reassignTo is the id of the new owner and is used by OwnerDeleteReassign only.
+// A non-nil versions must contain the current version.
+func (s *Storage) DeleteOwner(ctx context.Context, ownerID int, policy OwnerDeletePolicy, reassignTo int, versions []int) error {
+	const op = "storage.postgres.DeleteOwner"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		_, err := changeOwner(ctx, tx, ownerID, actionDelete, false, versions, func() error {
+			carIDs, err := currentCarIDs(ctx, tx, ownerID)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case len(carIDs) == 0:
+			case policy == OwnerDeleteCascade:
+				for _, carID := range carIDs {
+					if err = deleteCar(ctx, tx, carID, nil); err != nil {
+						return err
+					}
+				}
+			case policy == OwnerDeleteReassign:
+				if _, err = lockOwner(ctx, tx, reassignTo, false); err != nil {
+					return err
+				}
+
+				for _, carID := range carIDs {
+					_, err = changeCar(ctx, tx, carID, actionUpdate, false, nil, func() error {
+						return setCarOwner(ctx, tx, carID, reassignTo)
+					})
+					if err != nil {
+						return err
+					}
+				}
+			default:
+				return storage.ErrOwnerHasCars
+			}
 
-	_, err := s.db.Exec("UPDATE owners SET name = $1 WHERE owner_id = $2", newName, ownerID)
+			_, err = tx.ExecContext(ctx, "UPDATE owners SET deleted_at = now() WHERE owner_id = $1", ownerID)
+
+			return err
+		})
+
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("%s: %w", op, err)
 	}
@@ -260,24 +1103,135 @@ func (s *Storage) UpdateOwnerName(ownerID int, newName string) error {
 	return nil
 }
 
-func (s *Storage) UpdateOwnerSurname(ownerID int, newSurname string) error {
-	const op = "storage.postgres.UpdateOwnerSurname"
+// PatchCar applies the patch to an alive car in one transaction and returns the new version.
+// A non-nil versions must contain the current version. An empty patch changes nothing and returns the current version.
+func (s *Storage) PatchCar(ctx context.Context, carID int, patch CarPatch, versions []int) (int, error) {
+	const op = "storage.postgres.PatchCar"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var c conditions
+	var sets []string
+	if patch.RegNum != nil {
+		sets = append(sets, "reg_num = "+c.arg(*patch.RegNum))
+	}
+	if patch.Mark != nil {
+		sets = append(sets, "mark = "+c.arg(*patch.Mark))
+	}
+	if patch.Model != nil {
+		sets = append(sets, "model = "+c.arg(*patch.Model))
+	}
+	if patch.Year != nil {
+		sets = append(sets, "year = "+c.arg(*patch.Year))
+	}
+
+	var newVersion int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if len(sets) == 0 && patch.Owner == nil {
+			current, err := lockCar(ctx, tx, carID, false)
+			if err != nil {
+				return err
+			}
+			newVersion = current
+
+			return matchVersion(versions, current)
+		}
 
-	_, err := s.db.Exec("UPDATE owners SET surname = $1 WHERE owner_id = $2", newSurname, ownerID)
+		var newOwnerID int
+		if patch.Owner != nil {
+			var err error
+			if newOwnerID, err = ownerID(ctx, tx, *patch.Owner); err != nil {
+				return err
+			}
+		}
+
+		var err error
+		newVersion, err = changeCar(ctx, tx, carID, actionUpdate, false, versions, func() error {
+			if len(sets) > 0 {
+				_, err := tx.ExecContext(ctx, "UPDATE cars SET "+strings.Join(sets, ", ")+" WHERE car_id = "+c.arg(carID), c.args...)
+				if err != nil {
+					return err
+				}
+			}
+			if patch.Owner != nil {
+				return setCarOwner(ctx, tx, carID, newOwnerID)
+			}
+
+			return nil
+		})
+
+		return err
+	})
+	if isUniqueViolation(err) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrCarExists)
+	}
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return newVersion, nil
 }
 
-func (s *Storage) UpdateOwnerPatronymic(ownerID int, newPatronymic string) error {
-	const op = "storage.postgres.UpdateOwnerPatronymic"
+// PatchOwner applies the patch to an alive owner in one transaction and returns the new version.
+// Empty optional fields are stored as NULL. A non-nil versions must contain the current version.
+// An empty patch changes nothing and returns the current version.
+func (s *Storage) PatchOwner(ctx context.Context, ownerID int, patch OwnerPatch, versions []int) (int, error) {
+	const op = "storage.postgres.PatchOwner"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
 
-	_, err := s.db.Exec("UPDATE owners SET patronymic = $1 WHERE owner_id = $2", newPatronymic, ownerID)
+	var c conditions
+	var sets []string
+	if patch.Name != nil {
+		sets = append(sets, "name = "+c.arg(*patch.Name))
+	}
+	if patch.Surname != nil {
+		sets = append(sets, "surname = "+c.arg(*patch.Surname))
+	}
+	for _, f := range []struct {
+		column string
+		value  *string
+	}{
+		{"patronymic", patch.Patronymic},
+		{"birth_date", patch.BirthDate},
+		{"phone", patch.Phone},
+		{"email", patch.Email},
+		{"document_number", patch.DocumentNumber},
+	} {
+		if f.value != nil {
+			sets = append(sets, f.column+" = "+c.arg(nullable(f.value)))
+		}
+	}
+
+	var newVersion int
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		if len(sets) == 0 {
+			current, err := lockOwner(ctx, tx, ownerID, false)
+			if err != nil {
+				return err
+			}
+			newVersion = current
+
+			return matchVersion(versions, current)
+		}
+
+		var err error
+		newVersion, err = changeOwner(ctx, tx, ownerID, actionUpdate, false, versions, func() error {
+			_, err := tx.ExecContext(ctx, "UPDATE owners SET "+strings.Join(sets, ", ")+" WHERE owner_id = "+c.arg(ownerID), c.args...)
+
+			return err
+		})
+
+		return err
+	})
+	if isUniqueViolation(err) {
+		return 0, fmt.Errorf("%s: %w", op, storage.ErrOwnerExists)
+	}
 	if err != nil {
-		return fmt.Errorf("%s: %w", op, err)
+		return 0, fmt.Errorf("%s: %w", op, err)
 	}
 
-	return nil
+	return newVersion, nil
 }
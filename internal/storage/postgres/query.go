@@ -0,0 +1,39 @@
+package postgres
+
+import (
+	"strconv"
+	"strings"
+)
+
+// conditions accumulates WHERE conditions together with their positional arguments,
+// so that search queries can be assembled from optional filters.
+type conditions struct {
+	parts []string
+	args  []any
+}
+
+// arg registers a query argument and returns its placeholder.
+func (c *conditions) arg(v any) string {
+	c.args = append(c.args, v)
+
+	return "$" + strconv.Itoa(len(c.args))
+}
+
+// add appends a condition; its placeholders must come from arg.
+func (c *conditions) add(cond string) {
+	c.parts = append(c.parts, cond)
+}
+
+// where returns the WHERE clause, or an empty string when there are no conditions.
+func (c *conditions) where() string {
+	if len(c.parts) == 0 {
+		return ""
+	}
+
+	return " WHERE " + strings.Join(c.parts, " AND ")
+}
+
+// page returns the LIMIT/OFFSET clause for a 1-based page number.
+func (c *conditions) page(pageNum, pageSize int) string {
+	return " LIMIT " + c.arg(pageSize) + " OFFSET " + c.arg(pageSize*(pageNum-1))
+}
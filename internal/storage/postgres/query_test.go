@@ -0,0 +1,115 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestConditions(t *testing.T) {
+	var c conditions
+	assert.Empty(t, c.where())
+
+	c.add("o.name ILIKE " + c.arg("%Ivan%"))
+	c.add("o.surname ILIKE " + c.arg("%Ivanov%"))
+
+	assert.Equal(t, " WHERE o.name ILIKE $1 AND o.surname ILIKE $2", c.where())
+	assert.Equal(t, " LIMIT $3 OFFSET $4", c.page(3, 10))
+	assert.Equal(t, []any{"%Ivan%", "%Ivanov%", 10, 20}, c.args)
+}
+
+func TestGetOwnersBySearchRequest(t *testing.T) {
+	t.Run("without filters", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(regexp.QuoteMeta("FROM owners o WHERE tenant_visible(o.tenant_id) AND o.deleted_at IS NULL ORDER BY o.owner_id LIMIT $1 OFFSET $2")).
+			WithArgs(20, 0).
+			WillReturnRows(sqlmock.NewRows(ownerColumnNames))
+		mock.ExpectCommit()
+
+		owners, err := s.GetOwnersBySearchRequest(context.Background(), OwnerSearchRequest{PageNum: 1, PageSize: 20})
+		require.NoError(t, err)
+
+		assert.NotNil(t, owners)
+		assert.Empty(t, owners)
+	})
+
+	t.Run("filters are matched case-insensitively by substring", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, "acme")
+		mock.ExpectQuery(regexp.QuoteMeta("WHERE tenant_visible(o.tenant_id) AND o.deleted_at IS NULL AND o.surname ILIKE $1 AND o.patronymic ILIKE $2 ORDER BY o.owner_id LIMIT $3 OFFSET $4")).
+			WithArgs("%ivanov%", "%ich%", 5, 10).
+			WillReturnRows(sqlmock.NewRows(ownerColumnNames).
+				AddRow(ownerRow(4, "Ivan", "Ivanov", "Ivanovich")...))
+		mock.ExpectCommit()
+
+		owners, err := s.GetOwnersBySearchRequest(tenant.WithTenant(context.Background(), "acme"), OwnerSearchRequest{
+			Surname: "ivanov", Patronymic: "ich", PageNum: 3, PageSize: 5,
+		})
+		require.NoError(t, err)
+
+		require.Len(t, owners, 1)
+		assert.Equal(t, "Ivanov", owners[0].Surname)
+		require.NotNil(t, owners[0].Patronymic)
+		assert.Equal(t, "Ivanovich", *owners[0].Patronymic)
+		assert.Nil(t, owners[0].Phone)
+	})
+}
+
+func TestEachCarBySearchRequest(t *testing.T) {
+	t.Run("page", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY c.car_id LIMIT $3 OFFSET $4")).
+			WithArgs((*time.Time)(nil), "Lada", 10, 10).
+			WillReturnRows(sqlmock.NewRows(carColumnNames).
+				AddRow(carRow(5, "X123XX150", "Lada", "Vesta", 2002, ownerRow(3, "Ivan", "Ivanov", nil))...))
+		mock.ExpectCommit()
+
+		var ids []int
+		err := s.EachCarBySearchRequest(context.Background(),
+			SearchRequest{CarFilter: CarFilter{Mark: "Lada"}, PageNum: 2, PageSize: 10},
+			func(car Car) error {
+				ids = append(ids, car.ID)
+
+				return nil
+			})
+		require.NoError(t, err)
+
+		assert.Equal(t, []int{5}, ids)
+	})
+
+	t.Run("zero page size returns all cars", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(regexp.QuoteMeta("ORDER BY c.car_id")+"$").
+			WithArgs((*time.Time)(nil), "Lada").
+			WillReturnRows(sqlmock.NewRows(carColumnNames).
+				AddRow(carRow(5, "X123XX150", "Lada", "Vesta", 2002, ownerRow(3, "Ivan", "Ivanov", nil))...).
+				AddRow(carRow(6, "X124XX150", "Lada", "Granta", 2015, ownerRow(3, "Ivan", "Ivanov", nil))...))
+		mock.ExpectRollback()
+
+		errStop := errors.New("client went away")
+
+		// an error of fn stops the iteration
+		calls := 0
+		err := s.EachCarBySearchRequest(context.Background(), SearchRequest{CarFilter: CarFilter{Mark: "Lada"}},
+			func(car Car) error {
+				calls++
+
+				return errStop
+			})
+		assert.ErrorIs(t, err, errStop)
+		assert.Equal(t, 1, calls)
+	})
+}
@@ -0,0 +1,199 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/actor"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// expectCarOwner expects the current owner of the car to be looked up.
+func expectCarOwner(mock sqlmock.Sqlmock, carID, ownerID int, ownerDeleted bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT o.owner_id, o.deleted_at IS NOT NULL")).
+		WithArgs(carID).
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id", "deleted"}).AddRow(ownerID, ownerDeleted))
+}
+
+func TestDeleteCar(t *testing.T) {
+	t.Run("soft-deletes the car", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, false)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET deleted_at = now() WHERE car_id = $1")).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarChanged(mock, 5)
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.DeleteCar(context.Background(), 5, nil))
+	})
+
+	t.Run("already deleted", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("FOR UPDATE").WithArgs(5, false).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.DeleteCar(context.Background(), 5, nil), storage.ErrCarNotFound)
+	})
+}
+
+func TestRestoreCar(t *testing.T) {
+	t.Run("restores the car and its current owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, true)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET deleted_at = NULL WHERE car_id = $1")).
+			WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarChanged(mock, 5)
+		expectCarOwner(mock, 5, 3, true)
+		expectOwnerLocked(mock, 3, true)
+		expectOwnerSnapshot(mock, 3)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE owners SET deleted_at = NULL WHERE owner_id = $1")).
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOwnerChanged(mock, 3)
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.RestoreCar(context.Background(), 5))
+	})
+
+	t.Run("alive owner is left alone", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, true)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec("UPDATE cars SET deleted_at = NULL").WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarChanged(mock, 5)
+		expectCarOwner(mock, 5, 3, false)
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.RestoreCar(context.Background(), 5))
+	})
+
+	t.Run("car is not deleted", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("FOR UPDATE").WithArgs(5, true).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.RestoreCar(context.Background(), 5), storage.ErrCarNotFound)
+	})
+
+	t.Run("reg num taken by another car", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, true)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec("UPDATE cars SET deleted_at = NULL").WithArgs(5).
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.RestoreCar(context.Background(), 5), storage.ErrCarExists)
+	})
+
+	t.Run("owner identity taken by another owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, true)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec("UPDATE cars SET deleted_at = NULL").WithArgs(5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarChanged(mock, 5)
+		expectCarOwner(mock, 5, 3, true)
+		expectOwnerLocked(mock, 3, true)
+		expectOwnerSnapshot(mock, 3)
+		mock.ExpectExec("UPDATE owners SET deleted_at = NULL").WithArgs(3).
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.RestoreCar(context.Background(), 5), storage.ErrOwnerExists)
+	})
+}
+
+func TestRestoreOwner(t *testing.T) {
+	t.Run("restores the owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerLocked(mock, 3, true)
+		expectOwnerSnapshot(mock, 3)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE owners SET deleted_at = NULL WHERE owner_id = $1")).
+			WithArgs(3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOwnerChanged(mock, 3)
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.RestoreOwner(context.Background(), 3))
+	})
+
+	t.Run("owner is not deleted", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery("FOR UPDATE").WithArgs(3, true).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.RestoreOwner(context.Background(), 3), storage.ErrOwnerNotFound)
+	})
+
+	t.Run("identity taken by another owner", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerLocked(mock, 3, true)
+		expectOwnerSnapshot(mock, 3)
+		mock.ExpectExec("UPDATE owners SET deleted_at = NULL").WithArgs(3).
+			WillReturnError(&pgconn.PgError{Code: "23505"})
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.RestoreOwner(context.Background(), 3), storage.ErrOwnerExists)
+	})
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	s, mock := newMock(t)
+
+	before := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	// ownership periods go first as they reference the purged rows, and every purged row is recorded
+	expectBegin(mock, tenant.Default)
+	mock.ExpectExec("(?s)DELETE FROM cars_owners co.*USING cars c").WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("(?s)WITH purged AS \\(DELETE FROM cars c WHERE c.deleted_at < \\$1.*INSERT INTO audit_log").
+		WithArgs(before, EntityCar, actionPurge, actor.System, nil).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("(?s)DELETE FROM cars_owners co.*USING owners o").WithArgs(before).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("(?s)WITH purged AS \\(DELETE FROM owners o WHERE o.deleted_at < \\$1.*INSERT INTO audit_log").
+		WithArgs(before, EntityOwner, actionPurge, actor.System, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	cars, owners, err := s.PurgeDeleted(actor.WithActor(context.Background(), actor.System), before)
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(2), cars)
+	assert.Equal(t, int64(1), owners)
+}
@@ -0,0 +1,22 @@
+package postgres
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestStatementName(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want string
+	}{
+		{sql: "SELECT 1", want: "SELECT"},
+		{sql: "\n\t\twith recent AS (SELECT 1) SELECT * FROM recent", want: "WITH"},
+		{sql: "insert into cars (reg_num) values ($1)", want: "INSERT"},
+		{sql: "   ", want: "SQL"},
+	}
+
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, statementName(tc.sql), tc.sql)
+	}
+}
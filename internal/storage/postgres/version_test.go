@@ -0,0 +1,108 @@
+package postgres
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+)
+
+func TestMatchVersion(t *testing.T) {
+	assert.NoError(t, matchVersion(nil, 3))
+	assert.NoError(t, matchVersion([]int{2, 3}, 3))
+	assert.ErrorIs(t, matchVersion([]int{2}, 3), storage.ErrVersionMismatch)
+}
+
+func TestPatchCarVersion(t *testing.T) {
+	mark, year := "Kia", 2010
+
+	t.Run("one of the expected versions", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, false)
+		expectCarSnapshot(mock, 5)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE cars SET mark = $1, year = $2 WHERE car_id = $3")).
+			WithArgs(mark, year, 5).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectCarChanged(mock, 5)
+		mock.ExpectCommit()
+
+		version, err := s.PatchCar(context.Background(), 5, CarPatch{Mark: &mark, Year: &year}, []int{1, 4})
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, version)
+	})
+
+	t.Run("changed by someone else", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, false)
+		mock.ExpectRollback()
+
+		_, err := s.PatchCar(context.Background(), 5, CarPatch{Mark: &mark}, []int{4})
+		assert.ErrorIs(t, err, storage.ErrVersionMismatch)
+	})
+
+	t.Run("empty patch returns the current version", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, false)
+		mock.ExpectCommit()
+
+		version, err := s.PatchCar(context.Background(), 5, CarPatch{}, []int{1})
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, version)
+	})
+
+	t.Run("empty patch of a changed car", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectCarLocked(mock, 5, false)
+		mock.ExpectRollback()
+
+		_, err := s.PatchCar(context.Background(), 5, CarPatch{}, []int{4})
+		assert.ErrorIs(t, err, storage.ErrVersionMismatch)
+	})
+}
+
+func TestPatchOwner(t *testing.T) {
+	name, phone := "Petr", ""
+
+	t.Run("clears empty optional fields", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerLocked(mock, 3, false)
+		expectOwnerSnapshot(mock, 3)
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE owners SET name = $1, phone = $2 WHERE owner_id = $3")).
+			WithArgs(name, nil, 3).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		expectOwnerChanged(mock, 3)
+		mock.ExpectCommit()
+
+		version, err := s.PatchOwner(context.Background(), 3, OwnerPatch{Name: &name, Phone: &phone}, nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, version)
+	})
+
+	t.Run("changed by someone else", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		expectOwnerLocked(mock, 3, false)
+		mock.ExpectRollback()
+
+		_, err := s.PatchOwner(context.Background(), 3, OwnerPatch{Name: &name}, []int{2})
+		assert.ErrorIs(t, err, storage.ErrVersionMismatch)
+	})
+}
@@ -0,0 +1,330 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Statuses of webhook deliveries.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	// DeliveryDead is a delivery that failed too many times and is no longer retried
+	DeliveryDead = "dead"
+)
+
+// @Schema
+type Webhook struct {
+	ID  int    `json:"id,omitempty"`
+	URL string `json:"url"`
+	// EventTypes are types like car.updated or patterns like owner.*; empty matches every event
+	EventTypes []string `json:"eventTypes"`
+	// Secret signs the payloads; it is returned only when the webhook is created
+	Secret string `json:"secret,omitempty"`
+	// RegNum and Mark restrict the webhook to the events of matching cars
+	RegNum    *string   `json:"regNum,omitempty"`
+	Mark      *string   `json:"mark,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// @Schema
+type WebhookDelivery struct {
+	ID        int64  `json:"id"`
+	WebhookID int    `json:"webhookId"`
+	EventID   int64  `json:"eventId"`
+	EventType string `json:"eventType"`
+	// Status is pending, delivered or dead
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt,omitempty"`
+	LastStatusCode *int       `json:"lastStatusCode,omitempty"`
+	LastError      *string    `json:"lastError,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+// @Schema
+type DeliverySearchRequest struct {
+	WebhookID int    `json:"webhookId"`
+	Status    string `json:"status"`
+	PageNum   int    `json:"pageNum"`
+	PageSize  int    `json:"pageSize"`
+}
+
+// DueDelivery is a delivery claimed for an attempt together with what is needed to send it.
+type DueDelivery struct {
+	ID        int64
+	WebhookID int
+	URL       string
+	Secret    string
+	// Attempts is the number of the previous attempts
+	Attempts int
+	Event    Event
+}
+
+// DeliveryAttempt is the outcome of an attempt to send a delivery.
+type DeliveryAttempt struct {
+	// StatusCode is zero if no response was received
+	StatusCode int
+	Error      string
+	// Status is the status of the delivery after the attempt
+	Status string
+	// RetryAt is when a pending delivery is attempted again
+	RetryAt time.Time
+}
+
+func (s *Storage) SaveWebhook(ctx context.Context, webhook Webhook) (int, error) {
+	const op = "storage.postgres.SaveWebhook"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	eventTypes := webhook.EventTypes
+	if eventTypes == nil {
+		eventTypes = []string{}
+	}
+
+	var id int
+	err := s.db.QueryRowContext(ctx, `INSERT INTO webhooks(tenant_id, url, event_types, secret, reg_num, mark)
+								VALUES ($1, $2, $3, $4, $5, $6) RETURNING webhook_id`,
+		tenant.FromContext(ctx), webhook.URL, eventTypes, webhook.Secret, nullable(webhook.RegNum), nullable(webhook.Mark)).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return id, nil
+}
+
+// GetWebhooks returns the webhooks of the tenant without their secrets.
+func (s *Storage) GetWebhooks(ctx context.Context) ([]Webhook, error) {
+	const op = "storage.postgres.GetWebhooks"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, `SELECT webhook_id, url, array_to_json(event_types), reg_num, mark, created_at
+								   FROM webhooks WHERE tenant_id = $1 ORDER BY webhook_id`, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var w Webhook
+		var eventTypes []byte
+		if err = rows.Scan(&w.ID, &w.URL, &eventTypes, &w.RegNum, &w.Mark, &w.CreatedAt); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		if err = json.Unmarshal(eventTypes, &w.EventTypes); err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		webhooks = append(webhooks, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return webhooks, nil
+}
+
+// DeleteWebhook deletes the webhook together with its deliveries.
+func (s *Storage) DeleteWebhook(ctx context.Context, webhookID int) error {
+	const op = "storage.postgres.DeleteWebhook"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE webhook_id = $1 AND tenant_id = $2", webhookID, tenant.FromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	return nil
+}
+
+// GetWebhookDeliveries returns the deliveries of a webhook, newest first.
+func (s *Storage) GetWebhookDeliveries(ctx context.Context, searchRequest DeliverySearchRequest) ([]WebhookDelivery, error) {
+	const op = "storage.postgres.GetWebhookDeliveries"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM webhooks WHERE webhook_id = $1 AND tenant_id = $2)",
+		searchRequest.WebhookID, tenant.FromContext(ctx)).Scan(&exists)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("%s: %w", op, storage.ErrWebhookNotFound)
+	}
+
+	var c conditions
+	c.add("d.webhook_id = " + c.arg(searchRequest.WebhookID))
+	if searchRequest.Status != "" {
+		c.add("d.status = " + c.arg(searchRequest.Status))
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT d.delivery_id, d.webhook_id, d.event_id, e.type, d.status, d.attempts,
+								   CASE WHEN d.status = 'pending' THEN d.next_attempt_at END,
+								   d.last_status_code, d.last_error, d.created_at, d.delivered_at
+								   FROM webhook_deliveries d
+								   JOIN events e ON e.event_id = d.event_id`+
+		c.where()+" ORDER BY d.delivery_id DESC"+c.page(searchRequest.PageNum, searchRequest.PageSize), c.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	deliveries := []WebhookDelivery{}
+	for rows.Next() {
+		var d WebhookDelivery
+		err = rows.Scan(&d.ID, &d.WebhookID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastStatusCode, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// RetryWebhookDelivery makes a dead delivery pending again, with a fresh number of attempts.
+func (s *Storage) RetryWebhookDelivery(ctx context.Context, webhookID int, deliveryID int64) error {
+	const op = "storage.postgres.RetryWebhookDelivery"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	err := s.withTx(ctx, func(tx *sql.Tx) error {
+		var status string
+		err := tx.QueryRowContext(ctx, `SELECT d.status FROM webhook_deliveries d
+									JOIN webhooks w ON w.webhook_id = d.webhook_id
+									WHERE d.delivery_id = $1 AND d.webhook_id = $2 AND w.tenant_id = $3
+									FOR UPDATE OF d`, deliveryID, webhookID, tenant.FromContext(ctx)).Scan(&status)
+		if errors.Is(err, sql.ErrNoRows) {
+			return storage.ErrDeliveryNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if status != DeliveryDead {
+			return storage.ErrDeliveryNotDead
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = now()
+									WHERE delivery_id = $1`, deliveryID)
+
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
+
+// ClaimWebhookDeliveries returns up to limit pending deliveries that are due, oldest first,
+// and postpones their next attempt by lease, so that other replicas don't send them meanwhile.
+func (s *Storage) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]DueDelivery, error) {
+	const op = "storage.postgres.ClaimWebhookDeliveries"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	rows, err := s.db.QueryContext(ctx, `WITH due AS (
+									 SELECT delivery_id FROM webhook_deliveries
+									 WHERE status = 'pending' AND next_attempt_at <= now()
+									 ORDER BY delivery_id
+									 LIMIT $1
+									 FOR UPDATE SKIP LOCKED
+								 ), claimed AS (
+									 UPDATE webhook_deliveries d
+									 SET next_attempt_at = now() + make_interval(secs => $2)
+									 FROM due
+									 WHERE d.delivery_id = due.delivery_id
+									 RETURNING d.delivery_id, d.webhook_id, d.event_id, d.attempts
+								 )
+								 SELECT c.delivery_id, c.webhook_id, w.url, w.secret, c.attempts,
+									 e.event_id, e.tenant_id, e.type, e.entity, e.entity_id, e.data, e.created_at
+								 FROM claimed c
+								 JOIN webhooks w ON w.webhook_id = c.webhook_id
+								 JOIN events e ON e.event_id = c.event_id
+								 ORDER BY c.delivery_id`, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+	defer rows.Close()
+
+	var deliveries []DueDelivery
+	for rows.Next() {
+		var d DueDelivery
+		var data []byte
+		err = rows.Scan(&d.ID, &d.WebhookID, &d.URL, &d.Secret, &d.Attempts,
+			&d.Event.ID, &d.Event.Tenant, &d.Event.Type, &d.Event.Entity, &d.Event.EntityID, &data, &d.Event.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", op, err)
+		}
+		d.Event.Data = data
+
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %w", op, err)
+	}
+
+	return deliveries, nil
+}
+
+// RecordDeliveryAttempt stores the outcome of an attempt to send a delivery.
+func (s *Storage) RecordDeliveryAttempt(ctx context.Context, deliveryID int64, attempt DeliveryAttempt) error {
+	const op = "storage.postgres.RecordDeliveryAttempt"
+
+	ctx, span := startSpan(ctx, op)
+	defer span.End()
+
+	var statusCode *int
+	if attempt.StatusCode != 0 {
+		statusCode = &attempt.StatusCode
+	}
+	var retryAt *time.Time
+	if attempt.Status == DeliveryPending {
+		retryAt = &attempt.RetryAt
+	}
+
+	_, err := s.db.ExecContext(ctx, `UPDATE webhook_deliveries
+								SET attempts = attempts + 1,
+									status = $2::varchar,
+									last_status_code = $3,
+									last_error = $4,
+									next_attempt_at = COALESCE($5::timestamptz, next_attempt_at),
+									delivered_at = CASE WHEN $2::varchar = 'delivered' THEN now() END
+								WHERE delivery_id = $1`,
+		deliveryID, attempt.Status, statusCode, nullable(&attempt.Error), retryAt)
+	if err != nil {
+		return fmt.Errorf("%s: %w", op, err)
+	}
+
+	return nil
+}
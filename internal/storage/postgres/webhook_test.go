@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql/driver"
+	"effective_mobile_test/internal/lib/tenant"
+	"effective_mobile_test/internal/storage"
+	"encoding/json"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"testing"
+	"time"
+)
+
+func TestDeleteWebhook(t *testing.T) {
+	s, mock := newMock(t)
+
+	// webhooks of other tenants are not found
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM webhooks WHERE webhook_id = $1 AND tenant_id = $2")).WithArgs(3, "acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.ErrorIs(t, s.DeleteWebhook(tenant.WithTenant(context.Background(), "acme"), 3), storage.ErrWebhookNotFound)
+}
+
+func TestGetWebhookDeliveries(t *testing.T) {
+	t.Run("dead deliveries", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+		mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM webhooks WHERE webhook_id = $1 AND tenant_id = $2)")).WithArgs(3, tenant.Default).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+		mock.ExpectQuery("(?s)FROM webhook_deliveries d.*WHERE d.webhook_id = \\$1 AND d.status = \\$2 "+
+			"ORDER BY d.delivery_id DESC LIMIT \\$3 OFFSET \\$4").
+			WithArgs(3, DeliveryDead, 10, 10).
+			WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "webhook_id", "event_id", "type", "status", "attempts",
+				"next_attempt_at", "last_status_code", "last_error", "created_at", "delivered_at"}).
+				AddRow(11, 3, 42, "car.updated", DeliveryDead, 8, nil, 502, "bad gateway", createdAt, nil))
+
+		deliveries, err := s.GetWebhookDeliveries(context.Background(),
+			DeliverySearchRequest{WebhookID: 3, Status: DeliveryDead, PageNum: 2, PageSize: 10})
+		require.NoError(t, err)
+
+		require.Len(t, deliveries, 1)
+		assert.Equal(t, int64(11), deliveries[0].ID)
+		assert.Equal(t, 502, *deliveries[0].LastStatusCode)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+	})
+
+	t.Run("webhook not found", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		mock.ExpectQuery("SELECT EXISTS").WithArgs(3, tenant.Default).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+		_, err := s.GetWebhookDeliveries(context.Background(), DeliverySearchRequest{WebhookID: 3, PageNum: 1, PageSize: 50})
+		assert.ErrorIs(t, err, storage.ErrWebhookNotFound)
+	})
+}
+
+func TestRetryWebhookDelivery(t *testing.T) {
+	lock := regexp.QuoteMeta("SELECT d.status FROM webhook_deliveries d")
+
+	t.Run("dead delivery is pending again", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(lock).WithArgs(int64(11), 3, tenant.Default).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(DeliveryDead))
+		mock.ExpectExec(regexp.QuoteMeta("UPDATE webhook_deliveries SET status = 'pending', attempts = 0")).WithArgs(int64(11)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, s.RetryWebhookDelivery(context.Background(), 3, 11))
+	})
+
+	t.Run("delivery of another webhook", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(lock).WithArgs(int64(11), 3, tenant.Default).WillReturnRows(sqlmock.NewRows([]string{"status"}))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.RetryWebhookDelivery(context.Background(), 3, 11), storage.ErrDeliveryNotFound)
+	})
+
+	t.Run("delivery is not dead", func(t *testing.T) {
+		s, mock := newMock(t)
+
+		expectBegin(mock, tenant.Default)
+		mock.ExpectQuery(lock).WithArgs(int64(11), 3, tenant.Default).
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(DeliveryPending))
+		mock.ExpectRollback()
+
+		assert.ErrorIs(t, s.RetryWebhookDelivery(context.Background(), 3, 11), storage.ErrDeliveryNotDead)
+	})
+}
+
+func TestClaimWebhookDeliveries(t *testing.T) {
+	s, mock := newMock(t)
+
+	createdAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("(?s)FOR UPDATE SKIP LOCKED.*make_interval\\(secs => \\$2\\)").WithArgs(100, float64(30)).
+		WillReturnRows(sqlmock.NewRows([]string{"delivery_id", "webhook_id", "url", "secret", "attempts",
+			"event_id", "tenant_id", "type", "entity", "entity_id", "data", "created_at"}).
+			AddRow(11, 3, "https://partner.example.com/hook", "secret", 2,
+				42, "acme", "car.updated", EntityCar, 5, []byte(`{"mark": "Lada"}`), createdAt))
+
+	deliveries, err := s.ClaimWebhookDeliveries(context.Background(), 100, 30*time.Second)
+	require.NoError(t, err)
+
+	assert.Equal(t, []DueDelivery{{
+		ID: 11, WebhookID: 3, URL: "https://partner.example.com/hook", Secret: "secret", Attempts: 2,
+		Event: Event{ID: 42, Tenant: "acme", Type: "car.updated", Entity: EntityCar, EntityID: 5,
+			Data: json.RawMessage(`{"mark": "Lada"}`), CreatedAt: createdAt},
+	}}, deliveries)
+}
+
+func TestRecordDeliveryAttempt(t *testing.T) {
+	retryAt := time.Date(2024, 3, 1, 12, 5, 0, 0, time.UTC)
+
+	cases := []struct {
+		name    string
+		attempt DeliveryAttempt
+		args    []driver.Value
+	}{
+		{
+			name:    "delivered",
+			attempt: DeliveryAttempt{StatusCode: 204, Status: DeliveryDelivered},
+			args:    []driver.Value{int64(11), DeliveryDelivered, 204, nil, (*time.Time)(nil)},
+		},
+		{
+			name:    "retried after a connection failure",
+			attempt: DeliveryAttempt{Error: "connection refused", Status: DeliveryPending, RetryAt: retryAt},
+			args:    []driver.Value{int64(11), DeliveryPending, (*int)(nil), "connection refused", retryAt},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s, mock := newMock(t)
+
+			mock.ExpectExec("UPDATE webhook_deliveries").WithArgs(tc.args...).
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			assert.NoError(t, s.RecordDeliveryAttempt(context.Background(), 11, tc.attempt))
+		})
+	}
+}
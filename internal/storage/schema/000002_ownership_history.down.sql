@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_cars_owners_owner;
+
+DROP INDEX IF EXISTS idx_cars_owners_current;
+
+DELETE FROM cars_owners WHERE valid_to IS NOT NULL;
+
+ALTER TABLE cars_owners
+    DROP CONSTRAINT cars_owners_valid_range,
+    DROP CONSTRAINT cars_owners_pkey,
+    ADD PRIMARY KEY (car_id, owner_id);
+
+ALTER TABLE cars_owners
+    DROP COLUMN valid_to,
+    DROP COLUMN valid_from;
@@ -0,0 +1,15 @@
+-- Every cars_owners row now describes a period of ownership: valid_to is NULL
+-- for the current owner and set when the car changes hands. Rows that existed
+-- before this migration are treated as owned since the migration time.
+ALTER TABLE cars_owners
+    ADD COLUMN valid_from TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN valid_to   TIMESTAMPTZ;
+
+ALTER TABLE cars_owners
+    DROP CONSTRAINT cars_owners_pkey,
+    ADD PRIMARY KEY (car_id, valid_from),
+    ADD CONSTRAINT cars_owners_valid_range CHECK (valid_to IS NULL OR valid_to >= valid_from);
+
+CREATE UNIQUE INDEX idx_cars_owners_current ON cars_owners(car_id) WHERE valid_to IS NULL;
+
+CREATE INDEX idx_cars_owners_owner ON cars_owners(owner_id, valid_from);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_owners_identity_trgm;
+
+DROP INDEX IF EXISTS idx_owners_identity;
+
+ALTER TABLE owners DROP COLUMN IF EXISTS identity_key;
+
+DROP FUNCTION IF EXISTS owner_name_part(TEXT);
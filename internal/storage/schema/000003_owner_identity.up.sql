@@ -0,0 +1,34 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- owner_name_part normalizes one part of an owner's full name: whitespace is
+-- trimmed and collapsed, case is folded and ё is treated as е.
+CREATE FUNCTION owner_name_part(part TEXT) RETURNS TEXT
+    LANGUAGE SQL IMMUTABLE PARALLEL SAFE
+AS $$
+    SELECT translate(lower(btrim(regexp_replace(coalesce(part, ''), '\s+', ' ', 'g'))), 'ёЁ', 'ее')
+$$;
+
+ALTER TABLE owners
+    ADD COLUMN identity_key TEXT GENERATED ALWAYS AS (
+        owner_name_part(surname) || '|' || owner_name_part(name) || '|' || owner_name_part(patronymic)
+    ) STORED;
+
+-- owners that already share an identity are collapsed into the one with the smallest id
+CREATE TEMPORARY TABLE owners_merge AS
+SELECT owner_id, min(owner_id) OVER (PARTITION BY identity_key) AS keep_id
+FROM owners;
+
+UPDATE cars_owners co
+SET owner_id = m.keep_id
+FROM owners_merge m
+WHERE co.owner_id = m.owner_id AND m.owner_id <> m.keep_id;
+
+DELETE FROM owners o
+USING owners_merge m
+WHERE o.owner_id = m.owner_id AND m.owner_id <> m.keep_id;
+
+DROP TABLE owners_merge;
+
+CREATE UNIQUE INDEX idx_owners_identity ON owners(identity_key);
+
+CREATE INDEX idx_owners_identity_trgm ON owners USING gin (identity_key gin_trgm_ops);
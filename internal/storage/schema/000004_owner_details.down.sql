@@ -0,0 +1,9 @@
+ALTER TABLE owners
+    DROP COLUMN IF EXISTS document_number,
+    DROP COLUMN IF EXISTS email,
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS birth_date;
+
+UPDATE owners SET patronymic = '' WHERE patronymic IS NULL;
+
+ALTER TABLE owners ALTER COLUMN patronymic SET NOT NULL;
@@ -0,0 +1,14 @@
+ALTER TABLE owners ALTER COLUMN patronymic DROP NOT NULL;
+
+UPDATE owners SET patronymic = NULL WHERE btrim(patronymic) = '';
+
+ALTER TABLE owners
+    ADD COLUMN birth_date      DATE,
+    ADD COLUMN phone           VARCHAR(16),
+    ADD COLUMN email           VARCHAR(254),
+    ADD COLUMN document_number VARCHAR(32);
+
+ALTER TABLE owners
+    ADD CONSTRAINT owners_birth_date_check CHECK (birth_date >= DATE '1900-01-01'),
+    ADD CONSTRAINT owners_phone_check CHECK (phone ~ '^\+[1-9][0-9]{6,14}$'),
+    ADD CONSTRAINT owners_email_check CHECK (email LIKE '_%@_%');
@@ -0,0 +1,27 @@
+DELETE FROM cars_owners co
+USING cars c
+WHERE co.car_id = c.car_id AND c.deleted_at IS NOT NULL;
+
+DELETE FROM cars WHERE deleted_at IS NOT NULL;
+
+DELETE FROM cars_owners co
+USING owners o
+WHERE co.owner_id = o.owner_id AND o.deleted_at IS NOT NULL;
+
+DELETE FROM owners WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_owners_deleted_at;
+
+DROP INDEX IF EXISTS idx_cars_deleted_at;
+
+DROP INDEX IF EXISTS idx_owners_identity;
+
+CREATE UNIQUE INDEX idx_owners_identity ON owners(identity_key);
+
+DROP INDEX IF EXISTS idx_cars_reg_num_alive;
+
+ALTER TABLE cars ADD CONSTRAINT cars_reg_num_key UNIQUE (reg_num);
+
+ALTER TABLE owners DROP COLUMN deleted_at;
+
+ALTER TABLE cars DROP COLUMN deleted_at;
@@ -0,0 +1,16 @@
+ALTER TABLE cars ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE owners ADD COLUMN deleted_at TIMESTAMPTZ;
+
+-- deleted rows must not block reusing their reg_num or owner identity
+ALTER TABLE cars DROP CONSTRAINT cars_reg_num_key;
+
+CREATE UNIQUE INDEX idx_cars_reg_num_alive ON cars(reg_num) WHERE deleted_at IS NULL;
+
+DROP INDEX idx_owners_identity;
+
+CREATE UNIQUE INDEX idx_owners_identity ON owners(identity_key) WHERE deleted_at IS NULL;
+
+CREATE INDEX idx_cars_deleted_at ON cars(deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX idx_owners_deleted_at ON owners(deleted_at) WHERE deleted_at IS NOT NULL;
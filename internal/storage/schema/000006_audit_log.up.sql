@@ -0,0 +1,35 @@
+CREATE TABLE audit_log
+(
+    audit_id BIGSERIAL PRIMARY KEY,
+    entity VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    action VARCHAR(16) NOT NULL,
+    before JSONB,
+    after JSONB,
+    actor VARCHAR(255) NOT NULL,
+    request_id VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_audit_log_entity ON audit_log(entity, entity_id, created_at);
+
+CREATE INDEX idx_audit_log_actor ON audit_log(actor, created_at);
+
+CREATE INDEX idx_audit_log_created_at ON audit_log(created_at);
+
+-- the audit log is append-only: rows can be inserted but never changed or removed
+CREATE FUNCTION audit_log_append_only() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$;
+
+CREATE TRIGGER audit_log_append_only
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
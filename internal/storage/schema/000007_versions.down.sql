@@ -0,0 +1,3 @@
+ALTER TABLE cars DROP COLUMN version;
+
+ALTER TABLE owners DROP COLUMN version;
@@ -0,0 +1,3 @@
+ALTER TABLE cars ADD COLUMN version INT NOT NULL DEFAULT 1;
+
+ALTER TABLE owners ADD COLUMN version INT NOT NULL DEFAULT 1;
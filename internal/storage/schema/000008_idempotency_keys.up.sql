@@ -0,0 +1,18 @@
+CREATE TABLE idempotency_keys
+(
+    key VARCHAR(255) NOT NULL,
+    method VARCHAR(16) NOT NULL,
+    path VARCHAR(255) NOT NULL,
+    request_hash BYTEA NOT NULL,
+    -- status is NULL while the first request with the key is in progress
+    status INT,
+    content_type VARCHAR(255),
+    body BYTEA,
+    -- truncated is set instead of body for responses too large to keep
+    truncated BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (key, method, path)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
@@ -0,0 +1,5 @@
+DROP TRIGGER audit_log_publish ON audit_log;
+
+DROP FUNCTION audit_log_publish();
+
+DROP TABLE events;
@@ -0,0 +1,41 @@
+CREATE TABLE events
+(
+    event_id BIGSERIAL PRIMARY KEY,
+    -- xid is the writing transaction, events are read in (xid, event_id) order
+    xid XID8 NOT NULL DEFAULT pg_current_xact_id(),
+    type VARCHAR(32) NOT NULL,
+    entity VARCHAR(16) NOT NULL,
+    entity_id INT NOT NULL,
+    data JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_events_xid ON events(xid, event_id);
+
+-- every change recorded in the audit log is published as an event, e.g. car.updated,
+-- and listeners of catalog_events are woken up when the transaction commits
+CREATE FUNCTION audit_log_publish() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    INSERT INTO events(type, entity, entity_id, data)
+    VALUES (NEW.entity || '.' || CASE NEW.action
+                                     WHEN 'create' THEN 'created'
+                                     WHEN 'update' THEN 'updated'
+                                     WHEN 'delete' THEN 'deleted'
+                                     WHEN 'restore' THEN 'restored'
+                                     WHEN 'purge' THEN 'purged'
+                                     ELSE NEW.action
+                                 END,
+            NEW.entity, NEW.entity_id, COALESCE(NEW.after, NEW.before));
+
+    -- identical notifications of a transaction are delivered once
+    PERFORM pg_notify('catalog_events', '');
+
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER audit_log_publish
+    AFTER INSERT ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_publish();
@@ -0,0 +1,7 @@
+DROP TRIGGER events_enqueue_webhooks ON events;
+
+DROP FUNCTION events_enqueue_webhooks();
+
+DROP TABLE webhook_deliveries;
+
+DROP TABLE webhooks;
@@ -0,0 +1,55 @@
+CREATE TABLE webhooks
+(
+    webhook_id SERIAL PRIMARY KEY,
+    url TEXT NOT NULL,
+    -- event_types are types like car.updated or patterns like owner.*, empty matches every event
+    event_types TEXT[] NOT NULL DEFAULT '{}',
+    secret TEXT NOT NULL,
+    -- reg_num and mark restrict the webhook to the events of matching cars
+    reg_num VARCHAR(255),
+    mark VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE TABLE webhook_deliveries
+(
+    delivery_id BIGSERIAL PRIMARY KEY,
+    webhook_id INT NOT NULL REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    event_id BIGINT NOT NULL REFERENCES events(event_id),
+    -- status is pending, delivered or dead
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_status_code INT,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+CREATE INDEX idx_webhook_deliveries_webhook ON webhook_deliveries(webhook_id, delivery_id);
+
+-- deliveries are queued in the transaction that publishes the event, so none is lost or sent for a rolled back change
+CREATE FUNCTION events_enqueue_webhooks() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    INSERT INTO webhook_deliveries(webhook_id, event_id)
+    SELECT w.webhook_id, NEW.event_id
+    FROM webhooks w
+    WHERE (cardinality(w.event_types) = 0 OR EXISTS (
+              SELECT 1
+              FROM unnest(w.event_types) t
+              WHERE t = NEW.type OR (right(t, 1) = '*' AND starts_with(NEW.type, left(t, -1)))
+          ))
+      AND (w.reg_num IS NULL OR (NEW.entity = 'car' AND NEW.data ->> 'reg_num' = w.reg_num))
+      AND (w.mark IS NULL OR (NEW.entity = 'car' AND NEW.data ->> 'mark' = w.mark));
+
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER events_enqueue_webhooks
+    AFTER INSERT ON events
+    FOR EACH ROW EXECUTE FUNCTION events_enqueue_webhooks();
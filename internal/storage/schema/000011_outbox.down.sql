@@ -0,0 +1,5 @@
+DROP TRIGGER events_enqueue_outbox ON events;
+
+DROP FUNCTION events_enqueue_outbox();
+
+DROP TABLE outbox;
@@ -0,0 +1,23 @@
+-- outbox holds the events still to be published to the message broker
+CREATE TABLE outbox
+(
+    message_id BIGSERIAL PRIMARY KEY,
+    event_id BIGINT NOT NULL REFERENCES events(event_id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- messages are queued in the transaction that publishes the event, after the changed row is locked,
+-- so the messages of every entity are numbered in the order of its changes
+CREATE FUNCTION events_enqueue_outbox() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    INSERT INTO outbox(event_id) VALUES (NEW.event_id);
+
+    RETURN NULL;
+END;
+$$;
+
+CREATE TRIGGER events_enqueue_outbox
+    AFTER INSERT ON events
+    FOR EACH ROW EXECUTE FUNCTION events_enqueue_outbox();
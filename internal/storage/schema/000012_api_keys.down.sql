@@ -0,0 +1,9 @@
+-- the same key may have been used by several callers; the stored responses are only a cache
+DELETE FROM idempotency_keys;
+
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey,
+    DROP COLUMN principal,
+    ADD PRIMARY KEY (key, method, path);
+
+DROP TABLE api_keys;
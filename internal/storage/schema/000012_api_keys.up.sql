@@ -0,0 +1,21 @@
+-- api_keys authenticate clients; only the SHA-256 of a key is stored, the key itself is shown once
+CREATE TABLE api_keys
+(
+    key_id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    -- revoked keys are kept so that the audit log can still be traced back to them
+    revoked_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX idx_api_keys_name ON api_keys(name) WHERE revoked_at IS NULL;
+
+-- idempotency keys chosen by different callers don't collide;
+-- keys stored before belong to no caller and expire unused
+ALTER TABLE idempotency_keys ADD COLUMN principal VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE idempotency_keys
+    ALTER COLUMN principal DROP DEFAULT,
+    DROP CONSTRAINT idempotency_keys_pkey,
+    ADD PRIMARY KEY (principal, key, method, path);
@@ -0,0 +1,5 @@
+-- the role of a key decides what it may do; keys created before roles existed keep full access
+ALTER TABLE api_keys ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'admin'
+    CHECK (role IN ('viewer', 'editor', 'admin'));
+
+ALTER TABLE api_keys ALTER COLUMN role DROP DEFAULT;
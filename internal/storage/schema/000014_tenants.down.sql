@@ -0,0 +1,92 @@
+ALTER TABLE api_keys DROP COLUMN multi_tenant, DROP COLUMN tenant_id;
+
+DELETE FROM idempotency_keys WHERE tenant_id <> 'default';
+
+ALTER TABLE idempotency_keys
+    DROP CONSTRAINT idempotency_keys_pkey,
+    ADD PRIMARY KEY (principal, key, method, path),
+    DROP COLUMN tenant_id;
+
+CREATE OR REPLACE FUNCTION events_enqueue_webhooks() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    INSERT INTO webhook_deliveries(webhook_id, event_id)
+    SELECT w.webhook_id, NEW.event_id
+    FROM webhooks w
+    WHERE (cardinality(w.event_types) = 0 OR EXISTS (
+              SELECT 1
+              FROM unnest(w.event_types) t
+              WHERE t = NEW.type OR (right(t, 1) = '*' AND starts_with(NEW.type, left(t, -1)))
+          ))
+      AND (w.reg_num IS NULL OR (NEW.entity = 'car' AND NEW.data ->> 'reg_num' = w.reg_num))
+      AND (w.mark IS NULL OR (NEW.entity = 'car' AND NEW.data ->> 'mark' = w.mark));
+
+    RETURN NULL;
+END;
+$$;
+
+CREATE OR REPLACE FUNCTION audit_log_publish() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    INSERT INTO events(type, entity, entity_id, data)
+    VALUES (NEW.entity || '.' || CASE NEW.action
+                                     WHEN 'create' THEN 'created'
+                                     WHEN 'update' THEN 'updated'
+                                     WHEN 'delete' THEN 'deleted'
+                                     WHEN 'restore' THEN 'restored'
+                                     WHEN 'purge' THEN 'purged'
+                                     ELSE NEW.action
+                                 END,
+            NEW.entity, NEW.entity_id, COALESCE(NEW.after, NEW.before));
+
+    -- identical notifications of a transaction are delivered once
+    PERFORM pg_notify('catalog_events', '');
+
+    RETURN NULL;
+END;
+$$;
+
+DROP POLICY tenant_isolation ON cars_owners;
+ALTER TABLE cars_owners NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE cars_owners DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON owners;
+ALTER TABLE owners NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE owners DISABLE ROW LEVEL SECURITY;
+
+DROP POLICY tenant_isolation ON cars;
+ALTER TABLE cars NO FORCE ROW LEVEL SECURITY;
+ALTER TABLE cars DISABLE ROW LEVEL SECURITY;
+
+DROP INDEX idx_events_tenant;
+
+DROP INDEX idx_audit_log_tenant;
+
+ALTER TABLE cars_owners
+    DROP CONSTRAINT cars_owners_tenant_car_fkey,
+    DROP CONSTRAINT cars_owners_tenant_owner_fkey,
+    ADD CONSTRAINT cars_owners_car_id_fkey FOREIGN KEY (car_id) REFERENCES cars(car_id),
+    ADD CONSTRAINT cars_owners_owner_id_fkey FOREIGN KEY (owner_id) REFERENCES owners(owner_id);
+
+ALTER TABLE owners DROP CONSTRAINT owners_tenant_owner_key;
+ALTER TABLE cars DROP CONSTRAINT cars_tenant_car_key;
+
+-- fails if tenants share reg_nums or owner identities
+DROP INDEX idx_owners_identity;
+
+CREATE UNIQUE INDEX idx_owners_identity ON owners(identity_key) WHERE deleted_at IS NULL;
+
+DROP INDEX idx_cars_reg_num_alive;
+
+CREATE UNIQUE INDEX idx_cars_reg_num_alive ON cars(reg_num) WHERE deleted_at IS NULL;
+
+ALTER TABLE webhooks DROP COLUMN tenant_id;
+ALTER TABLE events DROP COLUMN tenant_id;
+ALTER TABLE audit_log DROP COLUMN tenant_id;
+ALTER TABLE cars_owners DROP COLUMN tenant_id;
+ALTER TABLE owners DROP COLUMN tenant_id;
+ALTER TABLE cars DROP COLUMN tenant_id;
+
+DROP FUNCTION tenant_visible(TEXT);
@@ -0,0 +1,132 @@
+-- Every transaction of the service works for one tenant, set with set_config('app.tenant_id', ..., true),
+-- or for all of them when it is '*' (background jobs). tenant_visible tells whether the rows of a tenant
+-- are visible to the current transaction.
+CREATE FUNCTION tenant_visible(tenant TEXT) RETURNS BOOLEAN
+    LANGUAGE SQL STABLE PARALLEL SAFE
+AS $$
+    SELECT tenant = current_setting('app.tenant_id', true) OR current_setting('app.tenant_id', true) = '*'
+$$;
+
+-- rows that existed before tenants belong to the default tenant, new rows to the tenant of their transaction
+ALTER TABLE cars ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE owners ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE cars_owners ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE audit_log ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE events ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+ALTER TABLE webhooks ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE cars
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id'),
+    ADD CONSTRAINT cars_tenant_id_check CHECK (tenant_id <> '*');
+ALTER TABLE owners
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id'),
+    ADD CONSTRAINT owners_tenant_id_check CHECK (tenant_id <> '*');
+ALTER TABLE cars_owners
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id'),
+    ADD CONSTRAINT cars_owners_tenant_id_check CHECK (tenant_id <> '*');
+ALTER TABLE audit_log
+    ALTER COLUMN tenant_id SET DEFAULT current_setting('app.tenant_id'),
+    ADD CONSTRAINT audit_log_tenant_id_check CHECK (tenant_id <> '*');
+ALTER TABLE events ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE webhooks ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- reg_nums and owner identities are unique within a tenant only
+DROP INDEX idx_cars_reg_num_alive;
+
+CREATE UNIQUE INDEX idx_cars_reg_num_alive ON cars(tenant_id, reg_num) WHERE deleted_at IS NULL;
+
+DROP INDEX idx_owners_identity;
+
+CREATE UNIQUE INDEX idx_owners_identity ON owners(tenant_id, identity_key) WHERE deleted_at IS NULL;
+
+-- a car can only be held by an owner of its tenant
+ALTER TABLE cars ADD CONSTRAINT cars_tenant_car_key UNIQUE (tenant_id, car_id);
+ALTER TABLE owners ADD CONSTRAINT owners_tenant_owner_key UNIQUE (tenant_id, owner_id);
+
+ALTER TABLE cars_owners
+    DROP CONSTRAINT cars_owners_car_id_fkey,
+    DROP CONSTRAINT cars_owners_owner_id_fkey,
+    ADD CONSTRAINT cars_owners_tenant_car_fkey FOREIGN KEY (tenant_id, car_id) REFERENCES cars(tenant_id, car_id),
+    ADD CONSTRAINT cars_owners_tenant_owner_fkey FOREIGN KEY (tenant_id, owner_id) REFERENCES owners(tenant_id, owner_id);
+
+CREATE INDEX idx_audit_log_tenant ON audit_log(tenant_id, audit_id);
+
+CREATE INDEX idx_events_tenant ON events(tenant_id, xid, event_id);
+
+-- the queries of the service are scoped by tenant; row-level security hides the rows of other
+-- tenants as a second line of defense, unless the database user is a superuser or has BYPASSRLS
+ALTER TABLE cars ENABLE ROW LEVEL SECURITY;
+ALTER TABLE cars FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON cars
+    USING (tenant_visible(tenant_id)) WITH CHECK (tenant_visible(tenant_id));
+
+ALTER TABLE owners ENABLE ROW LEVEL SECURITY;
+ALTER TABLE owners FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON owners
+    USING (tenant_visible(tenant_id)) WITH CHECK (tenant_visible(tenant_id));
+
+ALTER TABLE cars_owners ENABLE ROW LEVEL SECURITY;
+ALTER TABLE cars_owners FORCE ROW LEVEL SECURITY;
+CREATE POLICY tenant_isolation ON cars_owners
+    USING (tenant_visible(tenant_id)) WITH CHECK (tenant_visible(tenant_id));
+
+-- events belong to the tenant of the recorded change
+CREATE OR REPLACE FUNCTION audit_log_publish() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    INSERT INTO events(tenant_id, type, entity, entity_id, data)
+    VALUES (NEW.tenant_id,
+            NEW.entity || '.' || CASE NEW.action
+                                     WHEN 'create' THEN 'created'
+                                     WHEN 'update' THEN 'updated'
+                                     WHEN 'delete' THEN 'deleted'
+                                     WHEN 'restore' THEN 'restored'
+                                     WHEN 'purge' THEN 'purged'
+                                     ELSE NEW.action
+                                 END,
+            NEW.entity, NEW.entity_id, COALESCE(NEW.after, NEW.before));
+
+    -- identical notifications of a transaction are delivered once
+    PERFORM pg_notify('catalog_events', '');
+
+    RETURN NULL;
+END;
+$$;
+
+-- webhooks receive the events of their tenant only
+CREATE OR REPLACE FUNCTION events_enqueue_webhooks() RETURNS trigger
+    LANGUAGE plpgsql AS
+$$
+BEGIN
+    INSERT INTO webhook_deliveries(webhook_id, event_id)
+    SELECT w.webhook_id, NEW.event_id
+    FROM webhooks w
+    WHERE w.tenant_id = NEW.tenant_id
+      AND (cardinality(w.event_types) = 0 OR EXISTS (
+              SELECT 1
+              FROM unnest(w.event_types) t
+              WHERE t = NEW.type OR (right(t, 1) = '*' AND starts_with(NEW.type, left(t, -1)))
+          ))
+      AND (w.reg_num IS NULL OR (NEW.entity = 'car' AND NEW.data ->> 'reg_num' = w.reg_num))
+      AND (w.mark IS NULL OR (NEW.entity = 'car' AND NEW.data ->> 'mark' = w.mark));
+
+    RETURN NULL;
+END;
+$$;
+
+-- idempotency keys chosen by clients of different tenants don't collide
+ALTER TABLE idempotency_keys ADD COLUMN tenant_id VARCHAR(64) NOT NULL DEFAULT 'default';
+
+ALTER TABLE idempotency_keys
+    ALTER COLUMN tenant_id DROP DEFAULT,
+    DROP CONSTRAINT idempotency_keys_pkey,
+    ADD PRIMARY KEY (tenant_id, principal, key, method, path);
+
+-- an API key may be bound to a tenant
+ALTER TABLE api_keys ADD COLUMN tenant_id VARCHAR(64);
+
+-- only keys flagged multi-tenant (and admins) choose the tenant with the X-Tenant-ID header;
+-- other keys without a tenant work in the default one
+ALTER TABLE api_keys ADD COLUMN multi_tenant BOOLEAN NOT NULL DEFAULT false
+    CHECK (NOT multi_tenant OR tenant_id IS NULL);
@@ -0,0 +1,38 @@
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+)
+
+//go:embed *.sql
+var migrations embed.FS
+
+var upMigration = regexp.MustCompile(`^(\d+)_.+\.up\.sql$`)
+
+// Version returns the version of the latest migration, which the database of the service must be migrated to.
+func Version() (uint, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var latest uint
+	for _, entry := range entries {
+		match := upMigration.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse migration %s: %w", entry.Name(), err)
+		}
+		latest = max(latest, uint(version))
+	}
+
+	return latest, nil
+}
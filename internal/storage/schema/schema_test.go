@@ -0,0 +1,18 @@
+package schema
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/fs"
+	"testing"
+)
+
+func TestVersion(t *testing.T) {
+	ups, err := fs.Glob(migrations, "*.up.sql")
+	require.NoError(t, err)
+
+	version, err := Version()
+	require.NoError(t, err)
+
+	assert.EqualValues(t, len(ups), version)
+}
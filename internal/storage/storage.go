@@ -0,0 +1,30 @@
+package storage
+
+import "errors"
+
+var (
+	ErrCarNotFound   = errors.New("car not found")
+	ErrCarExists     = errors.New("car already exists")
+	ErrOwnerNotFound = errors.New("owner not found")
+	ErrOwnerExists   = errors.New("owner already exists")
+	ErrOwnerHasCars  = errors.New("owner still holds cars")
+
+	ErrVersionMismatch = errors.New("version mismatch")
+
+	ErrDuplicateOperation = errors.New("car is targeted by several operations")
+	ErrTooManyMatches     = errors.New("too many cars match the filter")
+
+	ErrIdempotencyKeyReused     = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyKeyInProgress = errors.New("idempotency key is used by a request in progress")
+
+	ErrEventNotFound = errors.New("event not found")
+
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+	ErrDeliveryNotDead  = errors.New("webhook delivery is not dead")
+
+	ErrOutboxBusy = errors.New("outbox is held by another relay")
+
+	ErrAPIKeyNotFound = errors.New("api key not found")
+	ErrAPIKeyExists   = errors.New("api key already exists")
+)
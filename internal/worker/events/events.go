@@ -0,0 +1,150 @@
+package events
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	minRetryDelay = time.Second
+	maxRetryDelay = 30 * time.Second
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Listener
+type Listener interface {
+	ListenEvents(ctx context.Context, fn func()) error
+}
+
+// Hub listens for published events with a single database connection and wakes
+// the subscribers up, so that every replica learns about the events of the others.
+// Subscribers are also woken up every poll interval, in case a notification was missed.
+type Hub struct {
+	log          *slog.Logger
+	listener     Listener
+	pollInterval time.Duration
+
+	mu          sync.Mutex
+	subscribers map[chan struct{}]struct{}
+	stopped     bool
+}
+
+func New(log *slog.Logger, listener Listener, pollInterval time.Duration) *Hub {
+	return &Hub{
+		log:          log.With(slog.String("component", "worker/events")),
+		listener:     listener,
+		pollInterval: pollInterval,
+		subscribers:  make(map[chan struct{}]struct{}),
+	}
+}
+
+// Run listens for events until ctx is done, reconnecting when the connection fails.
+// When it returns, the channels of all subscribers are closed.
+func (h *Hub) Run(ctx context.Context) {
+	h.log.Info("events hub started", slog.String("poll_interval", h.pollInterval.String()))
+
+	go h.poll(ctx)
+
+	delay := minRetryDelay
+	for {
+		// events published while the hub wasn't listening are picked up by the subscribers now
+		h.wake()
+
+		started := time.Now()
+		err := h.listener.ListenEvents(ctx, h.wake)
+		if ctx.Err() != nil {
+			break
+		}
+
+		if time.Since(started) > maxRetryDelay {
+			delay = minRetryDelay
+		}
+		h.log.Error("failed to listen for events", sl.Err(err), slog.String("retry_in", delay.String()))
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		delay = min(2*delay, maxRetryDelay)
+	}
+
+	h.stop()
+
+	h.log.Info("events hub stopped")
+}
+
+// Subscribe returns a channel that receives a value whenever new events may be available,
+// and a function that cancels the subscription. The channel is closed when the hub stops.
+func (h *Hub) Subscribe() (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stopped {
+		close(ch)
+
+		return ch, func() {}
+	}
+	h.subscribers[ch] = struct{}{}
+
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if _, ok := h.subscribers[ch]; ok {
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Close ends all subscriptions, as if the hub stopped, while Run keeps listening until its ctx is done.
+// Long-lived subscribers such as event streams return then, so that the server can be shut down
+// before the workers.
+func (h *Hub) Close() {
+	h.stop()
+}
+
+func (h *Hub) poll(ctx context.Context) {
+	ticker := time.NewTicker(h.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.wake()
+		}
+	}
+}
+
+func (h *Hub) wake() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		// a subscriber that hasn't caught up yet will read the new events anyway
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (h *Hub) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopped = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
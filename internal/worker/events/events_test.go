@@ -0,0 +1,173 @@
+package events_test
+
+import (
+	"context"
+	"effective_mobile_test/internal/worker/events"
+	"effective_mobile_test/internal/worker/events/mocks"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// received reports whether ch receives a value in time, and whether it is still open.
+func received(ch <-chan struct{}) (got, open bool) {
+	select {
+	case _, ok := <-ch:
+		return true, ok
+	case <-time.After(5 * time.Second):
+		return false, true
+	}
+}
+
+func TestHub(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notify := make(chan func())
+
+	listener := mocks.NewListener(t)
+	listener.On("ListenEvents", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			notify <- args.Get(1).(func())
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(context.Canceled).Once()
+
+	hub := events.New(slog.New(slog.NewTextHandler(io.Discard, nil)), listener, time.Hour)
+
+	wake, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+
+	fn := <-notify
+
+	// the subscriber is woken up on start for the events published before the hub listened
+	got, open := received(wake)
+	require.True(t, got)
+	require.True(t, open)
+
+	fn()
+	got, open = received(wake)
+	require.True(t, got, "notification did not wake the subscriber up")
+	require.True(t, open)
+
+	cancel()
+	<-done
+
+	got, open = received(wake)
+	assert.True(t, got)
+	assert.False(t, open, "subscription was not closed when the hub stopped")
+
+	// subscribing to a stopped hub ends the stream at once
+	late, _ := hub.Subscribe()
+	got, open = received(late)
+	assert.True(t, got)
+	assert.False(t, open)
+}
+
+func TestHubRetries(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener := mocks.NewListener(t)
+	listener.On("ListenEvents", mock.Anything, mock.Anything).Return(errors.New("connection lost")).Once()
+	listener.On("ListenEvents", mock.Anything, mock.Anything).Return(context.Canceled).Once().
+		Run(func(mock.Arguments) { cancel() })
+
+	done := make(chan struct{})
+	go func() {
+		events.New(slog.New(slog.NewTextHandler(io.Discard, nil)), listener, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hub did not listen again after the connection failed")
+	}
+}
+
+func TestHubPolls(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listener := mocks.NewListener(t)
+	listener.On("ListenEvents", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { <-args.Get(0).(context.Context).Done() }).
+		Return(context.Canceled).Maybe()
+
+	hub := events.New(slog.New(slog.NewTextHandler(io.Discard, nil)), listener, time.Millisecond)
+
+	wake, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	go hub.Run(ctx)
+
+	// without notifications the subscriber is still woken up every poll interval
+	for i := 0; i < 3; i++ {
+		got, open := received(wake)
+		require.True(t, got)
+		require.True(t, open)
+	}
+}
+
+func TestHubClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	listening := make(chan struct{})
+
+	listener := mocks.NewListener(t)
+	listener.On("ListenEvents", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			close(listening)
+			<-args.Get(0).(context.Context).Done()
+		}).
+		Return(context.Canceled).Once()
+
+	hub := events.New(slog.New(slog.NewTextHandler(io.Discard, nil)), listener, time.Hour)
+
+	wake, unsubscribe := hub.Subscribe()
+	defer unsubscribe()
+
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+	<-listening
+
+	hub.Close()
+
+	// the subscription ends, as if the hub stopped, so that streams return before the server shuts down
+	for {
+		got, open := received(wake)
+		require.True(t, got)
+		if !open {
+			break
+		}
+	}
+
+	select {
+	case <-done:
+		t.Fatal("hub stopped listening when it was closed")
+	default:
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hub did not stop after it was closed")
+	}
+}
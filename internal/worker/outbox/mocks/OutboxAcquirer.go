@@ -0,0 +1,60 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+
+	mock "github.com/stretchr/testify/mock"
+
+	postgres "effective_mobile_test/internal/storage/postgres"
+)
+
+// OutboxAcquirer is an autogenerated mock type for the OutboxAcquirer type
+type OutboxAcquirer struct {
+	mock.Mock
+}
+
+// AcquireOutbox provides a mock function with given fields: ctx
+func (_m *OutboxAcquirer) AcquireOutbox(ctx context.Context) (*postgres.Outbox, error) {
+	ret := _m.Called(ctx)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AcquireOutbox")
+	}
+
+	var r0 *postgres.Outbox
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) (*postgres.Outbox, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) *postgres.Outbox); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*postgres.Outbox)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// NewOutboxAcquirer creates a new instance of OutboxAcquirer. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewOutboxAcquirer(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *OutboxAcquirer {
+	mock := &OutboxAcquirer{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,170 @@
+package outbox
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/publisher"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+// batchSize is the number of messages read from the outbox at once.
+const batchSize = 100
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=OutboxAcquirer
+type OutboxAcquirer interface {
+	AcquireOutbox(ctx context.Context) (*postgres.Outbox, error)
+}
+
+// Queue is the outbox held by the relay, see postgres.Outbox.
+//
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Queue
+type Queue interface {
+	Messages(ctx context.Context, limit int) ([]postgres.OutboxMessage, error)
+	Ack(ctx context.Context, messageIDs []int64) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Subscriber
+type Subscriber interface {
+	Subscribe() (<-chan struct{}, func())
+}
+
+// Relay publishes the messages of the outbox to a broker, one at a time and in the order
+// they were queued, and removes them once the broker has accepted them. A message is
+// published again if the relay stops before removing it, so consumers get every event
+// at least once and the events of a car in order.
+// Only one replica relays at a time; the others take over when it stops.
+type Relay struct {
+	log        *slog.Logger
+	store      OutboxAcquirer
+	publisher  publisher.Publisher
+	subscriber Subscriber
+}
+
+func New(log *slog.Logger, store OutboxAcquirer, publisher publisher.Publisher, subscriber Subscriber) *Relay {
+	return &Relay{
+		log:        log.With(slog.String("component", "worker/outbox")),
+		store:      store,
+		publisher:  publisher,
+		subscriber: subscriber,
+	}
+}
+
+// Run relays messages until ctx is done or the events hub stops.
+// After a failure it retries on the next wake-up of the events hub.
+func (r *Relay) Run(ctx context.Context) {
+	wake, unsubscribe := r.subscriber.Subscribe()
+	defer unsubscribe()
+
+	r.log.Info("outbox relay started")
+
+	for {
+		if err := r.relay(ctx, wake); err != nil && ctx.Err() == nil {
+			r.log.Error("failed to relay outbox", sl.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			r.log.Info("outbox relay stopped")
+
+			return
+		case _, ok := <-wake:
+			if !ok {
+				r.log.Info("outbox relay stopped")
+
+				return
+			}
+		}
+	}
+}
+
+// relay holds the outbox and publishes its messages whenever events are published,
+// until ctx is done, the events hub stops or publishing fails.
+func (r *Relay) relay(ctx context.Context, wake <-chan struct{}) error {
+	outbox, err := r.store.AcquireOutbox(ctx)
+	if errors.Is(err, storage.ErrOutboxBusy) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := outbox.Release(); err != nil {
+			r.log.Error("failed to release outbox", sl.Err(err))
+		}
+	}()
+
+	r.log.Debug("outbox acquired")
+
+	for {
+		if err = r.drain(ctx, outbox); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-wake:
+			if !ok {
+				return nil
+			}
+		}
+	}
+}
+
+// drain publishes the messages of the outbox until none are left.
+func (r *Relay) drain(ctx context.Context, outbox Queue) error {
+	for {
+		messages, err := outbox.Messages(ctx, batchSize)
+		if err != nil {
+			return err
+		}
+
+		published := make([]int64, 0, len(messages))
+		for _, m := range messages {
+			if err = r.publish(ctx, m); err != nil {
+				break
+			}
+			published = append(published, m.ID)
+		}
+
+		// messages published before a failure aren't published again
+		if ackErr := outbox.Ack(context.WithoutCancel(ctx), published); ackErr != nil {
+			return ackErr
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(published) > 0 {
+			r.log.Debug("outbox messages published", slog.Int("count", len(published)))
+		}
+
+		if len(messages) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (r *Relay) publish(ctx context.Context, m postgres.OutboxMessage) error {
+	payload, err := json.Marshal(m.Event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %d: %w", m.Event.ID, err)
+	}
+
+	err = r.publisher.Publish(ctx, publisher.Message{
+		ID:      m.Event.ID,
+		Subject: "catalog." + m.Event.Type,
+		Key:     fmt.Sprintf("%s.%d", m.Event.Entity, m.Event.EntityID),
+		Payload: payload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish event %d: %w", m.Event.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package outbox
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/publisher"
+	pubmocks "effective_mobile_test/internal/lib/publisher/mocks"
+	"effective_mobile_test/internal/storage"
+	"effective_mobile_test/internal/storage/postgres"
+	"effective_mobile_test/internal/worker/outbox/mocks"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func message(id int64, eventType string, entityID int) postgres.OutboxMessage {
+	return postgres.OutboxMessage{ID: id, Event: postgres.Event{
+		ID: id + 100, Type: eventType, Entity: postgres.EntityCar, EntityID: entityID,
+		Data: json.RawMessage(`{"mark":"Lada"}`), CreatedAt: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}}
+}
+
+func newRelay(t *testing.T, pub publisher.Publisher) *Relay {
+	return New(slog.New(slog.NewTextHandler(io.Discard, nil)), mocks.NewOutboxAcquirer(t), pub, mocks.NewSubscriber(t))
+}
+
+func TestDrain(t *testing.T) {
+	t.Run("messages are published in order and acked", func(t *testing.T) {
+		messages := []postgres.OutboxMessage{message(1, "car.created", 5), message(2, "car.updated", 5)}
+
+		queue := mocks.NewQueue(t)
+		queue.On("Messages", mock.Anything, batchSize).Return(messages, nil).Once()
+		queue.On("Ack", mock.Anything, []int64{1, 2}).Return(nil).Once()
+
+		pub := pubmocks.NewPublisher(t)
+		for _, m := range messages {
+			payload, err := json.Marshal(m.Event)
+			require.NoError(t, err)
+
+			pub.On("Publish", mock.Anything, publisher.Message{
+				ID: m.Event.ID, Subject: "catalog." + m.Event.Type, Key: "car.5", Payload: payload,
+			}).Return(nil).Once()
+		}
+
+		require.NoError(t, newRelay(t, pub).drain(context.Background(), queue))
+	})
+
+	t.Run("a full batch is followed by the next one", func(t *testing.T) {
+		first := make([]postgres.OutboxMessage, batchSize)
+		ids := make([]int64, batchSize)
+		for i := range first {
+			first[i] = message(int64(i+1), "car.updated", i+1)
+			ids[i] = int64(i + 1)
+		}
+
+		queue := mocks.NewQueue(t)
+		queue.On("Messages", mock.Anything, batchSize).Return(first, nil).Once()
+		queue.On("Ack", mock.Anything, ids).Return(nil).Once()
+		queue.On("Messages", mock.Anything, batchSize).Return([]postgres.OutboxMessage(nil), nil).Once()
+		queue.On("Ack", mock.Anything, []int64{}).Return(nil).Once()
+
+		pub := pubmocks.NewPublisher(t)
+		pub.On("Publish", mock.Anything, mock.Anything).Return(nil).Times(batchSize)
+
+		require.NoError(t, newRelay(t, pub).drain(context.Background(), queue))
+	})
+
+	t.Run("messages published before a failure are acked", func(t *testing.T) {
+		messages := []postgres.OutboxMessage{message(1, "car.created", 5), message(2, "car.updated", 5), message(3, "car.deleted", 5)}
+
+		queue := mocks.NewQueue(t)
+		queue.On("Messages", mock.Anything, batchSize).Return(messages, nil).Once()
+		queue.On("Ack", mock.Anything, []int64{1}).Return(nil).Once()
+
+		pub := pubmocks.NewPublisher(t)
+		pub.On("Publish", mock.Anything, mock.MatchedBy(func(m publisher.Message) bool { return m.ID == 101 })).Return(nil).Once()
+		// the message and the ones after it are kept in the outbox, so the events of the car stay in order
+		pub.On("Publish", mock.Anything, mock.MatchedBy(func(m publisher.Message) bool { return m.ID == 102 })).
+			Return(errors.New("no ack")).Once()
+
+		err := newRelay(t, pub).drain(context.Background(), queue)
+		assert.ErrorContains(t, err, "failed to publish event 102")
+	})
+}
+
+func TestRun(t *testing.T) {
+	t.Run("another replica holds the outbox", func(t *testing.T) {
+		wake := make(chan struct{})
+
+		store := mocks.NewOutboxAcquirer(t)
+		store.On("AcquireOutbox", mock.Anything).Return(nil, fmt.Errorf("acquire: %w", storage.ErrOutboxBusy)).Once().
+			Run(func(mock.Arguments) { close(wake) })
+
+		subscriber := mocks.NewSubscriber(t)
+		subscriber.On("Subscribe").Return((<-chan struct{})(wake), func() {}).Once()
+
+		done := make(chan struct{})
+		go func() {
+			New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, pubmocks.NewPublisher(t), subscriber).Run(context.Background())
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("relay did not stop after the events hub stopped")
+		}
+	})
+}
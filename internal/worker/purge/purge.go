@@ -0,0 +1,93 @@
+package purge
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/actor"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/tenant"
+	"log/slog"
+	"time"
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Purger
+type Purger interface {
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, int64, error)
+	PurgeIdempotencyKeys(ctx context.Context) (int64, error)
+}
+
+// Worker periodically purges cars and owners that were soft-deleted longer than retention ago,
+// and expired idempotency keys.
+type Worker struct {
+	log       *slog.Logger
+	purger    Purger
+	retention time.Duration
+	interval  time.Duration
+}
+
+func New(log *slog.Logger, purger Purger, retention, interval time.Duration) *Worker {
+	return &Worker{
+		log:       log.With(slog.String("component", "worker/purge")),
+		purger:    purger,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run purges on every tick until ctx is done. Purges cover every tenant and are attributed to actor.System.
+func (w *Worker) Run(ctx context.Context) {
+	ctx = actor.WithActor(ctx, actor.System)
+	ctx = tenant.WithTenant(ctx, tenant.All)
+
+	w.log.Info("purge worker started",
+		slog.String("retention", w.retention.String()),
+		slog.String("interval", w.interval.String()),
+	)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.log.Info("purge worker stopped")
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) purge(ctx context.Context) {
+	w.purgeDeleted(ctx)
+	w.purgeIdempotencyKeys(ctx)
+}
+
+func (w *Worker) purgeDeleted(ctx context.Context) {
+	before := time.Now().Add(-w.retention)
+
+	cars, owners, err := w.purger.PurgeDeleted(ctx, before)
+	if err != nil {
+		w.log.Error("failed to purge deleted rows", sl.Err(err))
+
+		return
+	}
+
+	w.log.Debug("deleted rows purged",
+		slog.Int64("cars", cars),
+		slog.Int64("owners", owners),
+		slog.Time("before", before),
+	)
+}
+
+func (w *Worker) purgeIdempotencyKeys(ctx context.Context) {
+	keys, err := w.purger.PurgeIdempotencyKeys(ctx)
+	if err != nil {
+		w.log.Error("failed to purge idempotency keys", sl.Err(err))
+
+		return
+	}
+
+	w.log.Debug("expired idempotency keys purged", slog.Int64("keys", keys))
+}
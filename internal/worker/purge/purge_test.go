@@ -0,0 +1,70 @@
+package purge_test
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/actor"
+	"effective_mobile_test/internal/worker/purge"
+	"effective_mobile_test/internal/worker/purge/mocks"
+	"errors"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	const retention = 720 * time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	purger := mocks.NewPurger(t)
+	// a failed purge is retried on the next tick
+	purger.On("PurgeDeleted", mock.Anything, mock.Anything).Return(int64(0), int64(0), errors.New("connection lost")).Once()
+	purger.On("PurgeDeleted", mock.MatchedBy(func(ctx context.Context) bool {
+		return actor.FromContext(ctx) == actor.System
+	}), mock.MatchedBy(func(before time.Time) bool {
+		return time.Since(before.Add(retention)) < time.Minute
+	})).Return(int64(2), int64(1), nil).Once().Run(func(mock.Arguments) { cancel() })
+	// expired idempotency keys are purged on every tick, even when purging deleted rows failed
+	purger.On("PurgeIdempotencyKeys", mock.Anything).Return(int64(3), nil).Twice()
+	// a tick may race with ctx being done
+	purger.On("PurgeDeleted", mock.Anything, mock.Anything).Return(int64(0), int64(0), nil).Maybe()
+	purger.On("PurgeIdempotencyKeys", mock.Anything).Return(int64(0), nil).Maybe()
+
+	done := make(chan struct{})
+	go func() {
+		purge.New(slog.New(slog.NewTextHandler(io.Discard, nil)), purger, retention, time.Millisecond).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not stop after ctx was done")
+	}
+}
+
+func TestRunPurgesOnStart(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	purger := mocks.NewPurger(t)
+	purger.On("PurgeDeleted", mock.Anything, mock.Anything).Return(int64(0), int64(0), nil).Once().
+		Run(func(mock.Arguments) { cancel() })
+	purger.On("PurgeIdempotencyKeys", mock.Anything).Return(int64(0), nil).Once()
+
+	done := make(chan struct{})
+	go func() {
+		// the first tick is an hour away, so only the purge on start can stop the worker in time
+		purge.New(slog.New(slog.NewTextHandler(io.Discard, nil)), purger, time.Hour, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker did not purge on start")
+	}
+}
@@ -0,0 +1,79 @@
+// Code generated by mockery v2.53.3. DO NOT EDIT.
+
+package mocks
+
+import (
+	context "context"
+	postgres "effective_mobile_test/internal/storage/postgres"
+
+	mock "github.com/stretchr/testify/mock"
+
+	time "time"
+)
+
+// DeliveryStore is an autogenerated mock type for the DeliveryStore type
+type DeliveryStore struct {
+	mock.Mock
+}
+
+// ClaimWebhookDeliveries provides a mock function with given fields: ctx, limit, lease
+func (_m *DeliveryStore) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]postgres.DueDelivery, error) {
+	ret := _m.Called(ctx, limit, lease)
+
+	if len(ret) == 0 {
+		panic("no return value specified for ClaimWebhookDeliveries")
+	}
+
+	var r0 []postgres.DueDelivery
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) ([]postgres.DueDelivery, error)); ok {
+		return rf(ctx, limit, lease)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int, time.Duration) []postgres.DueDelivery); ok {
+		r0 = rf(ctx, limit, lease)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]postgres.DueDelivery)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int, time.Duration) error); ok {
+		r1 = rf(ctx, limit, lease)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordDeliveryAttempt provides a mock function with given fields: ctx, deliveryID, attempt
+func (_m *DeliveryStore) RecordDeliveryAttempt(ctx context.Context, deliveryID int64, attempt postgres.DeliveryAttempt) error {
+	ret := _m.Called(ctx, deliveryID, attempt)
+
+	if len(ret) == 0 {
+		panic("no return value specified for RecordDeliveryAttempt")
+	}
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, int64, postgres.DeliveryAttempt) error); ok {
+		r0 = rf(ctx, deliveryID, attempt)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// NewDeliveryStore creates a new instance of DeliveryStore. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
+// The first argument is typically a *testing.T value.
+func NewDeliveryStore(t interface {
+	mock.TestingT
+	Cleanup(func())
+}) *DeliveryStore {
+	mock := &DeliveryStore{}
+	mock.Mock.Test(t)
+
+	t.Cleanup(func() { mock.AssertExpectations(t) })
+
+	return mock
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"effective_mobile_test/internal/lib/logger/sl"
+	"effective_mobile_test/internal/lib/webhook"
+	"effective_mobile_test/internal/storage/postgres"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// batchSize is the number of deliveries claimed at once.
+	batchSize = 50
+	// concurrency is the number of deliveries sent in parallel.
+	concurrency = 8
+	// maxAttempts is the number of failed attempts after which a delivery is dead.
+	maxAttempts = 10
+	minBackoff  = 10 * time.Second
+	maxBackoff  = time.Hour
+	// maxResponseSize is the part of a response body read before the connection is reused.
+	maxResponseSize = 64 << 10
+)
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=DeliveryStore
+type DeliveryStore interface {
+	ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]postgres.DueDelivery, error)
+	RecordDeliveryAttempt(ctx context.Context, deliveryID int64, attempt postgres.DeliveryAttempt) error
+}
+
+//go:generate go run github.com/vektra/mockery/v2@v2.42.1 --name=Subscriber
+type Subscriber interface {
+	Subscribe() (<-chan struct{}, func())
+}
+
+// Worker sends the queued webhook deliveries as signed POST requests, retrying failed ones
+// with exponential backoff until they are delivered or maxAttempts fail.
+// It wakes up whenever events are published and on every poll of the events hub.
+type Worker struct {
+	log        *slog.Logger
+	store      DeliveryStore
+	subscriber Subscriber
+	client     *http.Client
+	timeout    time.Duration
+}
+
+func New(log *slog.Logger, store DeliveryStore, subscriber Subscriber, timeout time.Duration) *Worker {
+	return &Worker{
+		log:        log.With(slog.String("component", "worker/webhook")),
+		store:      store,
+		subscriber: subscriber,
+		client:     webhook.Client(timeout),
+		timeout:    timeout,
+	}
+}
+
+// Run sends deliveries until ctx is done or the events hub stops.
+func (w *Worker) Run(ctx context.Context) {
+	wake, unsubscribe := w.subscriber.Subscribe()
+	defer unsubscribe()
+
+	w.log.Info("webhook worker started", slog.String("timeout", w.timeout.String()))
+
+	for {
+		w.dispatch(ctx)
+
+		select {
+		case <-ctx.Done():
+			w.log.Info("webhook worker stopped")
+
+			return
+		case _, ok := <-wake:
+			if !ok {
+				w.log.Info("webhook worker stopped")
+
+				return
+			}
+		}
+	}
+}
+
+// dispatch sends the due deliveries until none are left.
+func (w *Worker) dispatch(ctx context.Context) {
+	for ctx.Err() == nil {
+		// a claimed delivery isn't attempted by others until it is sent or its lease runs out
+		deliveries, err := w.store.ClaimWebhookDeliveries(ctx, batchSize, lease(w.timeout))
+		if err != nil {
+			w.log.Error("failed to claim webhook deliveries", sl.Err(err))
+
+			return
+		}
+
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		for _, d := range deliveries {
+			sem <- struct{}{}
+			wg.Add(1)
+			go func(d postgres.DueDelivery) {
+				defer func() { <-sem; wg.Done() }()
+
+				w.deliver(ctx, d)
+			}(d)
+		}
+		wg.Wait()
+
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, d postgres.DueDelivery) {
+	log := w.log.With(
+		slog.Int64("delivery_id", d.ID),
+		slog.Int("webhook_id", d.WebhookID),
+		slog.Int64("event_id", d.Event.ID),
+	)
+
+	statusCode, err := w.send(ctx, d)
+
+	attempt := postgres.DeliveryAttempt{StatusCode: statusCode, Status: postgres.DeliveryDelivered}
+	if err != nil {
+		attempt.Error = err.Error()
+		attempt.Status = postgres.DeliveryPending
+		attempt.RetryAt = time.Now().Add(backoff(d.Attempts + 1))
+		if d.Attempts+1 >= maxAttempts {
+			attempt.Status = postgres.DeliveryDead
+		}
+	}
+
+	// the outcome is kept even if the worker is stopping
+	if err := w.store.RecordDeliveryAttempt(context.WithoutCancel(ctx), d.ID, attempt); err != nil {
+		log.Error("failed to record webhook delivery attempt", sl.Err(err))
+
+		return
+	}
+
+	switch attempt.Status {
+	case postgres.DeliveryDelivered:
+		log.Debug("webhook delivered", slog.Int("status_code", statusCode))
+	case postgres.DeliveryDead:
+		log.Warn("webhook delivery is dead", sl.Err(err), slog.Int("attempts", d.Attempts+1))
+	default:
+		log.Info("webhook delivery failed", sl.Err(err), slog.Time("retry_at", attempt.RetryAt))
+	}
+}
+
+// send posts the event and returns the response status code, which is zero if there is no response.
+func (w *Worker) send(ctx context.Context, d postgres.DueDelivery) (int, error) {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(webhook.HeaderID, strconv.Itoa(d.WebhookID))
+	req.Header.Set(webhook.HeaderDelivery, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(webhook.HeaderEvent, d.Event.Type)
+	req.Header.Set(webhook.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(webhook.HeaderSignature, webhook.Sign(d.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// lease returns how long a claimed batch is kept from other workers: enough to send the whole batch
+// concurrency deliveries at a time, each taking up to timeout, with one more timeout to spare.
+func lease(timeout time.Duration) time.Duration {
+	rounds := (batchSize + concurrency - 1) / concurrency
+
+	return time.Duration(rounds+1) * timeout
+}
+
+// backoff returns the delay before the attempt following the given number of failed ones.
+func backoff(failed int) time.Duration {
+	delay := minBackoff
+	for i := 1; i < failed && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxBackoff)
+}
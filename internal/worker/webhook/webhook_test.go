@@ -0,0 +1,150 @@
+package webhook
+
+import (
+	"context"
+	"effective_mobile_test/internal/lib/webhook"
+	"effective_mobile_test/internal/storage/postgres"
+	"effective_mobile_test/internal/worker/webhook/mocks"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const timeout = time.Second
+
+func TestDispatch(t *testing.T) {
+	cases := []struct {
+		name string
+		// status is the response of the receiver, redirect makes it redirect instead
+		status   int
+		redirect bool
+		attempts int
+
+		wantCode   int
+		wantStatus string
+		wantError  bool
+	}{
+		{name: "delivered", status: http.StatusNoContent, wantCode: http.StatusNoContent, wantStatus: postgres.DeliveryDelivered},
+		{name: "server error is retried", status: http.StatusBadGateway, wantCode: http.StatusBadGateway, wantStatus: postgres.DeliveryPending, wantError: true},
+		{name: "client error is retried", status: http.StatusNotFound, wantCode: http.StatusNotFound, wantStatus: postgres.DeliveryPending, wantError: true},
+		{name: "last attempt is dead", status: http.StatusInternalServerError, attempts: maxAttempts - 1, wantCode: http.StatusInternalServerError, wantStatus: postgres.DeliveryDead, wantError: true},
+		{name: "redirect is not followed", redirect: true, wantCode: http.StatusFound, wantStatus: postgres.DeliveryPending, wantError: true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			event := postgres.Event{ID: 42, Tenant: "acme", Type: "car.updated", Entity: "car", EntityID: 7}
+
+			var received int
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/elsewhere" {
+					t.Error("redirect was followed")
+
+					return
+				}
+				received++
+
+				body, err := io.ReadAll(r.Body)
+				assert.NoError(t, err)
+
+				timestamp, err := strconv.ParseInt(r.Header.Get(webhook.HeaderTimestamp), 10, 64)
+				assert.NoError(t, err)
+				assert.True(t, webhook.Verify("secret", timestamp, body, r.Header.Get(webhook.HeaderSignature)))
+				assert.Equal(t, "3", r.Header.Get(webhook.HeaderID))
+				assert.Equal(t, "11", r.Header.Get(webhook.HeaderDelivery))
+				assert.Equal(t, "car.updated", r.Header.Get(webhook.HeaderEvent))
+
+				var got postgres.Event
+				assert.NoError(t, json.Unmarshal(body, &got))
+				assert.Equal(t, event.ID, got.ID)
+				// receivers can tell the tenant of the event
+				assert.Equal(t, "acme", got.Tenant)
+
+				if tc.redirect {
+					http.Redirect(w, r, "/elsewhere", http.StatusFound)
+
+					return
+				}
+				w.WriteHeader(tc.status)
+			}))
+			defer srv.Close()
+
+			store := mocks.NewDeliveryStore(t)
+			store.On("ClaimWebhookDeliveries", mock.Anything, batchSize, lease(timeout)).
+				Return([]postgres.DueDelivery{{
+					ID:        11,
+					WebhookID: 3,
+					URL:       srv.URL + "/hook",
+					Secret:    "secret",
+					Attempts:  tc.attempts,
+					Event:     event,
+				}}, nil).Once()
+			store.On("RecordDeliveryAttempt", mock.Anything, int64(11), mock.MatchedBy(func(a postgres.DeliveryAttempt) bool {
+				return a.StatusCode == tc.wantCode && a.Status == tc.wantStatus && (a.Error != "") == tc.wantError &&
+					(a.Status != postgres.DeliveryPending || a.RetryAt.After(time.Now()))
+			})).Return(nil).Once()
+
+			w := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, timeout)
+			// the receiver listens on the loopback interface, which the client refuses
+			w.client.Transport = http.DefaultTransport
+
+			w.dispatch(context.Background())
+
+			assert.Equal(t, 1, received)
+		})
+	}
+}
+
+func TestDispatchClaimsUntilBatchIsShort(t *testing.T) {
+	store := mocks.NewDeliveryStore(t)
+
+	full := make([]postgres.DueDelivery, batchSize)
+	for i := range full {
+		// unreachable, so that every attempt fails at once
+		full[i] = postgres.DueDelivery{ID: int64(i + 1), URL: "http://127.0.0.1:0/hook"}
+	}
+	store.On("ClaimWebhookDeliveries", mock.Anything, batchSize, lease(timeout)).Return(full, nil).Once()
+	store.On("ClaimWebhookDeliveries", mock.Anything, batchSize, lease(timeout)).Return(nil, nil).Once()
+	store.On("RecordDeliveryAttempt", mock.Anything, mock.Anything, mock.MatchedBy(func(a postgres.DeliveryAttempt) bool {
+		return a.Status == postgres.DeliveryPending && a.StatusCode == 0
+	})).Return(nil).Times(batchSize)
+
+	w := New(slog.New(slog.NewTextHandler(io.Discard, nil)), store, nil, timeout)
+
+	w.dispatch(context.Background())
+}
+
+func TestLease(t *testing.T) {
+	// a batch is sent in ceil(batchSize/concurrency) rounds of deliveries taking up to timeout each
+	rounds := (batchSize + concurrency - 1) / concurrency
+
+	assert.Equal(t, 80*time.Second, lease(10*time.Second))
+	assert.Greater(t, lease(timeout), time.Duration(rounds)*timeout)
+}
+
+func TestBackoff(t *testing.T) {
+	cases := []struct {
+		failed int
+		want   time.Duration
+	}{
+		{failed: 1, want: 10 * time.Second},
+		{failed: 2, want: 20 * time.Second},
+		{failed: 3, want: 40 * time.Second},
+		{failed: 9, want: 2560 * time.Second},
+		{failed: 10, want: time.Hour},
+		{failed: 50, want: time.Hour},
+	}
+
+	for _, tc := range cases {
+		t.Run(strconv.Itoa(tc.failed), func(t *testing.T) {
+			assert.Equal(t, tc.want, backoff(tc.failed))
+		})
+	}
+}